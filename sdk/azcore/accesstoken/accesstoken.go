@@ -0,0 +1,150 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package accesstoken
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// Claims are the claims of an access token.
+type Claims struct {
+	// AppID identifies the application that requested the token. It's the value of the "appid"
+	// claim in v1.0 tokens and the "azp" claim in v2.0 tokens.
+	AppID string
+
+	// Audience is the "aud" claim. When the token has multiple audiences, this is the first.
+	Audience string
+
+	// ExpiresOn is the "exp" claim.
+	ExpiresOn time.Time
+
+	// IdentityType is the "idtyp" claim, "app" or "user", when the token has it.
+	IdentityType string
+
+	// IssuedAt is the "iat" claim.
+	IssuedAt time.Time
+
+	// Issuer is the "iss" claim.
+	Issuer string
+
+	// NotBefore is the "nbf" claim.
+	NotBefore time.Time
+
+	// ObjectID is the "oid" claim, the object ID of the authenticated principal in its tenant.
+	ObjectID string
+
+	// Roles is the "roles" claim.
+	Roles []string
+
+	// Scopes is the space-separated "scp" claim, split into individual scopes.
+	Scopes []string
+
+	// Subject is the "sub" claim.
+	Subject string
+
+	// TenantID is the "tid" claim.
+	TenantID string
+
+	// UPN is the user principal name from the "upn" claim or, in v2.0 tokens lacking
+	// that claim, the "preferred_username" claim.
+	UPN string
+
+	// Raw contains all the token's claims, as decoded by encoding/json.
+	Raw map[string]interface{}
+}
+
+// Parse returns the claims of an access token. It doesn't verify the token's signature.
+func Parse(tk azcore.AccessToken) (Claims, error) {
+	return ParseJWT(tk.Token)
+}
+
+// ParseJWT returns the claims of a JWT. It doesn't verify the token's signature.
+func ParseJWT(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.New("token isn't a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return Claims{}, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+	raw := map[string]interface{}{}
+	d := json.NewDecoder(strings.NewReader(string(payload)))
+	d.UseNumber()
+	if err = d.Decode(&raw); err != nil {
+		return Claims{}, fmt.Errorf("failed to unmarshal token payload: %w", err)
+	}
+	c := Claims{
+		AppID:        stringClaim(raw, "appid", "azp"),
+		Audience:     stringClaim(raw, "aud"),
+		ExpiresOn:    timeClaim(raw, "exp"),
+		IdentityType: stringClaim(raw, "idtyp"),
+		IssuedAt:     timeClaim(raw, "iat"),
+		Issuer:       stringClaim(raw, "iss"),
+		NotBefore:    timeClaim(raw, "nbf"),
+		ObjectID:     stringClaim(raw, "oid"),
+		Raw:          raw,
+		Roles:        stringsClaim(raw, "roles"),
+		Subject:      stringClaim(raw, "sub"),
+		TenantID:     stringClaim(raw, "tid"),
+		UPN:          stringClaim(raw, "upn", "preferred_username"),
+	}
+	if scp := stringClaim(raw, "scp"); scp != "" {
+		c.Scopes = strings.Fields(scp)
+	}
+	return c, nil
+}
+
+// stringClaim returns the value of the first of names present in claims. When that value is an
+// array, stringClaim returns its first element.
+func stringClaim(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			return v
+		case []interface{}:
+			if len(v) > 0 {
+				if s, ok := v[0].(string); ok {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	var values []string
+	switch v := claims[name].(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// timeClaim returns the value of a NumericDate claim, or the zero time when the token lacks the claim
+func timeClaim(claims map[string]interface{}, name string) time.Time {
+	if n, ok := claims[name].(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			return time.Unix(int64(f), 0).UTC()
+		}
+	}
+	return time.Time{}
+}
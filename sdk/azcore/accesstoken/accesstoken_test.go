@@ -0,0 +1,81 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package accesstoken
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/stretchr/testify/require"
+)
+
+// newJWT returns an unsigned JWT having the specified payload
+func newJWT(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestParse(t *testing.T) {
+	tk := azcore.AccessToken{Token: newJWT(`{
+		"aud": "https://management.azure.com",
+		"iss": "https://sts.windows.net/tenant/",
+		"iat": 1672531200,
+		"nbf": 1672531200,
+		"exp": 1672534800,
+		"appid": "app",
+		"idtyp": "user",
+		"oid": "object",
+		"roles": ["Reader", "Writer"],
+		"scp": "user_impersonation  Files.Read",
+		"sub": "subject",
+		"tid": "tenant",
+		"upn": "user@contoso.com",
+		"custom": 42
+	}`)}
+	c, err := Parse(tk)
+	require.NoError(t, err)
+	require.Equal(t, "app", c.AppID)
+	require.Equal(t, "https://management.azure.com", c.Audience)
+	require.Equal(t, time.Unix(1672534800, 0).UTC(), c.ExpiresOn)
+	require.Equal(t, "user", c.IdentityType)
+	require.Equal(t, time.Unix(1672531200, 0).UTC(), c.IssuedAt)
+	require.Equal(t, "https://sts.windows.net/tenant/", c.Issuer)
+	require.Equal(t, time.Unix(1672531200, 0).UTC(), c.NotBefore)
+	require.Equal(t, "object", c.ObjectID)
+	require.Equal(t, []string{"Reader", "Writer"}, c.Roles)
+	require.Equal(t, []string{"user_impersonation", "Files.Read"}, c.Scopes)
+	require.Equal(t, "subject", c.Subject)
+	require.Equal(t, "tenant", c.TenantID)
+	require.Equal(t, "user@contoso.com", c.UPN)
+	require.EqualValues(t, "42", c.Raw["custom"])
+}
+
+func TestParseJWTv2(t *testing.T) {
+	c, err := ParseJWT(newJWT(`{"aud":["api://a","api://b"],"azp":"app","preferred_username":"user@contoso.com","exp":1672534800.5}`))
+	require.NoError(t, err)
+	require.Equal(t, "app", c.AppID)
+	require.Equal(t, "api://a", c.Audience)
+	require.Equal(t, time.Unix(1672534800, 0).UTC(), c.ExpiresOn)
+	require.Equal(t, "user@contoso.com", c.UPN)
+	require.True(t, c.IssuedAt.IsZero())
+	require.Nil(t, c.Roles)
+	require.Nil(t, c.Scopes)
+}
+
+func TestParseJWTErrors(t *testing.T) {
+	for _, token := range []string{
+		"",
+		"not.a.jwt.token",
+		"header.!!!.signature",
+		newJWT("not JSON"),
+	} {
+		_, err := ParseJWT(token)
+		require.Error(t, err, token)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package accesstoken contains helpers for inspecting the claims of Azure Active Directory access tokens,
+// such as the tenant and object ID of the identity a client authenticated as. These helpers don't verify
+// token signatures. Use them only to inspect tokens an application acquired itself, never to authorize requests.
+package accesstoken
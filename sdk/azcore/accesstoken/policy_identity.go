@@ -0,0 +1,98 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package accesstoken
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	headerCorrelationRequestID = "x-ms-correlation-request-id"
+	headerRequestID            = "x-ms-request-id"
+)
+
+// Identity describes the identity that sent a request.
+type Identity struct {
+	// Claims of the access token that authorized the request.
+	Claims Claims
+
+	// CorrelationID is the value of the response's x-ms-correlation-request-id header, Azure Resource
+	// Manager's correlation ID. It's empty when the request failed without a response or the service
+	// doesn't return the header.
+	CorrelationID string
+
+	// RequestID is the value of the response's x-ms-request-id header, when present.
+	RequestID string
+
+	// Request is the request as sent.
+	Request *http.Request
+
+	// Response is the service's response. It's nil when the request failed without a response.
+	Response *http.Response
+}
+
+// IdentityPolicyOptions contains optional parameters for NewIdentityPolicy.
+type IdentityPolicyOptions struct {
+	// placeholder for future options
+}
+
+// NewIdentityPolicy creates a policy that calls report with the identity that sent each request. Add it to
+// a client's ClientOptions.PerRetryPolicies so that it runs after the client's authentication policy and
+// reports every attempt, including retries. report runs synchronously on the request's goroutine and must be
+// safe for concurrent use. The policy doesn't call report for requests lacking a bearer token. Pass nil for
+// options to accept defaults.
+func NewIdentityPolicy(report func(Identity), options *IdentityPolicyOptions) policy.Policy {
+	return &identityPolicy{mtx: &sync.Mutex{}, report: report}
+}
+
+type identityPolicy struct {
+	// cache the claims of the latest token because clients typically send many requests with the same token
+	claims Claims
+	mtx    *sync.Mutex
+	report func(Identity)
+	token  string
+}
+
+func (p *identityPolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+	if p.report == nil {
+		return resp, err
+	}
+	auth := req.Raw().Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return resp, err
+	}
+	claims, parseErr := p.parse(auth[7:])
+	if parseErr != nil {
+		return resp, err
+	}
+	id := Identity{Claims: claims, Request: req.Raw(), Response: resp}
+	if resp != nil {
+		id.CorrelationID = resp.Header.Get(headerCorrelationRequestID)
+		id.RequestID = resp.Header.Get(headerRequestID)
+	}
+	p.report(id)
+	return resp, err
+}
+
+func (p *identityPolicy) parse(token string) (Claims, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if token == p.token {
+		return p.claims, nil
+	}
+	c, err := ParseJWT(token)
+	if err != nil {
+		return Claims{}, err
+	}
+	p.claims, p.token = c, token
+	return c, nil
+}
@@ -0,0 +1,86 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package accesstoken
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// authPolicy sets a constant bearer token, standing in for runtime.BearerTokenPolicy
+type authPolicy string
+
+func (p authPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if p != "" {
+		req.Raw().Header.Set("Authorization", "Bearer "+string(p))
+	}
+	return req.Next()
+}
+
+func TestIdentityPolicy(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable), mock.WithHeader(headerCorrelationRequestID, "first"))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerCorrelationRequestID, "second"), mock.WithHeader(headerRequestID, "request"))
+
+	mtx := &sync.Mutex{}
+	reported := []Identity{}
+	report := func(id Identity) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		reported = append(reported, id)
+	}
+	token := newJWT(`{"tid":"tenant","oid":"object","appid":"app"}`)
+	pl := runtime.NewPipeline("test", "v0.1.0", runtime.PipelineOptions{PerRetry: []policy.Policy{authPolicy(token)}}, &policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{NewIdentityPolicy(report, nil)},
+		Retry:            policy.RetryOptions{RetryDelay: 1, StatusCodes: []int{http.StatusServiceUnavailable}},
+		Transport:        srv,
+	})
+	req, err := runtime.NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the policy should report each attempt
+	require.Len(t, reported, 2)
+	for i, correlationID := range []string{"first", "second"} {
+		id := reported[i]
+		require.Equal(t, correlationID, id.CorrelationID)
+		require.Equal(t, "tenant", id.Claims.TenantID)
+		require.Equal(t, "object", id.Claims.ObjectID)
+		require.Equal(t, "app", id.Claims.AppID)
+		require.NotNil(t, id.Request)
+		require.NotNil(t, id.Response)
+	}
+	require.Equal(t, "request", reported[1].RequestID)
+}
+
+func TestIdentityPolicyNoToken(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	for _, token := range []string{"", "not a JWT"} {
+		called := false
+		pl := runtime.NewPipeline("test", "v0.1.0", runtime.PipelineOptions{PerRetry: []policy.Policy{authPolicy(token)}}, &policy.ClientOptions{
+			PerRetryPolicies: []policy.Policy{NewIdentityPolicy(func(Identity) { called = true }, nil)},
+			Transport:        srv,
+		})
+		req, err := runtime.NewRequest(context.Background(), http.MethodGet, srv.URL())
+		require.NoError(t, err)
+		_, err = pl.Do(req)
+		require.NoError(t, err)
+		require.False(t, called)
+	}
+}
@@ -0,0 +1,249 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/internal/shared"
+)
+
+const (
+	actionsIDTokenRequestToken = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+	actionsIDTokenRequestURL   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	systemAccessToken          = "SYSTEM_ACCESSTOKEN"
+	systemOIDCRequestURI       = "SYSTEM_OIDCREQUESTURI"
+
+	azurePipelinesOIDCAPIVersion = "7.1"
+	// defaultFederationAudience is the audience Azure AD expects in federated identity assertions
+	defaultFederationAudience = "api://AzureADTokenExchange"
+
+	nameGitHubActions  = "GitHubActionsAssertionProvider"
+	nameAzurePipelines = "AzurePipelinesAssertionProvider"
+	nameHTTPAssertion  = "HTTPAssertionProvider"
+)
+
+// assertionRefreshMargin is how long before an assertion expires an AssertionProvider fetches a new one
+const assertionRefreshMargin = 5 * time.Minute
+
+// AssertionProvider fetches client assertions, such as OpenID Connect ID tokens issued by a CI system, for
+// [ClientAssertionCredential]. Pass its GetAssertion method to [NewClientAssertionCredential]. AssertionProvider
+// caches each assertion until shortly before it expires. It's safe for concurrent use.
+type AssertionProvider struct {
+	// fetch requests a new assertion
+	fetch     func(context.Context) (string, error)
+	assertion string
+	expires   time.Time
+	mtx       *sync.Mutex
+}
+
+// GitHubActionsAssertionProviderOptions contains optional parameters for the provider constructed by
+// NewGitHubActionsAssertionProvider.
+type GitHubActionsAssertionProviderOptions struct {
+	azcore.ClientOptions
+
+	// Audience of the requested ID token. Defaults to "api://AzureADTokenExchange", the audience Azure AD
+	// expects. This value must match the audience of the app registration's federated identity credential.
+	Audience string
+}
+
+// NewGitHubActionsAssertionProvider constructs an AssertionProvider that requests ID tokens from GitHub Actions.
+// It reads the request URL and token from the ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN
+// environment variables, which GitHub sets for jobs having the "id-token: write" permission. Pass nil for options
+// to accept defaults.
+func NewGitHubActionsAssertionProvider(options *GitHubActionsAssertionProviderOptions) (*AssertionProvider, error) {
+	if options == nil {
+		options = &GitHubActionsAssertionProviderOptions{}
+	}
+	requestURL, token := os.Getenv(actionsIDTokenRequestURL), os.Getenv(actionsIDTokenRequestToken)
+	if requestURL == "" || token == "" {
+		return nil, newCredentialUnavailableError(nameGitHubActions, fmt.Sprintf(
+			"%s and %s must be set. Ensure the job has the id-token: write permission", actionsIDTokenRequestURL, actionsIDTokenRequestToken,
+		))
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return nil, err
+	}
+	audience := options.Audience
+	if audience == "" {
+		audience = defaultFederationAudience
+	}
+	q := u.Query()
+	q.Set("audience", audience)
+	u.RawQuery = q.Encode()
+	endpoint := u.String()
+	pl := runtime.NewPipeline(component, version, runtime.PipelineOptions{}, &options.ClientOptions)
+	fetch := func(ctx context.Context) (string, error) {
+		req, err := runtime.NewRequest(ctx, http.MethodGet, endpoint)
+		if err != nil {
+			return "", err
+		}
+		req.Raw().Header.Set("Authorization", "Bearer "+token)
+		return requestAssertion(pl, req, nameGitHubActions, "value")
+	}
+	return newAssertionProvider(fetch), nil
+}
+
+// AzurePipelinesAssertionProviderOptions contains optional parameters for the provider constructed by
+// NewAzurePipelinesAssertionProvider.
+type AzurePipelinesAssertionProviderOptions struct {
+	azcore.ClientOptions
+}
+
+// NewAzurePipelinesAssertionProvider constructs an AssertionProvider that requests ID tokens for an Azure Pipelines
+// service connection. serviceConnectionID identifies the service connection. The provider reads the OIDC request
+// URI and the job's access token from the SYSTEM_OIDCREQUESTURI and SYSTEM_ACCESSTOKEN environment variables.
+// Pipelines must map System.AccessToken into the job's environment explicitly. Pass nil for options to accept
+// defaults.
+func NewAzurePipelinesAssertionProvider(serviceConnectionID string, options *AzurePipelinesAssertionProviderOptions) (*AssertionProvider, error) {
+	if serviceConnectionID == "" {
+		return nil, errors.New("serviceConnectionID is required")
+	}
+	if options == nil {
+		options = &AzurePipelinesAssertionProviderOptions{}
+	}
+	requestURI, token := os.Getenv(systemOIDCRequestURI), os.Getenv(systemAccessToken)
+	if requestURI == "" || token == "" {
+		return nil, newCredentialUnavailableError(nameAzurePipelines, fmt.Sprintf("%s and %s must be set", systemOIDCRequestURI, systemAccessToken))
+	}
+	u, err := url.Parse(requestURI)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("api-version", azurePipelinesOIDCAPIVersion)
+	q.Set("serviceConnectionId", serviceConnectionID)
+	u.RawQuery = q.Encode()
+	endpoint := u.String()
+	pl := runtime.NewPipeline(component, version, runtime.PipelineOptions{}, &options.ClientOptions)
+	fetch := func(ctx context.Context) (string, error) {
+		req, err := runtime.NewRequest(ctx, http.MethodPost, endpoint)
+		if err != nil {
+			return "", err
+		}
+		req.Raw().Header.Set("Authorization", "Bearer "+token)
+		return requestAssertion(pl, req, nameAzurePipelines, "oidcToken")
+	}
+	return newAssertionProvider(fetch), nil
+}
+
+// HTTPAssertionProviderOptions contains optional parameters for the provider constructed by NewHTTPAssertionProvider.
+type HTTPAssertionProviderOptions struct {
+	azcore.ClientOptions
+
+	// Header contains headers to add to each request, for example an Authorization header.
+	Header http.Header
+
+	// Method is the HTTP method of each request. Defaults to GET.
+	Method string
+
+	// TokenProperty is the name of the property containing the assertion when the endpoint responds
+	// with a JSON object. When this field is empty, the provider uses the entire response body as the assertion.
+	TokenProperty string
+}
+
+// NewHTTPAssertionProvider constructs an AssertionProvider that requests assertions from an arbitrary HTTP endpoint,
+// for example a CI system not having a dedicated provider or a token-issuing sidecar. Pass nil for options to
+// accept defaults.
+func NewHTTPAssertionProvider(endpoint string, options *HTTPAssertionProviderOptions) (*AssertionProvider, error) {
+	if options == nil {
+		options = &HTTPAssertionProviderOptions{}
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	method := options.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	header, property := options.Header.Clone(), options.TokenProperty
+	pl := runtime.NewPipeline(component, version, runtime.PipelineOptions{}, &options.ClientOptions)
+	fetch := func(ctx context.Context) (string, error) {
+		req, err := runtime.NewRequest(ctx, method, endpoint)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range header {
+			req.Raw().Header[k] = v
+		}
+		return requestAssertion(pl, req, nameHTTPAssertion, property)
+	}
+	return newAssertionProvider(fetch), nil
+}
+
+func newAssertionProvider(fetch func(context.Context) (string, error)) *AssertionProvider {
+	return &AssertionProvider{fetch: fetch, mtx: &sync.Mutex{}}
+}
+
+// GetAssertion returns a cached assertion, or fetches a new one when there's no cached assertion
+// or the cached assertion expires soon.
+func (p *AssertionProvider) GetAssertion(ctx context.Context) (string, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.assertion != "" && time.Now().Add(assertionRefreshMargin).Before(p.expires) {
+		return p.assertion, nil
+	}
+	assertion, err := p.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.assertion = assertion
+	// assertions whose expiration time can't be determined aren't cached
+	p.expires, _ = jwtExpiration(assertion)
+	return assertion, nil
+}
+
+// requestAssertion sends req and returns the assertion from the response. When property is empty,
+// the assertion is the entire response body. Otherwise, it's the value of the named JSON property.
+func requestAssertion(pl runtime.Pipeline, req *policy.Request, name, property string) (string, error) {
+	resp, err := pl.Do(req)
+	if err != nil {
+		return "", newAuthenticationFailedError(name, err.Error(), nil)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return "", newAuthenticationFailedError(name, "failed to get an assertion", resp)
+	}
+	var assertion string
+	if property == "" {
+		b, err := runtime.Payload(resp)
+		if err != nil {
+			return "", err
+		}
+		assertion = strings.TrimSpace(string(b))
+	} else {
+		v := map[string]interface{}{}
+		if err := runtime.UnmarshalAsJSON(resp, &v); err != nil {
+			return "", newAuthenticationFailedError(name, err.Error(), resp)
+		}
+		assertion, _ = v[property].(string)
+	}
+	if assertion == "" {
+		return "", newAuthenticationFailedError(name, "response contained no assertion", resp)
+	}
+	return assertion, nil
+}
+
+// jwtExpiration returns the value of a JWT's "exp" claim. It doesn't validate the token.
+func jwtExpiration(token string) (time.Time, error) {
+	claims, err := shared.JWTClaims(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return shared.NumericDateClaim(claims, "exp")
+}
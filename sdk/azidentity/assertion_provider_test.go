@@ -0,0 +1,228 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// fakeJWT returns an unsigned JWT having the specified expiration time
+func fakeJWT(exp time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	payload := enc.EncodeToString([]byte(fmt.Sprintf(`{"aud":"%s","exp":%d}`, defaultFederationAudience, exp.Unix())))
+	return header + "." + payload + "."
+}
+
+func TestGitHubActionsAssertionProvider(t *testing.T) {
+	jwt := fakeJWT(time.Now().Add(time.Hour))
+	srv, close := mock.NewServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(
+		mock.WithPredicate(func(req *http.Request) bool {
+			if actual := req.Header.Get("Authorization"); actual != "Bearer request-token" {
+				t.Errorf(`unexpected Authorization header "%s"`, actual)
+			}
+			if actual := req.URL.Query().Get("audience"); actual != "custom-audience" {
+				t.Errorf(`unexpected audience "%s"`, actual)
+			}
+			if actual := req.URL.Query().Get("api-version"); actual != "2.0" {
+				t.Errorf("request URL's query should be preserved, got %q", req.URL.RawQuery)
+			}
+			return true
+		}),
+		mock.WithBody([]byte(fmt.Sprintf(`{"count":1,"value":"%s"}`, jwt))),
+	)
+	srv.AppendResponse(mock.WithStatusCode(http.StatusTeapot))
+	setEnvironmentVariables(t, map[string]string{
+		actionsIDTokenRequestToken: "request-token",
+		actionsIDTokenRequestURL:   srv.URL() + "/token?api-version=2.0",
+	})
+	p, err := NewGitHubActionsAssertionProvider(&GitHubActionsAssertionProviderOptions{
+		Audience:      "custom-audience",
+		ClientOptions: azcore.ClientOptions{Transport: srv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		// the second call should return the cached assertion
+		actual, err := p.GetAssertion(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != jwt {
+			t.Fatalf(`unexpected assertion "%s"`, actual)
+		}
+	}
+}
+
+func TestGitHubActionsAssertionProvider_MissingEnvironment(t *testing.T) {
+	setEnvironmentVariables(t, map[string]string{actionsIDTokenRequestToken: "", actionsIDTokenRequestURL: ""})
+	_, err := NewGitHubActionsAssertionProvider(nil)
+	var e *credentialUnavailableError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected credentialUnavailableError, got %v", err)
+	}
+}
+
+func TestAzurePipelinesAssertionProvider(t *testing.T) {
+	srv, close := mock.NewServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	validate := func(req *http.Request) bool {
+		if req.Method != http.MethodPost {
+			t.Errorf("unexpected method %s", req.Method)
+		}
+		if actual := req.Header.Get("Authorization"); actual != "Bearer system-token" {
+			t.Errorf(`unexpected Authorization header "%s"`, actual)
+		}
+		if actual := req.URL.Query().Get("serviceConnectionId"); actual != "connection" {
+			t.Errorf(`unexpected service connection "%s"`, actual)
+		}
+		if actual := req.URL.Query().Get("api-version"); actual != azurePipelinesOIDCAPIVersion {
+			t.Errorf(`unexpected API version "%s"`, actual)
+		}
+		return true
+	}
+	// these assertions expire too soon to cache, so the provider should request a new one each time
+	first, second := fakeJWT(time.Now().Add(time.Minute)), fakeJWT(time.Now().Add(2*time.Minute))
+	srv.AppendResponse(mock.WithPredicate(validate), mock.WithBody([]byte(fmt.Sprintf(`{"oidcToken":"%s"}`, first))))
+	srv.AppendResponse()
+	srv.AppendResponse(mock.WithPredicate(validate), mock.WithBody([]byte(fmt.Sprintf(`{"oidcToken":"%s"}`, second))))
+	srv.AppendResponse()
+	setEnvironmentVariables(t, map[string]string{
+		systemAccessToken:    "system-token",
+		systemOIDCRequestURI: srv.URL() + "/oidctoken",
+	})
+	p, err := NewAzurePipelinesAssertionProvider("connection", &AzurePipelinesAssertionProviderOptions{
+		ClientOptions: azcore.ClientOptions{Transport: srv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{first, second} {
+		actual, err := p.GetAssertion(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Fatalf(`expected "%s", got "%s"`, expected, actual)
+		}
+	}
+	if _, err = NewAzurePipelinesAssertionProvider("", nil); err == nil {
+		t.Fatal("expected an error for an empty service connection ID")
+	}
+}
+
+func TestHTTPAssertionProvider(t *testing.T) {
+	jwt := fakeJWT(time.Now().Add(time.Hour))
+	for _, test := range []struct {
+		body, property string
+	}{
+		{body: jwt + "\n"},
+		{body: fmt.Sprintf(`{"token":"%s"}`, jwt), property: "token"},
+	} {
+		t.Run(test.property, func(t *testing.T) {
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(
+				mock.WithPredicate(func(req *http.Request) bool {
+					if actual := req.Header.Get("X-Custom"); actual != "value" {
+						t.Errorf(`unexpected header value "%s"`, actual)
+					}
+					return true
+				}),
+				mock.WithBody([]byte(test.body)),
+			)
+			srv.AppendResponse()
+			p, err := NewHTTPAssertionProvider(srv.URL(), &HTTPAssertionProviderOptions{
+				ClientOptions: azcore.ClientOptions{Transport: srv},
+				Header:        http.Header{"X-Custom": []string{"value"}},
+				TokenProperty: test.property,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := p.GetAssertion(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != jwt {
+				t.Fatalf(`unexpected assertion "%s"`, actual)
+			}
+		})
+	}
+}
+
+func TestHTTPAssertionProvider_Errors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		response []mock.ResponseOption
+	}{
+		{name: "status", response: []mock.ResponseOption{mock.WithStatusCode(http.StatusUnauthorized)}},
+		{name: "missing property", response: []mock.ResponseOption{mock.WithBody([]byte(`{"other":"value"}`))}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(test.response...)
+			p, err := NewHTTPAssertionProvider(srv.URL(), &HTTPAssertionProviderOptions{
+				ClientOptions: policy.ClientOptions{Retry: policy.RetryOptions{MaxRetries: -1}, Transport: srv},
+				TokenProperty: "token",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.GetAssertion(context.Background())
+			var afe *AuthenticationFailedError
+			if !errors.As(err, &afe) {
+				t.Fatalf("expected AuthenticationFailedError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAssertionProvider_ClientAssertionCredential(t *testing.T) {
+	assertion := fakeJWT(time.Now().Add(time.Hour))
+	srv, close := mock.NewServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(instanceDiscoveryResponse))
+	srv.AppendResponse(mock.WithBody(tenantDiscoveryResponse))
+	// the credential requests an assertion after discovering the authority's endpoints
+	srv.AppendResponse(mock.WithBody([]byte(assertion)))
+	srv.AppendResponse(mock.WithPredicate(func(req *http.Request) bool {
+		if err := req.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if actual := req.PostForm.Get("client_assertion"); actual != assertion {
+			t.Errorf(`unexpected assertion "%s"`, actual)
+		}
+		return true
+	}), mock.WithBody(accessTokenRespSuccess))
+	srv.AppendResponse()
+	p, err := NewHTTPAssertionProvider(srv.URL(), &HTTPAssertionProviderOptions{ClientOptions: azcore.ClientOptions{Transport: srv}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := NewClientAssertionCredential(fakeTenantID, fakeClientID, p.GetAssertion, &ClientAssertionCredentialOptions{
+		ClientOptions: azcore.ClientOptions{Transport: srv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetTokenSuccess(t, cred)
+}
@@ -9,18 +9,27 @@ package azidentity
 import (
 	"context"
 	"crypto"
+	"crypto/sha1"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
 	"github.com/AzureAD/microsoft-authentication-library-for-go/apps/confidential"
 	"golang.org/x/crypto/pkcs12"
 )
 
 const credNameCert = "ClientCertificateCredential"
 
+// certificateFileRefresh is the longest time a credential created by NewClientCertificateCredentialFromFile
+// goes without rereading its certificate file, regardless of whether the file's modification time changed
+const certificateFileRefresh = 5 * time.Minute
+
 // ClientCertificateCredentialOptions contains optional parameters for ClientCertificateCredential.
 type ClientCertificateCredentialOptions struct {
 	azcore.ClientOptions
@@ -34,16 +43,63 @@ type ClientCertificateCredentialOptions struct {
 // ClientCertificateCredential authenticates a service principal with a certificate.
 type ClientCertificateCredential struct {
 	client confidentialClient
+	// file is non-nil when the credential loads its certificate from a file
+	file *certificateFile
+}
+
+// certificateFile reloads a credential's certificate when the file containing it changes
+type certificateFile struct {
+	clientID, tenantID string
+	mtx                *sync.Mutex
+	opts               ClientCertificateCredentialOptions
+	password           []byte
+	watcher            *fileWatcher
+	// err is the error from the most recent attempt to load the file. It's sticky, so that
+	// GetToken fails until the file contains a valid certificate again.
+	err error
 }
 
 // NewClientCertificateCredential constructs a ClientCertificateCredential. Pass nil for options to accept defaults.
 func NewClientCertificateCredential(tenantID string, clientID string, certs []*x509.Certificate, key crypto.PrivateKey, options *ClientCertificateCredentialOptions) (*ClientCertificateCredential, error) {
-	if len(certs) == 0 {
-		return nil, errors.New("at least one certificate is required")
+	if options == nil {
+		options = &ClientCertificateCredentialOptions{}
+	}
+	c, err := newCertificateClient(tenantID, clientID, certs, key, options)
+	if err != nil {
+		return nil, err
 	}
+	return &ClientCertificateCredential{client: c}, nil
+}
+
+// NewClientCertificateCredentialFromFile constructs a ClientCertificateCredential that loads its certificate and
+// private key from a file in PEM or PKCS12 format. Pass nil for password if the private key isn't encrypted. The
+// credential checks the file for changes before requesting a token and replaces its certificate when the file's
+// content changes, so it's suitable for certificates rotated on disk by tools such as cert-manager. When the new
+// content can't be parsed, GetToken returns an error until the file again contains a valid certificate. Pass nil
+// for options to accept defaults.
+func NewClientCertificateCredentialFromFile(tenantID, clientID, path string, password []byte, options *ClientCertificateCredentialOptions) (*ClientCertificateCredential, error) {
 	if options == nil {
 		options = &ClientCertificateCredentialOptions{}
 	}
+	f := certificateFile{
+		clientID: clientID,
+		mtx:      &sync.Mutex{},
+		opts:     *options,
+		password: password,
+		tenantID: tenantID,
+		watcher:  newFileWatcher(path, certificateFileRefresh),
+	}
+	c := ClientCertificateCredential{file: &f}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func newCertificateClient(tenantID, clientID string, certs []*x509.Certificate, key crypto.PrivateKey, options *ClientCertificateCredentialOptions) (confidentialClient, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("at least one certificate is required")
+	}
 	cred, err := confidential.NewCredFromCertChain(certs, key)
 	if err != nil {
 		return nil, err
@@ -52,11 +108,7 @@ func NewClientCertificateCredential(tenantID string, clientID string, certs []*x
 	if options.SendCertificateChain {
 		o = append(o, confidential.WithX5C())
 	}
-	c, err := getConfidentialClient(clientID, tenantID, cred, &options.ClientOptions, o...)
-	if err != nil {
-		return nil, err
-	}
-	return &ClientCertificateCredential{client: c}, nil
+	return getConfidentialClient(clientID, tenantID, cred, &options.ClientOptions, o...)
 }
 
 // GetToken requests an access token from Azure Active Directory. This method is called automatically by Azure SDK clients.
@@ -64,13 +116,23 @@ func (c *ClientCertificateCredential) GetToken(ctx context.Context, opts policy.
 	if len(opts.Scopes) == 0 {
 		return azcore.AccessToken{}, errors.New(credNameCert + ": GetToken() requires at least one scope")
 	}
-	ar, err := c.client.AcquireTokenSilent(ctx, opts.Scopes)
+	client := c.client
+	if c.file != nil {
+		c.file.mtx.Lock()
+		err := c.reload()
+		client = c.client
+		c.file.mtx.Unlock()
+		if err != nil {
+			return azcore.AccessToken{}, newAuthenticationFailedError(credNameCert, err.Error(), nil)
+		}
+	}
+	ar, err := client.AcquireTokenSilent(ctx, opts.Scopes)
 	if err == nil {
 		logGetTokenSuccess(c, opts)
 		return azcore.AccessToken{Token: ar.AccessToken, ExpiresOn: ar.ExpiresOn.UTC()}, err
 	}
 
-	ar, err = c.client.AcquireTokenByCredential(ctx, opts.Scopes)
+	ar, err = client.AcquireTokenByCredential(ctx, opts.Scopes)
 	if err != nil {
 		return azcore.AccessToken{}, newAuthenticationFailedErrorFromMSALError(credNameCert, err)
 	}
@@ -78,6 +140,42 @@ func (c *ClientCertificateCredential) GetToken(ctx context.Context, opts policy.
 	return azcore.AccessToken{Token: ar.AccessToken, ExpiresOn: ar.ExpiresOn.UTC()}, err
 }
 
+// reload replaces the credential's client when its certificate file has changed. Callers
+// other than the constructor must hold the file's lock.
+func (c *ClientCertificateCredential) reload() error {
+	content, changed, err := c.file.watcher.poll()
+	if err != nil {
+		log.Writef(EventAuthentication, "ERROR: %s failed to read %s: %v", credNameCert, c.file.watcher.path, err)
+		// the file may be briefly missing while it's replaced, in which case the current certificate
+		// remains valid until the watcher's next refresh
+		if c.client == nil || c.file.watcher.stale() {
+			return err
+		}
+		return c.file.err
+	}
+	if !changed {
+		return c.file.err
+	}
+	certs, key, err := ParseCertificates(content, c.file.password)
+	if err == nil {
+		var client confidentialClient
+		if client, err = newCertificateClient(c.file.tenantID, c.file.clientID, certs, key, &c.file.opts); err == nil {
+			c.client = client
+			c.file.err = nil
+			log.Writef(EventAuthentication, "%s loaded certificate %s from %s", credNameCert, thumbprint(certs[0]), c.file.watcher.path)
+			return nil
+		}
+	}
+	c.file.err = fmt.Errorf("failed to load certificate from %s: %w", c.file.watcher.path, err)
+	log.Writef(EventAuthentication, "ERROR: %s %v", credNameCert, c.file.err)
+	return c.file.err
+}
+
+// thumbprint returns the hex encoded SHA-1 hash of a certificate, the form in which Azure AD displays it
+func thumbprint(cert *x509.Certificate) string {
+	return fmt.Sprintf("%X", sha1.Sum(cert.Raw))
+}
+
 // ParseCertificates loads certificates and a private key, in PEM or PKCS12 format, for use with NewClientCertificateCredential.
 // Pass nil for password if the private key isn't encrypted. This function can't decrypt keys in PEM format.
 func ParseCertificates(certData []byte, password []byte) ([]*x509.Certificate, crypto.PrivateKey, error) {
@@ -9,16 +9,24 @@ package azidentity
 import (
 	"context"
 	"crypto"
+	"crypto/sha1"
 	"crypto/x509"
-	"log"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 type certTest struct {
@@ -31,7 +39,7 @@ func newCertTest(name, certPath string, password string) certTest {
 	data, _ := os.ReadFile(certPath)
 	certs, key, err := ParseCertificates(data, []byte(password))
 	if err != nil {
-		log.Panicf("failed to parse %s: %v", certPath, err)
+		panic(fmt.Sprintf("failed to parse %s: %v", certPath, err))
 	}
 	return certTest{name: name, certs: certs, key: key}
 }
@@ -273,3 +281,131 @@ func TestClientCertificateCredential_Regional(t *testing.T) {
 	}
 	testGetTokenSuccess(t, cred)
 }
+
+func validateX5T(t *testing.T, cert *x509.Certificate) mock.ResponsePredicate {
+	return func(req *http.Request) bool {
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		token, _ := jwt.Parse(req.PostForm.Get("client_assertion"), nil)
+		if token == nil {
+			t.Fatal("expected a client assertion")
+		}
+		expected := base64.StdEncoding.EncodeToString(func() []byte { s := sha1.Sum(cert.Raw); return s[:] }())
+		if actual := token.Header["x5t"]; actual != expected {
+			t.Fatalf(`expected x5t "%s", got "%v"`, expected, actual)
+		}
+		return true
+	}
+}
+
+func TestClientCertificateCredential_FromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	first, second := newCertTest("pem", "testdata/certificate.pem", ""), newCertTest("pemChain", "testdata/certificate-with-chain.pem", "")
+	copyFile := func(src string) {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyFile("testdata/certificate.pem")
+
+	srv, close := mock.NewServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(instanceDiscoveryResponse))
+	srv.AppendResponse(mock.WithBody(tenantDiscoveryResponse))
+	srv.AppendResponse(mock.WithPredicate(validateX5T(t, first.certs[0])), mock.WithBody(accessTokenRespSuccess))
+	srv.AppendResponse()
+	srv.AppendResponse(mock.WithBody(instanceDiscoveryResponse))
+	srv.AppendResponse(mock.WithBody(tenantDiscoveryResponse))
+	srv.AppendResponse(mock.WithPredicate(validateX5T(t, second.certs[0])), mock.WithBody(accessTokenRespSuccess))
+	srv.AppendResponse()
+
+	var logged []string
+	log.SetListener(func(e log.Event, msg string) {
+		if e == EventAuthentication && strings.Contains(msg, "loaded certificate") {
+			logged = append(logged, msg)
+		}
+	})
+	defer log.SetListener(nil)
+
+	cred, err := NewClientCertificateCredentialFromFile(fakeTenantID, fakeClientID, path, nil, &ClientCertificateCredentialOptions{
+		ClientOptions: policy.ClientOptions{Transport: srv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGetTokenSuccess(t, cred)
+
+	copyFile("testdata/certificate-with-chain.pem")
+	if _, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{liveTestScope}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 2 {
+		t.Fatalf("expected 2 log messages, got %d", len(logged))
+	}
+	for i, c := range []certTest{first, second} {
+		if tp := thumbprint(c.certs[0]); !strings.Contains(logged[i], tp) {
+			t.Errorf(`expected thumbprint "%s" in log message "%s"`, tp, logged[i])
+		}
+	}
+
+	copyFile("testdata/certificate_empty.pem")
+	for i := 0; i < 2; i++ {
+		_, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{liveTestScope}})
+		var afe *AuthenticationFailedError
+		if !errors.As(err, &afe) {
+			t.Fatalf("expected AuthenticationFailedError, got %v", err)
+		}
+	}
+}
+
+func TestClientCertificateCredential_FromFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	data, err := os.ReadFile("testdata/certificate.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cred, err := NewClientCertificateCredentialFromFile(fakeTenantID, fakeClientID, path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// the loaded certificate remains valid while the file is missing, until the watcher's next refresh
+	cred.file.watcher.next = time.Now().Add(time.Minute)
+	if err = cred.reload(); err != nil {
+		t.Fatal(err)
+	}
+	cred.file.watcher.next = time.Now()
+	if err = cred.reload(); err == nil {
+		t.Fatal("expected an error")
+	}
+	_, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{liveTestScope}})
+	var afe *AuthenticationFailedError
+	if !errors.As(err, &afe) {
+		t.Fatalf("expected AuthenticationFailedError, got %v", err)
+	}
+}
+
+func TestClientCertificateCredential_FromFileErrors(t *testing.T) {
+	for _, path := range []string{
+		filepath.Join(t.TempDir(), "missing.pem"),
+		"testdata/certificate_empty.pem",
+		"testdata/certificate_nokey.pem",
+	} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := NewClientCertificateCredentialFromFile(fakeTenantID, fakeClientID, path, nil, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
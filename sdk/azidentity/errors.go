@@ -14,6 +14,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/internal/shared"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
 	msal "github.com/AzureAD/microsoft-authentication-library-for-go/apps/errors"
 )
@@ -41,6 +42,10 @@ type AuthenticationFailedError struct {
 	message  string
 }
 
+func init() {
+	shared.NewAuthenticationFailedError = newAuthenticationFailedError
+}
+
 func newAuthenticationFailedError(credType string, message string, resp *http.Response) error {
 	return &AuthenticationFailedError{credType: credType, message: message, RawResponse: resp}
 }
@@ -0,0 +1,281 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const imdsPath = "/metadata/identity/oauth2/token"
+
+// AuthorityOptions contains optional parameters for Authority.
+type AuthorityOptions struct {
+	// Claims of each token. The authority sets TenantID and AppID from the token request when the
+	// Claims don't specify them.
+	Claims Claims
+
+	// ClientSecrets maps client IDs to secrets. When this map isn't empty, the authority rejects client
+	// secret token requests for clients it doesn't contain or having the wrong secret. Otherwise, it
+	// accepts any secret. The authority doesn't validate client assertions.
+	ClientSecrets map[string]string
+
+	// Lifetime of each token. Defaults to one hour.
+	Lifetime time.Duration
+}
+
+// Authority is a fake of the Azure Active Directory endpoints that azidentity credentials call: instance
+// and OpenID discovery, token, JWKS and the IMDS managed identity endpoint. It's safe for concurrent use.
+//
+// Authority is an azcore policy.Transporter that routes every request to its server, regardless of the request's
+// host. Set it as the Transport of a credential's ClientOptions, and set the ActiveDirectoryAuthorityHost
+// of the ClientOptions' Cloud to the value returned by Host.
+type Authority struct {
+	claims        Claims
+	clientSecrets map[string]string
+	failures      []failure
+	lifetime      time.Duration
+	mtx           *sync.Mutex
+	requests      int
+	srv           *httptest.Server
+}
+
+// failure is a response queued by Fail or Throttle
+type failure struct {
+	code, description string
+	header            http.Header
+	statusCode        int
+}
+
+// NewAuthority starts an Authority. Call Close to stop it. Pass nil for options to accept defaults.
+func NewAuthority(options *AuthorityOptions) *Authority {
+	if options == nil {
+		options = &AuthorityOptions{}
+	}
+	a := &Authority{claims: options.Claims, clientSecrets: options.ClientSecrets, lifetime: options.Lifetime, mtx: &sync.Mutex{}}
+	if a.lifetime == 0 {
+		a.lifetime = defaultLifetime
+	}
+	a.srv = httptest.NewServer(http.HandlerFunc(a.serveHTTP))
+	return a
+}
+
+// Close stops the authority's server.
+func (a *Authority) Close() {
+	a.srv.Close()
+}
+
+// Host returns the authority host, suitable for cloud.Configuration.ActiveDirectoryAuthorityHost. The scheme
+// is https because credentials require it. Requests actually reach the authority over http via its Do method.
+func (a *Authority) Host() string {
+	return "https://" + a.srv.Listener.Addr().String() + "/"
+}
+
+// Do implements the policy.Transporter interface by sending req to the authority's server.
+func (a *Authority) Do(req *http.Request) (*http.Response, error) {
+	u, err := url.Parse(a.srv.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.URL.Scheme, r.URL.Host, r.Host = u.Scheme, u.Host, ""
+	return a.srv.Client().Do(r)
+}
+
+// Fail queues an Azure AD error response to a subsequent token request. For example, Fail(401, "invalid_client",
+// "AADSTS7000215: Invalid client secret provided.") causes a credential's next token request to fail with
+// an *azidentity.AuthenticationFailedError.
+func (a *Authority) Fail(statusCode int, code, description string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.failures = append(a.failures, failure{code: code, description: description, statusCode: statusCode})
+}
+
+// Throttle queues n 429 responses having a Retry-After header with the specified value.
+func (a *Authority) Throttle(n int, retryAfter time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	for i := 0; i < n; i++ {
+		a.failures = append(a.failures, failure{
+			code:        "temporarily_unavailable",
+			description: "the request was throttled",
+			header:      http.Header{"Retry-After": []string{strconv.Itoa(int(retryAfter.Seconds()))}},
+			statusCode:  http.StatusTooManyRequests,
+		})
+	}
+}
+
+// TokenRequests returns the number of token requests the authority has received, including IMDS requests.
+func (a *Authority) TokenRequests() int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.requests
+}
+
+func (a *Authority) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/common/discovery/instance":
+		a.instanceDiscovery(w, r)
+	case r.URL.Path == imdsPath:
+		a.imdsToken(w, r)
+	case len(segments) == 4 && segments[1] == "v2.0" && segments[2] == ".well-known" && segments[3] == "openid-configuration":
+		a.openIDConfiguration(w, segments[0])
+	case len(segments) == 4 && segments[1] == "discovery" && segments[3] == "keys":
+		writeJSON(w, http.StatusOK, jwks())
+	case len(segments) == 4 && segments[1] == "oauth2" && segments[3] == "token":
+		a.token(w, r, segments[0])
+	default:
+		writeError(w, http.StatusNotFound, "invalid_request", "unexpected path "+r.URL.Path, nil)
+	}
+}
+
+func (a *Authority) instanceDiscovery(w http.ResponseWriter, r *http.Request) {
+	tenant := "common"
+	if ep, err := url.Parse(r.URL.Query().Get("authorization_endpoint")); err == nil {
+		if s := strings.Split(strings.Trim(ep.Path, "/"), "/"); s[0] != "" {
+			tenant = s[0]
+		}
+	}
+	// MSAL identifies cached tokens by host name, without a port
+	host, _, err := net.SplitHostPort(a.srv.Listener.Addr().String())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"api-version":               "1.1",
+		"tenant_discovery_endpoint": a.Host() + tenant + "/v2.0/.well-known/openid-configuration",
+		"metadata": []map[string]interface{}{
+			{"preferred_network": host, "preferred_cache": host, "aliases": []string{host}},
+		},
+	})
+}
+
+func (a *Authority) openIDConfiguration(w http.ResponseWriter, tenant string) {
+	base := a.Host() + tenant
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"authorization_endpoint":                base + "/oauth2/v2.0/authorize",
+		"device_authorization_endpoint":         base + "/oauth2/v2.0/devicecode",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"issuer":                                base + "/v2.0",
+		"jwks_uri":                              base + "/discovery/v2.0/keys",
+		"response_types_supported":              []string{"code", "id_token", "code id_token", "id_token token"},
+		"token_endpoint":                        base + "/oauth2/v2.0/token",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_post", "private_key_jwt", "client_secret_basic"},
+	})
+}
+
+func (a *Authority) token(w http.ResponseWriter, r *http.Request, tenant string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "token requests must use POST", nil)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		return
+	}
+	if a.fail(w) {
+		return
+	}
+	clientID := r.PostForm.Get("client_id")
+	if secret := r.PostForm.Get("client_secret"); secret != "" && len(a.clientSecrets) > 0 {
+		if expected, ok := a.clientSecrets[clientID]; !ok {
+			writeError(w, http.StatusBadRequest, "unauthorized_client", fmt.Sprintf("AADSTS700016: Application with identifier '%s' was not found", clientID), nil)
+			return
+		} else if secret != expected {
+			writeError(w, http.StatusUnauthorized, "invalid_client", "AADSTS7000215: Invalid client secret provided.", nil)
+			return
+		}
+	}
+	c := Claims{AppID: clientID, TenantID: tenant}.merge(a.claims)
+	now := time.Now().UTC()
+	tk, err := newJWT(c, a.Host()+c.TenantID+"/v2.0", audience(strings.Split(r.PostForm.Get("scope"), " ")), now, now.Add(a.lifetime))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error(), nil)
+		return
+	}
+	expiresIn := int(a.lifetime.Seconds())
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":   tk,
+		"expires_in":     expiresIn,
+		"ext_expires_in": expiresIn,
+		"token_type":     "Bearer",
+	})
+}
+
+func (a *Authority) imdsToken(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata") != "true" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Required metadata header not specified", nil)
+		return
+	}
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		writeError(w, http.StatusBadRequest, "invalid_resource", "Resource parameter is required", nil)
+		return
+	}
+	if a.fail(w) {
+		return
+	}
+	c := Claims{AppID: r.URL.Query().Get("client_id")}.merge(a.claims)
+	now := time.Now().UTC()
+	expires := now.Add(a.lifetime)
+	tk, err := newJWT(c, a.Host()+c.TenantID+"/v2.0", strings.TrimSuffix(resource, "/"), now, expires)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error(), nil)
+		return
+	}
+	// IMDS returns numbers as strings
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": tk,
+		"expires_in":   strconv.Itoa(int(a.lifetime.Seconds())),
+		"expires_on":   strconv.FormatInt(expires.Unix(), 10),
+		"not_before":   strconv.FormatInt(now.Unix(), 10),
+		"resource":     resource,
+		"token_type":   "Bearer",
+	})
+}
+
+// fail counts a token request and writes the next queued failure, if any. It returns true when it wrote a failure.
+func (a *Authority) fail(w http.ResponseWriter) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.requests++
+	if len(a.failures) == 0 {
+		return false
+	}
+	f := a.failures[0]
+	a.failures = a.failures[1:]
+	writeError(w, f.statusCode, f.code, f.description, f.header)
+	return true
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code, description string, h http.Header) {
+	for k, v := range h {
+		w.Header()[k] = v
+	}
+	writeJSON(w, statusCode, map[string]interface{}{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(b)
+}
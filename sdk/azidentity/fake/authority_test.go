@@ -0,0 +1,116 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+func clientOptions(a *Authority) azcore.ClientOptions {
+	return azcore.ClientOptions{
+		Cloud:     cloud.Configuration{ActiveDirectoryAuthorityHost: a.Host()},
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: a,
+	}
+}
+
+func TestAuthority_ClientSecretCredential(t *testing.T) {
+	a := NewAuthority(&AuthorityOptions{
+		Claims:        Claims{ObjectID: "object", Roles: []string{"Admin"}},
+		ClientSecrets: map[string]string{"client": "secret"},
+	})
+	defer a.Close()
+	cred, err := azidentity.NewClientSecretCredential("tenant", "client", "secret", &azidentity.ClientSecretCredentialOptions{ClientOptions: clientOptions(a)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := verify(t, tk.Token)
+	for k, v := range map[string]string{"appid": "client", "aud": "https://management.azure.com", "oid": "object", "tid": "tenant"} {
+		if claims[k] != v {
+			t.Errorf(`expected %s "%s", got "%v"`, k, v, claims[k])
+		}
+	}
+	// the credential should cache the token
+	if _, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := a.TokenRequests(); n != 1 {
+		t.Fatalf("expected 1 token request, got %d", n)
+	}
+}
+
+func TestAuthority_Failures(t *testing.T) {
+	a := NewAuthority(&AuthorityOptions{ClientSecrets: map[string]string{"client": "secret"}})
+	defer a.Close()
+	for _, test := range []struct {
+		name, secret string
+		setup        func()
+		status       int
+	}{
+		{name: "wrong secret", secret: "wrong", status: http.StatusUnauthorized},
+		{name: "throttled", secret: "secret", setup: func() { a.Throttle(1, 0) }, status: http.StatusTooManyRequests},
+		{name: "queued failure", secret: "secret", setup: func() { a.Fail(http.StatusBadRequest, "invalid_grant", "AADSTS50173") }, status: http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.setup != nil {
+				test.setup()
+			}
+			cred, err := azidentity.NewClientSecretCredential("tenant", "client", test.secret, &azidentity.ClientSecretCredentialOptions{ClientOptions: clientOptions(a)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}})
+			var afe *azidentity.AuthenticationFailedError
+			if !errors.As(err, &afe) {
+				t.Fatalf("expected AuthenticationFailedError, got %v", err)
+			}
+			if afe.RawResponse == nil || afe.RawResponse.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %v", test.status, afe.RawResponse)
+			}
+		})
+	}
+}
+
+func TestAuthority_ManagedIdentityCredential(t *testing.T) {
+	for _, v := range []string{"IDENTITY_ENDPOINT", "MSI_ENDPOINT"} {
+		if _, ok := os.LookupEnv(v); ok {
+			t.Skipf("this test requires the IMDS environment but %s is set", v)
+		}
+	}
+	a := NewAuthority(&AuthorityOptions{Claims: Claims{TenantID: "tenant"}})
+	defer a.Close()
+	cred, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
+		ClientOptions: azcore.ClientOptions{Transport: a},
+		ID:            azidentity.ClientID("user-assigned"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := verify(t, tk.Token)
+	for k, v := range map[string]string{"appid": "user-assigned", "aud": "https://management.azure.com", "tid": "tenant"} {
+		if claims[k] != v {
+			t.Errorf(`expected %s "%s", got "%v"`, k, v, claims[k])
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+/*
+Package fake provides test doubles for code that authenticates with azidentity.
+
+TokenCredential is an azcore.TokenCredential that issues realistic JWT access tokens having claims chosen by
+the test, and can simulate token expiration, throttling and authentication failures:
+
+	cred := fake.NewTokenCredential(&fake.TokenCredentialOptions{
+		Claims: fake.Claims{TenantID: "tenant", ObjectID: "object", Roles: []string{"Reader"}},
+	})
+	client, err := armresources.NewClient("subscription", cred, nil)
+
+Authority is an in-process fake of the Azure Active Directory token, OpenID discovery and IMDS endpoints. It
+enables testing real credentials such as azidentity.ClientSecretCredential and azidentity.ManagedIdentityCredential
+end to end, without network access:
+
+	authority := fake.NewAuthority(nil)
+	defer authority.Close()
+	opts := azcore.ClientOptions{
+		Cloud:     cloud.Configuration{ActiveDirectoryAuthorityHost: authority.Host()},
+		Transport: authority,
+	}
+	cred, err := azidentity.NewClientSecretCredential("tenant", "client", "secret",
+		&azidentity.ClientSecretCredentialOptions{ClientOptions: opts},
+	)
+
+Tokens issued by both types are signed with a key published by Authority's JWKS endpoint. They aren't valid
+for any Azure service.
+*/
+package fake
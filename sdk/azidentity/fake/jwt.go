@@ -0,0 +1,150 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Claims are the claims of an access token issued by a fake.
+type Claims struct {
+	// TenantID is the value of the "tid" claim.
+	TenantID string
+
+	// ObjectID is the value of the "oid" and "sub" claims.
+	ObjectID string
+
+	// AppID is the value of the "appid" and "azp" claims, identifying the application which requested the token.
+	AppID string
+
+	// Roles is the value of the "roles" claim.
+	Roles []string
+
+	// Extra contains additional claims. Its values override the claims set by other fields.
+	Extra map[string]interface{}
+}
+
+// merge returns a copy of c having the non-zero values of o
+func (c Claims) merge(o Claims) Claims {
+	if o.TenantID != "" {
+		c.TenantID = o.TenantID
+	}
+	if o.ObjectID != "" {
+		c.ObjectID = o.ObjectID
+	}
+	if o.AppID != "" {
+		c.AppID = o.AppID
+	}
+	if o.Roles != nil {
+		c.Roles = o.Roles
+	}
+	if len(o.Extra) > 0 {
+		extra := make(map[string]interface{}, len(c.Extra)+len(o.Extra))
+		for k, v := range c.Extra {
+			extra[k] = v
+		}
+		for k, v := range o.Extra {
+			extra[k] = v
+		}
+		c.Extra = extra
+	}
+	return c
+}
+
+// signingKey signs all tokens issued by fakes. Generating an RSA key is slow, so the package generates one on demand.
+var signingKey struct {
+	once sync.Once
+	key  *rsa.PrivateKey
+	kid  string
+}
+
+func getSigningKey() (*rsa.PrivateKey, string) {
+	signingKey.once.Do(func() {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(fmt.Sprintf("fake: failed to generate a signing key: %v", err))
+		}
+		sum := sha256.Sum256(k.PublicKey.N.Bytes())
+		signingKey.key, signingKey.kid = k, base64.RawURLEncoding.EncodeToString(sum[:8])
+	})
+	return signingKey.key, signingKey.kid
+}
+
+// newJWT returns a signed access token having the specified claims, issuer and audience
+func newJWT(c Claims, issuer, audience string, issued, expires time.Time) (string, error) {
+	key, kid := getSigningKey()
+	payload := map[string]interface{}{
+		"aud": audience,
+		"exp": expires.Unix(),
+		"iat": issued.Unix(),
+		"iss": issuer,
+		"nbf": issued.Unix(),
+		"ver": "2.0",
+	}
+	for k, v := range map[string]string{"tid": c.TenantID, "oid": c.ObjectID, "sub": c.ObjectID, "appid": c.AppID, "azp": c.AppID} {
+		if v != "" {
+			payload[k] = v
+		}
+	}
+	if len(c.Roles) > 0 {
+		payload["roles"] = c.Roles
+	}
+	for k, v := range c.Extra {
+		payload[k] = v
+	}
+	h, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(h) + "." + enc.EncodeToString(p)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// jwks returns a JSON Web Key Set containing the public key that verifies tokens issued by fakes
+func jwks() map[string]interface{} {
+	key, kid := getSigningKey()
+	enc := base64.RawURLEncoding
+	return map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"alg": "RS256",
+			"e":   enc.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			"kid": kid,
+			"kty": "RSA",
+			"n":   enc.EncodeToString(key.PublicKey.N.Bytes()),
+			"use": "sig",
+		}},
+	}
+}
+
+// audience returns the audience of a token for the specified scopes, for example
+// "https://management.azure.com" for "https://management.azure.com//.default"
+func audience(scopes []string) string {
+	if len(scopes) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(scopes[0], "/.default"), "/")
+}
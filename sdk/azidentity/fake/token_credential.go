@@ -0,0 +1,144 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	// azidentity sets shared.NewAuthenticationFailedError
+	_ "github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/internal/shared"
+)
+
+const (
+	defaultLifetime = time.Hour
+	fakeIssuerHost  = "https://login.microsoftonline.com/"
+)
+
+// TokenCredentialOptions contains optional parameters for TokenCredential.
+type TokenCredentialOptions struct {
+	// Claims of each token. A token's audience is derived from the requested scope.
+	Claims Claims
+
+	// Lifetime of each token. Defaults to one hour. A negative value simulates expiration
+	// by issuing tokens which have already expired.
+	Lifetime time.Duration
+}
+
+// TokenCredential is a fake azcore.TokenCredential. It issues a new JWT for each GetToken call, unless
+// an error has been queued by SetErrors. It's safe for concurrent use.
+type TokenCredential struct {
+	claims   Claims
+	errs     []error
+	lifetime time.Duration
+	mtx      *sync.Mutex
+	requests []policy.TokenRequestOptions
+}
+
+// NewTokenCredential constructs a TokenCredential. Pass nil for options to accept defaults.
+func NewTokenCredential(options *TokenCredentialOptions) *TokenCredential {
+	if options == nil {
+		options = &TokenCredentialOptions{}
+	}
+	lifetime := options.Lifetime
+	if lifetime == 0 {
+		lifetime = defaultLifetime
+	}
+	return &TokenCredential{claims: options.Claims, lifetime: lifetime, mtx: &sync.Mutex{}}
+}
+
+// GetToken returns a new access token, or the next error queued by SetErrors.
+func (c *TokenCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.requests = append(c.requests, opts)
+	if err := ctx.Err(); err != nil {
+		return azcore.AccessToken{}, err
+	}
+	if len(opts.Scopes) == 0 {
+		return azcore.AccessToken{}, errors.New("fake.TokenCredential: GetToken() requires at least one scope")
+	}
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return azcore.AccessToken{}, err
+	}
+	now := time.Now().UTC()
+	expires := now.Add(c.lifetime)
+	tk, err := newJWT(c.claims, fakeIssuerHost+c.claims.TenantID+"/v2.0", audience(opts.Scopes), now, expires)
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	return azcore.AccessToken{Token: tk, ExpiresOn: expires}, nil
+}
+
+// Requests returns the options of every GetToken call, in the order the credential received them.
+func (c *TokenCredential) Requests() []policy.TokenRequestOptions {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]policy.TokenRequestOptions{}, c.requests...)
+}
+
+// SetClaims changes the claims of tokens the credential issues.
+func (c *TokenCredential) SetClaims(claims Claims) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.claims = claims
+}
+
+// SetErrors queues errors for subsequent GetToken calls to return, one per call. After returning every
+// queued error, the credential issues tokens again. [NewAuthenticationFailedError] and [NewThrottlingError]
+// construct errors like those returned by azidentity credentials.
+func (c *TokenCredential) SetErrors(errs ...error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.errs = append(c.errs, errs...)
+}
+
+// NewAuthenticationFailedError returns an *azidentity.AuthenticationFailedError carrying a response having the
+// specified status code and an Azure AD error body with the specified error code and description. credential is
+// the name of the credential type the error appears to come from, for example "ClientSecretCredential".
+func NewAuthenticationFailedError(credential string, statusCode int, code, description string) error {
+	return shared.NewAuthenticationFailedError(credential, description, newErrorResponse(statusCode, code, description, nil))
+}
+
+// NewThrottlingError returns an *azidentity.AuthenticationFailedError carrying a 429 response having
+// a Retry-After header with the specified value. credential is the name of the credential type the error
+// appears to come from, for example "ClientSecretCredential".
+func NewThrottlingError(credential string, retryAfter time.Duration) error {
+	h := http.Header{"Retry-After": []string{strconv.Itoa(int(retryAfter.Seconds()))}}
+	description := "the request was throttled"
+	return shared.NewAuthenticationFailedError(credential, description, newErrorResponse(http.StatusTooManyRequests, "temporarily_unavailable", description, h))
+}
+
+func newErrorResponse(statusCode int, code, description string, h http.Header) *http.Response {
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set("Content-Type", "application/json")
+	req, _ := http.NewRequest(http.MethodPost, fakeIssuerHost+"fake/oauth2/v2.0/token", nil)
+	body := fmt.Sprintf(`{"error":%q,"error_description":%q}`, code, description)
+	return &http.Response{
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+		Header:     h,
+		Request:    req,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode: statusCode,
+	}
+}
+
+var _ azcore.TokenCredential = (*TokenCredential)(nil)
@@ -0,0 +1,125 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/internal/shared"
+)
+
+const scope = "https://management.azure.com//.default"
+
+// verify checks a token's signature and returns its claims
+func verify(t *testing.T, token string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT, got %q", token)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := getSigningKey()
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	claims, err := shared.JWTClaims(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestTokenCredential(t *testing.T) {
+	cred := NewTokenCredential(&TokenCredentialOptions{
+		Claims: Claims{
+			AppID:    "app",
+			Extra:    map[string]interface{}{"scp": "user_impersonation"},
+			ObjectID: "object",
+			Roles:    []string{"Reader", "Writer"},
+			TenantID: "tenant",
+		},
+	})
+	tk, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(tk.ExpiresOn); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("unexpected expiration time %v", tk.ExpiresOn)
+	}
+	claims := verify(t, tk.Token)
+	for k, v := range map[string]string{
+		"appid": "app",
+		"aud":   "https://management.azure.com",
+		"iss":   "https://login.microsoftonline.com/tenant/v2.0",
+		"oid":   "object",
+		"scp":   "user_impersonation",
+		"tid":   "tenant",
+	} {
+		if claims[k] != v {
+			t.Errorf(`expected %s "%s", got "%v"`, k, v, claims[k])
+		}
+	}
+	if roles, ok := claims["roles"].([]interface{}); !ok || len(roles) != 2 || roles[0] != "Reader" {
+		t.Errorf("unexpected roles %v", claims["roles"])
+	}
+	if exp, err := shared.NumericDateClaim(claims, "exp"); err != nil || exp.Unix() != tk.ExpiresOn.Unix() {
+		t.Errorf("exp claim %v doesn't match ExpiresOn %v", exp, tk.ExpiresOn)
+	}
+	if actual := cred.Requests(); len(actual) != 1 || actual[0].Scopes[0] != scope {
+		t.Fatalf("unexpected requests %v", actual)
+	}
+}
+
+func TestTokenCredential_Expired(t *testing.T) {
+	cred := NewTokenCredential(&TokenCredentialOptions{Lifetime: -time.Minute})
+	tk, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tk.ExpiresOn.Before(time.Now()) {
+		t.Fatalf("expected an expired token, got expiration time %v", tk.ExpiresOn)
+	}
+}
+
+func TestTokenCredential_Errors(t *testing.T) {
+	cred := NewTokenCredential(nil)
+	cred.SetErrors(NewThrottlingError("ClientSecretCredential", 5*time.Second), NewAuthenticationFailedError("ClientSecretCredential", http.StatusUnauthorized, "invalid_client", "AADSTS7000215: Invalid client secret provided."))
+	for _, expected := range []int{http.StatusTooManyRequests, http.StatusUnauthorized} {
+		_, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}})
+		var afe *azidentity.AuthenticationFailedError
+		if !errors.As(err, &afe) {
+			t.Fatalf("expected AuthenticationFailedError, got %v", err)
+		}
+		if afe.RawResponse.StatusCode != expected {
+			t.Fatalf("expected status %d, got %d", expected, afe.RawResponse.StatusCode)
+		}
+		if expected == http.StatusTooManyRequests && afe.RawResponse.Header.Get("Retry-After") != "5" {
+			t.Fatalf("unexpected Retry-After %q", afe.RawResponse.Header.Get("Retry-After"))
+		}
+		if msg := err.Error(); !strings.HasPrefix(msg, "ClientSecretCredential authentication failed") || !strings.Contains(msg, http.StatusText(expected)) {
+			t.Fatalf("unexpected error message %q", msg)
+		}
+	}
+	// the credential should issue tokens after returning all queued errors
+	if _, err := cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{scope}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// fileWatcher detects changes to a file such as a certificate or token mounted into a container.
+// It isn't safe for concurrent use; callers must synchronize calls to poll.
+type fileWatcher struct {
+	path string
+	// refresh is the longest time poll will go without rereading the file. Modification times
+	// aren't reliable on every file system, so the watcher doesn't depend on them alone.
+	refresh time.Duration
+
+	// next is the time after which poll rereads the file even when its size and modification time are unchanged
+	next    time.Time
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+	read    bool
+}
+
+func newFileWatcher(path string, refresh time.Duration) *fileWatcher {
+	return &fileWatcher{path: path, refresh: refresh}
+}
+
+// poll returns the file's content and true when the content has changed since the previous
+// call, or nil and false when it hasn't. The first call always returns the file's content.
+func (w *fileWatcher) poll() ([]byte, bool, error) {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	if w.read && now.Before(w.next) && fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return nil, false, nil
+	}
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, false, err
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	w.next = now.Add(w.refresh)
+	sum := sha256.Sum256(content)
+	if w.read && sum == w.sum {
+		return nil, false, nil
+	}
+	w.read, w.sum = true, sum
+	return content, true, nil
+}
+
+// stale returns true when content returned by poll is due to be reread, that is, before the first
+// successful read and after the refresh deadline. Callers tolerating errors while a file is replaced
+// should stop using content they read previously when it's stale.
+func (w *fileWatcher) stale() bool {
+	return !w.read || !time.Now().Before(w.next)
+}
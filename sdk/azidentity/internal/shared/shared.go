@@ -0,0 +1,57 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package shared contains helpers shared by azidentity and its subpackages.
+package shared
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewAuthenticationFailedError constructs an *azidentity.AuthenticationFailedError. The azidentity package sets it,
+// so that subpackages can construct errors having a credential type.
+var NewAuthenticationFailedError func(credType string, message string, resp *http.Response) error
+
+// JWTClaims returns the claims of a JWT, with numbers decoded as json.Number. It doesn't verify the token's signature.
+// azcore/accesstoken.ParseJWT decodes tokens the same way. TODO: replace this function with it after azidentity
+// requires an azcore version having that package.
+func JWTClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token isn't a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+	claims := map[string]interface{}{}
+	d := json.NewDecoder(strings.NewReader(string(payload)))
+	d.UseNumber()
+	if err = d.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token payload: %w", err)
+	}
+	return claims, nil
+}
+
+// NumericDateClaim returns the value of a NumericDate claim such as "exp". It returns an error when the claims
+// lack the claim or its value isn't a number.
+func NumericDateClaim(claims map[string]interface{}, name string) (time.Time, error) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("token has no numeric %q claim", name)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(f), 0), nil
+}
@@ -28,14 +28,17 @@ import (
 
 const (
 	arcIMDSEndpoint          = "IMDS_ENDPOINT"
+	defaultIdentityClientID  = "DEFAULT_IDENTITY_CLIENT_ID"
 	identityEndpoint         = "IDENTITY_ENDPOINT"
 	identityHeader           = "IDENTITY_HEADER"
 	identityServerThumbprint = "IDENTITY_SERVER_THUMBPRINT"
 	headerMetadata           = "Metadata"
 	imdsEndpoint             = "http://169.254.169.254/metadata/identity/oauth2/token"
 	msiEndpoint              = "MSI_ENDPOINT"
+	msiSecret                = "MSI_SECRET"
 	imdsAPIVersion           = "2018-02-01"
 	azureArcAPIVersion       = "2019-08-15"
+	azureMLAPIVersion        = "2017-09-01"
 	serviceFabricAPIVersion  = "2019-07-01-preview"
 
 	qpClientID = "client_id"
@@ -47,11 +50,24 @@ type msiType int
 const (
 	msiTypeAppService msiType = iota
 	msiTypeAzureArc
+	msiTypeAzureML
 	msiTypeCloudShell
 	msiTypeIMDS
 	msiTypeServiceFabric
+	msiTypeTokenEndpoint
 )
 
+// msiTypes maps each ManagedIdentitySource to its msiType
+var msiTypes = map[ManagedIdentitySource]msiType{
+	ManagedIdentitySourceAppService:    msiTypeAppService,
+	ManagedIdentitySourceAzureArc:      msiTypeAzureArc,
+	ManagedIdentitySourceAzureML:       msiTypeAzureML,
+	ManagedIdentitySourceCloudShell:    msiTypeCloudShell,
+	ManagedIdentitySourceIMDS:          msiTypeIMDS,
+	ManagedIdentitySourceServiceFabric: msiTypeServiceFabric,
+	ManagedIdentitySourceTokenEndpoint: msiTypeTokenEndpoint,
+}
+
 // managedIdentityClient provides the base for authenticating in managed identity environments
 // This type includes an runtime.Pipeline and TokenCredentialOptions.
 type managedIdentityClient struct {
@@ -60,6 +76,7 @@ type managedIdentityClient struct {
 	endpoint    string
 	id          ManagedIDKind
 	imdsTimeout time.Duration
+	source      ManagedIdentitySourceInfo
 }
 
 type wrappedNumber json.Number
@@ -115,40 +132,117 @@ func newManagedIdentityClient(options *ManagedIdentityCredentialOptions) (*manag
 		options = &ManagedIdentityCredentialOptions{}
 	}
 	cp := options.ClientOptions
-	c := managedIdentityClient{id: options.ID, endpoint: imdsEndpoint, msiType: msiTypeIMDS}
-	env := "IMDS"
-	if endpoint, ok := os.LookupEnv(identityEndpoint); ok {
-		if _, ok := os.LookupEnv(identityHeader); ok {
-			if _, ok := os.LookupEnv(identityServerThumbprint); ok {
-				env = "Service Fabric"
-				c.endpoint = endpoint
-				c.msiType = msiTypeServiceFabric
-			} else {
-				env = "App Service"
-				c.endpoint = endpoint
-				c.msiType = msiTypeAppService
-			}
-		} else if _, ok := os.LookupEnv(arcIMDSEndpoint); ok {
-			env = "Azure Arc"
-			c.endpoint = endpoint
-			c.msiType = msiTypeAzureArc
+	c := managedIdentityClient{id: options.ID}
+	if options.Source == "" {
+		c.source = detectManagedIdentitySource()
+		if options.Endpoint != "" {
+			c.source.Endpoint = options.Endpoint
+			c.source.Reason += "; ManagedIdentityCredentialOptions.Endpoint overrides the endpoint"
 		}
-	} else if endpoint, ok := os.LookupEnv(msiEndpoint); ok {
-		env = "Cloud Shell"
-		c.endpoint = endpoint
-		c.msiType = msiTypeCloudShell
 	} else {
+		source, err := selectManagedIdentitySource(options.Source, options.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		c.source = source
+	}
+	c.endpoint = c.source.Endpoint
+	c.msiType = msiTypes[c.source.Source]
+	if c.msiType == msiTypeIMDS {
 		setIMDSRetryOptionDefaults(&cp.Retry)
 	}
+	if c.msiType == msiTypeAzureML && c.id == nil {
+		if id := os.Getenv(defaultIdentityClientID); id != "" {
+			c.id = ClientID(id)
+		}
+	}
 	c.pipeline = runtime.NewPipeline(component, version, runtime.PipelineOptions{}, &cp)
 
 	if log.Should(EventAuthentication) {
-		log.Writef(EventAuthentication, "Managed Identity Credential will use %s managed identity", env)
+		log.Writef(EventAuthentication, "Managed Identity Credential will use %s managed identity because %s", c.source.Source, c.source.Reason)
 	}
 
 	return &c, nil
 }
 
+// detectManagedIdentitySource determines the managed identity source from environment variables
+func detectManagedIdentitySource() ManagedIdentitySourceInfo {
+	if endpoint, ok := os.LookupEnv(identityEndpoint); ok {
+		if _, ok := os.LookupEnv(identityHeader); ok {
+			if _, ok := os.LookupEnv(identityServerThumbprint); ok {
+				return ManagedIdentitySourceInfo{
+					Endpoint: endpoint,
+					Reason:   fmt.Sprintf("%s, %s and %s are set", identityEndpoint, identityHeader, identityServerThumbprint),
+					Source:   ManagedIdentitySourceServiceFabric,
+				}
+			}
+			return ManagedIdentitySourceInfo{
+				Endpoint: endpoint,
+				Reason:   fmt.Sprintf("%s and %s are set", identityEndpoint, identityHeader),
+				Source:   ManagedIdentitySourceAppService,
+			}
+		} else if _, ok := os.LookupEnv(arcIMDSEndpoint); ok {
+			return ManagedIdentitySourceInfo{
+				Endpoint: endpoint,
+				Reason:   fmt.Sprintf("%s and %s are set", identityEndpoint, arcIMDSEndpoint),
+				Source:   ManagedIdentitySourceAzureArc,
+			}
+		}
+		return ManagedIdentitySourceInfo{
+			Endpoint: imdsEndpoint,
+			Reason:   fmt.Sprintf("%s is set without %s or %s", identityEndpoint, identityHeader, arcIMDSEndpoint),
+			Source:   ManagedIdentitySourceIMDS,
+		}
+	} else if endpoint, ok := os.LookupEnv(msiEndpoint); ok {
+		if _, ok := os.LookupEnv(msiSecret); ok {
+			return ManagedIdentitySourceInfo{
+				Endpoint: endpoint,
+				Reason:   fmt.Sprintf("%s and %s are set", msiEndpoint, msiSecret),
+				Source:   ManagedIdentitySourceAzureML,
+			}
+		}
+		return ManagedIdentitySourceInfo{
+			Endpoint: endpoint,
+			Reason:   fmt.Sprintf("%s is set and %s isn't", msiEndpoint, msiSecret),
+			Source:   ManagedIdentitySourceCloudShell,
+		}
+	}
+	return ManagedIdentitySourceInfo{
+		Endpoint: imdsEndpoint,
+		Reason:   "no environment variable indicates another source",
+		Source:   ManagedIdentitySourceIMDS,
+	}
+}
+
+// selectManagedIdentitySource returns information about a source specified by the application. When
+// endpoint is empty, the source's endpoint comes from the environment variable that would set it
+// during detection, or from the source's well-known default.
+func selectManagedIdentitySource(source ManagedIdentitySource, endpoint string) (ManagedIdentitySourceInfo, error) {
+	if _, ok := msiTypes[source]; !ok {
+		return ManagedIdentitySourceInfo{}, fmt.Errorf("%s: unknown managed identity source %q", credNameManagedIdentity, source)
+	}
+	info := ManagedIdentitySourceInfo{Endpoint: endpoint, Reason: "ManagedIdentityCredentialOptions.Source specifies it", Source: source}
+	if endpoint != "" {
+		return info, nil
+	}
+	var env string
+	switch source {
+	case ManagedIdentitySourceIMDS:
+		info.Endpoint = imdsEndpoint
+		return info, nil
+	case ManagedIdentitySourceAppService, ManagedIdentitySourceAzureArc, ManagedIdentitySourceServiceFabric:
+		env = identityEndpoint
+	case ManagedIdentitySourceAzureML, ManagedIdentitySourceCloudShell:
+		env = msiEndpoint
+	default:
+		return ManagedIdentitySourceInfo{}, fmt.Errorf("%s: managed identity source %q requires an endpoint. Set ManagedIdentityCredentialOptions.Endpoint", credNameManagedIdentity, source)
+	}
+	if info.Endpoint = os.Getenv(env); info.Endpoint == "" {
+		return ManagedIdentitySourceInfo{}, fmt.Errorf("%s: managed identity source %q requires an endpoint. Set %s or ManagedIdentityCredentialOptions.Endpoint", credNameManagedIdentity, source, env)
+	}
+	return info, nil
+}
+
 // provideToken acquires a token for MSAL's confidential.Client, which caches the token
 func (c *managedIdentityClient) provideToken(ctx context.Context, params confidential.TokenProviderParameters) (confidential.TokenProviderResult, error) {
 	result := confidential.TokenProviderResult{}
@@ -232,7 +326,7 @@ func (c *managedIdentityClient) createAccessToken(res *http.Response) (azcore.Ac
 
 func (c *managedIdentityClient) createAuthRequest(ctx context.Context, id ManagedIDKind, scopes []string) (*policy.Request, error) {
 	switch c.msiType {
-	case msiTypeIMDS:
+	case msiTypeIMDS, msiTypeTokenEndpoint:
 		return c.createIMDSAuthRequest(ctx, id, scopes)
 	case msiTypeAppService:
 		return c.createAppServiceAuthRequest(ctx, id, scopes)
@@ -248,6 +342,8 @@ func (c *managedIdentityClient) createAuthRequest(ctx context.Context, id Manage
 		return c.createServiceFabricAuthRequest(ctx, id, scopes)
 	case msiTypeCloudShell:
 		return c.createCloudShellAuthRequest(ctx, id, scopes)
+	case msiTypeAzureML:
+		return c.createAzureMLAuthRequest(ctx, id, scopes)
 	default:
 		return nil, newCredentialUnavailableError(credNameManagedIdentity, "managed identity isn't supported in this environment")
 	}
@@ -293,6 +389,27 @@ func (c *managedIdentityClient) createAppServiceAuthRequest(ctx context.Context,
 	return request, nil
 }
 
+func (c *managedIdentityClient) createAzureMLAuthRequest(ctx context.Context, id ManagedIDKind, scopes []string) (*policy.Request, error) {
+	request, err := runtime.NewRequest(ctx, http.MethodGet, c.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	request.Raw().Header.Set("secret", os.Getenv(msiSecret))
+	q := request.Raw().URL.Query()
+	q.Add("api-version", azureMLAPIVersion)
+	q.Add("resource", strings.Join(scopes, " "))
+	if id != nil {
+		if id.idKind() == miResourceID {
+			log.Write(EventAuthentication, "WARNING: Azure Machine Learning doesn't support specifying a user-assigned identity by resource ID")
+			q.Add(qpResID, id.String())
+		} else {
+			q.Add("clientid", id.String())
+		}
+	}
+	request.Raw().URL.RawQuery = q.Encode()
+	return request, nil
+}
+
 func (c *managedIdentityClient) createServiceFabricAuthRequest(ctx context.Context, id ManagedIDKind, scopes []string) (*policy.Request, error) {
 	request, err := runtime.NewRequest(ctx, http.MethodGet, c.endpoint)
 	if err != nil {
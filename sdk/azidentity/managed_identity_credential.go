@@ -64,6 +64,47 @@ type ManagedIdentityCredentialOptions struct {
 	// instead of the hosting environment's default. The value may be the identity's client ID or resource ID, but note that
 	// some platforms don't accept resource IDs.
 	ID ManagedIDKind
+
+	// Source forces the credential to use a specific managed identity source instead of detecting one from
+	// environment variables. ManagedIdentitySourceTokenEndpoint requires Endpoint.
+	Source ManagedIdentitySource
+
+	// Endpoint overrides the token endpoint of the managed identity source. Set this field to use a
+	// ManagedIdentitySourceTokenEndpoint such as a sidecar that implements the IMDS token protocol.
+	Endpoint string
+}
+
+// ManagedIdentitySource identifies a source of managed identity tokens, typically a hosting environment.
+type ManagedIdentitySource string
+
+const (
+	// ManagedIdentitySourceAppService is Azure App Service and Azure Functions.
+	ManagedIdentitySourceAppService ManagedIdentitySource = "App Service"
+	// ManagedIdentitySourceAzureArc is an Azure Arc-enabled server.
+	ManagedIdentitySourceAzureArc ManagedIdentitySource = "Azure Arc"
+	// ManagedIdentitySourceAzureML is Azure Machine Learning compute.
+	ManagedIdentitySourceAzureML ManagedIdentitySource = "Azure Machine Learning"
+	// ManagedIdentitySourceCloudShell is Azure Cloud Shell.
+	ManagedIdentitySourceCloudShell ManagedIdentitySource = "Cloud Shell"
+	// ManagedIdentitySourceIMDS is the Azure Instance Metadata Service, available on Azure VMs and AKS nodes.
+	ManagedIdentitySourceIMDS ManagedIdentitySource = "IMDS"
+	// ManagedIdentitySourceServiceFabric is Azure Service Fabric.
+	ManagedIdentitySourceServiceFabric ManagedIdentitySource = "Service Fabric"
+	// ManagedIdentitySourceTokenEndpoint is an application-provided endpoint implementing the IMDS token protocol,
+	// such as a sidecar. The credential uses this source only when ManagedIdentityCredentialOptions.Source specifies it.
+	ManagedIdentitySourceTokenEndpoint ManagedIdentitySource = "Token Endpoint"
+)
+
+// ManagedIdentitySourceInfo describes the managed identity source a ManagedIdentityCredential uses.
+type ManagedIdentitySourceInfo struct {
+	// Source is the managed identity source.
+	Source ManagedIdentitySource
+
+	// Endpoint is the source's token endpoint.
+	Endpoint string
+
+	// Reason explains why the credential uses Source, for example which environment variables are set.
+	Reason string
 }
 
 // ManagedIdentityCredential authenticates an Azure managed identity in any hosting environment supporting managed identities.
@@ -120,4 +161,9 @@ func (c *ManagedIdentityCredential) GetToken(ctx context.Context, opts policy.To
 	return azcore.AccessToken{Token: ar.AccessToken, ExpiresOn: ar.ExpiresOn.UTC()}, err
 }
 
+// SourceInfo describes the managed identity source the credential uses and why. It's intended for diagnostics.
+func (c *ManagedIdentityCredential) SourceInfo() ManagedIdentitySourceInfo {
+	return c.mic.source
+}
+
 var _ azcore.TokenCredential = (*ManagedIdentityCredential)(nil)
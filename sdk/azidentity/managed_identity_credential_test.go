@@ -523,3 +523,141 @@ func TestManagedIdentityCredential_ServiceFabric(t *testing.T) {
 	}
 	testGetTokenSuccess(t, cred)
 }
+
+func TestManagedIdentityCredential_AzureML(t *testing.T) {
+	for _, test := range []struct {
+		id       ManagedIDKind
+		expected string
+	}{
+		{expected: "default-client-id"},
+		{id: ClientID("explicit-client-id"), expected: "explicit-client-id"},
+	} {
+		t.Run(test.expected, func(t *testing.T) {
+			validateReq := func(req *http.Request) bool {
+				if h := req.Header.Get("secret"); h != "secret" {
+					t.Fatalf("unexpected secret header: %s", h)
+				}
+				q := req.URL.Query()
+				if v := q.Get("api-version"); v != azureMLAPIVersion {
+					t.Fatalf(`unexpected api-version "%s"`, v)
+				}
+				if v := q.Get("resource"); v != strings.TrimSuffix(liveTestScope, defaultSuffix) {
+					t.Fatalf(`unexpected resource "%s"`, v)
+				}
+				if v := q.Get("clientid"); v != test.expected {
+					t.Fatalf(`unexpected clientid "%s"`, v)
+				}
+				return true
+			}
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(mock.WithPredicate(validateReq), mock.WithBody([]byte(expiresOnIntResp)))
+			srv.AppendResponse()
+			setEnvironmentVariables(t, map[string]string{
+				defaultIdentityClientID: "default-client-id",
+				msiEndpoint:             srv.URL(),
+				msiSecret:               "secret",
+			})
+			cred, err := NewManagedIdentityCredential(&ManagedIdentityCredentialOptions{
+				ClientOptions: azcore.ClientOptions{Transport: srv},
+				ID:            test.id,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := cred.SourceInfo().Source; s != ManagedIdentitySourceAzureML {
+				t.Fatalf("unexpected source %s", s)
+			}
+			if _, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{liveTestScope}}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestManagedIdentityCredential_TokenEndpoint(t *testing.T) {
+	validateReq := func(req *http.Request) bool {
+		if req.URL.Path != "/sidecar/token" {
+			t.Fatalf("unexpected path: %s", req.URL.Path)
+		}
+		if h := req.Header.Get(headerMetadata); h != "true" {
+			t.Fatalf("unexpected metadata header: %s", h)
+		}
+		if v := req.URL.Query().Get(qpClientID); v != fakeClientID {
+			t.Fatalf(`unexpected client_id "%s"`, v)
+		}
+		return true
+	}
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithPredicate(validateReq), mock.WithBody(accessTokenRespSuccess))
+	srv.AppendResponse()
+	// a forced source should take precedence over environment variables
+	setEnvironmentVariables(t, map[string]string{msiEndpoint: "https://localhost/cloudshell"})
+	cred, err := NewManagedIdentityCredential(&ManagedIdentityCredentialOptions{
+		ClientOptions: azcore.ClientOptions{Transport: srv},
+		Endpoint:      srv.URL() + "/sidecar/token",
+		ID:            ClientID(fakeClientID),
+		Source:        ManagedIdentitySourceTokenEndpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := cred.SourceInfo()
+	if info.Source != ManagedIdentitySourceTokenEndpoint || info.Endpoint != srv.URL()+"/sidecar/token" || info.Reason == "" {
+		t.Fatalf("unexpected source info %+v", info)
+	}
+	testGetTokenSuccess(t, cred)
+}
+
+func TestManagedIdentityCredential_SourceInfo(t *testing.T) {
+	for _, test := range []struct {
+		env      map[string]string
+		expected ManagedIdentitySource
+	}{
+		{env: map[string]string{}, expected: ManagedIdentitySourceIMDS},
+		{env: map[string]string{identityEndpoint: "https://localhost", identityHeader: "h"}, expected: ManagedIdentitySourceAppService},
+		{env: map[string]string{identityEndpoint: "https://localhost", arcIMDSEndpoint: "https://localhost"}, expected: ManagedIdentitySourceAzureArc},
+		{env: map[string]string{identityEndpoint: "https://localhost", identityHeader: "h", identityServerThumbprint: "t"}, expected: ManagedIdentitySourceServiceFabric},
+		{env: map[string]string{msiEndpoint: "https://localhost"}, expected: ManagedIdentitySourceCloudShell},
+		{env: map[string]string{msiEndpoint: "https://localhost", msiSecret: "s"}, expected: ManagedIdentitySourceAzureML},
+		// IDENTITY_ENDPOINT takes precedence over MSI_ENDPOINT, even when it doesn't indicate a source
+		{env: map[string]string{identityEndpoint: "https://localhost", msiEndpoint: "https://localhost"}, expected: ManagedIdentitySourceIMDS},
+		{env: map[string]string{identityEndpoint: "https://localhost", msiEndpoint: "https://localhost", msiSecret: "s"}, expected: ManagedIdentitySourceIMDS},
+	} {
+		t.Run(string(test.expected), func(t *testing.T) {
+			for _, k := range []string{arcIMDSEndpoint, identityEndpoint, identityHeader, identityServerThumbprint, msiEndpoint, msiSecret} {
+				if _, ok := test.env[k]; !ok {
+					prev, set := os.LookupEnv(k)
+					clearEnvVars(k)
+					if set {
+						t.Cleanup(func() { os.Setenv(k, prev) })
+					}
+				}
+			}
+			setEnvironmentVariables(t, test.env)
+			cred, err := NewManagedIdentityCredential(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := cred.SourceInfo()
+			if info.Source != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, info.Source)
+			}
+			if info.Endpoint == "" || info.Reason == "" {
+				t.Fatalf("expected an endpoint and reason, got %+v", info)
+			}
+		})
+	}
+}
+
+func TestManagedIdentityCredential_SourceErrors(t *testing.T) {
+	clearEnvVars(identityEndpoint, msiEndpoint)
+	for _, source := range []ManagedIdentitySource{"Unknown", ManagedIdentitySourceTokenEndpoint, ManagedIdentitySourceAppService} {
+		t.Run(string(source), func(t *testing.T) {
+			if _, err := NewManagedIdentityCredential(&ManagedIdentityCredentialOptions{Source: source}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -8,12 +8,12 @@ package azidentity
 
 import (
 	"context"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
 )
 
 const credNameWorkloadIdentity = "WorkloadIdentityCredential"
@@ -23,10 +23,10 @@ const credNameWorkloadIdentity = "WorkloadIdentityCredential"
 //
 // [AKS documentation]: https://learn.microsoft.com/azure/aks/workload-identity-overview
 type WorkloadIdentityCredential struct {
-	assertion, file string
-	cred            *ClientAssertionCredential
-	expires         time.Time
-	mtx             *sync.RWMutex
+	assertion string
+	cred      *ClientAssertionCredential
+	mtx       *sync.Mutex
+	watcher   *fileWatcher
 }
 
 // WorkloadIdentityCredentialOptions contains optional parameters for WorkloadIdentityCredential.
@@ -40,7 +40,11 @@ func NewWorkloadIdentityCredential(tenantID, clientID, file string, options *Wor
 	if options == nil {
 		options = &WorkloadIdentityCredentialOptions{}
 	}
-	w := WorkloadIdentityCredential{file: file, mtx: &sync.RWMutex{}}
+	// Kubernetes rotates service account tokens when they reach 80% of their total TTL. The shortest TTL
+	// is 1 hour. That implies a token is valid for at least 12 minutes (20% of 1 hour) after the credential
+	// reads it. So, the credential rereads the file at least every 10 minutes, in addition to rereading it
+	// whenever its modification time changes.
+	w := WorkloadIdentityCredential{mtx: &sync.Mutex{}, watcher: newFileWatcher(file, 10*time.Minute)}
 	cred, err := NewClientAssertionCredential(tenantID, clientID, w.getAssertion, &ClientAssertionCredentialOptions{ClientOptions: options.ClientOptions})
 	if err != nil {
 		return nil, err
@@ -58,26 +62,20 @@ func (w *WorkloadIdentityCredential) GetToken(ctx context.Context, opts policy.T
 // getAssertion returns the specified file's content, which is expected to be a Kubernetes service account token.
 // Kubernetes is responsible for updating the file as service account tokens expire.
 func (w *WorkloadIdentityCredential) getAssertion(context.Context) (string, error) {
-	w.mtx.RLock()
-	if w.expires.Before(time.Now()) {
-		// ensure only one goroutine at a time updates the assertion
-		w.mtx.RUnlock()
-		w.mtx.Lock()
-		defer w.mtx.Unlock()
-		// double check because another goroutine may have acquired the write lock first and done the update
-		if now := time.Now(); w.expires.Before(now) {
-			content, err := os.ReadFile(w.file)
-			if err != nil {
-				return "", err
-			}
-			w.assertion = string(content)
-			// Kubernetes rotates service account tokens when they reach 80% of their total TTL. The shortest TTL
-			// is 1 hour. That implies the token we just read is valid for at least 12 minutes (20% of 1 hour),
-			// but we add some margin for safety.
-			w.expires = now.Add(10 * time.Minute)
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	content, changed, err := w.watcher.poll()
+	if err != nil {
+		log.Writef(EventAuthentication, "ERROR: %s failed to read %s: %v", credNameWorkloadIdentity, w.watcher.path, err)
+		// the file may be briefly missing while Kubernetes replaces it, in which case the current token
+		// remains valid until the watcher's next refresh
+		if w.assertion == "" || w.watcher.stale() {
+			return "", err
 		}
-	} else {
-		defer w.mtx.RUnlock()
+		return w.assertion, nil
+	}
+	if changed {
+		w.assertion = string(content)
 	}
 	return w.assertion, nil
 }
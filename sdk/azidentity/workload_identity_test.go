@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
 )
 
@@ -100,9 +101,75 @@ func TestWorkloadIdentityCredential_Expiration(t *testing.T) {
 		if _, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{s}}); err != nil {
 			t.Fatal(err)
 		}
-		cred.expires = time.Now().Add(-time.Second)
+		cred.watcher.next = time.Now().Add(-time.Second)
 	}
 	if tokenReqs != 2 {
 		t.Fatalf("expected 2 token requests, got %d", tokenReqs)
 	}
 }
+
+func TestWorkloadIdentityCredential_Reload(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "test-workload-token-file")
+	expected := ""
+	validateReq := func(req *http.Request) bool {
+		if err := req.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if actual := req.PostForm.Get("client_assertion"); actual != expected {
+			t.Errorf(`expected assertion "%s", got "%s"`, expected, actual)
+		}
+		return true
+	}
+	srv, close := mock.NewServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(instanceDiscoveryResponse))
+	srv.AppendResponse(mock.WithBody(tenantDiscoveryResponse))
+	srv.AppendResponse(mock.WithPredicate(validateReq), mock.WithBody(accessTokenRespSuccess))
+	srv.AppendResponse()
+	srv.AppendResponse(mock.WithPredicate(validateReq), mock.WithBody(accessTokenRespSuccess))
+	srv.AppendResponse()
+	opts := WorkloadIdentityCredentialOptions{
+		ClientOptions: policy.ClientOptions{Transport: srv},
+	}
+	cred, err := NewWorkloadIdentityCredential(fakeTenantID, fakeClientID, tempFile, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the file's size changes with each write, so the credential should notice each new token
+	// immediately, without waiting for its periodic refresh
+	for i, assertion := range []string{"token", "rotated-token"} {
+		expected = assertion
+		if err = os.WriteFile(tempFile, []byte(assertion), os.ModePerm); err != nil {
+			t.Fatalf("failed to write token file: %v", err)
+		}
+		if _, err = cred.GetToken(context.Background(), policy.TokenRequestOptions{Scopes: []string{fmt.Sprint(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the current token remains valid while the file is missing
+	if err = os.Remove(tempFile); err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := cred.getAssertion(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if actual != expected {
+		t.Fatalf(`expected "%s", got "%s"`, expected, actual)
+	}
+
+	// ...until the watcher's next refresh, after which the error is returned
+	var logged []string
+	log.SetListener(func(e log.Event, msg string) {
+		if e == EventAuthentication {
+			logged = append(logged, msg)
+		}
+	})
+	defer log.SetListener(nil)
+	cred.watcher.next = time.Now()
+	if _, err := cred.getAssertion(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], tempFile) {
+		t.Fatalf("expected a log message naming %s, got %v", tempFile, logged)
+	}
+}
@@ -0,0 +1,329 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azappconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	// FeatureFlagKeyPrefix is the prefix of the keys of feature flag settings.
+	FeatureFlagKeyPrefix = ".appconfig.featureflag/"
+
+	// FeatureFlagContentType is the content type of feature flag settings.
+	FeatureFlagContentType = "application/vnd.microsoft.appconfig.ff+json;charset=utf-8"
+
+	featureFlagMediaType = "application/vnd.microsoft.appconfig.ff+json"
+)
+
+// Names of the built-in feature filters. Filters may also be named without the "Microsoft." prefix.
+const (
+	PercentageFilterName = "Microsoft.Percentage"
+	TimeWindowFilterName = "Microsoft.TimeWindow"
+	TargetingFilterName  = "Microsoft.Targeting"
+)
+
+// timeWindowFormat is the format of times in time window filters written by the Azure portal.
+const timeWindowFormat = http.TimeFormat
+
+// FeatureFlagRequirementType specifies whether any or all of a feature flag's client filters must be satisfied.
+type FeatureFlagRequirementType string
+
+const (
+	// FeatureFlagRequirementTypeAny enables the feature flag if any client filter is satisfied.
+	FeatureFlagRequirementTypeAny FeatureFlagRequirementType = "Any"
+
+	// FeatureFlagRequirementTypeAll enables the feature flag if all client filters are satisfied.
+	FeatureFlagRequirementTypeAll FeatureFlagRequirementType = "All"
+)
+
+// PossibleFeatureFlagRequirementTypeValues returns the possible values for the FeatureFlagRequirementType const type.
+func PossibleFeatureFlagRequirementTypeValues() []FeatureFlagRequirementType {
+	return []FeatureFlagRequirementType{
+		FeatureFlagRequirementTypeAny,
+		FeatureFlagRequirementTypeAll,
+	}
+}
+
+// FeatureFlagSetting is a feature flag, stored as a configuration setting with a key starting with
+// FeatureFlagKeyPrefix and a JSON value of content type FeatureFlagContentType.
+type FeatureFlagSetting struct {
+	// The name of the feature flag. The key of its configuration setting is FeatureFlagKeyPrefix followed by the ID.
+	ID string `json:"id"`
+
+	// A description of the feature flag.
+	Description string `json:"description,omitempty"`
+
+	// The name of the feature flag displayed by the Azure portal.
+	DisplayName string `json:"display_name,omitempty"`
+
+	// A value indicating whether the feature flag is enabled. An enabled feature flag with client filters is
+	// only enabled when its client filters are satisfied.
+	Enabled bool `json:"enabled"`
+
+	// The conditions enabling the feature flag.
+	Conditions FeatureFlagConditions `json:"conditions"`
+
+	// A value used to group configuration settings.
+	Label *string `json:"-"`
+
+	// An ETag indicating the state of the feature flag's configuration setting within a configuration store.
+	ETag *azcore.ETag `json:"-"`
+
+	// A dictionary of tags used to assign additional properties to the feature flag's configuration setting.
+	Tags map[string]string `json:"-"`
+
+	// The last time a modifying operation was performed on the feature flag's configuration setting.
+	LastModified *time.Time `json:"-"`
+
+	// A value indicating whether the feature flag's configuration setting is read only.
+	IsReadOnly *bool `json:"-"`
+}
+
+// FeatureFlagConditions are the conditions enabling a feature flag.
+type FeatureFlagConditions struct {
+	// Specifies whether any or all client filters must be satisfied. The default is FeatureFlagRequirementTypeAny.
+	RequirementType FeatureFlagRequirementType `json:"requirement_type,omitempty"`
+
+	// The client filters of the feature flag. A feature flag without client filters is enabled when Enabled is true.
+	ClientFilters []FeatureFlagFilter `json:"client_filters"`
+}
+
+// FeatureFlagFilter is a client filter of a feature flag.
+type FeatureFlagFilter struct {
+	// The name of the filter, such as PercentageFilterName or the name of a custom filter.
+	Name string `json:"name"`
+
+	// The parameters of the filter.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// PercentageFilterParameters are the parameters of the built-in percentage filter.
+type PercentageFilterParameters struct {
+	// The percentage of evaluations, from 0 to 100, for which the filter is satisfied.
+	Value float64
+}
+
+// TimeWindowFilterParameters are the parameters of the built-in time window filter. At least one of Start and End
+// must be set.
+type TimeWindowFilterParameters struct {
+	// The time from which the filter is satisfied. If nil, the filter is satisfied from any time before End.
+	Start *time.Time
+
+	// The time until which the filter is satisfied. If nil, the filter is satisfied at any time after Start.
+	End *time.Time
+}
+
+// TargetingFilterParameters are the parameters of the built-in targeting filter.
+type TargetingFilterParameters struct {
+	// The audience of the feature flag.
+	Audience TargetingAudience
+}
+
+// TargetingAudience is the audience of a targeting filter.
+type TargetingAudience struct {
+	// The IDs of users the filter is satisfied for.
+	Users []string `json:",omitempty"`
+
+	// Groups of users the filter is satisfied for a percentage of.
+	Groups []TargetingGroup `json:",omitempty"`
+
+	// The percentage of all other users, from 0 to 100, the filter is satisfied for.
+	DefaultRolloutPercentage float64
+
+	// Users and groups the filter isn't satisfied for, even if they're also in Users or Groups.
+	Exclusion *TargetingExclusion `json:",omitempty"`
+}
+
+// TargetingGroup is a group of users in the audience of a targeting filter.
+type TargetingGroup struct {
+	// The name of the group.
+	Name string
+
+	// The percentage of the group's users, from 0 to 100, the filter is satisfied for.
+	RolloutPercentage float64
+}
+
+// TargetingExclusion are the users and groups excluded from the audience of a targeting filter.
+type TargetingExclusion struct {
+	// The IDs of the excluded users.
+	Users []string `json:",omitempty"`
+
+	// The names of the excluded groups.
+	Groups []string `json:",omitempty"`
+}
+
+// NewPercentageFilter returns a filter satisfied for the specified percentage of evaluations.
+func NewPercentageFilter(parameters PercentageFilterParameters) FeatureFlagFilter {
+	return FeatureFlagFilter{Name: PercentageFilterName, Parameters: map[string]interface{}{"Value": parameters.Value}}
+}
+
+// NewTimeWindowFilter returns a filter satisfied between the specified times.
+func NewTimeWindowFilter(parameters TimeWindowFilterParameters) FeatureFlagFilter {
+	p := map[string]interface{}{}
+	if parameters.Start != nil {
+		p["Start"] = parameters.Start.UTC().Format(timeWindowFormat)
+	}
+	if parameters.End != nil {
+		p["End"] = parameters.End.UTC().Format(timeWindowFormat)
+	}
+	return FeatureFlagFilter{Name: TimeWindowFilterName, Parameters: p}
+}
+
+// NewTargetingFilter returns a filter satisfied for the specified audience.
+func NewTargetingFilter(parameters TargetingFilterParameters) FeatureFlagFilter {
+	// the parameters always marshal, and unmarshal to a map
+	data, _ := json.Marshal(parameters)
+	var p map[string]interface{}
+	_ = json.Unmarshal(data, &p)
+	return FeatureFlagFilter{Name: TargetingFilterName, Parameters: p}
+}
+
+// PercentageParameters returns the parameters of a percentage filter.
+func (f FeatureFlagFilter) PercentageParameters() (PercentageFilterParameters, error) {
+	var p PercentageFilterParameters
+	if err := f.unmarshalParameters(&p); err != nil {
+		return PercentageFilterParameters{}, err
+	}
+	if p.Value < 0 || p.Value > 100 {
+		return PercentageFilterParameters{}, fmt.Errorf("percentage %v isn't between 0 and 100", p.Value)
+	}
+	return p, nil
+}
+
+// TimeWindowParameters returns the parameters of a time window filter. Times are in the RFC 1123 format written by
+// the Azure portal, such as "Wed, 01 May 2019 13:59:59 GMT", or in the RFC 3339 format.
+func (f FeatureFlagFilter) TimeWindowParameters() (TimeWindowFilterParameters, error) {
+	var raw struct {
+		Start *string
+		End   *string
+	}
+	if err := f.unmarshalParameters(&raw); err != nil {
+		return TimeWindowFilterParameters{}, err
+	}
+	var p TimeWindowFilterParameters
+	var err error
+	if p.Start, err = parseTimeWindowTime(raw.Start); err != nil {
+		return TimeWindowFilterParameters{}, err
+	}
+	if p.End, err = parseTimeWindowTime(raw.End); err != nil {
+		return TimeWindowFilterParameters{}, err
+	}
+	if p.Start == nil && p.End == nil {
+		return TimeWindowFilterParameters{}, errors.New("time window filter must have a Start or an End")
+	}
+	return p, nil
+}
+
+func parseTimeWindowTime(s *string) (*time.Time, error) {
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{timeWindowFormat, time.RFC1123, time.RFC3339} {
+		if t, err := time.Parse(layout, *s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time window time %q", *s)
+}
+
+// TargetingParameters returns the parameters of a targeting filter.
+func (f FeatureFlagFilter) TargetingParameters() (TargetingFilterParameters, error) {
+	var p TargetingFilterParameters
+	if err := f.unmarshalParameters(&p); err != nil {
+		return TargetingFilterParameters{}, err
+	}
+	percentages := []float64{p.Audience.DefaultRolloutPercentage}
+	for _, g := range p.Audience.Groups {
+		percentages = append(percentages, g.RolloutPercentage)
+	}
+	for _, percentage := range percentages {
+		if percentage < 0 || percentage > 100 {
+			return TargetingFilterParameters{}, fmt.Errorf("rollout percentage %v isn't between 0 and 100", percentage)
+		}
+	}
+	return p, nil
+}
+
+// unmarshalParameters unmarshals the filter's parameters into v. Parameter names are matched case-insensitively.
+func (f FeatureFlagFilter) unmarshalParameters(v interface{}) error {
+	data, err := json.Marshal(f.Parameters)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid parameters for filter %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// IsFeatureFlagSetting returns true if the setting's key starts with FeatureFlagKeyPrefix and its content type is
+// FeatureFlagContentType.
+func IsFeatureFlagSetting(setting Setting) bool {
+	return setting.Key != nil && strings.HasPrefix(*setting.Key, FeatureFlagKeyPrefix) && hasMediaType(setting.ContentType, featureFlagMediaType)
+}
+
+// FeatureFlagSettingFromSetting parses a feature flag from its configuration setting. It returns an error if the
+// setting isn't a feature flag, as described for IsFeatureFlagSetting.
+func FeatureFlagSettingFromSetting(setting Setting) (FeatureFlagSetting, error) {
+	if !IsFeatureFlagSetting(setting) {
+		return FeatureFlagSetting{}, errors.New("setting isn't a feature flag")
+	}
+	var flag FeatureFlagSetting
+	if setting.Value != nil {
+		if err := json.Unmarshal([]byte(*setting.Value), &flag); err != nil {
+			return FeatureFlagSetting{}, err
+		}
+	}
+	if flag.ID == "" {
+		flag.ID = strings.TrimPrefix(*setting.Key, FeatureFlagKeyPrefix)
+	}
+	flag.Label = setting.Label
+	flag.ETag = setting.ETag
+	flag.Tags = setting.Tags
+	flag.LastModified = setting.LastModified
+	flag.IsReadOnly = setting.IsReadOnly
+	return flag, nil
+}
+
+// Key returns the key of the feature flag's configuration setting.
+func (f FeatureFlagSetting) Key() string {
+	return FeatureFlagKeyPrefix + f.ID
+}
+
+// ToSetting returns the configuration setting storing the feature flag. Pass its Value, Label and ContentType to
+// AddSetting or SetSetting to store the feature flag.
+func (f FeatureFlagSetting) ToSetting() (Setting, error) {
+	if f.ID == "" {
+		return Setting{}, errors.New("feature flag must have an ID")
+	}
+	if f.Conditions.ClientFilters == nil {
+		f.Conditions.ClientFilters = []FeatureFlagFilter{}
+	}
+	value, err := json.Marshal(f)
+	if err != nil {
+		return Setting{}, err
+	}
+	key, v, contentType := f.Key(), string(value), FeatureFlagContentType
+	return Setting{
+		Key:          &key,
+		Value:        &v,
+		Label:        f.Label,
+		ContentType:  &contentType,
+		ETag:         f.ETag,
+		Tags:         f.Tags,
+		LastModified: f.LastModified,
+		IsReadOnly:   f.IsReadOnly,
+	}, nil
+}
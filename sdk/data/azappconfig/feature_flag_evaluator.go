@@ -0,0 +1,209 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azappconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const builtInFilterPrefix = "Microsoft."
+
+// TargetingContext identifies the user a feature flag is evaluated for.
+type TargetingContext struct {
+	// The ID of the user.
+	UserID string
+
+	// The names of the groups the user belongs to.
+	Groups []string
+}
+
+// FeatureFilterEvaluationContext is the client filter of a feature flag being evaluated.
+type FeatureFilterEvaluationContext struct {
+	// The ID of the feature flag.
+	FeatureID string
+
+	// The client filter to evaluate.
+	Filter FeatureFlagFilter
+
+	// The user the feature flag is evaluated for. It's nil if the user isn't known.
+	Target *TargetingContext
+}
+
+// FeatureFilter evaluates client filters of feature flags.
+type FeatureFilter interface {
+	// Evaluate returns true if the client filter is satisfied.
+	Evaluate(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error)
+}
+
+// FeatureFilterFunc is a function implementing FeatureFilter.
+type FeatureFilterFunc func(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error)
+
+// Evaluate implements the FeatureFilter interface for the FeatureFilterFunc type.
+func (f FeatureFilterFunc) Evaluate(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error) {
+	return f(ctx, evaluation)
+}
+
+// FeatureFlagEvaluatorOptions contains the optional parameters for NewFeatureFlagEvaluator.
+type FeatureFlagEvaluatorOptions struct {
+	// Custom client filters by name. A custom filter replaces the built-in filter with the same name.
+	Filters map[string]FeatureFilter
+}
+
+// FeatureFlagEvaluator evaluates feature flags with the built-in percentage, time window and targeting filters, and
+// custom filters.
+//
+// The targeting filter assigns users to rollout percentages with the same hash as the .NET feature management
+// library, so a user is in the same rollout in every service. The percentage filter is evaluated the same way for
+// a known user, and randomly otherwise.
+type FeatureFlagEvaluator struct {
+	filters map[string]FeatureFilter
+	now     func() time.Time
+}
+
+// NewFeatureFlagEvaluator returns a FeatureFlagEvaluator. Pass nil to accept the default options.
+func NewFeatureFlagEvaluator(options *FeatureFlagEvaluatorOptions) *FeatureFlagEvaluator {
+	e := &FeatureFlagEvaluator{
+		now: time.Now,
+	}
+	e.filters = map[string]FeatureFilter{
+		PercentageFilterName: FeatureFilterFunc(evaluatePercentageFilter),
+		TimeWindowFilterName: FeatureFilterFunc(e.evaluateTimeWindowFilter),
+		TargetingFilterName:  FeatureFilterFunc(evaluateTargetingFilter),
+	}
+	if options != nil {
+		for name, f := range options.Filters {
+			e.filters[name] = f
+		}
+	}
+	return e
+}
+
+// IsEnabled returns true if the feature flag is enabled for the target, which is nil if the user isn't known.
+// A feature flag is enabled if its Enabled field is true and any or all of its client filters, as specified by its
+// RequirementType, are satisfied. IsEnabled returns an error for a client filter without a FeatureFilter, or with
+// invalid parameters.
+func (e *FeatureFlagEvaluator) IsEnabled(ctx context.Context, flag FeatureFlagSetting, target *TargetingContext) (bool, error) {
+	if !flag.Enabled {
+		return false, nil
+	}
+	requireAll := false
+	switch flag.Conditions.RequirementType {
+	case "", FeatureFlagRequirementTypeAny:
+	case FeatureFlagRequirementTypeAll:
+		requireAll = true
+	default:
+		return false, fmt.Errorf("feature flag %s has unknown requirement type %q", flag.ID, flag.Conditions.RequirementType)
+	}
+	if len(flag.Conditions.ClientFilters) == 0 {
+		return true, nil
+	}
+	for _, filter := range flag.Conditions.ClientFilters {
+		f, ok := e.filter(filter.Name)
+		if !ok {
+			return false, fmt.Errorf("feature flag %s has unknown filter %s", flag.ID, filter.Name)
+		}
+		satisfied, err := f.Evaluate(ctx, FeatureFilterEvaluationContext{FeatureID: flag.ID, Filter: filter, Target: target})
+		if err != nil {
+			return false, fmt.Errorf("feature flag %s: %w", flag.ID, err)
+		}
+		if satisfied != requireAll {
+			return satisfied, nil
+		}
+	}
+	return requireAll, nil
+}
+
+// filter returns the FeatureFilter for a filter name. Built-in filters may be named without the "Microsoft." prefix.
+func (e *FeatureFlagEvaluator) filter(name string) (FeatureFilter, bool) {
+	if f, ok := e.filters[name]; ok {
+		return f, true
+	}
+	if strings.HasPrefix(name, builtInFilterPrefix) {
+		f, ok := e.filters[strings.TrimPrefix(name, builtInFilterPrefix)]
+		return f, ok
+	}
+	f, ok := e.filters[builtInFilterPrefix+name]
+	return f, ok
+}
+
+func evaluatePercentageFilter(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error) {
+	p, err := evaluation.Filter.PercentageParameters()
+	if err != nil {
+		return false, err
+	}
+	if evaluation.Target == nil || evaluation.Target.UserID == "" {
+		return rand.Float64()*100 < p.Value, nil
+	}
+	return isTargeted(evaluation.Target.UserID+"\n"+evaluation.FeatureID, p.Value), nil
+}
+
+func (e *FeatureFlagEvaluator) evaluateTimeWindowFilter(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error) {
+	p, err := evaluation.Filter.TimeWindowParameters()
+	if err != nil {
+		return false, err
+	}
+	now := e.now()
+	return (p.Start == nil || !now.Before(*p.Start)) && (p.End == nil || now.Before(*p.End)), nil
+}
+
+func evaluateTargetingFilter(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error) {
+	p, err := evaluation.Filter.TargetingParameters()
+	if err != nil {
+		return false, err
+	}
+	target := evaluation.Target
+	if target == nil {
+		return false, nil
+	}
+	audience := p.Audience
+	if e := audience.Exclusion; e != nil {
+		if contains(e.Users, target.UserID) {
+			return false, nil
+		}
+		for _, g := range target.Groups {
+			if contains(e.Groups, g) {
+				return false, nil
+			}
+		}
+	}
+	if contains(audience.Users, target.UserID) {
+		return true, nil
+	}
+	for _, group := range audience.Groups {
+		if contains(target.Groups, group.Name) && isTargeted(target.UserID+"\n"+evaluation.FeatureID+"\n"+group.Name, group.RolloutPercentage) {
+			return true, nil
+		}
+	}
+	return isTargeted(target.UserID+"\n"+evaluation.FeatureID, audience.DefaultRolloutPercentage), nil
+}
+
+// isTargeted returns true if the context ID is in the percentage of IDs rolled out to. An ID's position is
+// the first four bytes of its SHA-256 hash, as the .NET feature management library computes it.
+func isTargeted(contextID string, percentage float64) bool {
+	if percentage >= 100 {
+		return true
+	}
+	hash := sha256.Sum256([]byte(contextID))
+	position := float64(binary.LittleEndian.Uint32(hash[:4])) / math.MaxUint32 * 100
+	return position < percentage
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
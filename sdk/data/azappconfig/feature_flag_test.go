@@ -0,0 +1,260 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azappconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/stretchr/testify/require"
+)
+
+const portalFeatureFlag = `{
+	"id": "Beta",
+	"description": "The beta UI",
+	"enabled": true,
+	"conditions": {
+		"client_filters": [
+			{"name": "Microsoft.TimeWindow", "parameters": {"Start": "Wed, 01 May 2019 13:59:59 GMT", "End": ""}},
+			{"name": "Microsoft.Targeting", "parameters": {"Audience": {"Users": ["alice"], "Groups": [{"Name": "ring0", "RolloutPercentage": 50}], "DefaultRolloutPercentage": 10, "Exclusion": {"Users": ["mallory"]}}}}
+		]
+	}
+}`
+
+func TestFeatureFlagSettingFromSetting(t *testing.T) {
+	key, value, label, contentType := FeatureFlagKeyPrefix+"Beta", portalFeatureFlag, "prod", FeatureFlagContentType
+	etag := azcore.ETag("etag")
+	flag, err := FeatureFlagSettingFromSetting(Setting{Key: &key, Value: &value, Label: &label, ContentType: &contentType, ETag: &etag})
+	require.NoError(t, err)
+	require.Equal(t, "Beta", flag.ID)
+	require.Equal(t, "The beta UI", flag.Description)
+	require.True(t, flag.Enabled)
+	require.Equal(t, &label, flag.Label)
+	require.Equal(t, &etag, flag.ETag)
+	require.Len(t, flag.Conditions.ClientFilters, 2)
+
+	window, err := flag.Conditions.ClientFilters[0].TimeWindowParameters()
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2019, 5, 1, 13, 59, 59, 0, time.UTC), window.Start.UTC())
+	require.Nil(t, window.End)
+
+	targeting, err := flag.Conditions.ClientFilters[1].TargetingParameters()
+	require.NoError(t, err)
+	require.Equal(t, TargetingAudience{
+		Users:                    []string{"alice"},
+		Groups:                   []TargetingGroup{{Name: "ring0", RolloutPercentage: 50}},
+		DefaultRolloutPercentage: 10,
+		Exclusion:                &TargetingExclusion{Users: []string{"mallory"}},
+	}, targeting.Audience)
+
+	setting, err := flag.ToSetting()
+	require.NoError(t, err)
+	require.Equal(t, key, *setting.Key)
+	require.Equal(t, FeatureFlagContentType, *setting.ContentType)
+	require.Equal(t, &label, setting.Label)
+	roundTripped, err := FeatureFlagSettingFromSetting(setting)
+	require.NoError(t, err)
+	require.Equal(t, flag, roundTripped)
+
+	for _, s := range []Setting{
+		{Key: &key, Value: &value},
+		{Key: &value, Value: &value, ContentType: &contentType},
+	} {
+		require.False(t, IsFeatureFlagSetting(s))
+		_, err := FeatureFlagSettingFromSetting(s)
+		require.Error(t, err)
+	}
+	invalid := "{"
+	_, err = FeatureFlagSettingFromSetting(Setting{Key: &key, Value: &invalid, ContentType: &contentType})
+	require.Error(t, err)
+}
+
+func TestFeatureFlagFilters(t *testing.T) {
+	start := time.Date(2022, 12, 1, 8, 0, 0, 0, time.UTC)
+	flag := FeatureFlagSetting{
+		ID:      "Beta",
+		Enabled: true,
+		Conditions: FeatureFlagConditions{
+			RequirementType: FeatureFlagRequirementTypeAll,
+			ClientFilters: []FeatureFlagFilter{
+				NewPercentageFilter(PercentageFilterParameters{Value: 20}),
+				NewTimeWindowFilter(TimeWindowFilterParameters{Start: &start}),
+				NewTargetingFilter(TargetingFilterParameters{Audience: TargetingAudience{Users: []string{"alice"}}}),
+			},
+		},
+	}
+	setting, err := flag.ToSetting()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"Beta","enabled":true,"conditions":{"requirement_type":"All","client_filters":[
+		{"name":"Microsoft.Percentage","parameters":{"Value":20}},
+		{"name":"Microsoft.TimeWindow","parameters":{"Start":"Thu, 01 Dec 2022 08:00:00 GMT"}},
+		{"name":"Microsoft.Targeting","parameters":{"Audience":{"Users":["alice"],"DefaultRolloutPercentage":0}}}
+	]}}`, *setting.Value)
+
+	parsed, err := FeatureFlagSettingFromSetting(setting)
+	require.NoError(t, err)
+	percentage, err := parsed.Conditions.ClientFilters[0].PercentageParameters()
+	require.NoError(t, err)
+	require.Equal(t, PercentageFilterParameters{Value: 20}, percentage)
+	window, err := parsed.Conditions.ClientFilters[1].TimeWindowParameters()
+	require.NoError(t, err)
+	require.True(t, start.Equal(*window.Start))
+
+	for _, f := range []FeatureFlagFilter{
+		NewPercentageFilter(PercentageFilterParameters{Value: 101}),
+		{Name: PercentageFilterName, Parameters: map[string]interface{}{"Value": "half"}},
+	} {
+		_, err := f.PercentageParameters()
+		require.Error(t, err)
+	}
+	for _, f := range []FeatureFlagFilter{
+		NewTimeWindowFilter(TimeWindowFilterParameters{}),
+		{Name: TimeWindowFilterName, Parameters: map[string]interface{}{"Start": "tomorrow"}},
+	} {
+		_, err := f.TimeWindowParameters()
+		require.Error(t, err)
+	}
+	_, err = NewTargetingFilter(TargetingFilterParameters{Audience: TargetingAudience{Groups: []TargetingGroup{{Name: "g", RolloutPercentage: -1}}}}).TargetingParameters()
+	require.Error(t, err)
+}
+
+func TestFeatureFlagEvaluator(t *testing.T) {
+	evaluator := NewFeatureFlagEvaluator(nil)
+	isEnabled := func(flag FeatureFlagSetting, target *TargetingContext) bool {
+		enabled, err := evaluator.IsEnabled(context.Background(), flag, target)
+		require.NoError(t, err)
+		return enabled
+	}
+	alice := &TargetingContext{UserID: "alice"}
+
+	require.False(t, isEnabled(FeatureFlagSetting{ID: "f"}, alice))
+	require.True(t, isEnabled(FeatureFlagSetting{ID: "f", Enabled: true}, alice))
+
+	always := NewPercentageFilter(PercentageFilterParameters{Value: 100})
+	never := NewPercentageFilter(PercentageFilterParameters{Value: 0})
+	require.True(t, isEnabled(FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{never, always}}}, nil))
+	require.False(t, isEnabled(FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{never, never}}}, nil))
+	require.False(t, isEnabled(FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{RequirementType: FeatureFlagRequirementTypeAll, ClientFilters: []FeatureFlagFilter{always, never}}}, nil))
+	require.True(t, isEnabled(FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{RequirementType: FeatureFlagRequirementTypeAll, ClientFilters: []FeatureFlagFilter{always, always}}}, nil))
+	require.False(t, isEnabled(FeatureFlagSetting{ID: "f", Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{always}}}, nil))
+
+	// built-in filters can be named without the "Microsoft." prefix
+	require.True(t, isEnabled(FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{{Name: "Percentage", Parameters: always.Parameters}}}}, nil))
+
+	for _, flag := range []FeatureFlagSetting{
+		{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{{Name: "Unknown"}}}},
+		{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{NewPercentageFilter(PercentageFilterParameters{Value: 200})}}},
+		{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{RequirementType: "Some", ClientFilters: []FeatureFlagFilter{always}}},
+	} {
+		_, err := evaluator.IsEnabled(context.Background(), flag, alice)
+		require.Error(t, err)
+	}
+}
+
+func TestFeatureFlagEvaluatorTimeWindow(t *testing.T) {
+	evaluator := NewFeatureFlagEvaluator(nil)
+	start, end := time.Date(2022, 12, 1, 8, 0, 0, 0, time.UTC), time.Date(2022, 12, 2, 8, 0, 0, 0, time.UTC)
+	flag := FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{
+		NewTimeWindowFilter(TimeWindowFilterParameters{Start: &start, End: &end}),
+	}}}
+	for now, expected := range map[time.Time]bool{
+		start.Add(-time.Second): false,
+		start:                   true,
+		end.Add(-time.Second):   true,
+		end:                     false,
+	} {
+		evaluator.now = func() time.Time { return now }
+		enabled, err := evaluator.IsEnabled(context.Background(), flag, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, enabled, "at %v", now)
+	}
+}
+
+func TestFeatureFlagEvaluatorTargeting(t *testing.T) {
+	evaluator := NewFeatureFlagEvaluator(nil)
+	flag := FeatureFlagSetting{ID: "Beta", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{
+		NewTargetingFilter(TargetingFilterParameters{Audience: TargetingAudience{
+			Users:                    []string{"alice", "mallory"},
+			Groups:                   []TargetingGroup{{Name: "ring0", RolloutPercentage: 100}, {Name: "ring1", RolloutPercentage: 50}},
+			DefaultRolloutPercentage: 20,
+			Exclusion:                &TargetingExclusion{Users: []string{"mallory"}, Groups: []string{"blocked"}},
+		}}),
+	}}}
+	isEnabled := func(target *TargetingContext) bool {
+		enabled, err := evaluator.IsEnabled(context.Background(), flag, target)
+		require.NoError(t, err)
+		return enabled
+	}
+
+	require.False(t, isEnabled(nil))
+	require.True(t, isEnabled(&TargetingContext{UserID: "alice"}))
+	require.False(t, isEnabled(&TargetingContext{UserID: "mallory"}))
+	require.False(t, isEnabled(&TargetingContext{UserID: "alice", Groups: []string{"blocked"}}))
+	require.True(t, isEnabled(&TargetingContext{UserID: "bob", Groups: []string{"ring0"}}))
+
+	// rollouts are deterministic per user, and cover about their percentage of users
+	ring1, others := 0, 0
+	for i := 0; i < 2000; i++ {
+		user := fmt.Sprintf("user%d", i)
+		enabled := isEnabled(&TargetingContext{UserID: user, Groups: []string{"ring1"}})
+		require.Equal(t, enabled, isEnabled(&TargetingContext{UserID: user, Groups: []string{"ring1"}}))
+		if enabled {
+			ring1++
+		}
+		if isEnabled(&TargetingContext{UserID: user}) {
+			others++
+		}
+	}
+	// ring1's users are also in the default rollout
+	require.InDelta(t, 1200, ring1, 100)
+	require.InDelta(t, 400, others, 80)
+
+	// users rolled out to by percentage stay rolled out when the percentage grows
+	percentage := func(value float64) FeatureFlagSetting {
+		return FeatureFlagSetting{ID: "Beta", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{
+			NewPercentageFilter(PercentageFilterParameters{Value: value}),
+		}}}
+	}
+	for i := 0; i < 200; i++ {
+		target := &TargetingContext{UserID: fmt.Sprintf("user%d", i)}
+		at10, err := evaluator.IsEnabled(context.Background(), percentage(10), target)
+		require.NoError(t, err)
+		at50, err := evaluator.IsEnabled(context.Background(), percentage(50), target)
+		require.NoError(t, err)
+		require.False(t, at10 && !at50)
+	}
+}
+
+func TestFeatureFlagEvaluatorCustomFilter(t *testing.T) {
+	var evaluated FeatureFilterEvaluationContext
+	evaluator := NewFeatureFlagEvaluator(&FeatureFlagEvaluatorOptions{Filters: map[string]FeatureFilter{
+		"Region": FeatureFilterFunc(func(ctx context.Context, evaluation FeatureFilterEvaluationContext) (bool, error) {
+			evaluated = evaluation
+			region, ok := evaluation.Filter.Parameters["Region"].(string)
+			if !ok {
+				return false, errors.New("missing region")
+			}
+			return region == "westus", nil
+		}),
+	}})
+	target := &TargetingContext{UserID: "alice"}
+	flag := FeatureFlagSetting{ID: "f", Enabled: true, Conditions: FeatureFlagConditions{ClientFilters: []FeatureFlagFilter{
+		{Name: "Region", Parameters: map[string]interface{}{"Region": "westus"}},
+	}}}
+	enabled, err := evaluator.IsEnabled(context.Background(), flag, target)
+	require.NoError(t, err)
+	require.True(t, enabled)
+	require.Equal(t, FeatureFilterEvaluationContext{FeatureID: "f", Filter: flag.Conditions.ClientFilters[0], Target: target}, evaluated)
+
+	flag.Conditions.ClientFilters[0].Parameters = nil
+	_, err = evaluator.IsEnabled(context.Background(), flag, target)
+	require.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 )
@@ -22,6 +23,7 @@ type syncToken struct {
 }
 
 type syncTokenPolicy struct {
+	mu         sync.Mutex
 	syncTokens map[string]syncToken
 }
 
@@ -75,9 +77,11 @@ func parseToken(tok string) (syncToken, error) {
 }
 
 func (policy *syncTokenPolicy) addToken(tok string) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
 	for _, t := range strings.Split(tok, ",") {
 		if st, err := parseToken(t); err == nil {
-			if existing := policy.syncTokens[st.id]; existing.seqNo < st.seqNo {
+			if existing, ok := policy.syncTokens[st.id]; !ok || existing.seqNo < st.seqNo {
 				policy.syncTokens[st.id] = st
 			}
 		}
@@ -87,11 +91,15 @@ func (policy *syncTokenPolicy) addToken(tok string) {
 func (policy *syncTokenPolicy) Do(req *policy.Request) (*http.Response, error) {
 	const syncTokenHeaderName = "Sync-Token"
 	var tokens []string
+	policy.mu.Lock()
 	for _, st := range policy.syncTokens {
-		tokens = append(tokens, st.id)
+		tokens = append(tokens, st.id+"="+st.value)
 	}
+	policy.mu.Unlock()
 
-	req.Raw().Header[syncTokenHeaderName] = tokens
+	if len(tokens) > 0 {
+		req.Raw().Header.Set(syncTokenHeaderName, strings.Join(tokens, ","))
+	}
 
 	resp, err := req.Next()
 
@@ -58,6 +58,30 @@ func TestSyncTokenPolicy(t *testing.T) {
 	require.Equal(t, "jtqGc1I4", st.id)
 	require.Equal(t, int64(28), st.seqNo)
 	require.Equal(t, "MDoyOA==", st.value)
+
+	// the token is sent with later requests
+	var sent []string
+	pl = runtime.NewPipeline("TestSyncTokenPolicy", moduleVersion, runtime.PipelineOptions{PerRetry: []policy.Policy{stp}}, &policy.ClientOptions{
+		Transport: TransportFunc(func(req *http.Request) (*http.Response, error) {
+			sent = req.Header.Values("Sync-Token")
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}}, nil
+		}),
+	})
+	req, err = runtime.NewRequest(context.Background(), http.MethodGet, "http://test.contoso.com")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"jtqGc1I4=MDoyOA=="}, sent)
+}
+
+func TestSyncTokenPolicyAddToken(t *testing.T) {
+	stp := newSyncTokenPolicy()
+	stp.addToken("a=1")
+	require.Equal(t, syncToken{id: "a", value: "1"}, stp.syncTokens["a"])
+	stp.addToken("a=2;sn=2,b=3;sn=1")
+	stp.addToken("a=old;sn=1")
+	require.Equal(t, syncToken{id: "a", value: "2", seqNo: 2}, stp.syncTokens["a"])
+	require.Equal(t, syncToken{id: "b", value: "3", seqNo: 1}, stp.syncTokens["b"])
 }
 
 func TestSyncTokenPolicyError(t *testing.T) {
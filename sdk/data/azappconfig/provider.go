@@ -0,0 +1,340 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azappconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	// KeyVaultReferenceContentType is the content type of settings referencing Azure Key Vault secrets.
+	KeyVaultReferenceContentType = "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"
+
+	keyVaultReferenceMediaType = "application/vnd.microsoft.appconfig.keyvaultref+json"
+
+	// nullLabel is the label filter selecting settings without a label.
+	nullLabel = "\x00"
+
+	defaultKeySeparator    = ":"
+	defaultRefreshInterval = 30 * time.Second
+)
+
+// SecretResolver resolves the secrets referenced by Key Vault reference settings.
+type SecretResolver interface {
+	// ResolveSecret returns the value of the secret with the specified URI, such as
+	// "https://myvault.vault.azure.net/secrets/mysecret".
+	ResolveSecret(ctx context.Context, secretURI string) (string, error)
+}
+
+// SecretResolverFunc is a function implementing SecretResolver.
+type SecretResolverFunc func(ctx context.Context, secretURI string) (string, error)
+
+// ResolveSecret implements the SecretResolver interface for the SecretResolverFunc type.
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, secretURI string) (string, error) {
+	return f(ctx, secretURI)
+}
+
+// ProviderOptions contains the optional parameters for NewProvider.
+type ProviderOptions struct {
+	// Selectors select the settings to load. A setting selected by several selectors has the value selected by the
+	// last of them, so a selector for a label such as "prod" after a selector for settings without a label overrides
+	// their values. The default selects all settings without a label. Fields and AcceptDateTime are ignored.
+	Selectors []SettingSelector
+
+	// TrimKeyPrefixes are prefixes removed from the keys of settings. The first prefix in the list that a key starts
+	// with is removed.
+	TrimKeyPrefixes []string
+
+	// KeySeparator separates the segments of hierarchical keys, as used by Provider.Map and Provider.Bind. The default
+	// is ":".
+	KeySeparator string
+
+	// SecretResolver resolves Key Vault reference settings. If nil, loading a Key Vault reference returns an error.
+	SecretResolver SecretResolver
+
+	// Refresh configures the refresh of the settings. If nil, the settings aren't refreshed.
+	Refresh *ProviderRefreshOptions
+}
+
+// ProviderRefreshOptions configures the refresh of a Provider's settings.
+type ProviderRefreshOptions struct {
+	// SentinelKey is the key of the sentinel setting. All settings are reloaded when the sentinel's ETag changes, so
+	// update the sentinel after updating other settings.
+	SentinelKey string
+
+	// SentinelLabel is the label of the sentinel setting.
+	SentinelLabel *string
+
+	// Interval is the minimum time between checks of the sentinel. The default is 30 seconds.
+	Interval time.Duration
+
+	// OnRefreshError is called with the errors of refreshes by Provider.Run.
+	OnRefreshError func(error)
+}
+
+// Provider loads configuration settings selected by key and label, and refreshes them when a sentinel setting
+// changes. Feature flag settings are skipped. Each Provider is safe for concurrent use.
+type Provider struct {
+	client    *Client
+	selectors []SettingSelector
+	prefixes  []string
+	separator string
+	resolver  SecretResolver
+	refresh   *ProviderRefreshOptions
+	interval  time.Duration
+	now       func() time.Time
+
+	// refreshMu serializes loads and sentinel checks.
+	refreshMu    sync.Mutex
+	sentinelETag *azcore.ETag
+	nextCheck    time.Time
+
+	mu       sync.RWMutex
+	settings map[string]string
+}
+
+// NewProvider creates a Provider and loads its settings. Pass nil to accept the default options.
+func NewProvider(ctx context.Context, client *Client, options *ProviderOptions) (*Provider, error) {
+	if options == nil {
+		options = &ProviderOptions{}
+	}
+	p := &Provider{
+		client:    client,
+		selectors: options.Selectors,
+		prefixes:  options.TrimKeyPrefixes,
+		separator: options.KeySeparator,
+		resolver:  options.SecretResolver,
+		refresh:   options.Refresh,
+		now:       time.Now,
+	}
+	if len(p.selectors) == 0 {
+		all, noLabel := "*", nullLabel
+		p.selectors = []SettingSelector{{KeyFilter: &all, LabelFilter: &noLabel}}
+	}
+	if p.separator == "" {
+		p.separator = defaultKeySeparator
+	}
+	if p.refresh != nil {
+		if p.refresh.SentinelKey == "" {
+			return nil, errors.New("refresh options must have a SentinelKey")
+		}
+		p.interval = p.refresh.Interval
+		if p.interval <= 0 {
+			p.interval = defaultRefreshInterval
+		}
+	}
+
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+	if err := p.load(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// load reads the sentinel's ETag and loads the settings. The caller must hold p.refreshMu.
+func (p *Provider) load(ctx context.Context) error {
+	// read the sentinel first, so that settings updated after it are loaded again on the next refresh
+	var sentinelETag *azcore.ETag
+	if p.refresh != nil {
+		etag, _, err := p.getSentinel(ctx, nil)
+		if err != nil {
+			return err
+		}
+		sentinelETag = etag
+	}
+
+	settings := map[string]string{}
+	for _, selector := range p.selectors {
+		pager := p.client.NewListSettingsPager(SettingSelector{KeyFilter: selector.KeyFilter, LabelFilter: selector.LabelFilter}, nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, setting := range page.Settings {
+				if setting.Key == nil || IsFeatureFlagSetting(setting) {
+					continue
+				}
+				value, err := p.settingValue(ctx, setting)
+				if err != nil {
+					return fmt.Errorf("setting %s: %w", *setting.Key, err)
+				}
+				settings[p.trimKey(*setting.Key)] = value
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.settings = settings
+	p.mu.Unlock()
+	p.sentinelETag = sentinelETag
+	p.nextCheck = p.now().Add(p.interval)
+	return nil
+}
+
+// settingValue returns the value of a setting, resolving Key Vault references.
+func (p *Provider) settingValue(ctx context.Context, setting Setting) (string, error) {
+	value := ""
+	if setting.Value != nil {
+		value = *setting.Value
+	}
+	if !hasMediaType(setting.ContentType, keyVaultReferenceMediaType) {
+		return value, nil
+	}
+	var reference struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal([]byte(value), &reference); err != nil {
+		return "", fmt.Errorf("invalid Key Vault reference: %w", err)
+	}
+	if reference.URI == "" {
+		return "", errors.New("Key Vault reference has no uri")
+	}
+	if p.resolver == nil {
+		return "", errors.New("Key Vault reference requires a SecretResolver")
+	}
+	return p.resolver.ResolveSecret(ctx, reference.URI)
+}
+
+func (p *Provider) trimKey(key string) string {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return key[len(prefix):]
+		}
+	}
+	return key
+}
+
+// getSentinel returns the sentinel's ETag, and whether it changed from the specified ETag. The ETag is nil if the
+// sentinel doesn't exist.
+func (p *Provider) getSentinel(ctx context.Context, etag *azcore.ETag) (*azcore.ETag, bool, error) {
+	resp, err := p.client.GetSetting(ctx, p.refresh.SentinelKey, &GetSettingOptions{Label: p.refresh.SentinelLabel, OnlyIfChanged: etag})
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.StatusCode {
+		case http.StatusNotModified:
+			return etag, false, nil
+		case http.StatusNotFound:
+			return nil, etag != nil, nil
+		}
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return resp.ETag, true, nil
+}
+
+// Refresh reloads the settings if the refresh interval has passed since the sentinel was last checked and the
+// sentinel's ETag changed. It returns true if the settings were reloaded. If the Provider has no refresh options,
+// Refresh does nothing. Calling Refresh often is cheap, because the sentinel is checked at most once per interval.
+func (p *Provider) Refresh(ctx context.Context) (bool, error) {
+	if p.refresh == nil {
+		return false, nil
+	}
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+	if p.now().Before(p.nextCheck) {
+		return false, nil
+	}
+	_, changed, err := p.getSentinel(ctx, p.sentinelETag)
+	if err != nil {
+		return false, err
+	}
+	p.nextCheck = p.now().Add(p.interval)
+	if !changed {
+		return false, nil
+	}
+	if err := p.load(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Run calls Refresh at the refresh interval until the context is done, and then returns the context's error. The
+// errors of refreshes are passed to the OnRefreshError callback of the refresh options. Run returns immediately if
+// the Provider has no refresh options.
+func (p *Provider) Run(ctx context.Context) error {
+	if p.refresh == nil {
+		return nil
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := p.Refresh(ctx); err != nil && p.refresh.OnRefreshError != nil {
+				p.refresh.OnRefreshError(err)
+			}
+		}
+	}
+}
+
+// UpdateSyncToken sets a synchronization token, such as one received in a change notification, so that the next
+// requests receive values at least as recent as the change, and makes the next Refresh check the sentinel.
+func (p *Provider) UpdateSyncToken(token string) {
+	p.client.UpdateSyncToken(token)
+	p.refreshMu.Lock()
+	p.nextCheck = time.Time{}
+	p.refreshMu.Unlock()
+}
+
+// Get returns the value of the setting with the specified key, after trimming key prefixes.
+func (p *Provider) Get(key string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	value, ok := p.settings[key]
+	return value, ok
+}
+
+// Settings returns the values of the settings by key, after trimming key prefixes.
+func (p *Provider) Settings() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	settings := make(map[string]string, len(p.settings))
+	for k, v := range p.settings {
+		settings[k] = v
+	}
+	return settings
+}
+
+// Map returns the settings as a hierarchy of maps, splitting keys at the key separator. The values of the map are
+// strings or maps of type map[string]interface{}. A setting whose key is also the parent of other keys, such as "a"
+// for "a:b", is omitted.
+func (p *Provider) Map() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	root := map[string]interface{}{}
+	for key, value := range p.settings {
+		segments := strings.Split(key, p.separator)
+		node := root
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[segment] = child
+			}
+			node = child
+		}
+		last := segments[len(segments)-1]
+		if _, ok := node[last].(map[string]interface{}); !ok {
+			node[last] = value
+		}
+	}
+	return root
+}
@@ -0,0 +1,197 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azappconfig
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bindTagName is the name of the struct tag naming the setting bound to a field.
+const bindTagName = "appconfig"
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind stores the settings, as returned by Map, in the value pointed to by v, which is usually a struct.
+//
+// A struct field is bound to the setting, or the map of settings, with the field's name matched case-insensitively.
+// The "appconfig" struct tag overrides the name, and the tag "-" skips the field. A map with string keys is bound to a
+// map of settings. A slice or an array is bound to a map of settings with the keys "0", "1" and so on.
+//
+// Values are parsed for fields of bool, integer, floating-point and time.Duration types, and of types implementing
+// encoding.TextUnmarshaler. A value bound to another type, such as a struct, is unmarshaled as JSON. Fields without
+// a setting are left unchanged.
+func (p *Provider) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("Bind requires a non-nil pointer")
+	}
+	return bindValue(p.Map(), rv.Elem(), "")
+}
+
+func bindValue(value interface{}, rv reflect.Value, path string) error {
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return bindValue(value, rv.Elem(), path)
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return bindMap(value, rv, path)
+	case string:
+		if err := bindString(value, rv); err != nil {
+			return fmt.Errorf("setting %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func bindMap(values map[string]interface{}, rv reflect.Value, path string) error {
+	switch rv.Kind() {
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag := field.Tag.Get(bindTagName); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			for key, value := range values {
+				if strings.EqualFold(key, name) {
+					if err := bindValue(value, rv.Field(i), joinPath(path, key)); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("setting %s: can't bind to %s", path, rv.Type())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for key, value := range values {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if existing := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err := bindValue(value, elem, joinPath(path, key)); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+		}
+	case reflect.Slice, reflect.Array:
+		indexes := make([]int, 0, len(values))
+		for key := range values {
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 {
+				return fmt.Errorf("setting %s: %q isn't an index", path, key)
+			}
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		if len(indexes) == 0 {
+			return nil
+		}
+		length := indexes[len(indexes)-1] + 1
+		if rv.Kind() == reflect.Array && length > rv.Len() {
+			return fmt.Errorf("setting %s: index %d is out of range for %s", path, length-1, rv.Type())
+		}
+		if rv.Kind() == reflect.Slice && length > rv.Len() {
+			grown := reflect.MakeSlice(rv.Type(), length, length)
+			reflect.Copy(grown, rv)
+			rv.Set(grown)
+		}
+		for _, i := range indexes {
+			if err := bindValue(values[strconv.Itoa(i)], rv.Index(i), joinPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return fmt.Errorf("setting %s: can't bind to %s", path, rv.Type())
+		}
+		rv.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("setting %s: can't bind settings to %s", path, rv.Type())
+	}
+	return nil
+}
+
+func bindString(value string, rv reflect.Value) error {
+	if rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		return rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if rv.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return fmt.Errorf("can't bind to %s", rv.Type())
+		}
+		rv.Set(reflect.ValueOf(value))
+	default:
+		return json.Unmarshal([]byte(value), rv.Addr().Interface())
+	}
+	return nil
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
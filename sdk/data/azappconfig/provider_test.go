@@ -0,0 +1,222 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License. See License.txt in the project root for license information.
+
+package azappconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore serves the settings of an App Configuration store.
+type fakeStore struct {
+	mu         sync.Mutex
+	settings   []map[string]string
+	sentinel   string
+	lists      int
+	syncTokens []string
+}
+
+func (s *fakeStore) set(key, label, value, contentType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = append(s.settings, map[string]string{"key": key, "label": label, "value": value, "content_type": contentType})
+}
+
+func (s *fakeStore) do(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncTokens = append(s.syncTokens, req.Header.Get("Sync-Token"))
+	label := req.URL.Query().Get("label")
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{"application/json"}}, Request: req}
+	if strings.HasPrefix(req.URL.Path, "/kv/") {
+		key := strings.TrimPrefix(req.URL.Path, "/kv/")
+		if key != "sentinel" || s.sentinel == "" {
+			resp.StatusCode = http.StatusNotFound
+			resp.Body = http.NoBody
+			return resp, nil
+		}
+		if req.Header.Get("If-None-Match") == `"`+s.sentinel+`"` {
+			resp.StatusCode = http.StatusNotModified
+			resp.Body = http.NoBody
+			return resp, nil
+		}
+		resp.Header.Set("ETag", `"`+s.sentinel+`"`)
+		resp.Body = io.NopCloser(strings.NewReader(`{"key":"sentinel","value":"` + s.sentinel + `","etag":"` + s.sentinel + `"}`))
+		return resp, nil
+	}
+	s.lists++
+	items := []map[string]string{}
+	for _, setting := range s.settings {
+		if setting["label"] == label || (label == nullLabel && setting["label"] == "") {
+			item := map[string]string{"key": setting["key"], "value": setting["value"], "content_type": setting["content_type"]}
+			if setting["label"] != "" {
+				item["label"] = setting["label"]
+			}
+			items = append(items, item)
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"items": items})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func newFakeStoreClient(t *testing.T, s *fakeStore) *Client {
+	client, err := NewClientFromConnectionString("Endpoint=https://contoso.azconfig.io;Id=id;Secret=c2VjcmV0", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Transport: TransportFunc(s.do),
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestProvider(t *testing.T) {
+	s := &fakeStore{}
+	s.set("app:Title", "", "Contoso", "")
+	s.set("app:Color", "", "red", "")
+	s.set("app:Color", "prod", "blue", "")
+	s.set("app:Limits:Max", "", "10", "")
+	s.set("app:Timeout", "", "5s", "")
+	s.set("app:Servers:0", "", "a", "")
+	s.set("app:Servers:1", "", "b", "")
+	s.set(FeatureFlagKeyPrefix+"Beta", "", `{"id":"Beta","enabled":true}`, FeatureFlagContentType)
+
+	all, noLabel, prod := "*", nullLabel, "prod"
+	p, err := NewProvider(context.Background(), newFakeStoreClient(t, s), &ProviderOptions{
+		Selectors:       []SettingSelector{{KeyFilter: &all, LabelFilter: &noLabel}, {KeyFilter: &all, LabelFilter: &prod}},
+		TrimKeyPrefixes: []string{"app:"},
+	})
+	require.NoError(t, err)
+
+	value, ok := p.Get("Color")
+	require.True(t, ok)
+	require.Equal(t, "blue", value)
+	_, ok = p.Get(FeatureFlagKeyPrefix + "Beta")
+	require.False(t, ok)
+	require.Len(t, p.Settings(), 6)
+	require.Equal(t, map[string]interface{}{"Max": "10"}, p.Map()["Limits"])
+
+	var config struct {
+		Title  string
+		Color  string `appconfig:"color"`
+		Limits struct {
+			Max int
+		}
+		Timeout time.Duration
+		Servers []string
+		Ignored string `appconfig:"-"`
+	}
+	require.NoError(t, p.Bind(&config))
+	require.Equal(t, "Contoso", config.Title)
+	require.Equal(t, "blue", config.Color)
+	require.Equal(t, 10, config.Limits.Max)
+	require.Equal(t, 5*time.Second, config.Timeout)
+	require.Equal(t, []string{"a", "b"}, config.Servers)
+
+	var invalid struct {
+		Limits struct {
+			Max bool
+		}
+	}
+	require.Error(t, p.Bind(&invalid))
+	require.Error(t, p.Bind(config))
+
+	ok, err = p.Refresh(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestProviderKeyVaultReference(t *testing.T) {
+	s := &fakeStore{}
+	s.set("password", "", `{"uri":"https://contoso.vault.azure.net/secrets/password"}`, KeyVaultReferenceContentType)
+
+	_, err := NewProvider(context.Background(), newFakeStoreClient(t, s), nil)
+	require.Error(t, err)
+
+	p, err := NewProvider(context.Background(), newFakeStoreClient(t, s), &ProviderOptions{
+		SecretResolver: SecretResolverFunc(func(ctx context.Context, secretURI string) (string, error) {
+			require.Equal(t, "https://contoso.vault.azure.net/secrets/password", secretURI)
+			return "secret", nil
+		}),
+	})
+	require.NoError(t, err)
+	value, ok := p.Get("password")
+	require.True(t, ok)
+	require.Equal(t, "secret", value)
+}
+
+func TestProviderRefresh(t *testing.T) {
+	s := &fakeStore{sentinel: "1"}
+	s.set("color", "", "red", "")
+
+	_, err := NewProvider(context.Background(), newFakeStoreClient(t, s), &ProviderOptions{Refresh: &ProviderRefreshOptions{}})
+	require.Error(t, err)
+
+	p, err := NewProvider(context.Background(), newFakeStoreClient(t, s), &ProviderOptions{
+		Refresh: &ProviderRefreshOptions{SentinelKey: "sentinel", Interval: time.Minute},
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	require.Equal(t, 1, s.lists)
+
+	// the sentinel isn't checked before the interval has passed
+	s.set("size", "", "large", "")
+	s.sentinel = "2"
+	ok, err := p.Refresh(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok = p.Get("size")
+	require.False(t, ok)
+
+	now = now.Add(time.Minute)
+	ok, err = p.Refresh(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+	value, ok := p.Get("size")
+	require.True(t, ok)
+	require.Equal(t, "large", value)
+	require.Equal(t, 2, s.lists)
+
+	// an unchanged sentinel doesn't reload the settings
+	now = now.Add(time.Minute)
+	ok, err = p.Refresh(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 2, s.lists)
+
+	// a sync token makes the next refresh check the sentinel, and is sent with the requests
+	s.sentinel = "3"
+	p.UpdateSyncToken("jtqGc1I4=MDoyOA==;sn=28")
+	ok, err = p.Refresh(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 3, s.lists)
+	require.Equal(t, "jtqGc1I4=MDoyOA==", s.syncTokens[len(s.syncTokens)-1])
+
+	// a deleted sentinel is a change
+	s.sentinel = ""
+	now = now.Add(time.Minute)
+	ok, err = p.Refresh(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+}
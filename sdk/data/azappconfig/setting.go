@@ -7,6 +7,7 @@
 package azappconfig
 
 import (
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
@@ -83,3 +84,12 @@ func (cs Setting) toGenerated() generated.KeyValue {
 		Value:        cs.Value,
 	}
 }
+
+// hasMediaType returns true if the content type has the specified media type, ignoring its parameters.
+func hasMediaType(contentType *string, mediaType string) bool {
+	if contentType == nil {
+		return false
+	}
+	mt, _, _ := strings.Cut(*contentType, ";")
+	return strings.EqualFold(strings.TrimSpace(mt), mediaType)
+}
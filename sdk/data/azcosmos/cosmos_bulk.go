@@ -0,0 +1,595 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const (
+	// maxBulkBatchOperations and maxBulkBatchBodyBytes are the service's limits for a batch request
+	maxBulkBatchOperations      = 100
+	maxBulkBatchBodyBytes       = 2 * 1024 * 1024
+	defaultBulkMaxConcurrency   = 10
+	defaultBulkFlushInterval    = 100 * time.Millisecond
+	defaultBulkMaxRetryAttempts = 9
+	defaultBulkRetryAfter       = time.Second
+)
+
+// BulkOperation is an item operation executed by ContainerClient.ExecuteBulk.
+type BulkOperation struct {
+	partitionKey PartitionKey
+	operation    batchOperation
+}
+
+// NewBulkCreateItem creates an operation which creates an item.
+func NewBulkCreateItem(partitionKey PartitionKey, item []byte, o *TransactionalBatchItemOptions) BulkOperation {
+	b := TransactionalBatch{}
+	b.CreateItem(item, o)
+	return BulkOperation{partitionKey: partitionKey, operation: b.operations[0]}
+}
+
+// NewBulkUpsertItem creates an operation which creates or replaces an item.
+func NewBulkUpsertItem(partitionKey PartitionKey, item []byte, o *TransactionalBatchItemOptions) BulkOperation {
+	b := TransactionalBatch{}
+	b.UpsertItem(item, o)
+	return BulkOperation{partitionKey: partitionKey, operation: b.operations[0]}
+}
+
+// NewBulkReplaceItem creates an operation which replaces an item.
+func NewBulkReplaceItem(partitionKey PartitionKey, itemID string, item []byte, o *TransactionalBatchItemOptions) BulkOperation {
+	b := TransactionalBatch{}
+	b.ReplaceItem(itemID, item, o)
+	return BulkOperation{partitionKey: partitionKey, operation: b.operations[0]}
+}
+
+// NewBulkDeleteItem creates an operation which deletes an item.
+func NewBulkDeleteItem(partitionKey PartitionKey, itemID string, o *TransactionalBatchItemOptions) BulkOperation {
+	b := TransactionalBatch{}
+	b.DeleteItem(itemID, o)
+	return BulkOperation{partitionKey: partitionKey, operation: b.operations[0]}
+}
+
+// NewBulkPatchItem creates an operation which patches an item.
+func NewBulkPatchItem(partitionKey PartitionKey, itemID string, ops PatchOperations, o *TransactionalBatchItemOptions) BulkOperation {
+	b := TransactionalBatch{}
+	b.PatchItem(itemID, ops, o)
+	return BulkOperation{partitionKey: partitionKey, operation: b.operations[0]}
+}
+
+// BulkOptions includes options for ContainerClient.ExecuteBulk.
+type BulkOptions struct {
+	// MaxConcurrency limits the number of batch requests in flight. Defaults to 10.
+	MaxConcurrency int
+	// FlushInterval is the longest time an operation waits for a batch to fill before the batch is sent.
+	// Defaults to 100 milliseconds.
+	FlushInterval time.Duration
+	// MaxRetryAttempts limits the number of times an operation is retried after the service throttles it.
+	// Defaults to 9.
+	MaxRetryAttempts int
+	// When EnableContentResponseOnWrite is true, results contain the written items.
+	EnableContentResponseOnWrite bool
+	// FeedRange limits the operations to the items in part of the container, for example when workers divide
+	// a container's items by the ranges of ContainerClient.GetFeedRanges. Operations for partition keys outside
+	// the range fail without being sent.
+	FeedRange *FeedRange
+}
+
+// BulkOperationResult is the result of a BulkOperation.
+type BulkOperationResult struct {
+	// Index is the position of the operation in the stream of operations, starting from 0.
+	Index int
+	// StatusCode is the status code of the operation.
+	StatusCode int32
+	// RequestCharge is the request charge of the operation, including retries.
+	RequestCharge float32
+	// ResourceBody contains the item when the operation returns it.
+	ResourceBody []byte
+	// ETag is the item's ETag after the operation.
+	ETag azcore.ETag
+	// Err is set when the operation couldn't be executed, for example because its batch request failed.
+	// StatusCode is then unset.
+	Err error
+}
+
+// ExecuteBulk executes the operations received from operations, grouping them into batch requests to the
+// partition key range of each operation's partition key. Batch requests aren't atomic, each operation
+// succeeds or fails independently. Operations the service throttles are retried after the delay the service
+// requests, and batches for the throttled partition key range are held until then.
+// The returned channel receives one result per operation, in no particular order, and is closed once
+// operations is closed and every operation has a result. Callers must receive the results, because
+// ExecuteBulk stops reading operations while results are waiting. When ctx is done, ExecuteBulk stops
+// reading operations and reports an error for operations which haven't completed.
+// ctx - The context for the operations.
+// operations - The operations to execute. Close the channel after sending the last operation.
+// o - Options for the operations.
+func (c *ContainerClient) ExecuteBulk(ctx context.Context, operations <-chan BulkOperation, o *BulkOptions) <-chan BulkOperationResult {
+	options := BulkOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.MaxConcurrency <= 0 {
+		options.MaxConcurrency = defaultBulkMaxConcurrency
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = defaultBulkFlushInterval
+	}
+	if options.MaxRetryAttempts <= 0 {
+		options.MaxRetryAttempts = defaultBulkMaxRetryAttempts
+	}
+
+	e := &bulkExecutor{
+		container:      c,
+		options:        options,
+		results:        make(chan BulkOperationResult),
+		sem:            make(chan struct{}, options.MaxConcurrency),
+		pending:        map[string]*bulkBatch{},
+		throttledUntil: map[string]time.Time{},
+	}
+	go e.run(ctx, operations)
+	return e.results
+}
+
+// bulkItem is an operation waiting for its result
+type bulkItem struct {
+	index     int
+	operation BulkOperation
+	body      json.RawMessage
+	attempts  int
+	charge    float32
+}
+
+// bulkBatch is a batch of operations for one partition key range, or one partition key
+// when the client can't compute the partition key range of partition keys.
+type bulkBatch struct {
+	route        string
+	pkRangeID    string
+	partitionKey *PartitionKey
+	items        []*bulkItem
+	size         int
+}
+
+type bulkExecutor struct {
+	container   *ContainerClient
+	options     BulkOptions
+	results     chan BulkOperationResult
+	sem         chan struct{}
+	outstanding sync.WaitGroup
+
+	// mtx guards the fields below, which retries update
+	mtx            sync.Mutex
+	routingErr     error
+	pkDefinition   PartitionKeyDefinition
+	pkRanges       []partitionKeyRange
+	pending        map[string]*bulkBatch
+	full           []*bulkBatch
+	throttledUntil map[string]time.Time
+}
+
+func (e *bulkExecutor) run(ctx context.Context, operations <-chan BulkOperation) {
+	defer close(e.results)
+
+	e.routingErr = e.initRouting(ctx)
+
+	ticker := time.NewTicker(e.options.FlushInterval)
+	defer ticker.Stop()
+
+	var drained chan struct{}
+	done := ctx.Done()
+	index := 0
+	for {
+		select {
+		case op, ok := <-operations:
+			if !ok {
+				operations = nil
+				drained = e.drained()
+				continue
+			}
+			e.outstanding.Add(1)
+			e.enqueue(ctx, &bulkItem{index: index, operation: op})
+			index++
+			e.dispatchFull(ctx)
+		case <-done:
+			done = nil
+			if operations != nil {
+				operations = nil
+				drained = e.drained()
+			}
+			e.flush(ctx)
+		case <-ticker.C:
+			e.dispatchFull(ctx)
+			e.flush(ctx)
+		case <-drained:
+			return
+		}
+	}
+}
+
+// drained returns a channel which is closed when every operation has a result
+func (e *bulkExecutor) drained() chan struct{} {
+	drained := make(chan struct{})
+	go func() {
+		e.outstanding.Wait()
+		close(drained)
+	}()
+	return drained
+}
+
+// initRouting reads the container's partition key definition and partition key ranges
+func (e *bulkExecutor) initRouting(ctx context.Context) error {
+	def, _, err := e.container.getPartitionKeyDefinition(ctx)
+	if err != nil {
+		return err
+	}
+	if !canComputeEffectivePartitionKey(def) {
+		if e.options.FeedRange != nil {
+			return errors.New("operations can't be limited to a feed range, because the client can't compute the feed range of the container's partition keys")
+		}
+		return nil
+	}
+	e.pkDefinition = def
+	return e.refreshPartitionKeyRanges(ctx)
+}
+
+func (e *bulkExecutor) refreshPartitionKeyRanges(ctx context.Context) error {
+	pkRanges, _, err := e.container.getPartitionKeyRanges(ctx)
+	if err != nil {
+		return err
+	}
+	e.mtx.Lock()
+	e.pkRanges = pkRanges
+	e.mtx.Unlock()
+	return nil
+}
+
+// enqueue adds an item to the pending batch for its partition key range
+func (e *bulkExecutor) enqueue(ctx context.Context, item *bulkItem) {
+	if err := ctx.Err(); err != nil {
+		e.complete(item, BulkOperationResult{Err: err})
+		return
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.routingErr != nil {
+		e.completeLocked(item, BulkOperationResult{Err: e.routingErr})
+		return
+	}
+
+	if item.body == nil {
+		body, err := bulkOperationBody(item.operation)
+		if err != nil {
+			e.completeLocked(item, BulkOperationResult{Err: err})
+			return
+		}
+		item.body = body
+	}
+
+	batch, err := e.batchFor(item.operation.partitionKey)
+	if err != nil {
+		e.completeLocked(item, BulkOperationResult{Err: err})
+		return
+	}
+	if len(batch.items) > 0 && (len(batch.items) == maxBulkBatchOperations || batch.size+len(item.body)+1 > maxBulkBatchBodyBytes) {
+		e.full = append(e.full, batch)
+		delete(e.pending, batch.route)
+		batch, _ = e.batchFor(item.operation.partitionKey)
+	}
+	batch.items = append(batch.items, item)
+	batch.size += len(item.body) + 1
+}
+
+// batchFor returns the pending batch for a partition key, creating it when necessary. e.mtx must be locked.
+func (e *bulkExecutor) batchFor(pk PartitionKey) (*bulkBatch, error) {
+	batch := &bulkBatch{}
+	if len(e.pkRanges) > 0 {
+		epk, err := effectivePartitionKey(e.pkDefinition, pk)
+		if err != nil {
+			return nil, err
+		}
+		if fr := e.options.FeedRange; fr != nil && (epk < fr.MinInclusive || epk >= fr.MaxExclusive) {
+			return nil, fmt.Errorf("effective partition key %s is outside the feed range", epk)
+		}
+		i := sort.Search(len(e.pkRanges), func(i int) bool { return e.pkRanges[i].MaxExclusive > epk })
+		if i == len(e.pkRanges) {
+			return nil, fmt.Errorf("no partition key range contains effective partition key %s", epk)
+		}
+		batch.pkRangeID = e.pkRanges[i].ID
+		batch.route = "range:" + batch.pkRangeID
+	} else {
+		key, err := pk.toJsonString()
+		if err != nil {
+			return nil, err
+		}
+		batch.partitionKey = &pk
+		batch.route = "pk:" + key
+	}
+
+	if existing, ok := e.pending[batch.route]; ok {
+		return existing, nil
+	}
+	e.pending[batch.route] = batch
+	return batch, nil
+}
+
+// dispatchFull sends the batches which are full
+func (e *bulkExecutor) dispatchFull(ctx context.Context) {
+	e.mtx.Lock()
+	full := e.full
+	e.full = nil
+	e.mtx.Unlock()
+	for _, batch := range full {
+		e.dispatch(ctx, batch, true)
+	}
+}
+
+// flush sends the pending batches of partition key ranges which aren't throttled
+func (e *bulkExecutor) flush(ctx context.Context) {
+	e.mtx.Lock()
+	batches := make([]*bulkBatch, 0, len(e.pending))
+	now := time.Now()
+	for route, batch := range e.pending {
+		if ctx.Err() == nil && now.Before(e.throttledUntil[route]) {
+			continue
+		}
+		batches = append(batches, batch)
+		delete(e.pending, route)
+	}
+	e.mtx.Unlock()
+	for _, batch := range batches {
+		e.dispatch(ctx, batch, false)
+	}
+}
+
+// dispatch sends a batch once its partition key range isn't throttled and fewer than MaxConcurrency batches
+// are in flight. Waiting blocks the reading of operations, which applies backpressure to the caller.
+func (e *bulkExecutor) dispatch(ctx context.Context, batch *bulkBatch, wait bool) {
+	if wait {
+		e.mtx.Lock()
+		until := e.throttledUntil[batch.route]
+		e.mtx.Unlock()
+		if d := time.Until(until); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+			case <-t.C:
+			}
+			t.Stop()
+		}
+	}
+
+	select {
+	case e.sem <- struct{}{}:
+	case <-ctx.Done():
+		for _, item := range batch.items {
+			e.complete(item, BulkOperationResult{Err: ctx.Err()})
+		}
+		return
+	}
+
+	go func() {
+		defer func() { <-e.sem }()
+		e.send(ctx, batch)
+	}()
+}
+
+type bulkOperationResponse struct {
+	RetryAfterMilliseconds *int `json:"retryAfterMilliseconds"`
+}
+
+// send sends a batch request, then completes or retries each of its operations
+func (e *bulkExecutor) send(ctx context.Context, batch *bulkBatch) {
+	h := headerOptionsOverride{
+		partitionKey:                 batch.partitionKey,
+		enableContentResponseOnWrite: &e.options.EnableContentResponseOnWrite,
+	}
+	operationContext := pipelineRequestOptions{
+		resourceType:          resourceTypeDocument,
+		resourceAddress:       e.container.link,
+		isWriteOperation:      true,
+		headerOptionsOverride: &h,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeDocument, operationContext.resourceAddress, true)
+	if err != nil {
+		e.fail(batch, err)
+		return
+	}
+
+	operations := make([]batchOperation, len(batch.items))
+	for i, item := range batch.items {
+		operations[i] = bulkBatchOperation{operation: item.operation.operation, body: item.body}
+	}
+
+	// the executor retries throttled requests after the delay the service requests
+	ctx = azruntime.WithRetryOptions(ctx, policy.RetryOptions{
+		StatusCodes: []int{http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	})
+	azResponse, err := e.container.database.client.sendBatchRequest(
+		ctx,
+		path,
+		operations,
+		operationContext,
+		&bulkRequestOptions{},
+		func(r *policy.Request) {
+			if batch.pkRangeID != "" {
+				r.Raw().Header.Set(cosmosHeaderPartitionKeyRangeID, batch.pkRangeID)
+			}
+		})
+
+	var respErr *azcore.ResponseError
+	switch {
+	case err == nil:
+	case isPartitionKeyRangeGone(err):
+		// the range split, so route the operations to its children
+		if refreshErr := e.refreshPartitionKeyRanges(ctx); refreshErr != nil {
+			e.fail(batch, refreshErr)
+			return
+		}
+		e.retry(ctx, batch.route, batch.items, 0, err)
+		return
+	case errors.As(err, &respErr) && respErr.StatusCode == http.StatusTooManyRequests:
+		e.retry(ctx, batch.route, batch.items, retryAfter(respErr.RawResponse.Header.Get(cosmosHeaderRetryAfterMs), nil), err)
+		return
+	default:
+		e.fail(batch, err)
+		return
+	}
+
+	response, err := newTransactionalBatchResponse(azResponse)
+	if err != nil {
+		e.fail(batch, err)
+		return
+	}
+	var retryInfo []bulkOperationResponse
+	if err := azruntime.UnmarshalAsJSON(azResponse, &retryInfo); err != nil {
+		e.fail(batch, err)
+		return
+	}
+
+	throttled := []*bulkItem{}
+	var delay time.Duration
+	for i, item := range batch.items {
+		if i >= len(response.OperationResults) {
+			e.complete(item, BulkOperationResult{Err: errors.New("the batch response has no result for the operation")})
+			continue
+		}
+		r := response.OperationResults[i]
+		item.charge += r.RequestCharge
+		if r.StatusCode == http.StatusTooManyRequests && item.attempts < e.options.MaxRetryAttempts {
+			if d := retryAfter("", retryInfo[i].RetryAfterMilliseconds); d > delay {
+				delay = d
+			}
+			throttled = append(throttled, item)
+			continue
+		}
+		e.complete(item, BulkOperationResult{
+			StatusCode:   r.StatusCode,
+			ResourceBody: r.ResourceBody,
+			ETag:         r.ETag,
+		})
+	}
+	if len(throttled) > 0 {
+		e.retry(ctx, batch.route, throttled, delay, nil)
+	}
+}
+
+// retry sends items again after delay, and holds other batches for their partition key range until then.
+// Items which have been retried MaxRetryAttempts times complete with err, when it's set.
+func (e *bulkExecutor) retry(ctx context.Context, route string, items []*bulkItem, delay time.Duration, err error) {
+	e.mtx.Lock()
+	if until := time.Now().Add(delay); until.After(e.throttledUntil[route]) {
+		e.throttledUntil[route] = until
+	}
+	e.mtx.Unlock()
+
+	retries := []*bulkItem{}
+	for _, item := range items {
+		item.attempts++
+		if item.attempts > e.options.MaxRetryAttempts && err != nil {
+			e.complete(item, BulkOperationResult{Err: err})
+			continue
+		}
+		retries = append(retries, item)
+	}
+	if len(retries) == 0 {
+		return
+	}
+	time.AfterFunc(delay, func() {
+		for _, item := range retries {
+			e.enqueue(ctx, item)
+		}
+	})
+}
+
+// retryAfter returns the delay the service requested, either in a header value or a result's retryAfterMilliseconds
+func retryAfter(header string, ms *int) time.Duration {
+	if header != "" {
+		if v, err := strconv.ParseFloat(header, 64); err == nil {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+	if ms != nil {
+		return time.Duration(*ms) * time.Millisecond
+	}
+	return defaultBulkRetryAfter
+}
+
+func (e *bulkExecutor) fail(batch *bulkBatch, err error) {
+	for _, item := range batch.items {
+		e.complete(item, BulkOperationResult{Err: err})
+	}
+}
+
+func (e *bulkExecutor) complete(item *bulkItem, result BulkOperationResult) {
+	result.Index = item.index
+	result.RequestCharge = item.charge
+	e.results <- result
+	e.outstanding.Done()
+}
+
+// completeLocked completes an item while e.mtx is locked. The result is delivered asynchronously, so
+// retries holding the lock don't wait for the caller to receive results.
+func (e *bulkExecutor) completeLocked(item *bulkItem, result BulkOperationResult) {
+	go e.complete(item, result)
+}
+
+// bulkOperationBody returns the JSON of an operation in a batch request, including its partition key
+func bulkOperationBody(op BulkOperation) (json.RawMessage, error) {
+	pk, err := op.partitionKey.toJsonString()
+	if err != nil {
+		return nil, err
+	}
+	pkJSON, err := json.Marshal(pk)
+	if err != nil {
+		return nil, err
+	}
+	operation, err := json.Marshal(op.operation)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, 0, len(operation)+len(pkJSON)+17)
+	body = append(body, `{"partitionKey":`...)
+	body = append(body, pkJSON...)
+	body = append(body, ',')
+	return append(body, operation[1:]...), nil
+}
+
+// bulkBatchOperation is an operation whose JSON includes its partition key
+type bulkBatchOperation struct {
+	operation batchOperation
+	body      json.RawMessage
+}
+
+func (b bulkBatchOperation) getOperationType() operationType {
+	return b.operation.getOperationType()
+}
+
+func (b bulkBatchOperation) MarshalJSON() ([]byte, error) {
+	return b.body, nil
+}
+
+// bulkRequestOptions are the options of a non-atomic batch request
+type bulkRequestOptions struct{}
+
+func (options *bulkRequestOptions) toHeaders() *map[string]string {
+	headers := map[string]string{
+		cosmosHeaderIsBatchRequest:         "True",
+		cosmosHeaderIsBatchAtomic:          "False",
+		cosmosHeaderIsBatchContinueOnError: "True",
+	}
+	return &headers
+}
@@ -0,0 +1,387 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+type fakeBulkOperation struct {
+	PartitionKey  string          `json:"partitionKey"`
+	OperationType string          `json:"operationType"`
+	ID            string          `json:"id"`
+	ResourceBody  json.RawMessage `json:"resourceBody"`
+}
+
+type fakeBulkRequest struct {
+	header     http.Header
+	operations []fakeBulkOperation
+}
+
+// fakeBulkGateway serves batch requests. It routes partition keys to ranges by effective partition key,
+// and rejects batches containing operations of other ranges.
+type fakeBulkGateway struct {
+	mtx      sync.Mutex
+	kind     PartitionKeyKind
+	version  int
+	ranges   []partitionKeyRange
+	requests []fakeBulkRequest
+	attempts map[string]int
+
+	// scenario knobs
+	throttleRequests int
+	throttleItem     func(id string, attempt int) bool
+	failWith         int
+	gone             map[string]bool
+}
+
+func newFakeBulkGateway() *fakeBulkGateway {
+	return &fakeBulkGateway{
+		kind:     PartitionKeyKindHash,
+		version:  2,
+		ranges:   newFakeGatewayRanges("20"),
+		attempts: map[string]int{},
+		gone:     map[string]bool{},
+	}
+}
+
+func (g *fakeBulkGateway) Do(req *http.Request) (*http.Response, error) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	header := http.Header{}
+	header.Set(cosmosHeaderRequestCharge, "1")
+	respond := func(status int, body string) (*http.Response, error) {
+		return newFakeGatewayResponse(req, status, header, body), nil
+	}
+
+	switch {
+	case isPartitionKeyRangesRequest(req):
+		return respond(http.StatusOK, partitionKeyRangesBody(g.ranges))
+	case req.Method == http.MethodGet:
+		return respond(http.StatusOK, fmt.Sprintf(`{"id":"container","partitionKey":{"paths":["/pk"],"kind":"%s","version":%d}}`, g.kind, g.version))
+	}
+
+	var ops []fakeBulkOperation
+	if err := json.NewDecoder(req.Body).Decode(&ops); err != nil {
+		return nil, err
+	}
+	g.requests = append(g.requests, fakeBulkRequest{header: req.Header.Clone(), operations: ops})
+
+	if g.failWith != 0 {
+		return respond(g.failWith, `{"code":"BadRequest"}`)
+	}
+	if g.throttleRequests > 0 {
+		g.throttleRequests--
+		header.Set(cosmosHeaderRetryAfterMs, "20")
+		return respond(http.StatusTooManyRequests, `{"code":"TooManyRequests"}`)
+	}
+
+	id := req.Header.Get(cosmosHeaderPartitionKeyRangeID)
+	if g.gone[id] {
+		header.Set(cosmosHeaderSubStatus, subStatusPartitionKeyRangeGone)
+		return respond(http.StatusGone, `{"code":"Gone"}`)
+	}
+
+	results := []string{}
+	status := http.StatusOK
+	for _, op := range ops {
+		if id != "" {
+			var values []interface{}
+			if err := json.Unmarshal([]byte(op.PartitionKey), &values); err != nil {
+				return nil, err
+			}
+			epk, _ := effectivePartitionKey(PartitionKeyDefinition{Paths: []string{"/pk"}, Kind: g.kind, Version: g.version}, PartitionKey{values: values})
+			if r := g.rangeOf(epk); r != id {
+				return nil, fmt.Errorf("operation for range %s sent to range %s", r, id)
+			}
+		} else if req.Header.Get(cosmosHeaderPartitionKey) != op.PartitionKey {
+			return nil, fmt.Errorf("operation for partition key %s sent to partition key %s", op.PartitionKey, req.Header.Get(cosmosHeaderPartitionKey))
+		}
+
+		itemID := op.ID
+		if itemID == "" {
+			var item struct {
+				ID string `json:"id"`
+			}
+			_ = json.Unmarshal(op.ResourceBody, &item)
+			itemID = item.ID
+		}
+		g.attempts[itemID]++
+		if g.throttleItem != nil && g.throttleItem(itemID, g.attempts[itemID]) {
+			status = http.StatusMultiStatus
+			results = append(results, `{"statusCode":429,"subStatusCode":3200,"requestCharge":0.5,"retryAfterMilliseconds":10}`)
+			continue
+		}
+		results = append(results, fmt.Sprintf(`{"statusCode":201,"requestCharge":2,"eTag":"\"%s\""}`, itemID))
+	}
+	return respond(status, "["+strings.Join(results, ",")+"]")
+}
+
+func (g *fakeBulkGateway) rangeOf(epk string) string {
+	for _, r := range g.ranges {
+		if r.MinInclusive <= epk && epk < r.MaxExclusive {
+			return r.ID
+		}
+	}
+	return ""
+}
+
+// executeBulk creates n items and returns the results by index
+func executeBulk(t *testing.T, container *ContainerClient, n int, o *BulkOptions) map[int]BulkOperationResult {
+	ops := make(chan BulkOperation)
+	go func() {
+		defer close(ops)
+		for i := 0; i < n; i++ {
+			item := fmt.Sprintf(`{"id":"%d","pk":"pk-%d"}`, i, i)
+			ops <- NewBulkCreateItem(NewPartitionKeyString(fmt.Sprintf("pk-%d", i)), []byte(item), nil)
+		}
+	}()
+
+	results := map[int]BulkOperationResult{}
+	for r := range container.ExecuteBulk(context.Background(), ops, o) {
+		if _, ok := results[r.Index]; ok {
+			t.Fatalf("duplicate result for operation %d", r.Index)
+		}
+		results[r.Index] = r
+	}
+	if len(results) != n {
+		t.Fatalf("expected %d results, got %d", n, len(results))
+	}
+	return results
+}
+
+func TestExecuteBulk(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	container := newFakeGatewayContainer(t, gateway)
+
+	results := executeBulk(t, container, 250, &BulkOptions{MaxConcurrency: 3})
+	for i, r := range results {
+		if r.Err != nil || r.StatusCode != http.StatusCreated || r.RequestCharge != 2 || r.ETag != azcore.ETag(fmt.Sprintf(`"%d"`, i)) {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+
+	ranges := map[string]bool{}
+	for _, req := range gateway.requests {
+		if len(req.operations) > maxBulkBatchOperations {
+			t.Errorf("a batch has %d operations", len(req.operations))
+		}
+		if req.header.Get(cosmosHeaderIsBatchAtomic) != "False" || req.header.Get(cosmosHeaderIsBatchContinueOnError) != "True" {
+			t.Errorf("unexpected batch headers %v", req.header)
+		}
+		if req.header.Get(cosmosHeaderPartitionKey) != "" {
+			t.Errorf("unexpected partition key header %s", req.header.Get(cosmosHeaderPartitionKey))
+		}
+		ranges[req.header.Get(cosmosHeaderPartitionKeyRangeID)] = true
+	}
+	if !ranges["0"] || !ranges["1"] || len(ranges) != 2 {
+		t.Errorf("expected batches for ranges 0 and 1, got %v", ranges)
+	}
+}
+
+func TestExecuteBulkOperationTypes(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	container := newFakeGatewayContainer(t, gateway)
+	pk := NewPartitionKeyString("pk")
+	etag := azcore.ETag("etag")
+	patch := PatchOperations{}
+	patch.AppendSet("/value", 1)
+
+	ops := make(chan BulkOperation, 5)
+	ops <- NewBulkCreateItem(pk, []byte(`{"id":"a"}`), nil)
+	ops <- NewBulkUpsertItem(pk, []byte(`{"id":"b"}`), nil)
+	ops <- NewBulkReplaceItem(pk, "c", []byte(`{"id":"c"}`), &TransactionalBatchItemOptions{IfMatchETag: &etag})
+	ops <- NewBulkDeleteItem(pk, "d", nil)
+	ops <- NewBulkPatchItem(pk, "e", patch, nil)
+	close(ops)
+
+	for r := range container.ExecuteBulk(context.Background(), ops, nil) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+	}
+
+	types := []string{}
+	for _, req := range gateway.requests {
+		for _, op := range req.operations {
+			if op.PartitionKey != `["pk"]` {
+				t.Errorf("unexpected partition key %s", op.PartitionKey)
+			}
+			types = append(types, op.OperationType)
+		}
+	}
+	if strings.Join(types, ",") != "Create,Upsert,Replace,Delete,Patch" {
+		t.Errorf("unexpected operations %v", types)
+	}
+}
+
+func TestExecuteBulkThrottling(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	gateway.throttleRequests = 1
+	gateway.throttleItem = func(id string, attempt int) bool { return id == "3" && attempt == 1 }
+	container := newFakeGatewayContainer(t, gateway)
+
+	start := time.Now()
+	results := executeBulk(t, container, 10, &BulkOptions{FlushInterval: 5 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected the executor to wait for the throttled range, finished after %v", elapsed)
+	}
+	for _, r := range results {
+		if r.Err != nil || r.StatusCode != http.StatusCreated {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	if results[3].RequestCharge != 2.5 {
+		t.Errorf("expected the charge of the throttled attempt to be included, got %v", results[3].RequestCharge)
+	}
+}
+
+func TestExecuteBulkRetriesExhausted(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	gateway.throttleItem = func(id string, attempt int) bool { return id == "0" }
+	container := newFakeGatewayContainer(t, gateway)
+
+	results := executeBulk(t, container, 1, &BulkOptions{MaxRetryAttempts: 2, FlushInterval: time.Millisecond})
+	if r := results[0]; r.Err != nil || r.StatusCode != http.StatusTooManyRequests || r.RequestCharge != 1.5 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if gateway.attempts["0"] != 3 {
+		t.Errorf("expected 3 attempts, got %d", gateway.attempts["0"])
+	}
+}
+
+func TestExecuteBulkRequestFailure(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	gateway.failWith = http.StatusBadRequest
+	container := newFakeGatewayContainer(t, gateway)
+
+	for _, r := range executeBulk(t, container, 3, nil) {
+		var respErr *azcore.ResponseError
+		if !errors.As(r.Err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected a response error, got %v", r.Err)
+		}
+	}
+}
+
+func TestExecuteBulkPartitionKeyV1(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	gateway.version = 1
+	// the effective partition keys of version 1 start with the encoding of a number, 05
+	gateway.ranges = []partitionKeyRange{
+		{ID: "0", MinInclusive: "", MaxExclusive: "05C1D0"},
+		{ID: "1", MinInclusive: "05C1D0", MaxExclusive: "FF"},
+	}
+	container := newFakeGatewayContainer(t, gateway)
+
+	for _, r := range executeBulk(t, container, 50, nil) {
+		if r.Err != nil || r.StatusCode != http.StatusCreated {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	ranges := map[string]bool{}
+	for _, req := range gateway.requests {
+		ranges[req.header.Get(cosmosHeaderPartitionKeyRangeID)] = true
+	}
+	if !ranges["0"] || !ranges["1"] || len(ranges) != 2 {
+		t.Errorf("expected batches for ranges 0 and 1, got %v", ranges)
+	}
+}
+
+func TestExecuteBulkFeedRange(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	container := newFakeGatewayContainer(t, gateway)
+
+	feedRange := FeedRange{MinInclusive: "20", MaxExclusive: "FF"}
+	results := executeBulk(t, container, 50, &BulkOptions{FeedRange: &feedRange})
+	outside := 0
+	for _, r := range results {
+		if r.Err != nil {
+			outside++
+		} else if r.StatusCode != http.StatusCreated {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	if outside == 0 || outside == len(results) {
+		t.Errorf("expected some operations outside the feed range, got %d", outside)
+	}
+	for _, req := range gateway.requests {
+		if id := req.header.Get(cosmosHeaderPartitionKeyRangeID); id != "1" {
+			t.Errorf("expected batches for range 1 only, got a batch for range %s", id)
+		}
+	}
+}
+
+func TestExecuteBulkPartitionKeyFallback(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	gateway.kind = "Range"
+	container := newFakeGatewayContainer(t, gateway)
+
+	for _, r := range executeBulk(t, container, 5, nil) {
+		if r.Err != nil || r.StatusCode != http.StatusCreated {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	if len(gateway.requests) != 5 {
+		t.Errorf("expected a batch per partition key, got %d batches", len(gateway.requests))
+	}
+}
+
+func TestExecuteBulkPartitionKeyRangeGone(t *testing.T) {
+	gateway := newFakeBulkGateway()
+	container := newFakeGatewayContainer(t, gateway)
+
+	// range 1 splits after the executor reads the partition key ranges
+	gateway.gone["1"] = true
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		gateway.mtx.Lock()
+		defer gateway.mtx.Unlock()
+		gateway.ranges = []partitionKeyRange{
+			{ID: "0", MinInclusive: "", MaxExclusive: "20"},
+			{ID: "2", MinInclusive: "20", MaxExclusive: "30"},
+			{ID: "3", MinInclusive: "30", MaxExclusive: "FF"},
+		}
+	}()
+
+	results := executeBulk(t, container, 50, &BulkOptions{FlushInterval: 20 * time.Millisecond})
+	n := 0
+	for _, r := range gateway.requests {
+		if r.header.Get(cosmosHeaderPartitionKeyRangeID) == "1" {
+			n++
+		}
+	}
+	if n == 0 {
+		t.Error("expected a batch to be sent to the split range")
+	}
+	for _, r := range results {
+		if r.Err != nil || r.StatusCode != http.StatusCreated {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+}
+
+func TestExecuteBulkCanceled(t *testing.T) {
+	container := newFakeGatewayContainer(t, newFakeBulkGateway())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ops := make(chan BulkOperation, 1)
+	ops <- NewBulkCreateItem(NewPartitionKeyString("pk"), []byte(`{"id":"a"}`), nil)
+	for r := range container.ExecuteBulk(ctx, ops, nil) {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", r.Err)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// ChangeFeedOptions includes options for reading the change feed.
+type ChangeFeedOptions struct {
+	// MaxItemCount limits the number of changes in each page.
+	MaxItemCount int32
+	// StartTime reads changes made after the given time. By default, the change feed starts from the
+	// beginning of the container's history.
+	StartTime *time.Time
+	// ContinuationToken resumes reading where a previous ChangeFeedResponse left off.
+	// When set, StartTime and FeedRange are ignored.
+	ContinuationToken string
+	// FeedRange reads the changes of part of the container, for example a range returned by
+	// ContainerClient.GetFeedRanges. By default, the whole container is read.
+	FeedRange *FeedRange
+}
+
+// ChangeFeedResponse contains a page of changes.
+type ChangeFeedResponse struct {
+	Response
+	// ContinuationToken resumes the change feed after this page. Pass it to ChangeFeedOptions.ContinuationToken
+	// to read later changes, for example after polling finds no changes.
+	ContinuationToken string
+	// Items contains the latest version of each changed item.
+	Items [][]byte
+}
+
+// changeFeedContinuation is the continuation token of a change feed. It records the position of each
+// part of the feed range being read.
+type changeFeedContinuation struct {
+	Container string                        `json:"container"`
+	StartTime string                        `json:"startTime,omitempty"`
+	Ranges    []changeFeedRangeContinuation `json:"ranges"`
+}
+
+type changeFeedRangeContinuation struct {
+	FeedRange
+	// Etag is the position in the range's change feed. It's empty until the range has been read.
+	Etag string `json:"etag,omitempty"`
+}
+
+// changeFeedRange is part of a feed range served by one partition key range.
+type changeFeedRange struct {
+	feedRange FeedRange
+	pkRange   partitionKeyRange
+	etag      string
+}
+
+// changeFeedReader reads the change feed of each partition key range in turn.
+type changeFeedReader struct {
+	container *ContainerClient
+	options   ChangeFeedOptions
+	startTime string
+
+	initialized bool
+	ranges      []*changeFeedRange
+	next        int
+	charge      float32
+}
+
+func newChangeFeedReader(container *ContainerClient, options ChangeFeedOptions) *changeFeedReader {
+	return &changeFeedReader{container: container, options: options}
+}
+
+func (r *changeFeedReader) initialize(ctx context.Context) error {
+	feedRange := fullFeedRange()
+	if r.options.FeedRange != nil {
+		feedRange = *r.options.FeedRange
+	}
+	ranges := []*changeFeedRange{{feedRange: feedRange}}
+	if r.options.StartTime != nil {
+		r.startTime = r.options.StartTime.UTC().Format(http.TimeFormat)
+	}
+
+	if r.options.ContinuationToken != "" {
+		var c changeFeedContinuation
+		if err := json.Unmarshal([]byte(r.options.ContinuationToken), &c); err != nil || len(c.Ranges) == 0 {
+			return errors.New("invalid change feed continuation token")
+		}
+		if c.Container != r.container.link {
+			return fmt.Errorf("the continuation token belongs to the change feed of %s", c.Container)
+		}
+		r.startTime = c.StartTime
+		ranges = ranges[:0]
+		for _, rc := range c.Ranges {
+			ranges = append(ranges, &changeFeedRange{feedRange: rc.FeedRange, etag: rc.Etag})
+		}
+	}
+
+	resolved, err := r.resolve(ctx, ranges)
+	if err != nil {
+		return err
+	}
+	r.ranges = resolved
+	r.initialized = true
+	return nil
+}
+
+// resolve assigns each range to the partition key ranges currently serving it, dividing ranges
+// which span several partition key ranges. Parts of a range keep its position, which remains
+// valid after partition key ranges split.
+func (r *changeFeedReader) resolve(ctx context.Context, ranges []*changeFeedRange) ([]*changeFeedRange, error) {
+	pkRanges, charge, err := r.container.getPartitionKeyRanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.charge += charge
+
+	resolved := []*changeFeedRange{}
+	for _, cr := range ranges {
+		for _, pkRange := range pkRanges {
+			if !cr.feedRange.overlaps(pkRange.feedRange()) {
+				continue
+			}
+			resolved = append(resolved, &changeFeedRange{
+				feedRange: cr.feedRange.intersect(pkRange.feedRange()),
+				pkRange:   pkRange,
+				etag:      cr.etag,
+			})
+		}
+	}
+	if len(resolved) == 0 {
+		return nil, errors.New("no partition key range serves the feed range")
+	}
+	return resolved, nil
+}
+
+// nextPage returns the changes of the next partition key range having any. It returns an empty page
+// when no range has changes.
+func (r *changeFeedReader) nextPage(ctx context.Context) (ChangeFeedResponse, error) {
+	r.charge = 0
+	ctx, diagnostics := WithDiagnostics(ctx)
+	if !r.initialized {
+		if err := r.initialize(ctx); err != nil {
+			return ChangeFeedResponse{}, err
+		}
+	}
+
+	var last *http.Response
+	var items [][]byte
+	for unchanged := 0; unchanged < len(r.ranges); {
+		cr := r.ranges[r.next]
+		azResponse, err := r.fetch(ctx, cr)
+		if isPartitionKeyRangeGone(err) {
+			children, err := r.resolve(ctx, []*changeFeedRange{cr})
+			if err != nil {
+				return ChangeFeedResponse{}, err
+			}
+			if len(children) == 1 && children[0].pkRange.ID == cr.pkRange.ID {
+				return ChangeFeedResponse{}, fmt.Errorf("partition key range %s is gone but still listed", cr.pkRange.ID)
+			}
+			r.ranges = append(r.ranges[:r.next], append(children, r.ranges[r.next+1:]...)...)
+			continue
+		}
+		if err != nil {
+			return ChangeFeedResponse{}, err
+		}
+
+		last = azResponse
+		r.charge += newResponse(azResponse).RequestCharge
+		if etag := azResponse.Header.Get(cosmosHeaderEtag); etag != "" {
+			cr.etag = etag
+		}
+		r.next = (r.next + 1) % len(r.ranges)
+
+		if azResponse.StatusCode != http.StatusNotModified {
+			var page queryDocumentsResponse
+			if err := runtime.UnmarshalAsJSON(azResponse, &page); err != nil {
+				return ChangeFeedResponse{}, err
+			}
+			if len(page.Documents) > 0 {
+				for _, doc := range page.Documents {
+					items = append(items, doc)
+				}
+				break
+			}
+		}
+		unchanged++
+	}
+
+	token, err := r.continuationToken(r.ranges)
+	if err != nil {
+		return ChangeFeedResponse{}, err
+	}
+
+	response := ChangeFeedResponse{
+		Response:          newResponse(last),
+		ContinuationToken: token,
+		Items:             items,
+	}
+	response.RequestCharge = r.charge
+	response.Diagnostics = diagnostics
+	return response, nil
+}
+
+func (r *changeFeedReader) fetch(ctx context.Context, cr *changeFeedRange) (*http.Response, error) {
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeDocument,
+		resourceAddress: r.container.link,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeDocument, operationContext.resourceAddress, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.container.database.client.sendGetRequest(
+		path,
+		ctx,
+		operationContext,
+		nil,
+		func(req *policy.Request) {
+			h := req.Raw().Header
+			h.Set(cosmosHeaderAIM, cosmosHeaderValuesChangeFeed)
+			h.Set(cosmosHeaderPartitionKeyRangeID, cr.pkRange.ID)
+			if cr.feedRange != cr.pkRange.feedRange() {
+				h.Set(cosmosHeaderStartEpk, cr.feedRange.MinInclusive)
+				h.Set(cosmosHeaderEndEpk, cr.feedRange.MaxExclusive)
+			}
+			if r.options.MaxItemCount > 0 {
+				h.Set(cosmosHeaderMaxItemCount, fmt.Sprint(r.options.MaxItemCount))
+			}
+			if cr.etag != "" {
+				h.Set(headerIfNoneMatch, cr.etag)
+			} else if r.startTime != "" {
+				h.Set(headerIfModifiedSince, r.startTime)
+			}
+		})
+}
+
+// continuationToken returns a token resuming the change feed of the given ranges
+func (r *changeFeedReader) continuationToken(ranges []*changeFeedRange) (string, error) {
+	c := changeFeedContinuation{Container: r.container.link, StartTime: r.startTime}
+	for _, cr := range ranges {
+		c.Ranges = append(c.Ranges, changeFeedRangeContinuation{FeedRange: cr.feedRange, Etag: cr.etag})
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
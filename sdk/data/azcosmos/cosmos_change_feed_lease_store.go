@@ -0,0 +1,224 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// ErrLeaseConflict is returned by ChangeFeedLeaseStore.Create when a lease having the same ID exists.
+var ErrLeaseConflict = errors.New("the lease already exists")
+
+// ErrLeaseLost is returned by ChangeFeedLeaseStore methods when a lease changed or was deleted
+// since it was read.
+var ErrLeaseLost = errors.New("the lease changed since it was read")
+
+// ChangeFeedLease assigns part of a container's change feed to one change feed processor instance.
+type ChangeFeedLease struct {
+	// ID identifies the lease.
+	ID string `json:"id"`
+	// FeedRange is the part of the monitored container the lease covers.
+	FeedRange FeedRange `json:"feedRange"`
+	// ContinuationToken is the change feed position the lease's owner last checkpointed.
+	ContinuationToken string `json:"continuationToken,omitempty"`
+	// Owner is the name of the processor instance holding the lease. It's empty when the lease is free.
+	Owner string `json:"owner,omitempty"`
+	// ETag identifies the version of the lease. The store sets it whenever the lease changes.
+	ETag azcore.ETag `json:"-"`
+}
+
+// ChangeFeedLeaseStore persists the leases of a change feed processor. Instances of a processor
+// share a lease store to coordinate. Implementations must be safe for concurrent use.
+type ChangeFeedLeaseStore interface {
+	// List returns every lease.
+	List(ctx context.Context) ([]ChangeFeedLease, error)
+	// Create adds a lease. It returns ErrLeaseConflict when a lease having the same ID exists.
+	Create(ctx context.Context, lease ChangeFeedLease) (ChangeFeedLease, error)
+	// Replace updates a lease when its ETag matches the stored lease's. It returns ErrLeaseLost otherwise.
+	Replace(ctx context.Context, lease ChangeFeedLease) (ChangeFeedLease, error)
+	// Delete removes a lease when its ETag matches the stored lease's. It returns ErrLeaseLost otherwise.
+	Delete(ctx context.Context, lease ChangeFeedLease) error
+}
+
+// NewInMemoryLeaseStore creates a lease store keeping leases in memory. It's useful for tests, and for
+// processors running in a single process.
+func NewInMemoryLeaseStore() ChangeFeedLeaseStore {
+	return &inMemoryLeaseStore{leases: map[string]ChangeFeedLease{}}
+}
+
+type inMemoryLeaseStore struct {
+	mtx     sync.Mutex
+	leases  map[string]ChangeFeedLease
+	version int
+}
+
+func (s *inMemoryLeaseStore) List(ctx context.Context) ([]ChangeFeedLease, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	leases := make([]ChangeFeedLease, 0, len(s.leases))
+	for _, lease := range s.leases {
+		leases = append(leases, lease)
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+	return leases, nil
+}
+
+func (s *inMemoryLeaseStore) Create(ctx context.Context, lease ChangeFeedLease) (ChangeFeedLease, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.leases[lease.ID]; ok {
+		return ChangeFeedLease{}, ErrLeaseConflict
+	}
+	return s.store(lease), nil
+}
+
+func (s *inMemoryLeaseStore) Replace(ctx context.Context, lease ChangeFeedLease) (ChangeFeedLease, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if stored, ok := s.leases[lease.ID]; !ok || stored.ETag != lease.ETag {
+		return ChangeFeedLease{}, ErrLeaseLost
+	}
+	return s.store(lease), nil
+}
+
+func (s *inMemoryLeaseStore) Delete(ctx context.Context, lease ChangeFeedLease) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if stored, ok := s.leases[lease.ID]; !ok || stored.ETag != lease.ETag {
+		return ErrLeaseLost
+	}
+	delete(s.leases, lease.ID)
+	return nil
+}
+
+func (s *inMemoryLeaseStore) store(lease ChangeFeedLease) ChangeFeedLease {
+	s.version++
+	lease.ETag = azcore.ETag(fmt.Sprintf("\"%d\"", s.version))
+	s.leases[lease.ID] = lease
+	return lease
+}
+
+// NewContainerLeaseStore creates a lease store keeping leases as items of a Cosmos container. The container's
+// partition key path must be /id. The store prefixes lease IDs with prefix, so processors of different
+// containers can share a lease container.
+func NewContainerLeaseStore(container *ContainerClient, prefix string) ChangeFeedLeaseStore {
+	return &containerLeaseStore{container: container, prefix: prefix}
+}
+
+type containerLeaseStore struct {
+	container *ContainerClient
+	prefix    string
+}
+
+// leaseDocument is a lease stored in a container
+type leaseDocument struct {
+	ChangeFeedLease
+	ETag azcore.ETag `json:"_etag,omitempty"`
+}
+
+func (s *containerLeaseStore) List(ctx context.Context) ([]ChangeFeedLease, error) {
+	pager := s.container.NewCrossPartitionQueryItemsPager(
+		"SELECT * FROM c WHERE STARTSWITH(c.id, @prefix)",
+		&QueryOptions{QueryParameters: []QueryParameter{{Name: "@prefix", Value: s.prefix}}})
+
+	leases := []ChangeFeedLease{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			lease, err := s.decode(item)
+			if err != nil {
+				return nil, err
+			}
+			leases = append(leases, lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+	return leases, nil
+}
+
+func (s *containerLeaseStore) Create(ctx context.Context, lease ChangeFeedLease) (ChangeFeedLease, error) {
+	id, item, err := s.encode(lease)
+	if err != nil {
+		return ChangeFeedLease{}, err
+	}
+	response, err := s.container.CreateItem(ctx, NewPartitionKeyString(id), item, nil)
+	if hasStatusCode(err, http.StatusConflict) {
+		return ChangeFeedLease{}, ErrLeaseConflict
+	}
+	if err != nil {
+		return ChangeFeedLease{}, err
+	}
+	lease.ETag = response.ETag
+	return lease, nil
+}
+
+func (s *containerLeaseStore) Replace(ctx context.Context, lease ChangeFeedLease) (ChangeFeedLease, error) {
+	id, item, err := s.encode(lease)
+	if err != nil {
+		return ChangeFeedLease{}, err
+	}
+	etag := lease.ETag
+	response, err := s.container.ReplaceItem(ctx, NewPartitionKeyString(id), id, item, &ItemOptions{IfMatchEtag: &etag})
+	if hasStatusCode(err, http.StatusPreconditionFailed, http.StatusNotFound) {
+		return ChangeFeedLease{}, ErrLeaseLost
+	}
+	if err != nil {
+		return ChangeFeedLease{}, err
+	}
+	lease.ETag = response.ETag
+	return lease, nil
+}
+
+func (s *containerLeaseStore) Delete(ctx context.Context, lease ChangeFeedLease) error {
+	id := s.prefix + lease.ID
+	etag := lease.ETag
+	_, err := s.container.DeleteItem(ctx, NewPartitionKeyString(id), id, &ItemOptions{IfMatchEtag: &etag})
+	if hasStatusCode(err, http.StatusPreconditionFailed, http.StatusNotFound) {
+		return ErrLeaseLost
+	}
+	return err
+}
+
+func (s *containerLeaseStore) encode(lease ChangeFeedLease) (string, []byte, error) {
+	lease.ID = s.prefix + lease.ID
+	item, err := json.Marshal(lease)
+	return lease.ID, item, err
+}
+
+func (s *containerLeaseStore) decode(item []byte) (ChangeFeedLease, error) {
+	var doc leaseDocument
+	if err := json.Unmarshal(item, &doc); err != nil {
+		return ChangeFeedLease{}, err
+	}
+	lease := doc.ChangeFeedLease
+	lease.ID = strings.TrimPrefix(lease.ID, s.prefix)
+	lease.ETag = doc.ETag
+	return lease, nil
+}
+
+// hasStatusCode returns true when err is a response error having one of the given status codes
+func hasStatusCode(err error, statusCodes ...int) bool {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	for _, sc := range statusCodes {
+		if respErr.StatusCode == sc {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestInMemoryLeaseStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryLeaseStore()
+
+	lease, err := store.Create(ctx, ChangeFeedLease{ID: "b", FeedRange: fullFeedRange()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.ETag == "" {
+		t.Fatal("expected an ETag")
+	}
+	if _, err := store.Create(ctx, ChangeFeedLease{ID: "b"}); !errors.Is(err, ErrLeaseConflict) {
+		t.Fatalf("expected ErrLeaseConflict, got %v", err)
+	}
+	if _, err := store.Create(ctx, ChangeFeedLease{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lease.Owner = "instance"
+	updated, err := store.Replace(ctx, lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ETag == lease.ETag {
+		t.Fatal("expected the ETag to change")
+	}
+	if _, err := store.Replace(ctx, lease); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if err := store.Delete(ctx, lease); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+
+	leases, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 2 || leases[0].ID != "a" || leases[1] != updated {
+		t.Fatalf("unexpected leases %v", leases)
+	}
+
+	if err := store.Delete(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Replace(ctx, updated); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+}
+
+func TestContainerLeaseStore(t *testing.T) {
+	container, srv, transport, close := newMockContainer(t, "leases", testClientOptions{})
+	defer close()
+	store := NewContainerLeaseStore(container, "orders.")
+	ctx := context.Background()
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithHeader(cosmosHeaderEtag, `"1"`))
+	lease, err := store.Create(ctx, ChangeFeedLease{ID: "range[,FF)", FeedRange: fullFeedRange(), Owner: "instance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.ID != "range[,FF)" || lease.ETag != `"1"` {
+		t.Fatalf("unexpected lease %+v", lease)
+	}
+	body := transport.bodies[0]
+	var doc map[string]interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["id"] != "orders.range[,FF)" || doc["owner"] != "instance" {
+		t.Errorf("unexpected lease document %s", body)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusConflict))
+	if _, err := store.Create(ctx, lease); !errors.Is(err, ErrLeaseConflict) {
+		t.Fatalf("expected ErrLeaseConflict, got %v", err)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusPreconditionFailed))
+	if _, err := store.Replace(ctx, lease); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if ifMatch := transport.requests[2].Header.Get(headerIfMatch); ifMatch != `"1"` {
+		t.Errorf("unexpected If-Match %q", ifMatch)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNotFound))
+	if err := store.Delete(ctx, lease); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusBadRequest))
+	if _, err := store.Replace(ctx, lease); err == nil || errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected a response error, got %v", err)
+	}
+
+	item := ChangeFeedLease{ID: "orders.range[,FF)", ContinuationToken: "token"}
+	b, _ := json.Marshal(leaseDocument{ChangeFeedLease: item, ETag: `"2"`})
+	decoded, err := store.(*containerLeaseStore).decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != "range[,FF)" || decoded.ETag != `"2"` || decoded.ContinuationToken != "token" {
+		t.Fatalf("unexpected lease %+v", decoded)
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
+)
+
+// EventChangeFeedProcessor entries contain information about change feed processors acquiring, releasing
+// and splitting leases, and errors they encounter.
+const EventChangeFeedProcessor azlog.Event = "ChangeFeedProcessor"
+
+const (
+	defaultFeedPollInterval        = 5 * time.Second
+	defaultLeaseAcquireInterval    = 13 * time.Second
+	defaultLeaseRenewInterval      = 17 * time.Second
+	defaultLeaseExpirationInterval = 60 * time.Second
+)
+
+// ChangeFeedHandler processes a page of changes. When it returns an error, the processor delivers the
+// changes again after ChangeFeedProcessorOptions.FeedPollInterval.
+type ChangeFeedHandler func(ctx context.Context, changes ChangeFeedResponse) error
+
+// ChangeFeedProcessorOptions contains optional parameters for NewChangeFeedProcessor.
+type ChangeFeedProcessorOptions struct {
+	// InstanceName identifies the processor instance in leases. It must be unique among the instances sharing
+	// a lease store. Defaults to a random name.
+	InstanceName string
+	// StartTime is the time from which to read changes of leases having no checkpoint. By default, the
+	// processor reads changes from the beginning of the container's history.
+	StartTime *time.Time
+	// MaxItemCount limits the number of changes delivered to the handler at once.
+	MaxItemCount int32
+	// FeedPollInterval is the delay between reads of a feed range having no changes. Defaults to 5 seconds.
+	FeedPollInterval time.Duration
+	// LeaseAcquireInterval is the delay between checks for leases to acquire. Defaults to 13 seconds.
+	LeaseAcquireInterval time.Duration
+	// LeaseRenewInterval is the delay between renewals of owned leases. Defaults to 17 seconds.
+	LeaseRenewInterval time.Duration
+	// LeaseExpirationInterval is the time after which an instance may take a lease its owner didn't renew.
+	// It must be greater than LeaseRenewInterval. Defaults to 60 seconds.
+	LeaseExpirationInterval time.Duration
+	// OnError is called with errors the processor recovers from, such as handler errors and failures
+	// to read the change feed.
+	OnError func(err error)
+}
+
+// ChangeFeedProcessor distributes the change feed of a container among processor instances sharing
+// a lease store, and delivers changes to a handler. Each instance holds leases on feed ranges of the
+// container, and records its progress in the leases, so another instance can continue the work of an
+// instance which stops. Changes are delivered at least once.
+type ChangeFeedProcessor struct {
+	container *ContainerClient
+	leases    ChangeFeedLeaseStore
+	handler   ChangeFeedHandler
+	options   ChangeFeedProcessorOptions
+
+	mtx     sync.Mutex
+	workers map[string]*leaseWorker
+	// observed records when the processor saw each lease change. A lease whose owner doesn't renew it
+	// within the expiration interval is expired. Comparing local times avoids depending on clocks
+	// being synchronized among instances.
+	observed map[string]leaseObservation
+}
+
+type leaseObservation struct {
+	etag azcore.ETag
+	at   time.Time
+}
+
+// NewChangeFeedProcessor creates a processor delivering the changes of the container to handler.
+// leases - The lease store the processor's instances share.
+// handler - The function processing changes.
+// o - Options for the processor.
+func (c *ContainerClient) NewChangeFeedProcessor(leases ChangeFeedLeaseStore, handler ChangeFeedHandler, o *ChangeFeedProcessorOptions) (*ChangeFeedProcessor, error) {
+	if leases == nil {
+		return nil, errors.New("a lease store is required")
+	}
+	if handler == nil {
+		return nil, errors.New("a handler is required")
+	}
+
+	options := ChangeFeedProcessorOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.InstanceName == "" {
+		name, err := uuid.New()
+		if err != nil {
+			return nil, err
+		}
+		options.InstanceName = name.String()
+	}
+	if options.FeedPollInterval <= 0 {
+		options.FeedPollInterval = defaultFeedPollInterval
+	}
+	if options.LeaseAcquireInterval <= 0 {
+		options.LeaseAcquireInterval = defaultLeaseAcquireInterval
+	}
+	if options.LeaseRenewInterval <= 0 {
+		options.LeaseRenewInterval = defaultLeaseRenewInterval
+	}
+	if options.LeaseExpirationInterval <= 0 {
+		options.LeaseExpirationInterval = defaultLeaseExpirationInterval
+	}
+	if options.LeaseExpirationInterval <= options.LeaseRenewInterval {
+		return nil, errors.New("LeaseExpirationInterval must be greater than LeaseRenewInterval")
+	}
+
+	return &ChangeFeedProcessor{
+		container: c,
+		leases:    leases,
+		handler:   handler,
+		options:   options,
+		workers:   map[string]*leaseWorker{},
+		observed:  map[string]leaseObservation{},
+	}, nil
+}
+
+// Run processes changes until ctx is done. It then releases the processor's leases, so other instances
+// can acquire them, and returns ctx's error.
+func (p *ChangeFeedProcessor) Run(ctx context.Context) error {
+	defer p.stop()
+
+	ticker := time.NewTicker(p.options.LeaseAcquireInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.balance(ctx); err != nil && ctx.Err() == nil {
+			p.reportError(fmt.Errorf("balancing leases: %w", err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// balance acquires leases until the processor owns its share of them
+func (p *ChangeFeedProcessor) balance(ctx context.Context) error {
+	leases, err := p.leases.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(leases) == 0 {
+		if err := p.createLeases(ctx); err != nil {
+			return err
+		}
+		if leases, err = p.leases.List(ctx); err != nil {
+			return err
+		}
+	}
+
+	p.mtx.Lock()
+	now := time.Now()
+	observed := make(map[string]leaseObservation, len(leases))
+	for _, lease := range leases {
+		if o, ok := p.observed[lease.ID]; ok && o.etag == lease.ETag {
+			observed[lease.ID] = o
+		} else {
+			observed[lease.ID] = leaseObservation{etag: lease.ETag, at: now}
+		}
+	}
+	p.observed = observed
+
+	owned := map[string][]ChangeFeedLease{p.options.InstanceName: nil}
+	available := []ChangeFeedLease{}
+	for _, lease := range leases {
+		if _, ok := p.workers[lease.ID]; ok {
+			owned[p.options.InstanceName] = append(owned[p.options.InstanceName], lease)
+			continue
+		}
+		if lease.Owner == "" || lease.Owner == p.options.InstanceName || now.Sub(observed[lease.ID].at) >= p.options.LeaseExpirationInterval {
+			available = append(available, lease)
+			continue
+		}
+		owned[lease.Owner] = append(owned[lease.Owner], lease)
+	}
+	p.mtx.Unlock()
+
+	target := (len(leases) + len(owned) - 1) / len(owned)
+	need := target - len(owned[p.options.InstanceName])
+	if need <= 0 {
+		return nil
+	}
+
+	candidates := available
+	if len(candidates) == 0 {
+		// steal a lease from the instance owning the most leases when it owns more than its share
+		busiest := ""
+		for owner, leases := range owned {
+			if busiest == "" || len(leases) > len(owned[busiest]) || (len(leases) == len(owned[busiest]) && owner < busiest) {
+				busiest = owner
+			}
+		}
+		if len(owned[busiest]) > target {
+			candidates = owned[busiest][:1]
+		}
+	}
+
+	for _, lease := range candidates {
+		if need == 0 {
+			break
+		}
+		previousOwner := lease.Owner
+		lease.Owner = p.options.InstanceName
+		acquired, err := p.leases.Replace(ctx, lease)
+		if errors.Is(err, ErrLeaseLost) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		log.Writef(EventChangeFeedProcessor, "%s acquired lease %s from %q", p.options.InstanceName, lease.ID, previousOwner)
+		p.startWorker(ctx, acquired)
+		need--
+	}
+
+	return nil
+}
+
+// createLeases creates a lease for each partition key range of the container
+func (p *ChangeFeedProcessor) createLeases(ctx context.Context) error {
+	pkRanges, _, err := p.container.getPartitionKeyRanges(ctx)
+	if err != nil {
+		return err
+	}
+	for _, pkRange := range pkRanges {
+		if err := p.createLease(ctx, pkRange.feedRange(), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ChangeFeedProcessor) createLease(ctx context.Context, feedRange FeedRange, continuationToken string) error {
+	_, err := p.leases.Create(ctx, ChangeFeedLease{
+		ID:                leaseID(feedRange),
+		FeedRange:         feedRange,
+		ContinuationToken: continuationToken,
+	})
+	if errors.Is(err, ErrLeaseConflict) {
+		// another instance created the lease
+		return nil
+	}
+	return err
+}
+
+// leaseID returns the ID of the lease covering a feed range. IDs are deterministic so instances
+// creating leases concurrently create the same leases.
+func leaseID(fr FeedRange) string {
+	return fmt.Sprintf("range[%s,%s)", fr.MinInclusive, fr.MaxExclusive)
+}
+
+func (p *ChangeFeedProcessor) startWorker(ctx context.Context, lease ChangeFeedLease) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &leaseWorker{processor: p, id: lease.ID, lease: lease, cancel: cancel, done: make(chan struct{})}
+
+	p.mtx.Lock()
+	p.workers[lease.ID] = w
+	p.mtx.Unlock()
+
+	go w.run(ctx)
+}
+
+// stop stops the workers and releases their leases
+func (p *ChangeFeedProcessor) stop() {
+	p.mtx.Lock()
+	workers := make([]*leaseWorker, 0, len(p.workers))
+	for _, w := range p.workers {
+		workers = append(workers, w)
+	}
+	p.mtx.Unlock()
+
+	for _, w := range workers {
+		w.cancel()
+		<-w.done
+	}
+}
+
+func (p *ChangeFeedProcessor) reportError(err error) {
+	log.Writef(EventChangeFeedProcessor, "%s: %v", p.options.InstanceName, err)
+	if p.options.OnError != nil {
+		p.options.OnError(err)
+	}
+}
+
+// leaseWorker processes the changes of one lease's feed range
+type leaseWorker struct {
+	processor *ChangeFeedProcessor
+	id        string
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	// mtx serializes updates to the lease, which the renewal and checkpoints both make
+	mtx   sync.Mutex
+	lease ChangeFeedLease
+}
+
+func (w *leaseWorker) run(ctx context.Context) {
+	p := w.processor
+	renewed := make(chan struct{})
+	defer func() {
+		w.cancel()
+		<-renewed
+		w.release()
+		p.mtx.Lock()
+		delete(p.workers, w.id)
+		p.mtx.Unlock()
+		close(w.done)
+	}()
+
+	go func() {
+		defer close(renewed)
+		w.renew(ctx)
+	}()
+
+	reader := w.newReader()
+	for ctx.Err() == nil {
+		page, err := reader.nextPage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.reportError(fmt.Errorf("reading the change feed of lease %s: %w", w.id, err))
+				w.wait(ctx, p.options.FeedPollInterval)
+			}
+			continue
+		}
+
+		if len(page.Items) > 0 {
+			if err := p.handler(ctx, page); err != nil {
+				if ctx.Err() == nil {
+					p.reportError(fmt.Errorf("handling changes of lease %s: %w", w.id, err))
+					// deliver the changes again
+					w.wait(ctx, p.options.FeedPollInterval)
+					reader = w.newReader()
+				}
+				continue
+			}
+		}
+
+		if err := w.update(ctx, func(l *ChangeFeedLease) { l.ContinuationToken = page.ContinuationToken }); err != nil {
+			if !errors.Is(err, ErrLeaseLost) && ctx.Err() == nil {
+				p.reportError(fmt.Errorf("checkpointing lease %s: %w", w.id, err))
+				w.wait(ctx, p.options.FeedPollInterval)
+			}
+			return
+		}
+
+		if len(reader.ranges) > 1 {
+			w.split(ctx, reader)
+			return
+		}
+
+		if len(page.Items) == 0 {
+			w.wait(ctx, p.options.FeedPollInterval)
+		}
+	}
+}
+
+func (w *leaseWorker) newReader() *changeFeedReader {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	feedRange := w.lease.FeedRange
+	return newChangeFeedReader(w.processor.container, ChangeFeedOptions{
+		MaxItemCount:      w.processor.options.MaxItemCount,
+		StartTime:         w.processor.options.StartTime,
+		ContinuationToken: w.lease.ContinuationToken,
+		FeedRange:         &feedRange,
+	})
+}
+
+// renew periodically updates the lease, so other instances don't consider it expired
+func (w *leaseWorker) renew(ctx context.Context) {
+	ticker := time.NewTicker(w.processor.options.LeaseRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := w.update(ctx, func(*ChangeFeedLease) {}); err != nil && ctx.Err() == nil {
+			if !errors.Is(err, ErrLeaseLost) {
+				w.processor.reportError(fmt.Errorf("renewing lease %s: %w", w.id, err))
+			}
+			return
+		}
+	}
+}
+
+// update modifies the lease and stores it. When the lease was lost to another instance, update stops the worker.
+func (w *leaseWorker) update(ctx context.Context, modify func(*ChangeFeedLease)) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	lease := w.lease
+	modify(&lease)
+	updated, err := w.processor.leases.Replace(ctx, lease)
+	if errors.Is(err, ErrLeaseLost) {
+		log.Writef(EventChangeFeedProcessor, "%s lost lease %s", w.processor.options.InstanceName, w.id)
+		w.lease.ETag = ""
+		w.cancel()
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	w.lease = updated
+	return nil
+}
+
+// release frees the lease for other instances
+func (w *leaseWorker) release() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.lease.ETag == "" {
+		return
+	}
+	lease := w.lease
+	lease.Owner = ""
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := w.processor.leases.Replace(ctx, lease); err == nil {
+		log.Writef(EventChangeFeedProcessor, "%s released lease %s", w.processor.options.InstanceName, lease.ID)
+	}
+}
+
+// split replaces the lease with a lease for each partition key range now serving its feed range.
+// The new leases continue from the lease's position and are free for any instance to acquire.
+func (w *leaseWorker) split(ctx context.Context, reader *changeFeedReader) {
+	p := w.processor
+	for _, cr := range reader.ranges {
+		token, err := reader.continuationToken([]*changeFeedRange{cr})
+		if err == nil {
+			err = p.createLease(ctx, cr.feedRange, token)
+		}
+		if err != nil {
+			p.reportError(fmt.Errorf("splitting lease %s: %w", w.id, err))
+			return
+		}
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if err := p.leases.Delete(ctx, w.lease); err != nil && !errors.Is(err, ErrLeaseLost) {
+		p.reportError(fmt.Errorf("deleting lease %s after splitting it: %w", w.id, err))
+		return
+	}
+	log.Writef(EventChangeFeedProcessor, "%s split lease %s into %d leases", p.options.InstanceName, w.id, len(reader.ranges))
+	w.lease.ETag = ""
+}
+
+func (w *leaseWorker) wait(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
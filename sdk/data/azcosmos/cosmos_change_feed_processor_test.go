@@ -0,0 +1,289 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// changeRecorder is a change feed handler recording the IDs of changed items
+type changeRecorder struct {
+	mtx  sync.Mutex
+	ids  []string
+	fail int
+}
+
+func (r *changeRecorder) handle(ctx context.Context, changes ChangeFeedResponse) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("handler failure")
+	}
+	for _, item := range changes.Items {
+		var doc struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(item, &doc); err != nil {
+			return err
+		}
+		r.ids = append(r.ids, doc.ID)
+	}
+	return nil
+}
+
+func (r *changeRecorder) sorted() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	ids := append([]string{}, r.ids...)
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func testProcessorOptions(name string) *ChangeFeedProcessorOptions {
+	return &ChangeFeedProcessorOptions{
+		InstanceName:            name,
+		FeedPollInterval:        5 * time.Millisecond,
+		LeaseAcquireInterval:    10 * time.Millisecond,
+		LeaseRenewInterval:      20 * time.Millisecond,
+		LeaseExpirationInterval: 100 * time.Millisecond,
+	}
+}
+
+// runProcessor runs a processor until the returned function stops it
+func runProcessor(t *testing.T, p *ChangeFeedProcessor) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+	return func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+}
+
+func eventually(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func listLeases(t *testing.T, store ChangeFeedLeaseStore) []ChangeFeedLease {
+	leases, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leases
+}
+
+func TestChangeFeedProcessor(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	gateway.write("b", "90")
+	container := newFakeGatewayContainer(t, gateway)
+	store := NewInMemoryLeaseStore()
+	recorder := &changeRecorder{}
+
+	p, err := container.NewChangeFeedProcessor(store, recorder.handle, testProcessorOptions("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+	eventually(t, "the changes", func() bool { return recorder.sorted() == "a,b" })
+
+	gateway.write("c", "20")
+	eventually(t, "the later change", func() bool { return recorder.sorted() == "a,b,c" })
+	stop()
+
+	leases := listLeases(t, store)
+	if len(leases) != 2 {
+		t.Fatalf("expected a lease for each partition key range, got %v", leases)
+	}
+	for _, lease := range leases {
+		if lease.Owner != "" {
+			t.Errorf("expected lease %s to be released, but %s owns it", lease.ID, lease.Owner)
+		}
+		if lease.ContinuationToken == "" {
+			t.Errorf("expected lease %s to have a checkpoint", lease.ID)
+		}
+	}
+
+	// a new processor continues from the checkpoints
+	gateway.write("d", "A0")
+	recorder = &changeRecorder{}
+	p, err = container.NewChangeFeedProcessor(store, recorder.handle, testProcessorOptions("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop = runProcessor(t, p)
+	defer stop()
+	eventually(t, "the change made while stopped", func() bool { return recorder.sorted() == "d" })
+}
+
+func TestChangeFeedProcessorBalancesLeases(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	container := newFakeGatewayContainer(t, gateway)
+	store := NewInMemoryLeaseStore()
+	recorder := &changeRecorder{}
+
+	p1, err := container.NewChangeFeedProcessor(store, recorder.handle, testProcessorOptions("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop1 := runProcessor(t, p1)
+	owners := func() map[string]int {
+		owners := map[string]int{}
+		for _, lease := range listLeases(t, store) {
+			owners[lease.Owner]++
+		}
+		return owners
+	}
+	eventually(t, "one to own both leases", func() bool { return owners()["one"] == 2 })
+
+	p2, err := container.NewChangeFeedProcessor(store, recorder.handle, testProcessorOptions("two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop2 := runProcessor(t, p2)
+	defer stop2()
+	eventually(t, "two to take a lease", func() bool {
+		o := owners()
+		return o["one"] == 1 && o["two"] == 1
+	})
+
+	gateway.write("a", "10")
+	gateway.write("b", "90")
+	eventually(t, "the changes", func() bool { return recorder.sorted() == "a,b" })
+
+	// when one stops, two takes its lease
+	stop1()
+	eventually(t, "two to own both leases", func() bool { return owners()["two"] == 2 })
+	gateway.write("c", "20")
+	gateway.write("d", "A0")
+	eventually(t, "the later changes", func() bool { return recorder.sorted() == "a,b,c,d" })
+}
+
+func TestChangeFeedProcessorTakesExpiredLeases(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	container := newFakeGatewayContainer(t, gateway)
+	store := NewInMemoryLeaseStore()
+	for _, fr := range []FeedRange{{MinInclusive: "", MaxExclusive: "7F"}, {MinInclusive: "7F", MaxExclusive: "FF"}} {
+		if _, err := store.Create(context.Background(), ChangeFeedLease{ID: leaseID(fr), FeedRange: fr, Owner: "crashed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorder := &changeRecorder{}
+	p, err := container.NewChangeFeedProcessor(store, recorder.handle, testProcessorOptions("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned := func() int {
+		n := 0
+		for _, lease := range listLeases(t, store) {
+			if lease.Owner == "instance" {
+				n++
+			}
+		}
+		return n
+	}
+
+	start := time.Now()
+	stop := runProcessor(t, p)
+	defer stop()
+	// the processor takes its share of the leases from the busier instance, then the rest when they expire
+	eventually(t, "a lease", func() bool { return owned() == 1 })
+	eventually(t, "both leases", func() bool { return owned() == 2 })
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("took both leases after %v, before they expired", elapsed)
+	}
+	gateway.write("a", "10")
+	gateway.write("b", "90")
+	eventually(t, "the changes", func() bool { return recorder.sorted() == "a,b" })
+}
+
+func TestChangeFeedProcessorSplitsLeases(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	container := newFakeGatewayContainer(t, gateway)
+	store := NewInMemoryLeaseStore()
+	recorder := &changeRecorder{}
+
+	p, err := container.NewChangeFeedProcessor(store, recorder.handle, testProcessorOptions("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+	defer stop()
+	eventually(t, "the changes", func() bool { return recorder.sorted() == "a" })
+
+	gateway.split("0", "40", "2", "3")
+	gateway.write("b", "20")
+	gateway.write("c", "50")
+	eventually(t, "the changes after the split", func() bool { return recorder.sorted() == "a,b,c" })
+	eventually(t, "the lease to split", func() bool {
+		ids := []string{}
+		for _, lease := range listLeases(t, store) {
+			ids = append(ids, lease.ID)
+		}
+		return strings.Join(ids, " ") == "range[,40) range[40,7F) range[7F,FF)"
+	})
+
+	gateway.write("d", "30")
+	eventually(t, "a change to a child range", func() bool { return recorder.sorted() == "a,b,c,d" })
+}
+
+func TestChangeFeedProcessorRedeliversAfterHandlerError(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	container := newFakeGatewayContainer(t, gateway)
+	recorder := &changeRecorder{fail: 2}
+	errs := make(chan error, 10)
+	options := testProcessorOptions("instance")
+	options.OnError = func(err error) { errs <- err }
+
+	p, err := container.NewChangeFeedProcessor(NewInMemoryLeaseStore(), recorder.handle, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runProcessor(t, p)
+	defer stop()
+	eventually(t, "the changes", func() bool { return recorder.sorted() == "a" })
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %d", len(errs))
+	}
+}
+
+func TestNewChangeFeedProcessorValidation(t *testing.T) {
+	container := newFakeGatewayContainer(t, newFakeChangeFeedGateway())
+	handler := (&changeRecorder{}).handle
+	if _, err := container.NewChangeFeedProcessor(nil, handler, nil); err == nil {
+		t.Error("expected an error for a missing lease store")
+	}
+	if _, err := container.NewChangeFeedProcessor(NewInMemoryLeaseStore(), nil, nil); err == nil {
+		t.Error("expected an error for a missing handler")
+	}
+	if _, err := container.NewChangeFeedProcessor(NewInMemoryLeaseStore(), handler, &ChangeFeedProcessorOptions{LeaseRenewInterval: time.Minute}); err == nil {
+		t.Error("expected an error for an expiration interval shorter than the renew interval")
+	}
+	p, err := container.NewChangeFeedProcessor(NewInMemoryLeaseStore(), handler, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.options.InstanceName == "" || p.options.LeaseExpirationInterval != defaultLeaseExpirationInterval {
+		t.Errorf("unexpected defaults %+v", p.options)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeChange struct {
+	lsn  int
+	epk  string
+	body string
+}
+
+// fakeChangeFeedGateway serves the change feeds of partition key ranges. A range's etag is the
+// LSN of the last change read from it, so positions remain valid after a range splits.
+type fakeChangeFeedGateway struct {
+	mtx      sync.Mutex
+	ranges   []partitionKeyRange
+	changes  map[string][]fakeChange
+	lsn      int
+	requests []*http.Request
+}
+
+func newFakeChangeFeedGateway() *fakeChangeFeedGateway {
+	return &fakeChangeFeedGateway{
+		ranges:  newFakeGatewayRanges("7F"),
+		changes: map[string][]fakeChange{},
+	}
+}
+
+// write records a change of the item having the given effective partition key
+func (g *fakeChangeFeedGateway) write(id, epk string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	for _, r := range g.ranges {
+		if r.MinInclusive <= epk && epk < r.MaxExclusive {
+			g.lsn++
+			g.changes[r.ID] = append(g.changes[r.ID], fakeChange{lsn: g.lsn, epk: epk, body: fmt.Sprintf(`{"id":"%s"}`, id)})
+			return
+		}
+	}
+}
+
+// split replaces a range with two children dividing it at epk
+func (g *fakeChangeFeedGateway) split(id, epk string, children ...string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	for i, r := range g.ranges {
+		if r.ID != id {
+			continue
+		}
+		left := partitionKeyRange{ID: children[0], MinInclusive: r.MinInclusive, MaxExclusive: epk, Parents: []string{id}}
+		right := partitionKeyRange{ID: children[1], MinInclusive: epk, MaxExclusive: r.MaxExclusive, Parents: []string{id}}
+		for _, c := range g.changes[id] {
+			if c.epk < epk {
+				g.changes[left.ID] = append(g.changes[left.ID], c)
+			} else {
+				g.changes[right.ID] = append(g.changes[right.ID], c)
+			}
+		}
+		delete(g.changes, id)
+		g.ranges = append(g.ranges[:i], append([]partitionKeyRange{left, right}, g.ranges[i+1:]...)...)
+		return
+	}
+}
+
+func (g *fakeChangeFeedGateway) Do(req *http.Request) (*http.Response, error) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.requests = append(g.requests, req)
+
+	header := http.Header{}
+	header.Set(cosmosHeaderRequestCharge, "1")
+	respond := func(status int, body string) (*http.Response, error) {
+		return newFakeGatewayResponse(req, status, header, body), nil
+	}
+
+	if isPartitionKeyRangesRequest(req) {
+		return respond(http.StatusOK, partitionKeyRangesBody(g.ranges))
+	}
+	if req.Header.Get(cosmosHeaderAIM) != cosmosHeaderValuesChangeFeed {
+		return nil, fmt.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	id := req.Header.Get(cosmosHeaderPartitionKeyRangeID)
+	changes, ok := g.changes[id]
+	if !ok && !g.hasRange(id) {
+		header.Set(cosmosHeaderSubStatus, subStatusPartitionKeyRangeGone)
+		return respond(http.StatusGone, `{"code":"Gone"}`)
+	}
+
+	after := 0
+	if etag := req.Header.Get(headerIfNoneMatch); etag != "" {
+		after, _ = strconv.Atoi(strings.Trim(etag, `"`))
+	}
+	max := len(changes)
+	if m := req.Header.Get(cosmosHeaderMaxItemCount); m != "" {
+		max, _ = strconv.Atoi(m)
+	}
+	minEpk, maxEpk := req.Header.Get(cosmosHeaderStartEpk), req.Header.Get(cosmosHeaderEndEpk)
+
+	docs := []string{}
+	last := after
+	for _, c := range changes {
+		if c.lsn <= after || len(docs) == max {
+			continue
+		}
+		if (minEpk != "" || maxEpk != "") && (c.epk < minEpk || c.epk >= maxEpk) {
+			continue
+		}
+		docs = append(docs, c.body)
+		last = c.lsn
+	}
+	if len(docs) == 0 && len(changes) > 0 && changes[len(changes)-1].lsn > last {
+		last = changes[len(changes)-1].lsn
+	}
+	header.Set(cosmosHeaderEtag, fmt.Sprintf(`"%d"`, last))
+	if len(docs) == 0 {
+		return respond(http.StatusNotModified, "")
+	}
+	return respond(http.StatusOK, fmt.Sprintf(`{"_rid":"rid","Documents":[%s],"_count":%d}`, strings.Join(docs, ","), len(docs)))
+}
+
+func (g *fakeChangeFeedGateway) hasRange(id string) bool {
+	for _, r := range g.ranges {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// readChanges reads the change feed until it has no more changes
+func readChanges(t *testing.T, container *ContainerClient, o *ChangeFeedOptions) ([]string, string) {
+	var ids []string
+	token := ""
+	pager := container.NewChangeFeedPager(o)
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range page.Items {
+			var doc struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(item, &doc); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, doc.ID)
+		}
+		token = page.ContinuationToken
+	}
+	return ids, token
+}
+
+func TestChangeFeedPagerFromBeginning(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	gateway.write("b", "90")
+	gateway.write("c", "20")
+	container := newFakeGatewayContainer(t, gateway)
+
+	pager := container.NewChangeFeedPager(nil)
+	page, err := pager.NextPage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("expected the changes of range 0, got %d items", len(page.Items))
+	}
+	// partition key ranges and the page of changes
+	if page.RequestCharge != 2 {
+		t.Errorf("expected a request charge of 2, got %v", page.RequestCharge)
+	}
+
+	ids, token := readChanges(t, container, nil)
+	if strings.Join(ids, ",") != "a,c,b" {
+		t.Fatalf("unexpected changes %v", ids)
+	}
+
+	// resuming from the token returns only later changes
+	gateway.write("a", "10")
+	gateway.write("d", "A0")
+	ids, _ = readChanges(t, container, &ChangeFeedOptions{ContinuationToken: token})
+	if strings.Join(ids, ",") != "a,d" {
+		t.Fatalf("unexpected changes %v", ids)
+	}
+}
+
+func TestChangeFeedPagerNoChanges(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	container := newFakeGatewayContainer(t, gateway)
+
+	pager := container.NewChangeFeedPager(nil)
+	page, err := pager.NextPage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 || page.ContinuationToken == "" {
+		t.Fatalf("expected an empty page having a continuation token, got %+v", page)
+	}
+	if pager.More() {
+		t.Fatal("expected no more pages")
+	}
+}
+
+func TestChangeFeedPagerStartTime(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	container := newFakeGatewayContainer(t, gateway)
+
+	start := time.Date(2022, 12, 1, 8, 30, 0, 0, time.FixedZone("PST", -8*60*60))
+	ids, _ := readChanges(t, container, &ChangeFeedOptions{StartTime: &start, MaxItemCount: 5})
+	if strings.Join(ids, ",") != "a" {
+		t.Fatalf("unexpected changes %v", ids)
+	}
+
+	var sawEtag bool
+	for _, req := range gateway.requests {
+		if req.Header.Get(cosmosHeaderAIM) == "" {
+			continue
+		}
+		if req.Header.Get(cosmosHeaderMaxItemCount) != "5" {
+			t.Errorf("unexpected max item count %q", req.Header.Get(cosmosHeaderMaxItemCount))
+		}
+		if etag := req.Header.Get(headerIfNoneMatch); etag != "" {
+			sawEtag = true
+			continue
+		}
+		if ims := req.Header.Get(headerIfModifiedSince); ims != "Thu, 01 Dec 2022 16:30:00 GMT" {
+			t.Errorf("unexpected If-Modified-Since %q", ims)
+		}
+	}
+	if !sawEtag {
+		t.Error("expected a request continuing from an etag")
+	}
+}
+
+func TestChangeFeedPagerFeedRange(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	gateway.write("b", "50")
+	gateway.write("c", "90")
+	container := newFakeGatewayContainer(t, gateway)
+
+	ids, _ := readChanges(t, container, &ChangeFeedOptions{FeedRange: &FeedRange{MinInclusive: "40", MaxExclusive: "A0"}})
+	if strings.Join(ids, ",") != "b,c" {
+		t.Fatalf("unexpected changes %v", ids)
+	}
+	for _, req := range gateway.requests {
+		if req.Header.Get(cosmosHeaderPartitionKeyRangeID) == "0" && req.Header.Get(cosmosHeaderStartEpk) != "40" {
+			t.Errorf("expected range 0 to be read from 40, got %q", req.Header.Get(cosmosHeaderStartEpk))
+		}
+	}
+}
+
+func TestChangeFeedPagerSplit(t *testing.T) {
+	gateway := newFakeChangeFeedGateway()
+	gateway.write("a", "10")
+	gateway.write("b", "50")
+	container := newFakeGatewayContainer(t, gateway)
+
+	ids, token := readChanges(t, container, nil)
+	if strings.Join(ids, ",") != "a,b" {
+		t.Fatalf("unexpected changes %v", ids)
+	}
+
+	// range 0 splits after the token was created, and while the pager reads
+	gateway.write("c", "60")
+	gateway.split("0", "40", "2", "3")
+	gateway.write("d", "20")
+	ids, token = readChanges(t, container, &ChangeFeedOptions{ContinuationToken: token})
+	if strings.Join(ids, ",") != "d,c" {
+		t.Fatalf("unexpected changes %v", ids)
+	}
+
+	// range 3 splits after the reader listed the partition key ranges
+	reader := newChangeFeedReader(container, ChangeFeedOptions{ContinuationToken: token})
+	if err := reader.initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	gateway.split("3", "60", "4", "5")
+	gateway.write("e", "70")
+	page, err := reader.nextPage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || string(page.Items[0]) != `{"id":"e"}` {
+		t.Fatalf("unexpected page %v", page.Items)
+	}
+	var c changeFeedContinuation
+	if err := json.Unmarshal([]byte(page.ContinuationToken), &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Ranges) != 4 {
+		t.Fatalf("expected a token for 4 ranges, got %v", c.Ranges)
+	}
+}
+
+func TestChangeFeedPagerInvalidContinuation(t *testing.T) {
+	container := newFakeGatewayContainer(t, newFakeChangeFeedGateway())
+	other, err := container.database.client.NewContainer("db", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, token := readChanges(t, other, nil)
+
+	for _, token := range []string{"nope", `{"ranges":[]}`, token} {
+		pager := container.NewChangeFeedPager(&ChangeFeedOptions{ContinuationToken: token})
+		if _, err := pager.NextPage(context.Background()); err == nil {
+			t.Errorf("expected an error for token %q", token)
+		}
+	}
+}
@@ -24,6 +24,12 @@ import (
 type Client struct {
 	endpoint string
 	pipeline azruntime.Pipeline
+	gem      *globalEndpointManager
+	session  *sessionContainer
+	pkRanges *partitionKeyRangeCache
+	// encryption is set when the client has a KeyWrapProvider
+	encryption      *encryptionPolicy
+	keyWrapProvider KeyWrapProvider
 }
 
 // Endpoint used to create the client.
@@ -36,7 +42,19 @@ func (c *Client) Endpoint() string {
 // cred - The credential used to authenticate with the cosmos service.
 // options - Optional Cosmos client options.  Pass nil to accept default values.
 func NewClientWithKey(endpoint string, cred KeyCredential, o *ClientOptions) (*Client, error) {
-	return &Client{endpoint: endpoint, pipeline: newPipeline(newSharedKeyCredPolicy(cred), o)}, nil
+	return newClient(endpoint, newSharedKeyCredPolicy(cred), o)
+}
+
+// NewClientWithResourceToken creates a new instance of Cosmos client with resource token authentication. It uses the default pipeline configuration.
+// The client can only access the resources the credential's tokens grant access to.
+// endpoint - The cosmos service endpoint to use.
+// cred - The credential used to authenticate with the cosmos service.
+// options - Optional Cosmos client options.  Pass nil to accept default values.
+func NewClientWithResourceToken(endpoint string, cred *ResourceTokenCredential, o *ClientOptions) (*Client, error) {
+	if cred == nil {
+		return nil, errors.New("cred is required")
+	}
+	return newClient(endpoint, newResourceTokenCredPolicy(cred), o)
 }
 
 // NewClient creates a new instance of Cosmos client with Azure AD access token authentication. It uses the default pipeline configuration.
@@ -48,7 +66,7 @@ func NewClient(endpoint string, cred azcore.TokenCredential, o *ClientOptions) (
 	if err != nil {
 		return nil, err
 	}
-	return &Client{endpoint: endpoint, pipeline: newPipeline(newCosmosBearerTokenPolicy(cred, scope, nil), o)}, nil
+	return newClient(endpoint, newCosmosBearerTokenPolicy(cred, scope, nil), o)
 }
 
 // NewClientFromConnectionString creates a new instance of Cosmos client from connection string. It uses the default pipeline configuration.
@@ -87,20 +105,64 @@ func NewClientFromConnectionString(connectionString string, o *ClientOptions) (*
 	return NewClientWithKey(endpoint, cred, o)
 }
 
-func newPipeline(authPolicy policy.Policy, options *ClientOptions) azruntime.Pipeline {
+func newClient(endpoint string, authPolicy policy.Policy, options *ClientOptions) (*Client, error) {
+	if options == nil {
+		options = &ClientOptions{}
+	}
+
+	retryPolicy := &clientRetryPolicy{}
+	session := newSessionContainer()
+	pkRanges := newPartitionKeyRangeCache()
+	var encryption *encryptionPolicy
+	if options.KeyWrapProvider != nil {
+		encryption = newEncryptionPolicy(options.KeyWrapProvider)
+	}
+	pipeline := newPipeline(authPolicy, retryPolicy, session, pkRanges, encryption, options)
+	gem, err := newGlobalEndpointManager(endpoint, pipeline, options.PreferredRegions, 0)
+	if err != nil {
+		return nil, err
+	}
+	retryPolicy.gem = gem
+
+	client := &Client{
+		endpoint:        endpoint,
+		pipeline:        pipeline,
+		gem:             gem,
+		session:         session,
+		pkRanges:        pkRanges,
+		encryption:      encryption,
+		keyWrapProvider: options.KeyWrapProvider,
+	}
+	if encryption != nil {
+		encryption.client = client
+	}
+	return client, nil
+}
+
+func newPipeline(authPolicy policy.Policy, retryPolicy *clientRetryPolicy, session *sessionContainer, pkRanges *partitionKeyRangeCache, encryption *encryptionPolicy, options *ClientOptions) azruntime.Pipeline {
 	if options == nil {
 		options = &ClientOptions{}
 	}
 
+	perCall := []policy.Policy{
+		&diagnosticsPolicy{handler: options.DiagnosticsHandler},
+		&headerPolicies{
+			enableContentResponseOnWrite: options.EnableContentResponseOnWrite,
+		},
+		&sessionPolicy{sessions: session},
+		&partitionKeyRangeCachePolicy{cache: pkRanges},
+	}
+	if encryption != nil {
+		perCall = append(perCall, encryption)
+	}
+
 	return azruntime.NewPipeline("azcosmos", serviceLibVersion,
 		azruntime.PipelineOptions{
-			PerCall: []policy.Policy{
-				&headerPolicies{
-					enableContentResponseOnWrite: options.EnableContentResponseOnWrite,
-				},
-			},
+			PerCall: perCall,
 			PerRetry: []policy.Policy{
+				retryPolicy,
 				authPolicy,
+				&attemptDiagnosticsPolicy{},
 			},
 		},
 		&options.ClientOptions)
@@ -227,6 +289,25 @@ func (c *Client) NewQueryDatabasesPager(query string, o *QueryDatabasesOptions)
 	})
 }
 
+// ExportSessionState returns the session tokens the client tracks, so another client can import them
+// with ImportSessionState and read the writes of this client under session consistency.
+func (c *Client) ExportSessionState() ([]byte, error) {
+	if c.session == nil {
+		return []byte("{}"), nil
+	}
+	return c.session.export()
+}
+
+// ImportSessionState merges session tokens exported by ExportSessionState into the session tokens
+// the client tracks.
+// state - The exported session tokens.
+func (c *Client) ImportSessionState(state []byte) error {
+	if c.session == nil {
+		return errors.New("the client doesn't track session tokens")
+	}
+	return c.session.merge(state)
+}
+
 func (c *Client) sendPostRequest(
 	path string,
 	ctx context.Context,
@@ -372,7 +453,7 @@ func (c *Client) createRequest(
 	requestOptions cosmosRequestOptions,
 	requestEnricher func(*policy.Request)) (*policy.Request, error) {
 
-	// todo: endpoint will be set originally by globalendpointmanager
+	// the client retry policy routes the request to a regional endpoint
 	finalURL := c.endpoint
 
 	if path != "" {
@@ -0,0 +1,168 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+)
+
+// CreateClientEncryptionKey creates a client encryption key in the Cosmos database. It generates a data encryption
+// key, and wraps it with the key encryption key of metadata using the client's ClientOptions.KeyWrapProvider.
+// ctx - The context for the request.
+// id - The id of the client encryption key, which client encryption policies refer to.
+// metadata - Identifies the key encryption key.
+// o - Options for the operation.
+func (db *DatabaseClient) CreateClientEncryptionKey(
+	ctx context.Context,
+	id string,
+	metadata KeyWrapMetadata,
+	o *ClientEncryptionKeyOptions) (ClientEncryptionKeyResponse, error) {
+	if o == nil {
+		o = &ClientEncryptionKeyOptions{}
+	}
+	if id == "" {
+		return ClientEncryptionKeyResponse{}, errors.New("id is required")
+	}
+	provider := db.client.keyWrapProvider
+	if provider == nil {
+		return ClientEncryptionKeyResponse{}, errors.New("client encryption keys require a client with a KeyWrapProvider")
+	}
+
+	key := make([]byte, aeadKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+	wrappedKey, err := provider.WrapKey(ctx, metadata, key)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypeClientEncryptionKey,
+		resourceAddress:  db.link,
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeClientEncryptionKey, db.link, true)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	azResponse, err := db.client.sendPostRequest(
+		path,
+		ctx,
+		ClientEncryptionKeyProperties{
+			ID:                       id,
+			EncryptionAlgorithm:      EncryptionAlgorithmAEADAES256CBCHMACSHA256,
+			WrappedDataEncryptionKey: wrappedKey,
+			KeyWrapMetadata:          metadata,
+		},
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	return newClientEncryptionKeyResponse(azResponse)
+}
+
+// ReadClientEncryptionKey obtains the properties of a client encryption key.
+// ctx - The context for the request.
+// id - The id of the client encryption key.
+// o - Options for the operation.
+func (db *DatabaseClient) ReadClientEncryptionKey(
+	ctx context.Context,
+	id string,
+	o *ClientEncryptionKeyOptions) (ClientEncryptionKeyResponse, error) {
+	if o == nil {
+		o = &ClientEncryptionKeyOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeClientEncryptionKey,
+		resourceAddress: createLink(db.link, pathSegmentClientEncryptionKey, id),
+	}
+
+	path, err := generatePathForNameBased(resourceTypeClientEncryptionKey, operationContext.resourceAddress, false)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	azResponse, err := db.client.sendGetRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	return newClientEncryptionKeyResponse(azResponse)
+}
+
+// RewrapClientEncryptionKey wraps the data encryption key of a client encryption key with another key encryption key,
+// for example to rotate the key encryption key. Properties encrypted with the client encryption key remain readable.
+// ctx - The context for the request.
+// id - The id of the client encryption key.
+// metadata - Identifies the new key encryption key.
+// o - Options for the operation.
+func (db *DatabaseClient) RewrapClientEncryptionKey(
+	ctx context.Context,
+	id string,
+	metadata KeyWrapMetadata,
+	o *ClientEncryptionKeyOptions) (ClientEncryptionKeyResponse, error) {
+	provider := db.client.keyWrapProvider
+	if provider == nil {
+		return ClientEncryptionKeyResponse{}, errors.New("client encryption keys require a client with a KeyWrapProvider")
+	}
+
+	current, err := db.ReadClientEncryptionKey(ctx, id, nil)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+	properties := *current.ClientEncryptionKeyProperties
+	key, err := provider.UnwrapKey(ctx, properties.KeyWrapMetadata, properties.WrappedDataEncryptionKey)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+	wrappedKey, err := provider.WrapKey(ctx, metadata, key)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+	properties.WrappedDataEncryptionKey = wrappedKey
+	properties.KeyWrapMetadata = metadata
+
+	if o == nil {
+		// the key mustn't change between the read and the replace
+		o = &ClientEncryptionKeyOptions{IfMatchEtag: properties.ETag}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypeClientEncryptionKey,
+		resourceAddress:  createLink(db.link, pathSegmentClientEncryptionKey, id),
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeClientEncryptionKey, operationContext.resourceAddress, false)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	azResponse, err := db.client.sendPutRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return ClientEncryptionKeyResponse{}, err
+	}
+
+	return newClientEncryptionKeyResponse(azResponse)
+}
@@ -0,0 +1,38 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// ClientEncryptionKeyProperties represents the properties of a client encryption key, a data encryption key
+// wrapped by a key encryption key, which encrypts the properties of items on the client.
+type ClientEncryptionKeyProperties struct {
+	// ID contains the unique id of the client encryption key.
+	ID string `json:"id"`
+	// EncryptionAlgorithm is the algorithm encrypting properties with the key,
+	// EncryptionAlgorithmAEADAES256CBCHMACSHA256.
+	EncryptionAlgorithm string `json:"encryptionAlgorithm"`
+	// WrappedDataEncryptionKey is the data encryption key, wrapped by the key encryption key of KeyWrapMetadata.
+	WrappedDataEncryptionKey []byte `json:"wrappedDataEncryptionKey"`
+	// KeyWrapMetadata identifies the key encryption key.
+	KeyWrapMetadata KeyWrapMetadata `json:"keyWrapMetadata"`
+	// ETag contains the entity etag of the client encryption key.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the client encryption key.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the client encryption key.
+	ResourceID string `json:"_rid,omitempty"`
+	// LastModified contains the last modified time of the client encryption key.
+	LastModified time.Time `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (p *ClientEncryptionKeyProperties) UnmarshalJSON(b []byte) error {
+	type properties ClientEncryptionKeyProperties
+	return unmarshalTimestampedProperties(b, (*properties)(p), &p.LastModified)
+}
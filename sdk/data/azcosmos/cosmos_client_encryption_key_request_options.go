@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// ClientEncryptionKeyOptions includes options for operations on client encryption keys.
+type ClientEncryptionKeyOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *ClientEncryptionKeyOptions) toHeaders() *map[string]string {
+	if options.IfMatchEtag == nil {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	headers[headerIfMatch] = string(*options.IfMatchEtag)
+	return &headers
+}
@@ -0,0 +1,30 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"net/http"
+
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// ClientEncryptionKeyResponse represents the response from a client encryption key request.
+type ClientEncryptionKeyResponse struct {
+	// ClientEncryptionKeyProperties contains the unmarshalled response body in ClientEncryptionKeyProperties format.
+	ClientEncryptionKeyProperties *ClientEncryptionKeyProperties
+	Response
+}
+
+func newClientEncryptionKeyResponse(resp *http.Response) (ClientEncryptionKeyResponse, error) {
+	response := ClientEncryptionKeyResponse{
+		Response: newResponse(resp),
+	}
+	properties := &ClientEncryptionKeyProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.ClientEncryptionKeyProperties = properties
+	return response, nil
+}
@@ -0,0 +1,29 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// ClientEncryptionPolicy names the properties of a container's items which clients encrypt, like
+// Always Encrypted in other Cosmos SDKs. Clients having a ClientOptions.KeyWrapProvider encrypt the properties
+// when they write items, and decrypt them when they read items.
+// For more information see https://docs.microsoft.com/azure/cosmos-db/how-to-always-encrypted
+type ClientEncryptionPolicy struct {
+	// IncludedPaths are the encrypted properties.
+	IncludedPaths []ClientEncryptionIncludedPath `json:"includedPaths"`
+	// PolicyFormatVersion is the version of the policy's format. Version 2 allows encrypting the id and
+	// partition key properties with deterministic encryption. Defaults to 1.
+	PolicyFormatVersion int `json:"policyFormatVersion"`
+}
+
+// ClientEncryptionIncludedPath describes the encryption of a property.
+type ClientEncryptionIncludedPath struct {
+	// Path is the path of a top level property, for example /ssn. The values of objects and arrays are
+	// encrypted individually.
+	Path string `json:"path"`
+	// ClientEncryptionKeyID is the id of the client encryption key encrypting the property.
+	ClientEncryptionKeyID string `json:"clientEncryptionKeyId"`
+	// EncryptionType is the type of encryption.
+	EncryptionType EncryptionType `json:"encryptionType"`
+	// EncryptionAlgorithm is the encryption algorithm, EncryptionAlgorithmAEADAES256CBCHMACSHA256.
+	EncryptionAlgorithm string `json:"encryptionAlgorithm"`
+}
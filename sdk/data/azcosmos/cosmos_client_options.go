@@ -13,4 +13,17 @@ type ClientOptions struct {
 	// When EnableContentResponseOnWrite is false will cause the response to have a null resource. This reduces networking and CPU load by not sending the resource back over the network and serializing it on the client.
 	// The default is false.
 	EnableContentResponseOnWrite bool
+	// PreferredRegions is the list of regions, in order of preference, the client sends requests to, for example
+	// []string{"West US", "East US"}. The client reads the regions of the database account in the background and
+	// fails over to the next available preferred region when a region is unavailable. When the account allows writes
+	// in multiple regions, writes are also sent to the preferred regions. By default, requests are sent to the
+	// account's write region.
+	PreferredRegions []string
+	// DiagnosticsHandler is called with the Diagnostics of each operation once it completes, for example
+	// to record the request units consumed by the client. It's called concurrently by concurrent operations.
+	DiagnosticsHandler func(*Diagnostics)
+	// KeyWrapProvider unwraps the client encryption keys of databases. When it's set, the client encrypts the
+	// properties named by containers' client encryption policies when it writes items, and decrypts them when
+	// it reads items. See NewKeyVaultKeyWrapProvider.
+	KeyWrapProvider KeyWrapProvider
 }
@@ -0,0 +1,131 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+const subStatusWriteForbidden string = "3"
+
+// contactedRegionKey is the context key for the name of the region a request was sent to
+type contactedRegionKey struct{}
+
+// clientRetryPolicy sends each request to the most preferred available regional endpoint and
+// fails over to the next one when a region is unavailable or no longer accepts writes.
+type clientRetryPolicy struct {
+	gem *globalEndpointManager
+}
+
+func (p *clientRetryPolicy) Do(req *policy.Request) (*http.Response, error) {
+	o := pipelineRequestOptions{}
+	if p.gem == nil || !req.OperationValue(&o) || o.resourceType == resourceTypeDatabaseAccount {
+		return req.Next()
+	}
+
+	ctx := req.Raw().Context()
+	p.gem.refreshIfNeeded()
+	isWriteOperation := isWriteRequest(req, o)
+
+	var resp *http.Response
+	var err error
+	tried := map[url.URL]bool{}
+	for {
+		endpoint, ok, resolveErr := p.gem.resolveServiceEndpoint(isWriteOperation, tried)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		if !ok {
+			// every endpoint failed, return the last result so the retry policy can try again later
+			return resp, err
+		}
+
+		if len(tried) > 0 {
+			azruntime.Drain(resp)
+			if rewindErr := req.RewindBody(); rewindErr != nil {
+				return nil, rewindErr
+			}
+		}
+		tried[endpoint] = true
+
+		region := p.gem.getLocation(endpoint)
+		clone := req.Clone(context.WithValue(ctx, contactedRegionKey{}, region))
+		clone.Raw().URL.Scheme = endpoint.Scheme
+		clone.Raw().URL.Host = endpoint.Host
+		clone.Raw().Host = endpoint.Host
+
+		resp, err = clone.Next()
+		if !p.shouldFailover(ctx, endpoint, isWriteOperation, resp, err) {
+			return resp, err
+		}
+		log.Writef(azlog.EventRetryPolicy, "Region %q (%s) is unavailable, failing over", region, endpoint.Host)
+	}
+}
+
+// shouldFailover returns true when the request should be retried in another region. It marks the
+// endpoint unavailable for the operation, and refreshes the account's regions when the endpoint
+// no longer accepts writes.
+func (p *clientRetryPolicy) shouldFailover(ctx context.Context, endpoint url.URL, isWriteOperation bool, resp *http.Response, err error) bool {
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		// the region couldn't be reached
+		return p.markEndpointUnavailable(endpoint, isWriteOperation) == nil
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return p.markEndpointUnavailable(endpoint, isWriteOperation) == nil
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get(cosmosHeaderSubStatus) == subStatusWriteForbidden:
+		// the region is no longer a write region, for example after a manual failover
+		if p.gem.markEndpointUnavailableForWrite(endpoint) != nil {
+			return false
+		}
+		_ = p.gem.forceRefresh(ctx)
+		return true
+	}
+
+	return false
+}
+
+func (p *clientRetryPolicy) markEndpointUnavailable(endpoint url.URL, isWriteOperation bool) error {
+	if isWriteOperation {
+		return p.gem.markEndpointUnavailableForWrite(endpoint)
+	}
+	return p.gem.markEndpointUnavailableForRead(endpoint)
+}
+
+// isWriteRequest returns true for requests a write region must serve. Queries are sent with POST but only read data.
+func isWriteRequest(req *policy.Request, o pipelineRequestOptions) bool {
+	if o.isWriteOperation {
+		return true
+	}
+
+	switch req.Raw().Method {
+	case http.MethodGet, http.MethodHead:
+		return false
+	case http.MethodPost:
+		return req.Raw().Header.Get(cosmosHeaderQuery) != "True"
+	}
+
+	return true
+}
+
+// contactedRegion returns the name of the region which served resp, or "" when it isn't known.
+func contactedRegion(resp *http.Response) string {
+	if resp == nil || resp.Request == nil {
+		return ""
+	}
+
+	region, _ := resp.Request.Context().Value(contactedRegionKey{}).(string)
+	return region
+}
@@ -0,0 +1,217 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+const (
+	testAccountHost = "account.documents.azure.com:443"
+	testWestHost    = "account-westus.documents.azure.com:443"
+	testEastHost    = "account-eastus.documents.azure.com:443"
+)
+
+func newTestClientWithRegions(t *testing.T, srv *mock.Server, preferredRegions []string) (*Client, *recordingTransport) {
+	transport := &recordingTransport{srv: srv}
+	cred, _ := NewKeyCredential("dG8gZW5jb2RlIGFuZCBzaWduIHJlcXVlc3Rz")
+	client, err := NewClientWithKey(testAccountEndpoint, cred, &ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+			Transport: transport,
+		},
+		PreferredRegions: preferredRegions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, transport
+}
+
+func TestClientRetryPolicyRoutesToPreferredRegion(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(true, "West US", "East US")))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"db"}`)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"db"}`)), mock.WithStatusCode(http.StatusCreated))
+
+	client, transport := newTestClientWithRegions(t, srv, []string{"East US", "West US"})
+	db, _ := client.NewDatabase("db")
+	resp, err := db.Read(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContactedRegion != "East US" {
+		t.Errorf("expected the read to be served by East US, but got %q", resp.ContactedRegion)
+	}
+
+	// the account accepts writes in every region, so writes go to the preferred region too
+	resp, err = client.CreateDatabase(context.Background(), DatabaseProperties{ID: "db"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContactedRegion != "East US" {
+		t.Errorf("expected the write to be served by East US, but got %q", resp.ContactedRegion)
+	}
+
+	expected := []string{testAccountHost, testEastHost, testEastHost}
+	if !reflect.DeepEqual(transport.hosts, expected) {
+		t.Errorf("expected requests to %v, but got %v", expected, transport.hosts)
+	}
+}
+
+func TestClientRetryPolicyFailoverOnServiceUnavailable(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"db"}`)))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"db"}`)))
+
+	client, transport := newTestClientWithRegions(t, srv, []string{"East US", "West US"})
+	db, _ := client.NewDatabase("db")
+	resp, err := db.Read(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContactedRegion != "West US" {
+		t.Errorf("expected the read to fail over to West US, but got %q", resp.ContactedRegion)
+	}
+
+	// East US stays unavailable for reads, so the next read goes straight to West US
+	if _, err = db.Read(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{testAccountHost, testEastHost, testWestHost, testWestHost}
+	if !reflect.DeepEqual(transport.hosts, expected) {
+		t.Errorf("expected requests to %v, but got %v", expected, transport.hosts)
+	}
+}
+
+func TestClientRetryPolicyFailoverOnNetworkError(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+	srv.AppendError(errors.New("connection reset"))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"db"}`)))
+
+	client, transport := newTestClientWithRegions(t, srv, []string{"East US", "West US"})
+	db, _ := client.NewDatabase("db")
+	resp, err := db.Read(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContactedRegion != "West US" {
+		t.Errorf("expected the read to fail over to West US, but got %q", resp.ContactedRegion)
+	}
+
+	expected := []string{testAccountHost, testEastHost, testWestHost}
+	if !reflect.DeepEqual(transport.hosts, expected) {
+		t.Errorf("expected requests to %v, but got %v", expected, transport.hosts)
+	}
+}
+
+func TestClientRetryPolicyFailoverOnWriteForbidden(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusForbidden), mock.WithHeader(cosmosHeaderSubStatus, subStatusWriteForbidden))
+	// the account's write region changed
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "East US")))
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"db"}`)), mock.WithStatusCode(http.StatusCreated))
+
+	client, transport := newTestClientWithRegions(t, srv, []string{"West US", "East US"})
+	resp, err := client.CreateDatabase(context.Background(), DatabaseProperties{ID: "db"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContactedRegion != "East US" {
+		t.Errorf("expected the write to fail over to East US, but got %q", resp.ContactedRegion)
+	}
+
+	expected := []string{testAccountHost, testWestHost, testAccountHost, testEastHost}
+	if !reflect.DeepEqual(transport.hosts, expected) {
+		t.Errorf("expected requests to %v, but got %v", expected, transport.hosts)
+	}
+}
+
+func TestClientRetryPolicyReturnsLastResponseWhenAllRegionsFail(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+
+	client, transport := newTestClientWithRegions(t, srv, []string{"East US", "West US"})
+	db, _ := client.NewDatabase("db")
+	_, err := db.Read(context.Background(), nil)
+	var responseErr *azcore.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503 error, got %v", err)
+	}
+
+	expected := []string{testAccountHost, testEastHost, testWestHost}
+	if !reflect.DeepEqual(transport.hosts, expected) {
+		t.Errorf("expected requests to %v, but got %v", expected, transport.hosts)
+	}
+}
+
+func TestClientRetryPolicyWithoutEndpointManager(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+
+	pl := azruntime.NewPipeline("azcosmostest", "v1.0.0", azruntime.PipelineOptions{PerRetry: []policy.Policy{&clientRetryPolicy{}}}, &policy.ClientOptions{Transport: srv})
+	client := &Client{endpoint: srv.URL(), pipeline: pl}
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeDatabase,
+		resourceAddress: "",
+	}
+	resp, err := client.sendGetRequest("/", context.Background(), operationContext, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region := newResponse(resp).ContactedRegion; region != "" {
+		t.Errorf("expected no region, got %q", region)
+	}
+}
+
+func TestIsWriteRequest(t *testing.T) {
+	for _, test := range []struct {
+		method   string
+		query    bool
+		o        pipelineRequestOptions
+		expected bool
+	}{
+		{method: http.MethodGet},
+		{method: http.MethodHead},
+		{method: http.MethodPost, query: true},
+		{method: http.MethodPost, expected: true},
+		{method: http.MethodPut, expected: true},
+		{method: http.MethodPatch, expected: true},
+		{method: http.MethodDelete, expected: true},
+		{method: http.MethodGet, o: pipelineRequestOptions{isWriteOperation: true}, expected: true},
+	} {
+		req, err := azruntime.NewRequest(context.Background(), test.method, testAccountEndpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.query {
+			req.Raw().Header.Set(cosmosHeaderQuery, "True")
+		}
+		if actual := isWriteRequest(req, test.o); actual != test.expected {
+			t.Errorf("expected %t for %s (query: %t), but got %t", test.expected, test.method, test.query, actual)
+		}
+	}
+}
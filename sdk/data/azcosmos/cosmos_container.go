@@ -35,6 +35,15 @@ func (c *ContainerClient) ID() string {
 	return c.id
 }
 
+// SessionToken returns the session tokens the client tracks for the partition key ranges of the container.
+// It returns an empty string when the client hasn't made requests to the container.
+func (c *ContainerClient) SessionToken() string {
+	if c.database.client.session == nil {
+		return ""
+	}
+	return c.database.client.session.get(c.link, "")
+}
+
 // Read obtains the information for a Cosmos container.
 // ctx - The context for the request.
 // o - Options for the operation.
@@ -457,6 +466,80 @@ func (c *ContainerClient) NewQueryItemsPager(query string, partitionKey Partitio
 	})
 }
 
+// NewCrossPartitionQueryItemsPager executes a query across all partitions of a Cosmos container.
+// The client requests a query plan from the service, queries each partition key range the query targets,
+// and merges the results to apply ORDER BY, TOP, OFFSET LIMIT, DISTINCT, GROUP BY and aggregates.
+// Queries having aggregates, GROUP BY or DISTINCT without ORDER BY read every result before returning
+// the first page, and can't be resumed with a continuation token.
+// query - The SQL query to execute.
+// o - Options for the operation. PageSizeHint limits the number of items in each page.
+func (c *ContainerClient) NewCrossPartitionQueryItemsPager(query string, o *QueryOptions) *runtime.Pager[QueryItemsResponse] {
+	queryOptions := QueryOptions{}
+	if o != nil {
+		queryOptions = *o
+	}
+
+	executor := newCrossPartitionQueryExecutor(c, query, queryOptions)
+
+	return runtime.NewPager(runtime.PagingHandler[QueryItemsResponse]{
+		More: func(page QueryItemsResponse) bool {
+			return !executor.done
+		},
+		Fetcher: func(ctx context.Context, page *QueryItemsResponse) (QueryItemsResponse, error) {
+			return executor.nextPage(ctx)
+		},
+	})
+}
+
+// NewPrefixPartitionKeyQueryItemsPager executes a query on the items of a prefix partition key in a Cosmos container
+// with hierarchical partition keys. For example, the prefix NewPartitionKey().AppendString("tenant") of a container
+// partitioned by /tenantId, /userId and /sessionId targets the items of every user and session of the tenant.
+// The query targets the partition key ranges storing the prefix's items, and supports the same queries as
+// NewCrossPartitionQueryItemsPager.
+// query - The SQL query to execute.
+// prefix - The values of the first paths of the container's partition key.
+// o - Options for the operation. PageSizeHint limits the number of items in each page.
+func (c *ContainerClient) NewPrefixPartitionKeyQueryItemsPager(query string, prefix PartitionKey, o *QueryOptions) *runtime.Pager[QueryItemsResponse] {
+	queryOptions := QueryOptions{}
+	if o != nil {
+		queryOptions = *o
+	}
+
+	executor := newCrossPartitionQueryExecutor(c, query, queryOptions)
+	executor.prefix = &prefix
+
+	return runtime.NewPager(runtime.PagingHandler[QueryItemsResponse]{
+		More: func(page QueryItemsResponse) bool {
+			return !executor.done
+		},
+		Fetcher: func(ctx context.Context, page *QueryItemsResponse) (QueryItemsResponse, error) {
+			return executor.nextPage(ctx)
+		},
+	})
+}
+
+// NewChangeFeedPager reads the changes made to items in a Cosmos container, in the order of modification
+// within each partition key range. The pager reads the partition key ranges in turn, and stops when none
+// has more changes. To poll for later changes, create a pager from the last page's ContinuationToken.
+// o - Options for the operation.
+func (c *ContainerClient) NewChangeFeedPager(o *ChangeFeedOptions) *runtime.Pager[ChangeFeedResponse] {
+	changeFeedOptions := ChangeFeedOptions{}
+	if o != nil {
+		changeFeedOptions = *o
+	}
+
+	reader := newChangeFeedReader(c, changeFeedOptions)
+
+	return runtime.NewPager(runtime.PagingHandler[ChangeFeedResponse]{
+		More: func(page ChangeFeedResponse) bool {
+			return len(page.Items) > 0
+		},
+		Fetcher: func(ctx context.Context, page *ChangeFeedResponse) (ChangeFeedResponse, error) {
+			return reader.nextPage(ctx)
+		},
+	})
+}
+
 // PatchItem patches an item in a Cosmos container.
 // ctx - The context for the request.
 // partitionKey - The partition key for the item.
@@ -39,6 +39,8 @@ type ContainerProperties struct {
 	UniqueKeyPolicy *UniqueKeyPolicy
 	// ConflictResolutionPolicy contains the conflict resolution policy of the container.
 	ConflictResolutionPolicy *ConflictResolutionPolicy
+	// ClientEncryptionPolicy contains the client encryption policy of the container.
+	ClientEncryptionPolicy *ClientEncryptionPolicy
 }
 
 // MarshalJSON implements the json.Marshaler interface
@@ -110,6 +112,15 @@ func (tp ContainerProperties) MarshalJSON() ([]byte, error) {
 		buffer.Write(conflictPolicy)
 	}
 
+	if tp.ClientEncryptionPolicy != nil {
+		encryptionPolicy, err := json.Marshal(tp.ClientEncryptionPolicy)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteString(",\"clientEncryptionPolicy\":")
+		buffer.Write(encryptionPolicy)
+	}
+
 	buffer.WriteString("}")
 	return buffer.Bytes(), nil
 }
@@ -190,5 +201,11 @@ func (tp *ContainerProperties) UnmarshalJSON(b []byte) error {
 		}
 	}
 
+	if ep, ok := attributes["clientEncryptionPolicy"]; ok {
+		if err := json.Unmarshal(ep, &tp.ClientEncryptionPolicy); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -5,6 +5,7 @@ package azcosmos
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 
@@ -53,6 +54,17 @@ func TestContainerPropertiesSerialization(t *testing.T) {
 			Mode:           ConflictResolutionModeLastWriteWins,
 			ResolutionPath: "/someResolutionPath",
 		},
+		ClientEncryptionPolicy: &ClientEncryptionPolicy{
+			IncludedPaths: []ClientEncryptionIncludedPath{
+				{
+					Path:                  "/someEncryptedPath",
+					ClientEncryptionKeyID: "someKey",
+					EncryptionType:        EncryptionTypeDeterministic,
+					EncryptionAlgorithm:   EncryptionAlgorithmAEADAES256CBCHMACSHA256,
+				},
+			},
+			PolicyFormatVersion: 1,
+		},
 	}
 
 	jsonString, err := json.Marshal(properties)
@@ -153,6 +165,14 @@ func TestContainerPropertiesSerialization(t *testing.T) {
 	if otherProperties.ConflictResolutionPolicy.ResolutionPath != properties.ConflictResolutionPolicy.ResolutionPath {
 		t.Errorf("Expected ConflictResolutionPolicy.ResolutionPath to be %s, but got %s", properties.ConflictResolutionPolicy.ResolutionPath, otherProperties.ConflictResolutionPolicy.ResolutionPath)
 	}
+
+	if otherProperties.ClientEncryptionPolicy == nil {
+		t.Fatal("Expected ClientEncryptionPolicy to be not nil, but got nil")
+	}
+
+	if !reflect.DeepEqual(otherProperties.ClientEncryptionPolicy, properties.ClientEncryptionPolicy) {
+		t.Errorf("Expected ClientEncryptionPolicy to be %v, but got %v", properties.ClientEncryptionPolicy, otherProperties.ClientEncryptionPolicy)
+	}
 }
 
 func TestContainerPropertiesSerializationWithTTL(t *testing.T) {
@@ -0,0 +1,467 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// CreateStoredProcedure creates a stored procedure in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) CreateStoredProcedure(
+	ctx context.Context,
+	properties StoredProcedureProperties,
+	o *ScriptOptions) (StoredProcedureResponse, error) {
+	azResponse, err := c.createScript(ctx, resourceTypeStoredProcedure, properties, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// ReadStoredProcedure reads a stored procedure in the Cosmos container.
+// ctx - The context for the request.
+// id - The id of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) ReadStoredProcedure(
+	ctx context.Context,
+	id string,
+	o *ScriptOptions) (StoredProcedureResponse, error) {
+	azResponse, err := c.readScript(ctx, resourceTypeStoredProcedure, pathSegmentStoredProcedure, id, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// ReplaceStoredProcedure replaces a stored procedure in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the stored procedure. The ID identifies the stored procedure to replace.
+// o - Options for the operation.
+func (c *ContainerClient) ReplaceStoredProcedure(
+	ctx context.Context,
+	properties StoredProcedureProperties,
+	o *ScriptOptions) (StoredProcedureResponse, error) {
+	azResponse, err := c.replaceScript(ctx, resourceTypeStoredProcedure, pathSegmentStoredProcedure, properties.ID, properties, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// DeleteStoredProcedure deletes a stored procedure in the Cosmos container.
+// ctx - The context for the request.
+// id - The id of the stored procedure.
+// o - Options for the operation.
+func (c *ContainerClient) DeleteStoredProcedure(
+	ctx context.Context,
+	id string,
+	o *ScriptOptions) (StoredProcedureResponse, error) {
+	azResponse, err := c.deleteScript(ctx, resourceTypeStoredProcedure, pathSegmentStoredProcedure, id, o)
+	if err != nil {
+		return StoredProcedureResponse{}, err
+	}
+
+	return newStoredProcedureResponse(azResponse)
+}
+
+// NewQueryStoredProceduresPager executes query for stored procedures within the container.
+// query - The SQL query to execute, for example "SELECT * FROM s".
+// o - Options for the operation.
+func (c *ContainerClient) NewQueryStoredProceduresPager(query string, o *QueryStoredProceduresOptions) *runtime.Pager[QueryStoredProceduresResponse] {
+	queryOptions := &QueryStoredProceduresOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	return runtime.NewPager(runtime.PagingHandler[QueryStoredProceduresResponse]{
+		More: func(page QueryStoredProceduresResponse) bool {
+			return page.ContinuationToken != ""
+		},
+		Fetcher: func(ctx context.Context, page *QueryStoredProceduresResponse) (QueryStoredProceduresResponse, error) {
+			if page != nil {
+				if page.ContinuationToken != "" {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := c.queryScripts(ctx, resourceTypeStoredProcedure, query, queryOptions.QueryParameters, queryOptions)
+			if err != nil {
+				return QueryStoredProceduresResponse{}, err
+			}
+
+			return newStoredProceduresQueryResponse(azResponse)
+		},
+	})
+}
+
+// ExecuteStoredProcedure executes a stored procedure in the logical partition of a partition key.
+// ctx - The context for the request.
+// id - The id of the stored procedure.
+// partitionKey - The partition key of the logical partition the stored procedure runs in.
+// parameters - The arguments of the stored procedure's function, marshalled as JSON.
+// o - Options for the operation.
+func (c *ContainerClient) ExecuteStoredProcedure(
+	ctx context.Context,
+	id string,
+	partitionKey PartitionKey,
+	parameters []interface{},
+	o *ExecuteStoredProcedureOptions) (ExecuteStoredProcedureResponse, error) {
+	h := headerOptionsOverride{
+		partitionKey: &partitionKey,
+	}
+
+	if o == nil {
+		o = &ExecuteStoredProcedureOptions{}
+	}
+
+	if parameters == nil {
+		parameters = []interface{}{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:          resourceTypeStoredProcedure,
+		resourceAddress:       createLink(c.link, pathSegmentStoredProcedure, id),
+		isWriteOperation:      true,
+		headerOptionsOverride: &h}
+
+	path, err := generatePathForNameBased(resourceTypeStoredProcedure, operationContext.resourceAddress, false)
+	if err != nil {
+		return ExecuteStoredProcedureResponse{}, err
+	}
+
+	azResponse, err := c.database.client.sendPostRequest(
+		path,
+		ctx,
+		parameters,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return ExecuteStoredProcedureResponse{}, err
+	}
+
+	return newExecuteStoredProcedureResponse(azResponse)
+}
+
+// CreateTrigger creates a trigger in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) CreateTrigger(
+	ctx context.Context,
+	properties TriggerProperties,
+	o *ScriptOptions) (TriggerResponse, error) {
+	azResponse, err := c.createScript(ctx, resourceTypeTrigger, properties, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// ReadTrigger reads a trigger in the Cosmos container.
+// ctx - The context for the request.
+// id - The id of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) ReadTrigger(
+	ctx context.Context,
+	id string,
+	o *ScriptOptions) (TriggerResponse, error) {
+	azResponse, err := c.readScript(ctx, resourceTypeTrigger, pathSegmentTrigger, id, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// ReplaceTrigger replaces a trigger in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the trigger. The ID identifies the trigger to replace.
+// o - Options for the operation.
+func (c *ContainerClient) ReplaceTrigger(
+	ctx context.Context,
+	properties TriggerProperties,
+	o *ScriptOptions) (TriggerResponse, error) {
+	azResponse, err := c.replaceScript(ctx, resourceTypeTrigger, pathSegmentTrigger, properties.ID, properties, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// DeleteTrigger deletes a trigger in the Cosmos container.
+// ctx - The context for the request.
+// id - The id of the trigger.
+// o - Options for the operation.
+func (c *ContainerClient) DeleteTrigger(
+	ctx context.Context,
+	id string,
+	o *ScriptOptions) (TriggerResponse, error) {
+	azResponse, err := c.deleteScript(ctx, resourceTypeTrigger, pathSegmentTrigger, id, o)
+	if err != nil {
+		return TriggerResponse{}, err
+	}
+
+	return newTriggerResponse(azResponse)
+}
+
+// NewQueryTriggersPager executes query for triggers within the container.
+// query - The SQL query to execute, for example "SELECT * FROM t".
+// o - Options for the operation.
+func (c *ContainerClient) NewQueryTriggersPager(query string, o *QueryTriggersOptions) *runtime.Pager[QueryTriggersResponse] {
+	queryOptions := &QueryTriggersOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	return runtime.NewPager(runtime.PagingHandler[QueryTriggersResponse]{
+		More: func(page QueryTriggersResponse) bool {
+			return page.ContinuationToken != ""
+		},
+		Fetcher: func(ctx context.Context, page *QueryTriggersResponse) (QueryTriggersResponse, error) {
+			if page != nil {
+				if page.ContinuationToken != "" {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := c.queryScripts(ctx, resourceTypeTrigger, query, queryOptions.QueryParameters, queryOptions)
+			if err != nil {
+				return QueryTriggersResponse{}, err
+			}
+
+			return newTriggersQueryResponse(azResponse)
+		},
+	})
+}
+
+// CreateUserDefinedFunction creates a user-defined function in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) CreateUserDefinedFunction(
+	ctx context.Context,
+	properties UserDefinedFunctionProperties,
+	o *ScriptOptions) (UserDefinedFunctionResponse, error) {
+	azResponse, err := c.createScript(ctx, resourceTypeUserDefinedFunction, properties, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// ReadUserDefinedFunction reads a user-defined function in the Cosmos container.
+// ctx - The context for the request.
+// id - The id of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) ReadUserDefinedFunction(
+	ctx context.Context,
+	id string,
+	o *ScriptOptions) (UserDefinedFunctionResponse, error) {
+	azResponse, err := c.readScript(ctx, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, id, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// ReplaceUserDefinedFunction replaces a user-defined function in the Cosmos container.
+// ctx - The context for the request.
+// properties - The properties of the user-defined function. The ID identifies the function to replace.
+// o - Options for the operation.
+func (c *ContainerClient) ReplaceUserDefinedFunction(
+	ctx context.Context,
+	properties UserDefinedFunctionProperties,
+	o *ScriptOptions) (UserDefinedFunctionResponse, error) {
+	azResponse, err := c.replaceScript(ctx, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, properties.ID, properties, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// DeleteUserDefinedFunction deletes a user-defined function in the Cosmos container.
+// ctx - The context for the request.
+// id - The id of the user-defined function.
+// o - Options for the operation.
+func (c *ContainerClient) DeleteUserDefinedFunction(
+	ctx context.Context,
+	id string,
+	o *ScriptOptions) (UserDefinedFunctionResponse, error) {
+	azResponse, err := c.deleteScript(ctx, resourceTypeUserDefinedFunction, pathSegmentUserDefinedFunction, id, o)
+	if err != nil {
+		return UserDefinedFunctionResponse{}, err
+	}
+
+	return newUserDefinedFunctionResponse(azResponse)
+}
+
+// NewQueryUserDefinedFunctionsPager executes query for user-defined functions within the container.
+// query - The SQL query to execute, for example "SELECT * FROM f".
+// o - Options for the operation.
+func (c *ContainerClient) NewQueryUserDefinedFunctionsPager(query string, o *QueryUserDefinedFunctionsOptions) *runtime.Pager[QueryUserDefinedFunctionsResponse] {
+	queryOptions := &QueryUserDefinedFunctionsOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	return runtime.NewPager(runtime.PagingHandler[QueryUserDefinedFunctionsResponse]{
+		More: func(page QueryUserDefinedFunctionsResponse) bool {
+			return page.ContinuationToken != ""
+		},
+		Fetcher: func(ctx context.Context, page *QueryUserDefinedFunctionsResponse) (QueryUserDefinedFunctionsResponse, error) {
+			if page != nil {
+				if page.ContinuationToken != "" {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := c.queryScripts(ctx, resourceTypeUserDefinedFunction, query, queryOptions.QueryParameters, queryOptions)
+			if err != nil {
+				return QueryUserDefinedFunctionsResponse{}, err
+			}
+
+			return newUserDefinedFunctionsQueryResponse(azResponse)
+		},
+	})
+}
+
+// createScript creates a stored procedure, trigger or user-defined function in the container.
+func (c *ContainerClient) createScript(ctx context.Context, resourceType resourceType, properties interface{}, o *ScriptOptions) (*http.Response, error) {
+	if o == nil {
+		o = &ScriptOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceType,
+		resourceAddress:  c.link,
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.database.client.sendPostRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+}
+
+func (c *ContainerClient) readScript(ctx context.Context, resourceType resourceType, pathSegment string, id string, o *ScriptOptions) (*http.Response, error) {
+	if o == nil {
+		o = &ScriptOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceType,
+		resourceAddress: createLink(c.link, pathSegment, id),
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.database.client.sendGetRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+}
+
+func (c *ContainerClient) replaceScript(ctx context.Context, resourceType resourceType, pathSegment string, id string, properties interface{}, o *ScriptOptions) (*http.Response, error) {
+	if o == nil {
+		o = &ScriptOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceType,
+		resourceAddress:  createLink(c.link, pathSegment, id),
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.database.client.sendPutRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+}
+
+func (c *ContainerClient) deleteScript(ctx context.Context, resourceType resourceType, pathSegment string, id string, o *ScriptOptions) (*http.Response, error) {
+	if o == nil {
+		o = &ScriptOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceType,
+		resourceAddress:  createLink(c.link, pathSegment, id),
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.database.client.sendDeleteRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+}
+
+func (c *ContainerClient) queryScripts(ctx context.Context, resourceType resourceType, query string, parameters []QueryParameter, o cosmosRequestOptions) (*http.Response, error) {
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceType,
+		resourceAddress: c.link,
+	}
+
+	path, err := generatePathForNameBased(resourceType, operationContext.resourceAddress, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.database.client.sendQueryRequest(
+		path,
+		ctx,
+		query,
+		parameters,
+		operationContext,
+		o,
+		nil)
+}
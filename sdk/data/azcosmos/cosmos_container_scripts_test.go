@@ -0,0 +1,220 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func newTestScriptsContainer(t *testing.T) (*ContainerClient, *mock.Server, *capturingTransport, func()) {
+	return newMockContainer(t, "container", testClientOptions{})
+}
+
+func TestContainerStoredProcedures(t *testing.T) {
+	container, srv, transport, close := newTestScriptsContainer(t)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusCreated),
+		mock.WithBody([]byte(`{"id":"sproc","body":"function () {}","_etag":"\"1\"","_rid":"rid","_ts":1600000000}`)),
+		mock.WithHeader(cosmosHeaderRequestCharge, "4.5"))
+	created, err := container.CreateStoredProcedure(ctx, StoredProcedureProperties{ID: "sproc", Body: "function () {}"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.StoredProcedureProperties.ID != "sproc" || *created.StoredProcedureProperties.ETag != `"1"` || created.RequestCharge != 4.5 {
+		t.Errorf("unexpected response %+v", created.StoredProcedureProperties)
+	}
+	if !created.StoredProcedureProperties.LastModified.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("unexpected LastModified %v", created.StoredProcedureProperties.LastModified)
+	}
+	req := transport.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/dbs/db/colls/container/sprocs" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(transport.bodies[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["id"] != "sproc" || body["body"] != "function () {}" {
+		t.Errorf("unexpected body %s", transport.bodies[0])
+	}
+
+	etag := azcore.ETag(`"1"`)
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"sproc","body":"function () { return 1; }"}`)))
+	if _, err := container.ReplaceStoredProcedure(ctx, StoredProcedureProperties{ID: "sproc", Body: "function () { return 1; }"}, &ScriptOptions{IfMatchEtag: &etag}); err != nil {
+		t.Fatal(err)
+	}
+	req = transport.requests[1]
+	if req.Method != http.MethodPut || req.URL.Path != "/dbs/db/colls/container/sprocs/sproc" || req.Header.Get(headerIfMatch) != `"1"` {
+		t.Errorf("unexpected request %s %s %v", req.Method, req.URL.Path, req.Header)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"sproc","body":"function () { return 1; }"}`)))
+	read, err := container.ReadStoredProcedure(ctx, "sproc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.StoredProcedureProperties.Body != "function () { return 1; }" || transport.requests[2].Method != http.MethodGet {
+		t.Errorf("unexpected response %+v", read.StoredProcedureProperties)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	if _, err := container.DeleteStoredProcedure(ctx, "sproc", nil); err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.requests[3]; req.Method != http.MethodDelete || req.URL.Path != "/dbs/db/colls/container/sprocs/sproc" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNotFound))
+	_, err = container.ReadStoredProcedure(ctx, "sproc", nil)
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestContainerExecuteStoredProcedure(t *testing.T) {
+	container, srv, transport, close := newTestScriptsContainer(t)
+	defer close()
+
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"count":2}`)),
+		mock.WithHeader(cosmosHeaderRequestCharge, "12.5"),
+		mock.WithHeader(cosmosHeaderSessionToken, "0:1"),
+		mock.WithHeader(cosmosHeaderScriptLogResults, url.QueryEscape("counted 2 items; done")))
+	response, err := container.ExecuteStoredProcedure(context.Background(), "count", NewPartitionKeyString("tenant"),
+		[]interface{}{"prefix", 10}, &ExecuteStoredProcedureOptions{EnableScriptLogging: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Value) != `{"count":2}` || response.RequestCharge != 12.5 || response.SessionToken != "0:1" {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if response.ScriptLog != "counted 2 items; done" {
+		t.Errorf("unexpected script log %q", response.ScriptLog)
+	}
+
+	req := transport.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/dbs/db/colls/container/sprocs/count" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	if req.Header.Get(cosmosHeaderPartitionKey) != `["tenant"]` || req.Header.Get(cosmosHeaderScriptEnableLogging) != "true" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+	if string(transport.bodies[0]) != `["prefix",10]` {
+		t.Errorf("unexpected body %s", transport.bodies[0])
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`null`)))
+	if _, err := container.ExecuteStoredProcedure(context.Background(), "count", NewPartitionKeyString("tenant"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(transport.bodies[1]) != `[]` || transport.requests[1].Header.Get(cosmosHeaderScriptEnableLogging) != "" {
+		t.Errorf("unexpected request %s %v", transport.bodies[1], transport.requests[1].Header)
+	}
+}
+
+func TestContainerTriggersAndUserDefinedFunctions(t *testing.T) {
+	container, srv, transport, close := newTestScriptsContainer(t)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusCreated),
+		mock.WithBody([]byte(`{"id":"validate","body":"function () {}","triggerType":"Pre","triggerOperation":"Create"}`)))
+	trigger, err := container.CreateTrigger(ctx, TriggerProperties{ID: "validate", Body: "function () {}", Type: TriggerTypePre, Operation: TriggerOperationCreate}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trigger.TriggerProperties.Type != TriggerTypePre || trigger.TriggerProperties.Operation != TriggerOperationCreate {
+		t.Errorf("unexpected trigger %+v", trigger.TriggerProperties)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(transport.bodies[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["triggerType"] != "Pre" || body["triggerOperation"] != "Create" || transport.requests[0].URL.Path != "/dbs/db/colls/container/triggers" {
+		t.Errorf("unexpected request %s %s", transport.requests[0].URL.Path, transport.bodies[0])
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	if _, err := container.DeleteTrigger(ctx, "validate", nil); err != nil {
+		t.Fatal(err)
+	}
+	if transport.requests[1].URL.Path != "/dbs/db/colls/container/triggers/validate" {
+		t.Errorf("unexpected request %s", transport.requests[1].URL.Path)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"tax","body":"function (income) { return income * 0.2; }"}`)))
+	udf, err := container.ReplaceUserDefinedFunction(ctx, UserDefinedFunctionProperties{ID: "tax", Body: "function (income) { return income * 0.2; }"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if udf.UserDefinedFunctionProperties.ID != "tax" || transport.requests[2].URL.Path != "/dbs/db/colls/container/udfs/tax" {
+		t.Errorf("unexpected response %+v", udf.UserDefinedFunctionProperties)
+	}
+}
+
+func TestContainerQueryScripts(t *testing.T) {
+	container, srv, transport, close := newTestScriptsContainer(t)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"StoredProcedures":[{"id":"a","body":"function () {}"}]}`)),
+		mock.WithHeader(cosmosHeaderContinuationToken, "next"))
+	srv.AppendResponse(mock.WithBody([]byte(`{"StoredProcedures":[{"id":"b","body":"function () {}"}]}`)))
+	ids := []string{}
+	pager := container.NewQueryStoredProceduresPager("SELECT * FROM s", nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sproc := range page.StoredProcedures {
+			ids = append(ids, sproc.ID)
+		}
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("unexpected stored procedures %v", ids)
+	}
+	if req := transport.requests[0]; req.URL.Path != "/dbs/db/colls/container/sprocs" || req.Header.Get(cosmosHeaderQuery) != "True" {
+		t.Errorf("unexpected request %s %v", req.URL.Path, req.Header)
+	}
+	if token := transport.requests[1].Header.Get(cosmosHeaderContinuationToken); token != "next" {
+		t.Errorf("expected the continuation token of the first page, got %q", token)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"Triggers":[{"id":"t","triggerType":"Post","triggerOperation":"All"}]}`)))
+	triggers, err := container.NewQueryTriggersPager("SELECT * FROM t", nil).NextPage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers.Triggers) != 1 || triggers.Triggers[0].Type != TriggerTypePost || transport.requests[2].URL.Path != "/dbs/db/colls/container/triggers" {
+		t.Errorf("unexpected triggers %+v", triggers.Triggers)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"UserDefinedFunctions":[{"id":"f"}]}`)))
+	udfs, err := container.NewQueryUserDefinedFunctionsPager("SELECT * FROM f WHERE f.id = @id", &QueryUserDefinedFunctionsOptions{
+		QueryParameters: []QueryParameter{{Name: "@id", Value: "f"}},
+	}).NextPage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(udfs.UserDefinedFunctions) != 1 || udfs.UserDefinedFunctions[0].ID != "f" || transport.requests[3].URL.Path != "/dbs/db/colls/container/udfs" {
+		t.Errorf("unexpected user-defined functions %+v", udfs.UserDefinedFunctions)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// diagnosticsKey is the context key for the Diagnostics collecting the attempts of requests
+type diagnosticsKey struct{}
+
+// Diagnostics collects the attempts made to send one or more requests to the service, including retries and
+// regional failovers. Each Response contains the Diagnostics of its operation. Pages of cross partition queries
+// and change feeds contain the attempts of every request made for the page. Use WithDiagnostics to collect the
+// attempts of several operations, such as the pages of a query. Diagnostics is safe for concurrent use.
+type Diagnostics struct {
+	mtx      sync.Mutex
+	attempts []RequestAttempt
+	// parent also collects the attempts, when the operation is part of a larger one
+	parent *Diagnostics
+}
+
+// RequestAttempt describes an attempt to send a request to the service.
+type RequestAttempt struct {
+	// StartTime is the time the request was sent.
+	StartTime time.Time
+	// Latency is the time between sending the request and receiving the response headers.
+	Latency time.Duration
+	// Method is the HTTP method of the request.
+	Method string
+	// ResourceAddress is the link of the resource the request addressed, for example dbs/db/colls/container/docs/id.
+	ResourceAddress string
+	// Region is the name of the region the request was sent to. It's empty when the client doesn't know the
+	// account's regions.
+	Region string
+	// StatusCode is the status code of the response. It's 0 when no response was received.
+	StatusCode int
+	// SubStatusCode is the value of the x-ms-substatus header, or 0 when the response has none.
+	SubStatusCode int
+	// RequestCharge is the number of request units the request consumed.
+	RequestCharge float32
+	// ActivityID is the value of the activity id header, which identifies the request to the service.
+	ActivityID string
+	// QueryMetrics contains the metrics of the execution of a query, when the service returns them.
+	QueryMetrics *QueryMetrics
+	// IndexMetrics contains the index utilization of a query, when QueryOptions.PopulateIndexMetrics is true.
+	IndexMetrics *IndexMetrics
+	// Err is the error which prevented receiving a response, for example a network error.
+	Err error
+}
+
+// WithDiagnostics returns a context whose operations add their attempts to the returned Diagnostics,
+// for example to account the request units consumed by all the pages of a query.
+// ctx - The parent context.
+func WithDiagnostics(ctx context.Context) (context.Context, *Diagnostics) {
+	d := &Diagnostics{parent: diagnosticsFromContext(ctx)}
+	return context.WithValue(ctx, diagnosticsKey{}, d), d
+}
+
+func diagnosticsFromContext(ctx context.Context) *Diagnostics {
+	d, _ := ctx.Value(diagnosticsKey{}).(*Diagnostics)
+	return d
+}
+
+// diagnosticsOf returns the Diagnostics of the operation which received resp, or nil when they aren't collected
+func diagnosticsOf(resp *http.Response) *Diagnostics {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	return diagnosticsFromContext(resp.Request.Context())
+}
+
+// Attempts returns the attempts in the order they completed.
+func (d *Diagnostics) Attempts() []RequestAttempt {
+	if d == nil {
+		return nil
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	attempts := make([]RequestAttempt, len(d.attempts))
+	copy(attempts, d.attempts)
+	return attempts
+}
+
+// RequestCharge returns the number of request units consumed by all the attempts.
+func (d *Diagnostics) RequestCharge() float32 {
+	var charge float32
+	for _, attempt := range d.Attempts() {
+		charge += attempt.RequestCharge
+	}
+	return charge
+}
+
+// ContactedRegions returns the names of the regions the attempts were sent to, in the order they were first contacted.
+func (d *Diagnostics) ContactedRegions() []string {
+	regions := []string{}
+	seen := map[string]bool{}
+	for _, attempt := range d.Attempts() {
+		if attempt.Region != "" && !seen[attempt.Region] {
+			seen[attempt.Region] = true
+			regions = append(regions, attempt.Region)
+		}
+	}
+	return regions
+}
+
+// Duration returns the time between sending the first attempt and receiving the response of the last one.
+func (d *Diagnostics) Duration() time.Duration {
+	var start, end time.Time
+	for _, attempt := range d.Attempts() {
+		if start.IsZero() || attempt.StartTime.Before(start) {
+			start = attempt.StartTime
+		}
+		if attemptEnd := attempt.StartTime.Add(attempt.Latency); attemptEnd.After(end) {
+			end = attemptEnd
+		}
+	}
+	return end.Sub(start)
+}
+
+func (d *Diagnostics) record(attempt RequestAttempt) {
+	for ; d != nil; d = d.parent {
+		d.mtx.Lock()
+		d.attempts = append(d.attempts, attempt)
+		d.mtx.Unlock()
+	}
+}
+
+// QueryMetrics contains the metrics of the execution of a query, from the x-ms-documentdb-query-metrics header.
+type QueryMetrics struct {
+	// TotalExecutionTime is the time the service spent executing the query.
+	TotalExecutionTime time.Duration
+	// QueryCompileTime is the time spent compiling the query.
+	QueryCompileTime time.Duration
+	// LogicalPlanBuildTime is the time spent building the logical plan of the query.
+	LogicalPlanBuildTime time.Duration
+	// PhysicalPlanBuildTime is the time spent building the physical plan of the query.
+	PhysicalPlanBuildTime time.Duration
+	// QueryOptimizationTime is the time spent optimizing the query.
+	QueryOptimizationTime time.Duration
+	// VMExecutionTime is the time spent in the query runtime.
+	VMExecutionTime time.Duration
+	// IndexLookupTime is the time spent looking up the index.
+	IndexLookupTime time.Duration
+	// DocumentLoadTime is the time spent loading documents.
+	DocumentLoadTime time.Duration
+	// SystemFunctionExecutionTime is the time spent executing system functions.
+	SystemFunctionExecutionTime time.Duration
+	// UserFunctionExecutionTime is the time spent executing user-defined functions.
+	UserFunctionExecutionTime time.Duration
+	// DocumentWriteTime is the time spent writing the results.
+	DocumentWriteTime time.Duration
+	// RetrievedDocumentCount is the number of documents retrieved from the index.
+	RetrievedDocumentCount int64
+	// RetrievedDocumentSize is the size in bytes of the documents retrieved from the index.
+	RetrievedDocumentSize int64
+	// OutputDocumentCount is the number of documents in the results.
+	OutputDocumentCount int64
+	// OutputDocumentSize is the size in bytes of the documents in the results.
+	OutputDocumentSize int64
+	// IndexHitRatio is the ratio of the retrieved documents which matched the query's filter.
+	IndexHitRatio float64
+}
+
+// parseQueryMetrics parses the semicolon delimited name=value pairs of the query metrics header.
+// Unknown or malformed metrics are ignored.
+func parseQueryMetrics(header string) *QueryMetrics {
+	durations := map[string]func(m *QueryMetrics) *time.Duration{
+		"totalExecutionTimeInMs":         func(m *QueryMetrics) *time.Duration { return &m.TotalExecutionTime },
+		"queryCompileTimeInMs":           func(m *QueryMetrics) *time.Duration { return &m.QueryCompileTime },
+		"queryLogicalPlanBuildTimeInMs":  func(m *QueryMetrics) *time.Duration { return &m.LogicalPlanBuildTime },
+		"queryPhysicalPlanBuildTimeInMs": func(m *QueryMetrics) *time.Duration { return &m.PhysicalPlanBuildTime },
+		"queryOptimizationTimeInMs":      func(m *QueryMetrics) *time.Duration { return &m.QueryOptimizationTime },
+		"VMExecutionTimeInMs":            func(m *QueryMetrics) *time.Duration { return &m.VMExecutionTime },
+		"indexLookupTimeInMs":            func(m *QueryMetrics) *time.Duration { return &m.IndexLookupTime },
+		"documentLoadTimeInMs":           func(m *QueryMetrics) *time.Duration { return &m.DocumentLoadTime },
+		"systemFunctionExecuteTimeInMs":  func(m *QueryMetrics) *time.Duration { return &m.SystemFunctionExecutionTime },
+		"userFunctionExecuteTimeInMs":    func(m *QueryMetrics) *time.Duration { return &m.UserFunctionExecutionTime },
+		"writeOutputTimeInMs":            func(m *QueryMetrics) *time.Duration { return &m.DocumentWriteTime },
+	}
+	counts := map[string]func(m *QueryMetrics) *int64{
+		"retrievedDocumentCount": func(m *QueryMetrics) *int64 { return &m.RetrievedDocumentCount },
+		"retrievedDocumentSize":  func(m *QueryMetrics) *int64 { return &m.RetrievedDocumentSize },
+		"outputDocumentCount":    func(m *QueryMetrics) *int64 { return &m.OutputDocumentCount },
+		"outputDocumentSize":     func(m *QueryMetrics) *int64 { return &m.OutputDocumentSize },
+	}
+
+	metrics := &QueryMetrics{}
+	for _, pair := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			continue
+		}
+		if field, ok := durations[kv[0]]; ok {
+			*field(metrics) = time.Duration(value * float64(time.Millisecond))
+		} else if field, ok := counts[kv[0]]; ok {
+			*field(metrics) = int64(value)
+		} else if kv[0] == "indexUtilizationRatio" {
+			metrics.IndexHitRatio = value
+		}
+	}
+	return metrics
+}
+
+// IndexMetrics describes the indexes a query used, and the indexes which could improve it.
+type IndexMetrics struct {
+	// UtilizedSingleIndexes are the single indexes the query used.
+	UtilizedSingleIndexes []SingleIndexMetrics `json:"UtilizedSingleIndexes"`
+	// PotentialSingleIndexes are single indexes which could improve the query.
+	PotentialSingleIndexes []SingleIndexMetrics `json:"PotentialSingleIndexes"`
+	// UtilizedCompositeIndexes are the composite indexes the query used.
+	UtilizedCompositeIndexes []CompositeIndexMetrics `json:"UtilizedCompositeIndexes"`
+	// PotentialCompositeIndexes are composite indexes which could improve the query.
+	PotentialCompositeIndexes []CompositeIndexMetrics `json:"PotentialCompositeIndexes"`
+}
+
+// SingleIndexMetrics describes a single index of IndexMetrics.
+type SingleIndexMetrics struct {
+	// FilterExpression is the filter of the query which can use the index.
+	FilterExpression string `json:"FilterExpression"`
+	// IndexSpec is the path of the index.
+	IndexSpec string `json:"IndexSpec"`
+	// FilterPreciseSet tells whether the filter is served precisely by the index.
+	FilterPreciseSet bool `json:"FilterPreciseSet"`
+	// IndexPreciseSet tells whether the index is precise.
+	IndexPreciseSet bool `json:"IndexPreciseSet"`
+	// IndexImpactScore is the impact of the index on the query, such as High or Low.
+	IndexImpactScore string `json:"IndexImpactScore"`
+}
+
+// CompositeIndexMetrics describes a composite index of IndexMetrics.
+type CompositeIndexMetrics struct {
+	// IndexSpecs are the paths and orders of the index.
+	IndexSpecs []string `json:"IndexSpecs"`
+	// IndexPreciseSet tells whether the index is precise.
+	IndexPreciseSet bool `json:"IndexPreciseSet"`
+	// IndexImpactScore is the impact of the index on the query, such as High or Low.
+	IndexImpactScore string `json:"IndexImpactScore"`
+}
+
+// parseIndexMetrics decodes the base64 encoded JSON of the index utilization header.
+// It returns nil when the header is malformed.
+func parseIndexMetrics(header string) *IndexMetrics {
+	b, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return nil
+	}
+	metrics := &IndexMetrics{}
+	if err := json.Unmarshal(b, metrics); err != nil {
+		return nil
+	}
+	return metrics
+}
+
+// diagnosticsPolicy collects the attempts of each operation in a Diagnostics, and passes them to
+// the client's handler once the operation completes.
+type diagnosticsPolicy struct {
+	handler func(*Diagnostics)
+}
+
+func (p *diagnosticsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	ctx, d := WithDiagnostics(req.Raw().Context())
+	resp, err := req.Clone(ctx).Next()
+	if p.handler != nil {
+		p.handler(d)
+	}
+	return resp, err
+}
+
+// attemptDiagnosticsPolicy records each attempt to send a request in the operation's Diagnostics.
+type attemptDiagnosticsPolicy struct{}
+
+func (p *attemptDiagnosticsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	d := diagnosticsFromContext(req.Raw().Context())
+	if d == nil {
+		return req.Next()
+	}
+
+	start := time.Now()
+	resp, err := req.Next()
+	attempt := RequestAttempt{
+		StartTime: start,
+		Latency:   time.Since(start),
+		Method:    req.Raw().Method,
+		Err:       err,
+	}
+	attempt.Region, _ = req.Raw().Context().Value(contactedRegionKey{}).(string)
+	o := pipelineRequestOptions{}
+	if req.OperationValue(&o) {
+		attempt.ResourceAddress = o.resourceAddress
+	}
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+		attempt.SubStatusCode, _ = strconv.Atoi(resp.Header.Get(cosmosHeaderSubStatus))
+		attempt.RequestCharge = newResponse(resp).RequestCharge
+		attempt.ActivityID = resp.Header.Get(cosmosHeaderActivityId)
+		if header := resp.Header.Get(cosmosHeaderQueryMetrics); header != "" {
+			attempt.QueryMetrics = parseQueryMetrics(header)
+		}
+		if header := resp.Header.Get(cosmosHeaderIndexUtilization); header != "" {
+			attempt.IndexMetrics = parseIndexMetrics(header)
+		}
+	}
+	d.record(attempt)
+	return resp, err
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func newTestDiagnosticsClient(t *testing.T, srv *mock.Server, handler func(*Diagnostics)) *Client {
+	cred, err := NewKeyCredential("00000000000000000000000000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := newPipeline(newSharedKeyCredPolicy(cred), &clientRetryPolicy{}, newSessionContainer(), newPartitionKeyRangeCache(), nil, &ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Transport: srv,
+			Retry:     policy.RetryOptions{RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond},
+		},
+		DiagnosticsHandler: handler,
+	})
+	return &Client{endpoint: srv.URL(), pipeline: pl}
+}
+
+func TestDiagnosticsAttempts(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+
+	var mtx sync.Mutex
+	handled := []*Diagnostics{}
+	client := newTestDiagnosticsClient(t, srv, func(d *Diagnostics) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		handled = append(handled, d)
+	})
+	container, _ := client.NewContainer("db", "c")
+
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusTooManyRequests),
+		mock.WithHeader(cosmosHeaderSubStatus, "3200"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "0.5"),
+		mock.WithHeader(cosmosHeaderActivityId, "first"))
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusOK),
+		mock.WithBody([]byte(`{"id":"1"}`)),
+		mock.WithHeader(cosmosHeaderRequestCharge, "1"),
+		mock.WithHeader(cosmosHeaderActivityId, "second"))
+
+	response, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := response.Diagnostics.Attempts()
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(attempts))
+	}
+	if attempts[0].StatusCode != http.StatusTooManyRequests || attempts[0].SubStatusCode != 3200 || attempts[0].ActivityID != "first" {
+		t.Errorf("unexpected first attempt %+v", attempts[0])
+	}
+	if attempts[1].StatusCode != http.StatusOK || attempts[1].SubStatusCode != 0 || attempts[1].ActivityID != "second" {
+		t.Errorf("unexpected second attempt %+v", attempts[1])
+	}
+	if attempts[1].Method != http.MethodGet || attempts[1].ResourceAddress != "dbs/db/colls/c/docs/1" {
+		t.Errorf("unexpected request %s %s", attempts[1].Method, attempts[1].ResourceAddress)
+	}
+	if charge := response.Diagnostics.RequestCharge(); charge != 1.5 {
+		t.Errorf("expected a request charge of 1.5, got %v", charge)
+	}
+	if response.RequestCharge != 1 {
+		t.Errorf("expected the response's request charge to be 1, got %v", response.RequestCharge)
+	}
+	if response.Diagnostics.Duration() < attempts[0].Latency+attempts[1].Latency {
+		t.Errorf("unexpected duration %v", response.Diagnostics.Duration())
+	}
+	if regions := response.Diagnostics.ContactedRegions(); len(regions) != 0 {
+		t.Errorf("unexpected regions %v", regions)
+	}
+
+	if len(handled) != 1 || handled[0] != response.Diagnostics {
+		t.Fatalf("expected the handler to receive the response's diagnostics once, got %d calls", len(handled))
+	}
+}
+
+func TestWithDiagnostics(t *testing.T) {
+	srv, close := mock.NewTLSServer()
+	defer close()
+	client := newTestDiagnosticsClient(t, srv, nil)
+	container, _ := client.NewContainer("db", "c")
+
+	metrics := "totalExecutionTimeInMs=33.67;queryCompileTimeInMs=0.06;retrievedDocumentCount=2000;outputDocumentCount=20;indexUtilizationRatio=0.50"
+	for i := 0; i < 2; i++ {
+		srv.AppendResponse(
+			mock.WithStatusCode(http.StatusOK),
+			mock.WithBody([]byte(`{"Documents":[{"id":"1"}]}`)),
+			mock.WithHeader(cosmosHeaderRequestCharge, "2.5"),
+			mock.WithHeader(cosmosHeaderQueryMetrics, metrics),
+			mock.WithHeader(cosmosHeaderContinuationToken, []string{"next", ""}[i]))
+	}
+
+	ctx, diagnostics := WithDiagnostics(context.Background())
+	pager := container.NewQueryItemsPager("SELECT * FROM c", NewPartitionKeyString("1"), nil)
+	pages := 0
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if attempts := page.Diagnostics.Attempts(); len(attempts) != 1 || attempts[0].QueryMetrics == nil {
+			t.Fatalf("expected an attempt with query metrics, got %+v", attempts)
+		}
+	}
+	if pages != 2 {
+		t.Fatalf("expected 2 pages, got %d", pages)
+	}
+
+	attempts := diagnostics.Attempts()
+	if len(attempts) != 2 || diagnostics.RequestCharge() != 5 {
+		t.Fatalf("expected 2 attempts consuming 5 RUs, got %d consuming %v", len(attempts), diagnostics.RequestCharge())
+	}
+	if m := attempts[1].QueryMetrics; m.TotalExecutionTime != 33670*time.Microsecond || m.RetrievedDocumentCount != 2000 || m.OutputDocumentCount != 20 || m.IndexHitRatio != 0.5 {
+		t.Errorf("unexpected query metrics %+v", m)
+	}
+}
+
+func TestParseQueryMetrics(t *testing.T) {
+	header := "totalExecutionTimeInMs=33.67;queryCompileTimeInMs=0.06;queryLogicalPlanBuildTimeInMs=0.02;queryPhysicalPlanBuildTimeInMs=0.10;" +
+		"queryOptimizationTimeInMs=0.00;VMExecutionTimeInMs=32.56;indexLookupTimeInMs=0.99;documentLoadTimeInMs=9.58;systemFunctionExecuteTimeInMs=0.00;" +
+		"userFunctionExecuteTimeInMs=0.00;retrievedDocumentCount=2000;retrievedDocumentSize=1125600;outputDocumentCount=2000;outputDocumentSize=1125600;" +
+		"writeOutputTimeInMs=18.10;indexUtilizationRatio=1.00;unknownMetric=1;malformed;documentLoadTimeInMs=x"
+	m := parseQueryMetrics(header)
+	expected := QueryMetrics{
+		TotalExecutionTime:     33670 * time.Microsecond,
+		QueryCompileTime:       60 * time.Microsecond,
+		LogicalPlanBuildTime:   20 * time.Microsecond,
+		PhysicalPlanBuildTime:  100 * time.Microsecond,
+		VMExecutionTime:        32560 * time.Microsecond,
+		IndexLookupTime:        990 * time.Microsecond,
+		DocumentLoadTime:       9580 * time.Microsecond,
+		DocumentWriteTime:      18100 * time.Microsecond,
+		RetrievedDocumentCount: 2000,
+		RetrievedDocumentSize:  1125600,
+		OutputDocumentCount:    2000,
+		OutputDocumentSize:     1125600,
+		IndexHitRatio:          1,
+	}
+	// durations are parsed from floating point milliseconds
+	round := func(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+	for _, d := range []*time.Duration{&m.TotalExecutionTime, &m.QueryCompileTime, &m.LogicalPlanBuildTime, &m.PhysicalPlanBuildTime,
+		&m.VMExecutionTime, &m.IndexLookupTime, &m.DocumentLoadTime, &m.DocumentWriteTime} {
+		*d = round(*d)
+	}
+	if *m != expected {
+		t.Errorf("unexpected metrics %+v", *m)
+	}
+}
+
+func TestParseIndexMetrics(t *testing.T) {
+	header := base64.StdEncoding.EncodeToString([]byte(`{"UtilizedSingleIndexes":[{"FilterExpression":"","IndexSpec":"/name/?","FilterPreciseSet":true,"IndexPreciseSet":true,"IndexImpactScore":"High"}],` +
+		`"PotentialSingleIndexes":[],"UtilizedCompositeIndexes":[],"PotentialCompositeIndexes":[{"IndexSpecs":["/name ASC","/age ASC"],"IndexPreciseSet":false,"IndexImpactScore":"Low"}]}`))
+	m := parseIndexMetrics(header)
+	if m == nil {
+		t.Fatal("expected index metrics")
+	}
+	if len(m.UtilizedSingleIndexes) != 1 || m.UtilizedSingleIndexes[0].IndexSpec != "/name/?" || m.UtilizedSingleIndexes[0].IndexImpactScore != "High" {
+		t.Errorf("unexpected utilized indexes %+v", m.UtilizedSingleIndexes)
+	}
+	if len(m.PotentialCompositeIndexes) != 1 || len(m.PotentialCompositeIndexes[0].IndexSpecs) != 2 {
+		t.Errorf("unexpected potential indexes %+v", m.PotentialCompositeIndexes)
+	}
+	if parseIndexMetrics("not base64!") != nil {
+		t.Error("expected malformed metrics to be ignored")
+	}
+}
+
+func TestDiagnosticsNil(t *testing.T) {
+	var d *Diagnostics
+	if d.Attempts() != nil || d.RequestCharge() != 0 || len(d.ContactedRegions()) != 0 || d.Duration() != 0 {
+		t.Error("expected nil diagnostics to be empty")
+	}
+}
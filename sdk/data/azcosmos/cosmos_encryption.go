@@ -0,0 +1,574 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+)
+
+// the type markers prefixing encrypted values, which identify the JSON type of the plaintext
+const (
+	encryptionTypeMarkerNull    byte = 1
+	encryptionTypeMarkerString  byte = 2
+	encryptionTypeMarkerDouble  byte = 3
+	encryptionTypeMarkerLong    byte = 4
+	encryptionTypeMarkerBoolean byte = 5
+)
+
+// encryptedProperty describes the encryption of a top level property
+type encryptedProperty struct {
+	keyID         string
+	deterministic bool
+	key           *aeadKey
+}
+
+// containerEncryptionSettings holds the encrypted properties of a container, by property name.
+// It's empty for containers without a client encryption policy.
+type containerEncryptionSettings struct {
+	properties map[string]*encryptedProperty
+}
+
+// encryptionCache caches the encryption settings of containers, by container link. It is goroutine-safe.
+type encryptionCache struct {
+	mtx     sync.Mutex
+	entries map[string]*encryptionCacheEntry
+}
+
+type encryptionCacheEntry struct {
+	// readMtx serializes reads of the container's settings, so concurrent requests read them once
+	readMtx  sync.Mutex
+	settings *containerEncryptionSettings
+}
+
+func newEncryptionCache() *encryptionCache {
+	return &encryptionCache{entries: map[string]*encryptionCacheEntry{}}
+}
+
+func (c *encryptionCache) entry(containerLink string) *encryptionCacheEntry {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.entries[containerLink]
+	if !ok {
+		e = &encryptionCacheEntry{}
+		c.entries[containerLink] = e
+	}
+	return e
+}
+
+// getSettings returns the cached encryption settings of a container, reading them when they aren't cached.
+func (c *encryptionCache) getSettings(
+	ctx context.Context,
+	containerLink string,
+	read func(context.Context) (*containerEncryptionSettings, error)) (*containerEncryptionSettings, error) {
+	e := c.entry(containerLink)
+	e.readMtx.Lock()
+	defer e.readMtx.Unlock()
+	if e.settings != nil {
+		return e.settings, nil
+	}
+	settings, err := read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.settings = settings
+	return settings, nil
+}
+
+// remove removes the cached settings of a container, which was replaced or deleted
+func (c *encryptionCache) remove(containerLink string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.entries, containerLink)
+}
+
+// encryptionPolicy encrypts the properties of items named by their container's client encryption policy
+// in requests, and decrypts them in responses.
+type encryptionPolicy struct {
+	// client reads the encryption settings of containers. It's set once the client is created.
+	client   *Client
+	provider KeyWrapProvider
+	cache    *encryptionCache
+}
+
+func newEncryptionPolicy(provider KeyWrapProvider) *encryptionPolicy {
+	return &encryptionPolicy{provider: provider, cache: newEncryptionCache()}
+}
+
+func (p *encryptionPolicy) Do(req *policy.Request) (*http.Response, error) {
+	o := pipelineRequestOptions{}
+	if !req.OperationValue(&o) {
+		return req.Next()
+	}
+	containerLink := containerLinkOf(o.resourceAddress)
+	if containerLink == "" {
+		return req.Next()
+	}
+	if o.resourceType == resourceTypeCollection {
+		resp, err := req.Next()
+		if err == nil && (req.Raw().Method == http.MethodPut || req.Raw().Method == http.MethodDelete) && resp.StatusCode < 300 {
+			p.cache.remove(containerLink)
+		}
+		return resp, err
+	}
+	if o.resourceType != resourceTypeDocument || req.Raw().Header.Get(cosmosHeaderIsQueryPlanRequest) != "" {
+		return req.Next()
+	}
+
+	settings, err := p.settings(req.Raw().Context(), containerLink)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings.properties) == 0 {
+		return req.Next()
+	}
+
+	if err := settings.encryptRequest(req); err != nil {
+		return nil, err
+	}
+	resp, err := req.Next()
+	if err != nil || resp.StatusCode >= 300 || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	// queries and feeds return pages of items
+	isFeed := req.Raw().Header.Get(cosmosHeaderQuery) != "" ||
+		(req.Raw().Method == http.MethodGet && strings.Trim(o.resourceAddress, "/") == containerLink)
+	if err := settings.decryptResponse(req, resp, isFeed); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// settings returns the encryption settings of a container, reading its client encryption policy and
+// unwrapping its client encryption keys when they aren't cached.
+func (p *encryptionPolicy) settings(ctx context.Context, containerLink string) (*containerEncryptionSettings, error) {
+	return p.cache.getSettings(ctx, containerLink, func(ctx context.Context) (*containerEncryptionSettings, error) {
+		segments := strings.Split(containerLink, "/")
+		database, err := p.client.NewDatabase(segments[1])
+		if err != nil {
+			return nil, err
+		}
+		container, err := database.NewContainer(segments[3])
+		if err != nil {
+			return nil, err
+		}
+		response, err := container.Read(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return p.readSettings(ctx, database, response.ContainerProperties)
+	})
+}
+
+func (p *encryptionPolicy) readSettings(ctx context.Context, database *DatabaseClient, properties *ContainerProperties) (*containerEncryptionSettings, error) {
+	settings := &containerEncryptionSettings{properties: map[string]*encryptedProperty{}}
+	if properties == nil || properties.ClientEncryptionPolicy == nil {
+		return settings, nil
+	}
+
+	partitionKeyPaths := map[string]bool{}
+	for _, path := range properties.PartitionKeyDefinition.Paths {
+		partitionKeyPaths[path] = true
+	}
+	keys := map[string]*aeadKey{}
+	for _, included := range properties.ClientEncryptionPolicy.IncludedPaths {
+		name := strings.TrimPrefix(included.Path, "/")
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("client encryption of path %s isn't supported, only top level properties can be encrypted", included.Path)
+		}
+		if name == "id" || partitionKeyPaths[included.Path] {
+			return nil, fmt.Errorf("client encryption of the id or partition key path %s isn't supported", included.Path)
+		}
+		if included.EncryptionAlgorithm != EncryptionAlgorithmAEADAES256CBCHMACSHA256 {
+			return nil, fmt.Errorf("unsupported encryption algorithm %q of path %s", included.EncryptionAlgorithm, included.Path)
+		}
+		if included.EncryptionType != EncryptionTypeDeterministic && included.EncryptionType != EncryptionTypeRandomized {
+			return nil, fmt.Errorf("unsupported encryption type %q of path %s", included.EncryptionType, included.Path)
+		}
+
+		key, ok := keys[included.ClientEncryptionKeyID]
+		if !ok {
+			response, err := database.ReadClientEncryptionKey(ctx, included.ClientEncryptionKeyID, nil)
+			if err != nil {
+				return nil, err
+			}
+			cek := response.ClientEncryptionKeyProperties
+			dataEncryptionKey, err := p.provider.UnwrapKey(ctx, cek.KeyWrapMetadata, cek.WrappedDataEncryptionKey)
+			if err != nil {
+				return nil, fmt.Errorf("unwrapping client encryption key %s: %w", included.ClientEncryptionKeyID, err)
+			}
+			if key, err = newAEADKey(dataEncryptionKey); err != nil {
+				return nil, err
+			}
+			keys[included.ClientEncryptionKeyID] = key
+		}
+		settings.properties[name] = &encryptedProperty{
+			keyID:         included.ClientEncryptionKeyID,
+			deterministic: included.EncryptionType == EncryptionTypeDeterministic,
+			key:           key,
+		}
+	}
+	return settings, nil
+}
+
+// encryptRequest encrypts the items written by a request. Queries are sent as they are, their parameters
+// are encrypted with ContainerClient.EncryptQueryParameter.
+func (s *containerEncryptionSettings) encryptRequest(req *policy.Request) error {
+	raw := req.Raw()
+	if raw.Header.Get(cosmosHeaderQuery) != "" || req.Body() == nil {
+		return nil
+	}
+	var encrypt func([]byte) ([]byte, error)
+	switch {
+	case raw.Header.Get(cosmosHeaderIsBatchRequest) != "":
+		encrypt = s.encryptBatch
+	case raw.Method == http.MethodPatch:
+		encrypt = s.encryptPatch
+	case raw.Method == http.MethodPost || raw.Method == http.MethodPut:
+		encrypt = s.encryptItem
+	default:
+		return nil
+	}
+
+	body, err := io.ReadAll(req.Body())
+	if err != nil {
+		return err
+	}
+	encrypted, err := encrypt(body)
+	if err != nil {
+		return err
+	}
+	return req.SetBody(streaming.NopCloser(bytes.NewReader(encrypted)), raw.Header.Get("Content-Type"))
+}
+
+// decryptResponse decrypts the items of a response: an item, the items of a query or change feed page,
+// or the items of a batch's results.
+func (s *containerEncryptionSettings) decryptResponse(req *policy.Request, resp *http.Response, isFeed bool) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	decrypted := body
+	if len(body) > 0 {
+		switch {
+		case req.Raw().Header.Get(cosmosHeaderIsBatchRequest) != "":
+			decrypted, err = s.decryptBatch(body)
+		case isFeed:
+			decrypted, err = s.decryptFeed(body)
+		default:
+			decrypted, err = s.decryptItem(body)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(decrypted))
+	resp.ContentLength = int64(len(decrypted))
+	return nil
+}
+
+func (s *containerEncryptionSettings) encryptItem(body []byte) ([]byte, error) {
+	var item map[string]any
+	if err := unmarshalJSONNumbers(body, &item); err != nil {
+		return nil, err
+	}
+	if err := s.transformItem(item, true); err != nil {
+		return nil, err
+	}
+	return json.Marshal(item)
+}
+
+func (s *containerEncryptionSettings) encryptBatch(body []byte) ([]byte, error) {
+	var operations []map[string]any
+	if err := unmarshalJSONNumbers(body, &operations); err != nil {
+		return nil, err
+	}
+	for _, operation := range operations {
+		resourceBody, ok := operation["resourceBody"].(map[string]any)
+		if !ok {
+			continue
+		}
+		var err error
+		if operation["operationType"] == "Patch" {
+			err = s.encryptPatchOperations(resourceBody)
+		} else {
+			err = s.transformItem(resourceBody, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(operations)
+}
+
+func (s *containerEncryptionSettings) encryptPatch(body []byte) ([]byte, error) {
+	var patch map[string]any
+	if err := unmarshalJSONNumbers(body, &patch); err != nil {
+		return nil, err
+	}
+	if err := s.encryptPatchOperations(patch); err != nil {
+		return nil, err
+	}
+	return json.Marshal(patch)
+}
+
+// encryptPatchOperations encrypts the values patch operations write to encrypted properties
+func (s *containerEncryptionSettings) encryptPatchOperations(patch map[string]any) error {
+	if _, ok := patch["condition"]; ok {
+		// a condition could compare encrypted properties to plaintext values
+		return errors.New("conditional patches aren't supported on containers with a client encryption policy")
+	}
+	operations, _ := patch["operations"].([]any)
+	for _, o := range operations {
+		operation, ok := o.(map[string]any)
+		if !ok {
+			continue
+		}
+		path, _ := operation["path"].(string)
+		property, ok := s.properties[strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]]
+		if !ok {
+			continue
+		}
+		if operation["op"] == string(patchOperationTypeIncrement) {
+			return fmt.Errorf("incrementing the encrypted property %s isn't supported", path)
+		}
+		value, ok := operation["value"]
+		if !ok {
+			continue
+		}
+		encrypted, err := property.transform(value, true)
+		if err != nil {
+			return err
+		}
+		operation["value"] = encrypted
+	}
+	return nil
+}
+
+func (s *containerEncryptionSettings) decryptItem(body []byte) ([]byte, error) {
+	var item map[string]any
+	if err := unmarshalJSONNumbers(body, &item); err != nil {
+		return nil, err
+	}
+	if err := s.transformItem(item, false); err != nil {
+		return nil, err
+	}
+	return json.Marshal(item)
+}
+
+func (s *containerEncryptionSettings) decryptFeed(body []byte) ([]byte, error) {
+	var page map[string]any
+	if err := unmarshalJSONNumbers(body, &page); err != nil {
+		return nil, err
+	}
+	documents, _ := page["Documents"].([]any)
+	for _, d := range documents {
+		document, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		// the items of ORDER BY queries are wrapped in their payload
+		if payload, ok := document["payload"].(map[string]any); ok {
+			if _, ok := document["orderByItems"]; ok {
+				document = payload
+			}
+		}
+		if err := s.transformItem(document, false); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(page)
+}
+
+func (s *containerEncryptionSettings) decryptBatch(body []byte) ([]byte, error) {
+	var results []map[string]any
+	if err := unmarshalJSONNumbers(body, &results); err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if resourceBody, ok := result["resourceBody"].(map[string]any); ok {
+			if err := s.transformItem(resourceBody, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(results)
+}
+
+// transformItem encrypts or decrypts the encrypted properties of an item
+func (s *containerEncryptionSettings) transformItem(item map[string]any, encrypt bool) error {
+	for name, property := range s.properties {
+		value, ok := item[name]
+		if !ok {
+			continue
+		}
+		transformed, err := property.transform(value, encrypt)
+		if err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+		item[name] = transformed
+	}
+	return nil
+}
+
+// transform encrypts or decrypts a value. The values of objects and arrays are transformed individually,
+// and nulls aren't encrypted.
+func (p *encryptedProperty) transform(value any, encrypt bool) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		for name, child := range v {
+			transformed, err := p.transform(child, encrypt)
+			if err != nil {
+				return nil, err
+			}
+			v[name] = transformed
+		}
+		return v, nil
+	case []any:
+		for i, child := range v {
+			transformed, err := p.transform(child, encrypt)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = transformed
+		}
+		return v, nil
+	}
+	if encrypt {
+		return p.encryptValue(value)
+	}
+	return p.decryptValue(value)
+}
+
+// encryptValue returns the base64 encoding of the type marker and the ciphertext of a string, number or boolean.
+// Strings are encoded as UTF-8, integers as 64-bit little endian integers, other numbers as 64-bit little endian
+// IEEE 754 numbers and booleans as a byte.
+func (p *encryptedProperty) encryptValue(value any) (string, error) {
+	var marker byte
+	var plaintext []byte
+	switch v := value.(type) {
+	case string:
+		marker, plaintext = encryptionTypeMarkerString, []byte(v)
+	case bool:
+		marker, plaintext = encryptionTypeMarkerBoolean, []byte{0}
+		if v {
+			plaintext[0] = 1
+		}
+	case json.Number:
+		plaintext = make([]byte, 8)
+		if i, err := v.Int64(); err == nil {
+			marker = encryptionTypeMarkerLong
+			binary.LittleEndian.PutUint64(plaintext, uint64(i))
+		} else {
+			f, err := v.Float64()
+			if err != nil {
+				return "", err
+			}
+			marker = encryptionTypeMarkerDouble
+			binary.LittleEndian.PutUint64(plaintext, math.Float64bits(f))
+		}
+	default:
+		return "", fmt.Errorf("values of type %T can't be encrypted", value)
+	}
+	ciphertext, err := p.key.encrypt(plaintext, p.deterministic)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(append([]byte{marker}, ciphertext...)), nil
+}
+
+func (p *encryptedProperty) decryptValue(value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected an encrypted value, got %T", value)
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(encrypted) == 0 {
+		return nil, errors.New("expected an encrypted value")
+	}
+	plaintext, err := p.key.decrypt(encrypted[1:])
+	if err != nil {
+		return nil, err
+	}
+	switch encrypted[0] {
+	case encryptionTypeMarkerString:
+		return string(plaintext), nil
+	case encryptionTypeMarkerBoolean:
+		if len(plaintext) != 1 {
+			return nil, errors.New("invalid encrypted boolean")
+		}
+		return plaintext[0] != 0, nil
+	case encryptionTypeMarkerLong:
+		if len(plaintext) != 8 {
+			return nil, errors.New("invalid encrypted integer")
+		}
+		return int64(binary.LittleEndian.Uint64(plaintext)), nil
+	case encryptionTypeMarkerDouble:
+		if len(plaintext) != 8 {
+			return nil, errors.New("invalid encrypted number")
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(plaintext)), nil
+	case encryptionTypeMarkerNull:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown type marker %d of an encrypted value", encrypted[0])
+	}
+}
+
+// unmarshalJSONNumbers unmarshals JSON keeping numbers as json.Number, so integers are encrypted as integers
+func unmarshalJSONNumbers(b []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// EncryptQueryParameter encrypts the value of a query parameter compared to a property encrypted with
+// deterministic encryption, for example the @ssn parameter of SELECT * FROM c WHERE c.ssn = @ssn.
+// The client must have a ClientOptions.KeyWrapProvider.
+// ctx - The context for the request.
+// path - The path of the encrypted property, for example /ssn.
+// value - The value of the parameter, a string, number or boolean.
+func (c *ContainerClient) EncryptQueryParameter(ctx context.Context, path string, value any) (string, error) {
+	encryption := c.database.client.encryption
+	if encryption == nil {
+		return "", errors.New("encrypting query parameters requires a client with a KeyWrapProvider")
+	}
+	settings, err := encryption.settings(ctx, c.link)
+	if err != nil {
+		return "", err
+	}
+	property, ok := settings.properties[strings.TrimPrefix(path, "/")]
+	if !ok {
+		return "", fmt.Errorf("the client encryption policy of container %s doesn't include path %s", c.id, path)
+	}
+	if !property.deterministic {
+		return "", fmt.Errorf("path %s uses randomized encryption, which doesn't allow queries", path)
+	}
+	// numbers are encrypted like the numbers of items
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	var v any
+	if err := unmarshalJSONNumbers(b, &v); err != nil {
+		return "", err
+	}
+	return property.encryptValue(v)
+}
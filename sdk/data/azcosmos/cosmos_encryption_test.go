@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// encryptionTestClientOptions configures a client to encrypt items with keys wrapped by provider
+func encryptionTestClientOptions(provider KeyWrapProvider) testClientOptions {
+	encryption := newEncryptionPolicy(provider)
+	return testClientOptions{
+		perCall: []policy.Policy{encryption},
+		configure: func(c *Client) {
+			c.encryption, c.keyWrapProvider = encryption, provider
+			encryption.client = c
+		},
+	}
+}
+
+func newTestEncryptionContainer(t *testing.T) (*ContainerClient, *mock.Server, *capturingTransport, func()) {
+	provider, err := NewLocalKeyWrapProvider(map[string][]byte{"kek": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, srv, transport, close := newMockContainer(t, "container", encryptionTestClientOptions(provider))
+
+	// the container's policy and client encryption key, which are read by the first item request
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"container","partitionKey":{"paths":["/pk"],"kind":"Hash"},"clientEncryptionPolicy":{"includedPaths":[` +
+		`{"path":"/ssn","clientEncryptionKeyId":"cek","encryptionType":"Deterministic","encryptionAlgorithm":"AEAD_AES_256_CBC_HMAC_SHA256"},` +
+		`{"path":"/details","clientEncryptionKeyId":"cek","encryptionType":"Randomized","encryptionAlgorithm":"AEAD_AES_256_CBC_HMAC_SHA256"}],"policyFormatVersion":1}}`)))
+	metadata := NewLocalKeyWrapMetadata("kek")
+	wrappedKey, err := provider.WrapKey(context.Background(), metadata, bytes.Repeat([]byte{2}, aeadKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cek, err := json.Marshal(ClientEncryptionKeyProperties{
+		ID:                       "cek",
+		EncryptionAlgorithm:      EncryptionAlgorithmAEADAES256CBCHMACSHA256,
+		WrappedDataEncryptionKey: wrappedKey,
+		KeyWrapMetadata:          metadata,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AppendResponse(mock.WithBody(cek))
+	return container, srv, transport, close
+}
+
+func TestEncryptItems(t *testing.T) {
+	container, srv, transport, close := newTestEncryptionContainer(t)
+	defer close()
+	ctx := context.Background()
+	pk := NewPartitionKeyString("1")
+
+	item := `{"id":"1","pk":"1","name":"alice","ssn":"123-45-6789","details":{"age":42,"score":1.5,"verified":true,"tags":["a",null]}}`
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated))
+	if _, err := container.CreateItem(ctx, pk, []byte(item), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.requests) != 3 {
+		t.Fatalf("expected the container, key and item requests, got %d requests", len(transport.requests))
+	}
+	if path := transport.requests[1].URL.Path; path != "/dbs/db/clientencryptionkeys/cek" {
+		t.Errorf("unexpected key request %s", path)
+	}
+	stored := transport.bodies[2]
+	var storedItem map[string]any
+	if err := json.Unmarshal(stored, &storedItem); err != nil {
+		t.Fatal(err)
+	}
+	if storedItem["name"] != "alice" || storedItem["id"] != "1" {
+		t.Errorf("expected properties outside the policy to be sent as they are, got %s", stored)
+	}
+	details := storedItem["details"].(map[string]any)
+	// encrypted values are base64 strings, which may contain "42" by chance
+	if strings.Contains(string(stored), "123-45-6789") || details["age"] == float64(42) {
+		t.Errorf("expected the properties to be encrypted, got %s", stored)
+	}
+	if _, ok := details["age"].(string); !ok {
+		t.Errorf("expected an encrypted age, got %v", details["age"])
+	}
+	if tags := details["tags"].([]any); tags[1] != nil {
+		t.Errorf("expected nulls not to be encrypted, got %v", tags[1])
+	}
+
+	// reads decrypt the item
+	srv.AppendResponse(mock.WithBody(stored))
+	response, err := container.ReadItem(ctx, pk, "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readItem, expected map[string]any
+	if err := json.Unmarshal(response.Value, &readItem); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(item), &expected); err != nil {
+		t.Fatal(err)
+	}
+	readJSON, _ := json.Marshal(readItem)
+	expectedJSON, _ := json.Marshal(expected)
+	if !bytes.Equal(readJSON, expectedJSON) {
+		t.Errorf("expected %s, got %s", expectedJSON, readJSON)
+	}
+
+	// queries compare deterministically encrypted parameters, and decrypt the items
+	ssn, err := container.EncryptQueryParameter(ctx, "/ssn", "123-45-6789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ssn != storedItem["ssn"] {
+		t.Errorf("expected the parameter to be encrypted like the item, got %s", ssn)
+	}
+	if _, err := container.EncryptQueryParameter(ctx, "/details", "x"); err == nil {
+		t.Error("expected randomized paths to be rejected")
+	}
+	if _, err := container.EncryptQueryParameter(ctx, "/name", "x"); err == nil {
+		t.Error("expected paths outside the policy to be rejected")
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"Documents":[` + string(stored) + `],"_count":1}`)))
+	pager := container.NewQueryItemsPager("SELECT * FROM c WHERE c.ssn = @ssn", pk, &QueryOptions{
+		QueryParameters: []QueryParameter{{Name: "@ssn", Value: ssn}},
+	})
+	page, err := pager.NextPage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || !strings.Contains(string(page.Items[0]), `"ssn":"123-45-6789"`) {
+		t.Errorf("expected the queried item to be decrypted, got %s", page.Items)
+	}
+	if query := transport.bodies[len(transport.bodies)-1]; !bytes.Contains(query, []byte(ssn)) {
+		t.Errorf("expected the query to be sent as it is, got %s", query)
+	}
+	if len(transport.requests) != 5 {
+		t.Errorf("expected the encryption settings to be cached, got %d requests", len(transport.requests))
+	}
+}
+
+func TestEncryptPatch(t *testing.T) {
+	container, srv, transport, close := newTestEncryptionContainer(t)
+	defer close()
+	ctx := context.Background()
+	pk := NewPartitionKeyString("1")
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	ops := PatchOperations{}
+	ops.AppendSet("/ssn", "987-65-4321")
+	ops.AppendSet("/name", "bob")
+	if _, err := container.PatchItem(ctx, pk, "1", ops, nil); err != nil {
+		t.Fatal(err)
+	}
+	patch := string(transport.bodies[2])
+	if strings.Contains(patch, "987-65-4321") || !strings.Contains(patch, "bob") {
+		t.Errorf("expected the encrypted property to be encrypted, got %s", patch)
+	}
+
+	ops = PatchOperations{}
+	ops.AppendIncrement("/details/age", 1)
+	if _, err := container.PatchItem(ctx, pk, "1", ops, nil); err == nil {
+		t.Error("expected incrementing an encrypted property to fail")
+	}
+}
+
+func TestEncryptionPolicyWithoutClientEncryptionPolicy(t *testing.T) {
+	container, srv, transport, close := newMockContainer(t, "container", encryptionTestClientOptions(&LocalKeyWrapProvider{keys: map[string][]byte{}}))
+	defer close()
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"container","partitionKey":{"paths":["/pk"],"kind":"Hash"}}`)))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated))
+	item := []byte(`{"id":"1","pk":"1","ssn":"123-45-6789"}`)
+	if _, err := container.CreateItem(context.Background(), NewPartitionKeyString("1"), item, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(transport.bodies[1], item) {
+		t.Errorf("expected the item to be sent as it is, got %s", transport.bodies[1])
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"fmt"
+)
+
+// FeedRange is a range of effective partition key values of a container.
+// Change feed readers, cross partition queries and bulk operations use feed ranges to divide a container's
+// items among workers.
+type FeedRange struct {
+	// MinInclusive is the lowest effective partition key in the range.
+	MinInclusive string `json:"minInclusive"`
+	// MaxExclusive is the effective partition key following the range.
+	MaxExclusive string `json:"maxExclusive"`
+}
+
+// fullFeedRange returns the feed range covering a whole container.
+func fullFeedRange() FeedRange {
+	return FeedRange{MinInclusive: minInclusiveEffectivePartitionKey, MaxExclusive: maxExclusiveEffectivePartitionKey}
+}
+
+func (fr FeedRange) overlaps(other FeedRange) bool {
+	return fr.MinInclusive < other.MaxExclusive && other.MinInclusive < fr.MaxExclusive
+}
+
+// intersect returns the part of fr which other also covers. The ranges must overlap.
+func (fr FeedRange) intersect(other FeedRange) FeedRange {
+	r := fr
+	if other.MinInclusive > r.MinInclusive {
+		r.MinInclusive = other.MinInclusive
+	}
+	if other.MaxExclusive < r.MaxExclusive {
+		r.MaxExclusive = other.MaxExclusive
+	}
+	return r
+}
+
+func (pkr partitionKeyRange) feedRange() FeedRange {
+	return FeedRange{MinInclusive: pkr.MinInclusive, MaxExclusive: pkr.MaxExclusive}
+}
+
+// GetFeedRanges returns the feed ranges of the container's partition key ranges, which divide the container's
+// items by physical partition. The client caches the container's partition key ranges, and reads them again
+// after a request reports that a range split.
+// ctx - The context for the request.
+func (c *ContainerClient) GetFeedRanges(ctx context.Context) ([]FeedRange, error) {
+	pkRanges, _, err := c.getPartitionKeyRanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feedRanges := make([]FeedRange, len(pkRanges))
+	for i, pkRange := range pkRanges {
+		feedRanges[i] = pkRange.feedRange()
+	}
+	return feedRanges, nil
+}
+
+// FeedRangeFromPartitionKey returns the feed range of the items of a partition key. The client hashes the
+// partition key like the service does, after reading the container's partition key definition once.
+// For containers with hierarchical partition keys, the partition key can be a prefix, whose feed range
+// contains the items of every partition key starting with it.
+// ctx - The context for the request.
+// partitionKey - The partition key.
+func (c *ContainerClient) FeedRangeFromPartitionKey(ctx context.Context, partitionKey PartitionKey) (FeedRange, error) {
+	def, _, err := c.getPartitionKeyDefinition(ctx)
+	if err != nil {
+		return FeedRange{}, err
+	}
+	if !canComputeEffectivePartitionKey(def) {
+		return FeedRange{}, fmt.Errorf("the feed range of partition keys of kind %s can't be computed", def.Kind)
+	}
+	if def.Kind == PartitionKeyKindMultiHash && len(partitionKey.values) < len(def.Paths) {
+		return effectivePartitionKeyRange(def, partitionKey)
+	}
+
+	epk, err := effectivePartitionKey(def, partitionKey)
+	if err != nil {
+		return FeedRange{}, err
+	}
+	// no effective partition key sorts between epk and epk+"00"
+	return FeedRange{MinInclusive: epk, MaxExclusive: epk + "00"}, nil
+}
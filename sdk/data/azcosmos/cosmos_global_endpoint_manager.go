@@ -0,0 +1,216 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+const defaultAccountRefreshInterval time.Duration = time.Minute * 5
+
+// accountReadTimeout bounds reads of the database account, which don't run on the context of the request triggering them
+const accountReadTimeout time.Duration = time.Minute
+
+// globalEndpointManager routes requests to the account's regional endpoints. It reads the
+// database account's regions on first use and refreshes them in the background thereafter.
+type globalEndpointManager struct {
+	pipeline        azruntime.Pipeline
+	defaultEndpoint url.URL
+	locationCache   *locationCache
+	refreshInterval time.Duration
+	refreshMutex    sync.Mutex
+	lastRefreshTime time.Time
+	refreshing      bool
+}
+
+func newGlobalEndpointManager(endpoint string, pipeline azruntime.Pipeline, preferredRegions []string, refreshInterval time.Duration) (*globalEndpointManager, error) {
+	defaultEndpoint, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if refreshInterval <= 0 {
+		refreshInterval = defaultAccountRefreshInterval
+	}
+
+	lc := newLocationCache(preferredRegions, *defaultEndpoint)
+	lc.enableEndpointDiscovery = true
+	lc.useMultipleWriteLocations = true
+
+	return &globalEndpointManager{
+		pipeline:        pipeline,
+		defaultEndpoint: *defaultEndpoint,
+		locationCache:   lc,
+		refreshInterval: refreshInterval,
+	}, nil
+}
+
+// resolveServiceEndpoint returns the most preferred endpoint for the operation which isn't in excluded.
+// It returns false when every endpoint is excluded.
+func (gem *globalEndpointManager) resolveServiceEndpoint(isWriteOperation bool, excluded map[url.URL]bool) (url.URL, bool, error) {
+	var endpoints []url.URL
+	var err error
+	if isWriteOperation {
+		endpoints, err = gem.locationCache.writeEndpoints()
+	} else {
+		endpoints, err = gem.locationCache.readEndpoints()
+	}
+	if err != nil {
+		return url.URL{}, false, err
+	}
+
+	for _, endpoint := range endpoints {
+		if !excluded[endpoint] {
+			return endpoint, true, nil
+		}
+	}
+
+	return url.URL{}, false, nil
+}
+
+// getLocation returns the name of the region serving endpoint, or "" when it isn't known.
+func (gem *globalEndpointManager) getLocation(endpoint url.URL) string {
+	return gem.locationCache.getLocation(endpoint)
+}
+
+func (gem *globalEndpointManager) markEndpointUnavailableForRead(endpoint url.URL) error {
+	return gem.locationCache.markEndpointUnavailableForRead(endpoint)
+}
+
+func (gem *globalEndpointManager) markEndpointUnavailableForWrite(endpoint url.URL) error {
+	return gem.locationCache.markEndpointUnavailableForWrite(endpoint)
+}
+
+// refreshIfNeeded reads the database account on its first call, and again on later calls until a read
+// succeeds. Once the account was read, calls start a background refresh when it was last read more than
+// refreshInterval ago. The reads don't run on the context of the request triggering them, so that a
+// short deadline of the client's first request doesn't leave the client without the account's regions.
+func (gem *globalEndpointManager) refreshIfNeeded() {
+	gem.refreshMutex.Lock()
+	if gem.refreshing || time.Since(gem.lastRefreshTime) < gem.refreshInterval {
+		gem.refreshMutex.Unlock()
+		return
+	}
+	gem.refreshing = true
+	firstRefresh := gem.lastRefreshTime.IsZero()
+	gem.refreshMutex.Unlock()
+
+	if firstRefresh {
+		ctx, cancel := context.WithTimeout(context.Background(), accountReadTimeout)
+		defer cancel()
+		err := gem.update(ctx)
+		gem.endRefresh(err == nil)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), accountReadTimeout)
+		defer cancel()
+		_ = gem.update(ctx)
+		gem.endRefresh(true)
+	}()
+}
+
+// forceRefresh reads the database account now, unless a refresh is already in progress. The refresh
+// interval restarts only when the account was read.
+func (gem *globalEndpointManager) forceRefresh(ctx context.Context) error {
+	gem.refreshMutex.Lock()
+	if gem.refreshing {
+		gem.refreshMutex.Unlock()
+		return nil
+	}
+	gem.refreshing = true
+	gem.refreshMutex.Unlock()
+
+	err := gem.update(ctx)
+	gem.endRefresh(err == nil)
+	return err
+}
+
+// endRefresh ends the refresh in progress, and restarts the refresh interval when restartInterval is true.
+func (gem *globalEndpointManager) endRefresh(restartInterval bool) {
+	gem.refreshMutex.Lock()
+	defer gem.refreshMutex.Unlock()
+	gem.refreshing = false
+	if restartInterval {
+		gem.lastRefreshTime = time.Now()
+	}
+}
+
+// update reads the database account and updates the location cache with its regions. Callers
+// must hold the refresh, see refreshIfNeeded and forceRefresh.
+func (gem *globalEndpointManager) update(ctx context.Context) error {
+	properties, err := gem.readAccountProperties(ctx)
+	if err != nil {
+		log.Writef(azlog.EventRetryPolicy, "Failed to read the database account's regions: %v", err)
+		return err
+	}
+
+	return gem.locationCache.databaseAccountRead(properties)
+}
+
+// readAccountProperties reads the database account from the default endpoint, falling back
+// to the known regional endpoints when the default endpoint can't be reached.
+func (gem *globalEndpointManager) readAccountProperties(ctx context.Context) (accountProperties, error) {
+	endpoints := []url.URL{gem.defaultEndpoint}
+	if readEndpoints, err := gem.locationCache.readEndpoints(); err == nil {
+		for _, endpoint := range readEndpoints {
+			if endpoint != gem.defaultEndpoint {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		properties, err := gem.readAccountPropertiesFrom(ctx, endpoint)
+		if err == nil {
+			return properties, nil
+		}
+		if ctx.Err() != nil {
+			return accountProperties{}, err
+		}
+		lastErr = err
+	}
+
+	return accountProperties{}, lastErr
+}
+
+func (gem *globalEndpointManager) readAccountPropertiesFrom(ctx context.Context, endpoint url.URL) (accountProperties, error) {
+	req, err := azruntime.NewRequest(ctx, http.MethodGet, endpoint.String())
+	if err != nil {
+		return accountProperties{}, err
+	}
+
+	req.Raw().Header.Set(headerXmsDate, time.Now().UTC().Format(http.TimeFormat))
+	req.Raw().Header.Set(headerXmsVersion, "2020-11-05")
+	req.SetOperationValue(pipelineRequestOptions{
+		resourceType:    resourceTypeDatabaseAccount,
+		resourceAddress: "",
+	})
+
+	resp, err := gem.pipeline.Do(req)
+	if err != nil {
+		return accountProperties{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return accountProperties{}, newCosmosError(resp)
+	}
+
+	var properties accountProperties
+	if err := azruntime.UnmarshalAsJSON(resp, &properties); err != nil {
+		return accountProperties{}, err
+	}
+
+	return properties, nil
+}
@@ -0,0 +1,245 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+const (
+	testAccountEndpoint = "https://account.documents.azure.com:443/"
+	testWestEndpoint    = "https://account-westus.documents.azure.com:443/"
+	testEastEndpoint    = "https://account-eastus.documents.azure.com:443/"
+)
+
+// recordingTransport records the host of each request before sending it to a mock server
+type recordingTransport struct {
+	srv   *mock.Server
+	hosts []string
+}
+
+func (r *recordingTransport) Do(req *http.Request) (*http.Response, error) {
+	r.hosts = append(r.hosts, req.URL.Host)
+	return r.srv.Do(req)
+}
+
+func accountResponse(multipleWriteLocations bool, writeRegions ...string) []byte {
+	regions := map[string]string{"West US": testWestEndpoint, "East US": testEastEndpoint}
+	location := func(name string) string {
+		return fmt.Sprintf(`{"name":%q,"databaseAccountEndpoint":%q}`, name, regions[name])
+	}
+	writable := ""
+	for i, name := range writeRegions {
+		if i > 0 {
+			writable += ","
+		}
+		writable += location(name)
+	}
+	return []byte(fmt.Sprintf(`{"writableLocations":[%s],"readableLocations":[%s,%s],"enableMultipleWriteLocations":%t}`,
+		writable, location("West US"), location("East US"), multipleWriteLocations))
+}
+
+func newTestGlobalEndpointManager(t *testing.T, transport policy.Transporter, preferredRegions []string) *globalEndpointManager {
+	pl := azruntime.NewPipeline("azcosmostest", "v1.0.0", azruntime.PipelineOptions{}, &policy.ClientOptions{
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: transport,
+	})
+	gem, err := newGlobalEndpointManager(testAccountEndpoint, pl, preferredRegions, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gem
+}
+
+func TestGlobalEndpointManagerUpdate(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+	transport := &recordingTransport{srv: srv}
+
+	gem := newTestGlobalEndpointManager(t, transport, []string{"East US", "West US"})
+	if err := gem.update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.hosts) != 1 || transport.hosts[0] != "account.documents.azure.com:443" {
+		t.Fatalf("expected the account to be read from the default endpoint, got %v", transport.hosts)
+	}
+
+	read, _, err := gem.resolveServiceEndpoint(false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.String() != testEastEndpoint {
+		t.Errorf("expected reads to be sent to %s, but got %s", testEastEndpoint, read.String())
+	}
+
+	write, _, err := gem.resolveServiceEndpoint(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if write.String() != testWestEndpoint {
+		t.Errorf("expected writes to be sent to %s, but got %s", testWestEndpoint, write.String())
+	}
+
+	if region := gem.getLocation(read); region != "East US" {
+		t.Errorf("expected East US, but got %s", region)
+	}
+
+	_, ok, err := gem.resolveServiceEndpoint(false, map[url.URL]bool{read: true, write: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("expected no endpoint when every endpoint is excluded")
+	}
+}
+
+func TestGlobalEndpointManagerUpdateFailure(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
+
+	gem := newTestGlobalEndpointManager(t, srv, []string{"East US"})
+	if err := gem.update(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// without the account's regions, every request goes to the default endpoint
+	endpoint, _, err := gem.resolveServiceEndpoint(false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.String() != testAccountEndpoint {
+		t.Errorf("expected %s, but got %s", testAccountEndpoint, endpoint.String())
+	}
+}
+
+func TestGlobalEndpointManagerRefreshIfNeeded(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "East US")))
+
+	gem := newTestGlobalEndpointManager(t, srv, nil)
+
+	// the first refresh reads the account synchronously
+	gem.refreshIfNeeded()
+	if srv.Requests() != 1 {
+		t.Fatalf("expected 1 request, got %d", srv.Requests())
+	}
+	write, _, _ := gem.resolveServiceEndpoint(true, nil)
+	if write.String() != testWestEndpoint {
+		t.Fatalf("expected %s, but got %s", testWestEndpoint, write.String())
+	}
+
+	// the account was read recently, so there's nothing to do
+	gem.refreshIfNeeded()
+	if srv.Requests() != 1 {
+		t.Fatalf("expected 1 request, got %d", srv.Requests())
+	}
+
+	// later refreshes happen in the background
+	gem.refreshMutex.Lock()
+	gem.lastRefreshTime = time.Now().Add(-gem.refreshInterval)
+	gem.refreshMutex.Unlock()
+	gem.refreshIfNeeded()
+	for i := 0; i < 100; i++ {
+		write, _, _ = gem.resolveServiceEndpoint(true, nil)
+		if write.String() == testEastEndpoint {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the write endpoint to change to %s after a refresh, but got %s", testEastEndpoint, write.String())
+}
+
+func TestGlobalEndpointManagerRefreshIfNeededFirstReadFailure(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+
+	gem := newTestGlobalEndpointManager(t, srv, []string{"East US", "West US"})
+
+	// a failed first read doesn't restart the refresh interval, so the next call reads the account again
+	gem.refreshIfNeeded()
+	gem.refreshMutex.Lock()
+	if gem.refreshing || !gem.lastRefreshTime.IsZero() {
+		t.Fatalf("expected the refresh to end without restarting the interval, got refreshing %v at %v", gem.refreshing, gem.lastRefreshTime)
+	}
+	gem.refreshMutex.Unlock()
+	read, _, _ := gem.resolveServiceEndpoint(false, nil)
+	if read.String() != testAccountEndpoint {
+		t.Fatalf("expected %s, but got %s", testAccountEndpoint, read.String())
+	}
+
+	gem.refreshIfNeeded()
+	if srv.Requests() != 2 {
+		t.Fatalf("expected 2 requests, got %d", srv.Requests())
+	}
+	read, _, _ = gem.resolveServiceEndpoint(false, nil)
+	if read.String() != testEastEndpoint {
+		t.Fatalf("expected %s, but got %s", testEastEndpoint, read.String())
+	}
+	gem.refreshMutex.Lock()
+	if gem.lastRefreshTime.IsZero() {
+		t.Fatal("expected the successful read to restart the refresh interval")
+	}
+	gem.refreshMutex.Unlock()
+}
+
+func TestGlobalEndpointManagerForceRefresh(t *testing.T) {
+	srv, close := mock.NewTLSServer(mock.WithTransformAllRequestsToTestServerUrl())
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
+	srv.AppendResponse(mock.WithBody(accountResponse(false, "West US")))
+
+	gem := newTestGlobalEndpointManager(t, srv, []string{"East US", "West US"})
+
+	// a refresh in progress isn't interrupted or repeated
+	gem.refreshMutex.Lock()
+	gem.refreshing = true
+	gem.refreshMutex.Unlock()
+	if err := gem.forceRefresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Requests() != 0 {
+		t.Fatalf("expected no requests, got %d", srv.Requests())
+	}
+	gem.refreshMutex.Lock()
+	if !gem.refreshing {
+		t.Fatal("expected the refresh in progress to be unaffected")
+	}
+	gem.refreshing = false
+	gem.refreshMutex.Unlock()
+
+	// a failed refresh doesn't restart the refresh interval
+	if err := gem.forceRefresh(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	gem.refreshMutex.Lock()
+	if gem.refreshing || !gem.lastRefreshTime.IsZero() {
+		t.Fatalf("expected the refresh to end without restarting the interval, got refreshing %v at %v", gem.refreshing, gem.lastRefreshTime)
+	}
+	gem.refreshMutex.Unlock()
+
+	if err := gem.forceRefresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	gem.refreshMutex.Lock()
+	if gem.refreshing || gem.lastRefreshTime.IsZero() {
+		t.Fatalf("expected the refresh to end and restart the interval, got refreshing %v at %v", gem.refreshing, gem.lastRefreshTime)
+	}
+	gem.refreshMutex.Unlock()
+}
@@ -33,15 +33,31 @@ const (
 	cosmosHeaderIsBatchRequest                     string = "x-ms-cosmos-is-batch-request"
 	cosmosHeaderIsBatchAtomic                      string = "x-ms-cosmos-batch-atomic"
 	cosmosHeaderIsBatchOrdered                     string = "x-ms-cosmos-batch-ordered"
+	cosmosHeaderIsBatchContinueOnError             string = "x-ms-cosmos-batch-continue-on-error"
+	cosmosHeaderRetryAfterMs                       string = "x-ms-retry-after-ms"
+	cosmosHeaderScriptEnableLogging                string = "x-ms-documentdb-script-enable-logging"
+	cosmosHeaderScriptLogResults                   string = "x-ms-documentdb-script-log-results"
+	cosmosHeaderResourceTokenExpiry                string = "x-ms-documentdb-expiry-seconds"
+	cosmosHeaderSubStatus                          string = "x-ms-substatus"
+	cosmosHeaderPartitionKeyRangeID                string = "x-ms-documentdb-partitionkeyrangeid"
+	cosmosHeaderEnableCrossPartitionQuery          string = "x-ms-documentdb-query-enablecrosspartition"
+	cosmosHeaderIsQueryPlanRequest                 string = "x-ms-cosmos-is-query-plan-request"
+	cosmosHeaderSupportedQueryFeatures             string = "x-ms-cosmos-supported-query-features"
+	cosmosHeaderQueryVersion                       string = "x-ms-cosmos-query-version"
+	cosmosHeaderAIM                                string = "A-IM"
+	cosmosHeaderStartEpk                           string = "x-ms-start-epk"
+	cosmosHeaderEndEpk                             string = "x-ms-end-epk"
 	headerXmsDate                                  string = "x-ms-date"
 	headerAuthorization                            string = "Authorization"
 	headerContentType                              string = "Content-Type"
 	headerIfMatch                                  string = "If-Match"
 	headerIfNoneMatch                              string = "If-None-Match"
+	headerIfModifiedSince                          string = "If-Modified-Since"
 	headerXmsVersion                               string = "x-ms-version"
 )
 
 const (
 	cosmosHeaderValuesPreferMinimal string = "return=minimal"
 	cosmosHeaderValuesQuery         string = "application/query+json"
+	cosmosHeaderValuesChangeFeed    string = "Incremental feed"
 )
@@ -36,13 +36,13 @@ type databaseAccountLocationsInfo struct {
 }
 
 type accountRegion struct {
-	name     string
-	endpoint string
+	Name     string `json:"name"`
+	Endpoint string `json:"databaseAccountEndpoint"`
 }
 
 type accountProperties struct {
-	ReadRegions                  []accountRegion `json:"readRegions"`
-	WriteRegions                 []accountRegion `json:"writeRegions"`
+	ReadRegions                  []accountRegion `json:"readableLocations"`
+	WriteRegions                 []accountRegion `json:"writableLocations"`
 	EnableMultipleWriteLocations bool            `json:"enableMultipleWriteLocations"`
 }
 
@@ -53,6 +53,7 @@ type locationCache struct {
 	useMultipleWriteLocations         bool
 	locationUnavailabilityInfoMap     map[url.URL]locationUnavailabilityInfo
 	mapMutex                          sync.RWMutex
+	infoMutex                         sync.RWMutex
 	lastUpdateTime                    time.Time
 	enableMultipleWriteLocations      bool
 	unavailableLocationExpirationTime time.Duration
@@ -68,6 +69,8 @@ func newLocationCache(prefLocations []string, defaultEndpoint url.URL) *location
 }
 
 func (lc *locationCache) update(writeLocations []accountRegion, readLocations []accountRegion, prefList []string, enableMultipleWriteLocations *bool) error {
+	lc.infoMutex.Lock()
+	defer lc.infoMutex.Unlock()
 	nextLoc := copyDatabaseAccountLocationsInfo(lc.locationInfo)
 	if prefList != nil {
 		nextLoc.prefLocations = prefList
@@ -103,30 +106,43 @@ func (lc *locationCache) update(writeLocations []accountRegion, readLocations []
 }
 
 func (lc *locationCache) readEndpoints() ([]url.URL, error) {
-	lc.mapMutex.RLock()
-	defer lc.mapMutex.RUnlock()
-	if time.Since(lc.lastUpdateTime) > lc.unavailableLocationExpirationTime && len(lc.locationUnavailabilityInfoMap) > 0 {
+	if lc.shouldRefreshEndpoints() {
 		err := lc.update(nil, nil, nil, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
+	lc.infoMutex.RLock()
+	defer lc.infoMutex.RUnlock()
 	return lc.locationInfo.readEndpoints, nil
 }
 
 func (lc *locationCache) writeEndpoints() ([]url.URL, error) {
-	lc.mapMutex.RLock()
-	defer lc.mapMutex.RUnlock()
-	if time.Since(lc.lastUpdateTime) > lc.unavailableLocationExpirationTime && len(lc.locationUnavailabilityInfoMap) > 0 {
+	if lc.shouldRefreshEndpoints() {
 		err := lc.update(nil, nil, nil, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
+	lc.infoMutex.RLock()
+	defer lc.infoMutex.RUnlock()
 	return lc.locationInfo.writeEndpoints, nil
 }
 
+// shouldRefreshEndpoints returns true when some endpoints are marked unavailable and the
+// endpoint lists haven't been recomputed since those marks may have expired
+func (lc *locationCache) shouldRefreshEndpoints() bool {
+	lc.infoMutex.RLock()
+	lastUpdateTime := lc.lastUpdateTime
+	lc.infoMutex.RUnlock()
+	lc.mapMutex.RLock()
+	defer lc.mapMutex.RUnlock()
+	return time.Since(lastUpdateTime) > lc.unavailableLocationExpirationTime && len(lc.locationUnavailabilityInfoMap) > 0
+}
+
 func (lc *locationCache) getLocation(endpoint url.URL) string {
+	lc.infoMutex.RLock()
+	defer lc.infoMutex.RUnlock()
 	firstLoc := ""
 	for location, uri := range lc.locationInfo.availWriteEndpointsByLocation {
 		if uri == endpoint {
@@ -212,9 +228,10 @@ func (lc *locationCache) getPrefAvailableEndpoints(endpointsByLoc map[string]url
 	if lc.enableEndpointDiscovery {
 		if lc.canUseMultipleWriteLocs() || availOps&read != 0 {
 			unavailEndpoints := make([]url.URL, 0)
-			unavailEndpoints = append(unavailEndpoints, fallbackEndpoint)
+			hasFallback := false
 			for _, loc := range lc.locationInfo.prefLocations {
-				if endpoint, ok := endpointsByLoc[loc]; ok && endpoint != fallbackEndpoint {
+				if endpoint, ok := endpointsByLoc[loc]; ok {
+					hasFallback = hasFallback || endpoint == fallbackEndpoint
 					if lc.isEndpointUnavailable(endpoint, availOps) {
 						unavailEndpoints = append(unavailEndpoints, endpoint)
 					} else {
@@ -222,6 +239,11 @@ func (lc *locationCache) getPrefAvailableEndpoints(endpointsByLoc map[string]url
 					}
 				}
 			}
+			// the fallback endpoint keeps its position when it's preferred, otherwise it
+			// follows the available preferred endpoints
+			if !hasFallback {
+				endpoints = append(endpoints, fallbackEndpoint)
+			}
 			endpoints = append(endpoints, unavailEndpoints...)
 		} else {
 			for _, loc := range locs {
@@ -241,13 +263,13 @@ func getEndpointsByLocation(locs []accountRegion) (map[string]url.URL, []string,
 	endpointsByLoc := make(map[string]url.URL)
 	parsedLocs := make([]string, 0)
 	for _, loc := range locs {
-		endpoint, err := url.Parse(loc.endpoint)
+		endpoint, err := url.Parse(loc.Endpoint)
 		if err != nil {
 			return nil, nil, err
 		}
-		if loc.name != "" {
-			endpointsByLoc[loc.name] = *endpoint
-			parsedLocs = append(parsedLocs, loc.name)
+		if loc.Name != "" {
+			endpointsByLoc[loc.Name] = *endpoint
+			parsedLocs = append(parsedLocs, loc.Name)
 		}
 		// TODO else: log
 	}
@@ -117,6 +117,9 @@ func generatePathForNameBased(resourceType resourceType, ownerOrResourceId strin
 	}
 
 	if resourceType == resourceTypeClientEncryptionKey {
+		if isFeed {
+			return ownerOrResourceId + "/" + pathSegmentClientEncryptionKey, nil
+		}
 		return ownerOrResourceId, nil
 	}
 
@@ -0,0 +1,435 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// undefinedValue represents the absence of a value, for example the result of MIN over no items.
+// Cosmos DB orders it before every other value.
+type undefinedValue struct{}
+
+func isUndefined(v interface{}) bool {
+	_, ok := v.(undefinedValue)
+	return ok
+}
+
+// itemValue returns the "item" property of an object such as {"item": 42}, or undefined when it's absent.
+func itemValue(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		if item, ok := m["item"]; ok {
+			return item
+		}
+	}
+	return undefinedValue{}
+}
+
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case undefinedValue:
+		return 0
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// compareQueryValues orders values the way Cosmos DB does: undefined, null, booleans, numbers, strings,
+// then arrays and objects. It returns a negative number when a sorts before b, zero when they're equal
+// and a positive number otherwise.
+func compareQueryValues(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	switch av := a.(type) {
+	case undefinedValue, nil:
+		return 0
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		} else if !av {
+			return -1
+		}
+		return 1
+	case float64:
+		bv := b.(float64)
+		if av < bv {
+			return -1
+		} else if av > bv {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	default:
+		ja, _ := json.Marshal(a)
+		jb, _ := json.Marshal(b)
+		return bytes.Compare(ja, jb)
+	}
+}
+
+// distinctKey returns a key which is equal for equal values. Object properties are compared regardless of order.
+func distinctKey(item []byte) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(item, &v); err != nil {
+		return "", err
+	}
+	// json.Marshal sorts object keys, so equal values have equal encodings
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// aggregator combines the partial results of an aggregate function from each partition key range.
+type aggregator interface {
+	add(partial interface{})
+	result() interface{}
+}
+
+func newAggregator(t aggregateType) (aggregator, error) {
+	switch t {
+	case aggregateTypeAverage:
+		return &averageAggregator{}, nil
+	case aggregateTypeCount, aggregateTypeSum:
+		return &sumAggregator{}, nil
+	case aggregateTypeMax:
+		return &minMaxAggregator{property: "max", sign: -1, value: undefinedValue{}}, nil
+	case aggregateTypeMin:
+		return &minMaxAggregator{property: "min", sign: 1, value: undefinedValue{}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported aggregate %q", t)
+	}
+}
+
+type sumAggregator struct {
+	sum     float64
+	seen    bool
+	invalid bool
+}
+
+func (a *sumAggregator) add(partial interface{}) {
+	if isUndefined(partial) {
+		return
+	}
+	n, ok := partial.(float64)
+	if !ok {
+		a.invalid = true
+		return
+	}
+	a.sum += n
+	a.seen = true
+}
+
+func (a *sumAggregator) result() interface{} {
+	if a.invalid || !a.seen {
+		return undefinedValue{}
+	}
+	return a.sum
+}
+
+// averageAggregator combines partial averages having the form {"sum": 10, "count": 4}
+type averageAggregator struct {
+	sum     float64
+	count   float64
+	invalid bool
+}
+
+func (a *averageAggregator) add(partial interface{}) {
+	if isUndefined(partial) {
+		return
+	}
+	m, ok := partial.(map[string]interface{})
+	if !ok {
+		a.invalid = true
+		return
+	}
+	sum, sumOK := m["sum"].(float64)
+	count, countOK := m["count"].(float64)
+	if !countOK {
+		a.invalid = true
+		return
+	}
+	if count == 0 {
+		return
+	}
+	if !sumOK {
+		a.invalid = true
+		return
+	}
+	a.sum += sum
+	a.count += count
+}
+
+func (a *averageAggregator) result() interface{} {
+	if a.invalid || a.count == 0 {
+		return undefinedValue{}
+	}
+	return a.sum / a.count
+}
+
+// minMaxAggregator combines partial minimums or maximums. Partials have the form {"min": 1, "count": 4},
+// or are the value itself.
+type minMaxAggregator struct {
+	property string
+	// sign is 1 for MIN and -1 for MAX
+	sign  int
+	value interface{}
+}
+
+func (a *minMaxAggregator) add(partial interface{}) {
+	if m, ok := partial.(map[string]interface{}); ok {
+		if count, ok := m["count"].(float64); ok {
+			if count == 0 {
+				return
+			}
+			v, ok := m[a.property]
+			if !ok {
+				return
+			}
+			partial = v
+		}
+	}
+	if isUndefined(partial) {
+		return
+	}
+	if isUndefined(a.value) || a.sign*compareQueryValues(partial, a.value) < 0 {
+		a.value = partial
+	}
+}
+
+func (a *minMaxAggregator) result() interface{} {
+	return a.value
+}
+
+// valueAggregator computes VALUE aggregates such as SELECT VALUE COUNT(1) FROM c. Each partition key
+// range returns documents like [{"item": 42}], having one element per aggregate.
+type valueAggregator struct {
+	aggregators []aggregator
+}
+
+func newValueAggregator(types []aggregateType) (*valueAggregator, error) {
+	va := &valueAggregator{}
+	for _, t := range types {
+		a, err := newAggregator(t)
+		if err != nil {
+			return nil, err
+		}
+		va.aggregators = append(va.aggregators, a)
+	}
+	return va, nil
+}
+
+func (va *valueAggregator) add(doc []byte) error {
+	var partials []interface{}
+	if err := json.Unmarshal(doc, &partials); err != nil {
+		return err
+	}
+	for i, partial := range partials {
+		if i < len(va.aggregators) {
+			va.aggregators[i].add(itemValue(partial))
+		}
+	}
+	return nil
+}
+
+func (va *valueAggregator) results() ([][]byte, error) {
+	results := [][]byte{}
+	for _, a := range va.aggregators {
+		v := a.result()
+		if isUndefined(v) {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, b)
+	}
+	return results, nil
+}
+
+// groupByAggregator computes GROUP BY queries. Each partition key range returns documents like
+// {"groupByItems": [{"item": "a"}], "payload": {"alias": {"item": 42}}}, where the payload's aggregate
+// properties are partial results.
+type groupByAggregator struct {
+	info   queryInfo
+	groups map[string]*queryGroup
+	keys   []string
+}
+
+type queryGroup struct {
+	values      map[string]interface{}
+	aggregators map[string]aggregator
+}
+
+func newGroupByAggregator(info queryInfo) *groupByAggregator {
+	return &groupByAggregator{info: info, groups: map[string]*queryGroup{}}
+}
+
+type groupByDocument struct {
+	GroupByItems json.RawMessage `json:"groupByItems"`
+	Payload      interface{}     `json:"payload"`
+}
+
+func (g *groupByAggregator) add(doc []byte) error {
+	var d groupByDocument
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return err
+	}
+
+	if len(d.GroupByItems) == 0 {
+		d.GroupByItems = json.RawMessage("[]")
+	}
+	key, err := distinctKey(d.GroupByItems)
+	if err != nil {
+		return err
+	}
+
+	group, ok := g.groups[key]
+	if !ok {
+		group = &queryGroup{values: map[string]interface{}{}, aggregators: map[string]aggregator{}}
+		for alias, t := range g.info.GroupByAliasToAggregateType {
+			if t == nil {
+				continue
+			}
+			a, err := newAggregator(*t)
+			if err != nil {
+				return err
+			}
+			group.aggregators[alias] = a
+		}
+		g.groups[key] = group
+		g.keys = append(g.keys, key)
+	}
+
+	for _, alias := range g.aliases() {
+		var v interface{} = undefinedValue{}
+		if g.info.HasSelectValue {
+			v = d.Payload
+		} else if m, ok := d.Payload.(map[string]interface{}); ok {
+			if pv, ok := m[alias]; ok {
+				v = pv
+			}
+		}
+
+		if a, ok := group.aggregators[alias]; ok {
+			a.add(partialAggregate(v))
+		} else if current, ok := group.values[alias]; !ok || isUndefined(current) {
+			group.values[alias] = v
+		}
+	}
+
+	return nil
+}
+
+// partialAggregate unwraps partial aggregates having the forms {"item": 42} and [{"item": 42}]
+func partialAggregate(v interface{}) interface{} {
+	if a, ok := v.([]interface{}); ok && len(a) == 1 {
+		v = a[0]
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		if _, ok := m["item"]; ok {
+			return itemValue(m)
+		}
+		if len(m) == 0 {
+			return undefinedValue{}
+		}
+	}
+	return v
+}
+
+// aliases returns the projected aliases in query order
+func (g *groupByAggregator) aliases() []string {
+	if len(g.info.GroupByAliases) > 0 {
+		return g.info.GroupByAliases
+	}
+	aliases := make([]string, 0, len(g.info.GroupByAliasToAggregateType))
+	for alias := range g.info.GroupByAliasToAggregateType {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+func (g *groupByAggregator) results() ([][]byte, error) {
+	results := make([][]byte, 0, len(g.keys))
+	for _, key := range g.keys {
+		group := g.groups[key]
+		value := func(alias string) interface{} {
+			if a, ok := group.aggregators[alias]; ok {
+				return a.result()
+			}
+			return group.values[alias]
+		}
+
+		if g.info.HasSelectValue {
+			aliases := g.aliases()
+			if len(aliases) == 0 {
+				continue
+			}
+			v := value(aliases[0])
+			if isUndefined(v) {
+				continue
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, b)
+			continue
+		}
+
+		// write properties in the order the query projects them
+		buf := bytes.Buffer{}
+		buf.WriteByte('{')
+		first := true
+		for _, alias := range g.aliases() {
+			v := value(alias)
+			if isUndefined(v) {
+				continue
+			}
+			k, err := json.Marshal(alias)
+			if err != nil {
+				return nil, err
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(k)
+			buf.WriteByte(':')
+			buf.Write(b)
+		}
+		buf.WriteByte('}')
+		results = append(results, buf.Bytes())
+	}
+
+	return results, nil
+}
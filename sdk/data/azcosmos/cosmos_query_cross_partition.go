@@ -0,0 +1,604 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/uuid"
+)
+
+// defaultCrossPartitionPageSize is the maximum number of items in a page of cross partition query
+// results when QueryOptions.PageSizeHint isn't positive.
+const defaultCrossPartitionPageSize = 100
+
+// crossPartitionContinuation is the continuation token of a cross partition query. It records the
+// progress of the query in each partition key range.
+type crossPartitionContinuation struct {
+	Ranges []rangeContinuation `json:"ranges"`
+	// Position is the number of results the query has produced, including results skipped by OFFSET.
+	Position int `json:"position,omitempty"`
+	// LastDistinct identifies the last result of an ordered DISTINCT query.
+	LastDistinct string `json:"lastDistinct,omitempty"`
+}
+
+type rangeContinuation struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
+	// Token is the service's continuation token for the range's next page.
+	Token string `json:"token,omitempty"`
+	// Skip is the number of items of that page the query has already returned.
+	Skip int  `json:"skip,omitempty"`
+	Done bool `json:"done,omitempty"`
+}
+
+// rangeProducer reads the results of a query from one partition key range, a page at a time.
+type rangeProducer struct {
+	pkRange partitionKeyRange
+	// pageToken is the continuation token the buffered page was read with
+	pageToken string
+	nextToken string
+	hasMore   bool
+	buffer    []json.RawMessage
+	consumed  int
+	// skip is the number of items to skip in the next page, when resuming a query
+	skip int
+	// orderByItems caches the decoded ORDER BY values of the next item
+	orderByItems []interface{}
+}
+
+func (p *rangeProducer) hasItem() bool {
+	return p.consumed < len(p.buffer)
+}
+
+func (p *rangeProducer) peek() json.RawMessage {
+	return p.buffer[p.consumed]
+}
+
+func (p *rangeProducer) pop() json.RawMessage {
+	item := p.buffer[p.consumed]
+	p.consumed++
+	p.orderByItems = nil
+	return item
+}
+
+func (p *rangeProducer) continuation() rangeContinuation {
+	rc := rangeContinuation{Min: p.pkRange.MinInclusive, Max: p.pkRange.MaxExclusive}
+	if p.hasItem() {
+		rc.Token, rc.Skip = p.pageToken, p.consumed
+	} else {
+		rc.Token, rc.Skip, rc.Done = p.nextToken, p.skip, !p.hasMore
+	}
+	return rc
+}
+
+// crossPartitionQueryExecutor executes a query in every partition key range it targets and merges the results.
+type crossPartitionQueryExecutor struct {
+	container            *ContainerClient
+	query                string
+	options              QueryOptions
+	correlatedActivityId uuid.UUID
+	// prefix is set when the query targets the items of a prefix partition key. epkRange limits the query
+	// to the effective partition keys of the prefix's items and of QueryOptions.FeedRange.
+	prefix   *PartitionKey
+	epkRange *FeedRange
+
+	initialized bool
+	done        bool
+	plan        queryPlan
+	producers   []*rangeProducer
+
+	position     int
+	lastDistinct string
+	materialized [][]byte
+
+	// mtx guards charge and lastResponse, which concurrent page reads update
+	mtx          sync.Mutex
+	charge       float32
+	lastResponse *http.Response
+}
+
+func newCrossPartitionQueryExecutor(container *ContainerClient, query string, options QueryOptions) *crossPartitionQueryExecutor {
+	correlatedActivityId, _ := uuid.New()
+	return &crossPartitionQueryExecutor{
+		container:            container,
+		query:                query,
+		options:              options,
+		correlatedActivityId: correlatedActivityId,
+	}
+}
+
+func (e *crossPartitionQueryExecutor) pageSize() int {
+	if e.options.PageSizeHint > 0 {
+		return int(e.options.PageSizeHint)
+	}
+	return defaultCrossPartitionPageSize
+}
+
+// limit returns the number of results the query produces including those skipped by OFFSET, or -1 when it's unlimited
+func (e *crossPartitionQueryExecutor) limit() int {
+	qi := e.plan.QueryInfo
+	switch {
+	case qi.Top != nil:
+		return *qi.Top
+	case qi.Limit != nil:
+		return e.offset() + *qi.Limit
+	}
+	return -1
+}
+
+func (e *crossPartitionQueryExecutor) offset() int {
+	if e.plan.QueryInfo.Offset != nil {
+		return *e.plan.QueryInfo.Offset
+	}
+	return 0
+}
+
+func (e *crossPartitionQueryExecutor) initialize(ctx context.Context) error {
+	if e.prefix != nil {
+		def, charge, err := e.container.getPartitionKeyDefinition(ctx)
+		if err != nil {
+			return err
+		}
+		e.addCharge(charge, nil)
+		epkRange, err := effectivePartitionKeyRange(def, *e.prefix)
+		if err != nil {
+			return err
+		}
+		e.epkRange = &epkRange
+	}
+	if fr := e.options.FeedRange; fr != nil {
+		switch {
+		case e.epkRange == nil:
+			e.epkRange = &FeedRange{MinInclusive: fr.MinInclusive, MaxExclusive: fr.MaxExclusive}
+		case e.epkRange.overlaps(*fr):
+			epkRange := e.epkRange.intersect(*fr)
+			e.epkRange = &epkRange
+		default:
+			// an empty range, which overlaps no partition key range
+			e.epkRange = &FeedRange{}
+		}
+	}
+
+	plan, charge, err := e.container.getQueryPlan(ctx, e.query, &e.options)
+	if err != nil {
+		return err
+	}
+	e.plan = plan
+	e.addCharge(charge, nil)
+
+	ranges, charge, err := e.container.getPartitionKeyRanges(ctx)
+	if err != nil {
+		return err
+	}
+	e.addCharge(charge, nil)
+
+	for _, pkRange := range ranges {
+		if e.epkRange != nil && !pkRange.feedRange().overlaps(*e.epkRange) {
+			continue
+		}
+		for _, r := range plan.QueryRanges {
+			if pkRange.overlaps(r) {
+				e.producers = append(e.producers, &rangeProducer{pkRange: pkRange, hasMore: true})
+				break
+			}
+		}
+	}
+
+	if e.options.ContinuationToken != "" {
+		if plan.QueryInfo.requiresFullDrain() {
+			return errors.New("continuation tokens aren't supported for cross partition queries having aggregates, GROUP BY or DISTINCT without ORDER BY")
+		}
+		if err := e.resume(e.options.ContinuationToken); err != nil {
+			return err
+		}
+	}
+
+	e.initialized = true
+	return nil
+}
+
+// resume restores the query's progress from a continuation token
+func (e *crossPartitionQueryExecutor) resume(token string) error {
+	var c crossPartitionContinuation
+	if err := json.Unmarshal([]byte(token), &c); err != nil {
+		return errors.New("invalid continuation token for a cross partition query")
+	}
+	e.position, e.lastDistinct = c.Position, c.LastDistinct
+
+	for _, p := range e.producers {
+		for _, rc := range c.Ranges {
+			if rc.Min == p.pkRange.MinInclusive && rc.Max == p.pkRange.MaxExclusive {
+				p.nextToken, p.skip, p.hasMore = rc.Token, rc.Skip, !rc.Done
+				break
+			}
+			if rc.Min <= p.pkRange.MinInclusive && p.pkRange.MaxExclusive <= rc.Max {
+				// the range split after the token was created. The parent's continuation token is valid for its
+				// children, however items of the parent's page can't be attributed to a child, so none are skipped.
+				p.nextToken, p.hasMore = rc.Token, !rc.Done
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *crossPartitionQueryExecutor) continuationToken() (string, error) {
+	if e.done || e.plan.QueryInfo.requiresFullDrain() {
+		return "", nil
+	}
+	c := crossPartitionContinuation{Position: e.position, LastDistinct: e.lastDistinct}
+	for _, p := range e.producers {
+		c.Ranges = append(c.Ranges, p.continuation())
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (e *crossPartitionQueryExecutor) addCharge(charge float32, resp *http.Response) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.charge += charge
+	if resp != nil {
+		e.lastResponse = resp
+	}
+}
+
+type queryDocumentsResponse struct {
+	Documents []json.RawMessage `json:"Documents"`
+}
+
+// fetch reads the next page of results from a producer's partition key range
+func (e *crossPartitionQueryExecutor) fetch(ctx context.Context, p *rangeProducer) error {
+	options := e.options
+	options.ContinuationToken = p.nextToken
+
+	h := headerOptionsOverride{
+		correlatedActivityId: &e.correlatedActivityId,
+	}
+	operationContext := pipelineRequestOptions{
+		resourceType:          resourceTypeDocument,
+		resourceAddress:       e.container.link,
+		headerOptionsOverride: &h,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeDocument, operationContext.resourceAddress, true)
+	if err != nil {
+		return err
+	}
+
+	azResponse, err := e.container.database.client.sendQueryRequest(
+		path,
+		ctx,
+		e.plan.QueryInfo.rangeQuery(e.query),
+		options.QueryParameters,
+		operationContext,
+		&options,
+		func(r *policy.Request) {
+			r.Raw().Header.Set(cosmosHeaderPartitionKeyRangeID, p.pkRange.ID)
+			r.Raw().Header.Set(cosmosHeaderEnableCrossPartitionQuery, "True")
+			if e.epkRange != nil {
+				fr := p.pkRange.feedRange().intersect(*e.epkRange)
+				r.Raw().Header.Set(cosmosHeaderStartEpk, fr.MinInclusive)
+				r.Raw().Header.Set(cosmosHeaderEndEpk, fr.MaxExclusive)
+			}
+		})
+	if err != nil {
+		return err
+	}
+	e.addCharge(newResponse(azResponse).RequestCharge, azResponse)
+
+	var page queryDocumentsResponse
+	if err := runtime.UnmarshalAsJSON(azResponse, &page); err != nil {
+		return err
+	}
+
+	p.pageToken = p.nextToken
+	p.nextToken = azResponse.Header.Get(cosmosHeaderContinuationToken)
+	p.hasMore = p.nextToken != ""
+	p.buffer = page.Documents
+	p.consumed = p.skip
+	if p.consumed > len(p.buffer) {
+		p.consumed = len(p.buffer)
+	}
+	p.skip = 0
+	p.orderByItems = nil
+	return nil
+}
+
+// fill reads pages until each producer has an item or has no more results. It reads from producers concurrently.
+func (e *crossPartitionQueryExecutor) fill(ctx context.Context, producers []*rangeProducer) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(producers))
+	for i, p := range producers {
+		if p.hasItem() || !p.hasMore {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p *rangeProducer) {
+			defer wg.Done()
+			for !p.hasItem() && p.hasMore {
+				if err := e.fetch(ctx, p); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next returns the next result in query order, or false when there are no more results
+func (e *crossPartitionQueryExecutor) next(ctx context.Context) (json.RawMessage, bool, error) {
+	if len(e.plan.QueryInfo.OrderBy) == 0 {
+		// results from each range in turn
+		for _, p := range e.producers {
+			if err := e.fill(ctx, []*rangeProducer{p}); err != nil {
+				return nil, false, err
+			}
+			if p.hasItem() {
+				return p.pop(), true, nil
+			}
+		}
+		return nil, false, nil
+	}
+
+	if err := e.fill(ctx, e.producers); err != nil {
+		return nil, false, err
+	}
+
+	var min *rangeProducer
+	for _, p := range e.producers {
+		if !p.hasItem() {
+			continue
+		}
+		if p.orderByItems == nil {
+			items, err := decodeOrderByItems(p.peek())
+			if err != nil {
+				return nil, false, err
+			}
+			p.orderByItems = items
+		}
+		// ties go to the earlier range, so the order of results is deterministic
+		if min == nil || e.compareOrderByItems(p.orderByItems, min.orderByItems) < 0 {
+			min = p
+		}
+	}
+	if min == nil {
+		return nil, false, nil
+	}
+
+	var doc orderByDocument
+	if err := json.Unmarshal(min.pop(), &doc); err != nil {
+		return nil, false, err
+	}
+	return doc.Payload, true, nil
+}
+
+type orderByDocument struct {
+	OrderByItems []map[string]interface{} `json:"orderByItems"`
+	Payload      json.RawMessage          `json:"payload"`
+}
+
+func decodeOrderByItems(item []byte) ([]interface{}, error) {
+	var doc orderByDocument
+	if err := json.Unmarshal(item, &doc); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(doc.OrderByItems))
+	for i, obi := range doc.OrderByItems {
+		values[i] = itemValue(obi)
+	}
+	return values, nil
+}
+
+func (e *crossPartitionQueryExecutor) compareOrderByItems(a, b []interface{}) int {
+	for i, order := range e.plan.QueryInfo.OrderBy {
+		if i >= len(a) || i >= len(b) {
+			break
+		}
+		c := compareQueryValues(a[i], b[i])
+		if order == sortOrderDescending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// nextPage returns the next page of results.
+func (e *crossPartitionQueryExecutor) nextPage(ctx context.Context) (QueryItemsResponse, error) {
+	e.mtx.Lock()
+	e.charge = 0
+	e.mtx.Unlock()
+	ctx, diagnostics := WithDiagnostics(ctx)
+
+	if !e.initialized {
+		if err := e.initialize(ctx); err != nil {
+			return QueryItemsResponse{}, err
+		}
+	}
+
+	var items [][]byte
+	var err error
+	if e.plan.QueryInfo.requiresFullDrain() {
+		items, err = e.nextMaterializedPage(ctx)
+	} else {
+		items, err = e.nextStreamingPage(ctx)
+	}
+	if err != nil {
+		return QueryItemsResponse{}, err
+	}
+
+	token, err := e.continuationToken()
+	if err != nil {
+		return QueryItemsResponse{}, err
+	}
+
+	response := QueryItemsResponse{Items: items, ContinuationToken: token}
+	if e.lastResponse != nil {
+		response.Response = newResponse(e.lastResponse)
+	}
+	response.RequestCharge = e.charge
+	response.Diagnostics = diagnostics
+	return response, nil
+}
+
+func (e *crossPartitionQueryExecutor) nextStreamingPage(ctx context.Context) ([][]byte, error) {
+	items := [][]byte{}
+	limit := e.limit()
+	ordered := e.plan.QueryInfo.DistinctType == distinctTypeOrdered
+	for len(items) < e.pageSize() {
+		if limit >= 0 && e.position >= limit {
+			e.done = true
+			break
+		}
+
+		item, ok, err := e.next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			e.done = true
+			break
+		}
+
+		if ordered {
+			// duplicates are adjacent in ordered results
+			key, err := distinctKey(item)
+			if err != nil {
+				return nil, err
+			}
+			if key == e.lastDistinct {
+				continue
+			}
+			e.lastDistinct = key
+		}
+
+		position := e.position
+		e.position++
+		if position >= e.offset() {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// nextMaterializedPage reads every result on its first call, then returns a page of them on each call
+func (e *crossPartitionQueryExecutor) nextMaterializedPage(ctx context.Context) ([][]byte, error) {
+	if e.materialized == nil {
+		results, err := e.drain(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		offset, limit := e.offset(), e.limit()
+		if limit >= 0 && limit < len(results) {
+			results = results[:limit]
+		}
+		if offset < len(results) {
+			results = results[offset:]
+		} else {
+			results = [][]byte{}
+		}
+		e.materialized = results
+	}
+
+	n := e.pageSize()
+	if n > len(e.materialized) {
+		n = len(e.materialized)
+	}
+	items := e.materialized[:n]
+	e.materialized = e.materialized[n:]
+	if len(e.materialized) == 0 {
+		e.done = true
+	}
+	return items, nil
+}
+
+// drain reads every result of an aggregate, GROUP BY or unordered DISTINCT query
+func (e *crossPartitionQueryExecutor) drain(ctx context.Context) ([][]byte, error) {
+	qi := e.plan.QueryInfo
+	var add func([]byte) error
+	var results func() ([][]byte, error)
+
+	switch {
+	case qi.hasGroupBy():
+		g := newGroupByAggregator(qi)
+		add, results = g.add, g.results
+	case qi.hasAggregates():
+		va, err := newValueAggregator(qi.Aggregates)
+		if err != nil {
+			return nil, err
+		}
+		add, results = va.add, va.results
+	default:
+		all := [][]byte{}
+		add = func(item []byte) error {
+			all = append(all, item)
+			return nil
+		}
+		results = func() ([][]byte, error) { return all, nil }
+	}
+
+	for {
+		if err := e.fill(ctx, e.producers); err != nil {
+			return nil, err
+		}
+		more := false
+		for _, p := range e.producers {
+			for p.hasItem() {
+				if err := add(p.pop()); err != nil {
+					return nil, err
+				}
+			}
+			more = more || p.hasMore
+		}
+		if !more {
+			break
+		}
+	}
+
+	r, err := results()
+	if err != nil {
+		return nil, err
+	}
+
+	if qi.DistinctType == distinctTypeNone || qi.DistinctType == "" {
+		return r, nil
+	}
+
+	distinct := make([][]byte, 0, len(r))
+	seen := map[string]bool{}
+	for _, item := range r {
+		key, err := distinctKey(item)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, item)
+		}
+	}
+	return distinct, nil
+}
@@ -0,0 +1,458 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+// recorded from the gateway for SELECT * FROM c ORDER BY c.n
+const orderByQueryPlan = `{"partitionedQueryExecutionInfoVersion":2,"queryInfo":{"distinctType":"None","top":null,"offset":null,"limit":null,"orderBy":["Ascending"],"orderByExpressions":["c.n"],"groupByExpressions":[],"groupByAliases":[],"aggregates":[],"groupByAliasToAggregateType":{},"rewrittenQuery":"SELECT c._rid, [{\"item\": c.n}] AS orderByItems, c AS payload\nFROM c\nWHERE ({documentdb-formattableorderbyquery-filter})\nORDER BY c.n","hasSelectValue":false},"queryRanges":[{"min":"","max":"FF","isMinInclusive":true,"isMaxInclusive":false}]}`
+
+// fakeQueryGateway serves query plans, partition key ranges and pages of query results
+type fakeQueryGateway struct {
+	plan      string
+	ranges    []partitionKeyRange
+	documents map[string][]string
+	pageSize  int
+	// properties are the container's properties
+	properties string
+
+	mtx     sync.Mutex
+	queries []string
+	headers []http.Header
+}
+
+func newFakeQueryGateway(plan string, documents map[string][]string) *fakeQueryGateway {
+	return &fakeQueryGateway{
+		plan:      plan,
+		ranges:    newFakeGatewayRanges("7F"),
+		documents: documents,
+		pageSize:  2,
+	}
+}
+
+func (g *fakeQueryGateway) Do(req *http.Request) (*http.Response, error) {
+	header := http.Header{}
+	header.Set(cosmosHeaderRequestCharge, "1")
+	body := ""
+	switch {
+	case isPartitionKeyRangesRequest(req):
+		body = partitionKeyRangesBody(g.ranges)
+	case req.Header.Get(cosmosHeaderIsQueryPlanRequest) == "True":
+		body = g.plan
+	case req.Method == http.MethodGet:
+		body = g.properties
+	default:
+		var q queryBody
+		if err := json.NewDecoder(req.Body).Decode(&q); err != nil {
+			return nil, err
+		}
+		if strings.Contains(q.Query, orderByFilterPlaceholder) {
+			return nil, fmt.Errorf("unexpected query %q", q.Query)
+		}
+		if req.Header.Get(cosmosHeaderEnableCrossPartitionQuery) != "True" {
+			return nil, fmt.Errorf("cross partition query isn't enabled")
+		}
+		id := req.Header.Get(cosmosHeaderPartitionKeyRangeID)
+		g.mtx.Lock()
+		g.queries = append(g.queries, id)
+		g.headers = append(g.headers, req.Header.Clone())
+		g.mtx.Unlock()
+
+		docs := g.documents[id]
+		start := 0
+		if token := req.Header.Get(cosmosHeaderContinuationToken); token != "" {
+			start, _ = strconv.Atoi(token)
+		}
+		end := start + g.pageSize
+		if end < len(docs) {
+			header.Set(cosmosHeaderContinuationToken, strconv.Itoa(end))
+		} else {
+			end = len(docs)
+		}
+		body = fmt.Sprintf(`{"_rid":"rid","Documents":[%s],"_count":%d}`, strings.Join(docs[start:end], ","), end-start)
+	}
+
+	return newFakeGatewayResponse(req, http.StatusOK, header, body), nil
+}
+
+func newTestQueryPlan(t *testing.T, qi queryInfo) string {
+	if qi.DistinctType == "" {
+		qi.DistinctType = distinctTypeNone
+	}
+	b, err := json.Marshal(queryPlan{
+		Version:     2,
+		QueryInfo:   qi,
+		QueryRanges: []queryRange{{Min: "", Max: "FF", IsMinInclusive: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func orderByDocs(values ...int) []string {
+	docs := make([]string, len(values))
+	for i, v := range values {
+		docs[i] = fmt.Sprintf(`{"_rid":"r%d","orderByItems":[{"item":%d}],"payload":{"n":%d}}`, v, v, v)
+	}
+	return docs
+}
+
+// queryAll returns every item of every page, and the pages' continuation tokens
+func queryAll(t *testing.T, pager *azruntime.Pager[QueryItemsResponse]) ([]string, []string) {
+	var items, tokens []string
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range page.Items {
+			items = append(items, string(item))
+		}
+		tokens = append(tokens, page.ContinuationToken)
+	}
+	return items, tokens
+}
+
+func assertItems(t *testing.T, expected, actual []string) {
+	t.Helper()
+	if strings.Join(expected, ",") != strings.Join(actual, ",") {
+		t.Fatalf("expected items\n%v\nbut got\n%v", expected, actual)
+	}
+}
+
+func TestCrossPartitionQueryParallel(t *testing.T) {
+	gateway := newFakeQueryGateway(newTestQueryPlan(t, queryInfo{}), map[string][]string{
+		"0": {`{"id":"a"}`, `{"id":"b"}`, `{"id":"c"}`},
+		"1": {`{"id":"d"}`, `{"id":"e"}`},
+	})
+	container := newFakeGatewayContainer(t, gateway)
+
+	pager := container.NewCrossPartitionQueryItemsPager("SELECT * FROM c", &QueryOptions{PageSizeHint: 4})
+	page, err := pager.NextPage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, []string{`{"id":"a"}`, `{"id":"b"}`, `{"id":"c"}`, `{"id":"d"}`}, itemsToStrings(page.Items))
+	if page.ContinuationToken == "" {
+		t.Fatal("expected a continuation token")
+	}
+	// query plan, partition key ranges and three pages of results
+	if page.RequestCharge != 5 {
+		t.Errorf("expected a request charge of 5, got %v", page.RequestCharge)
+	}
+
+	// resuming the query returns the remaining items
+	items, tokens := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT * FROM c", &QueryOptions{ContinuationToken: page.ContinuationToken}))
+	assertItems(t, []string{`{"id":"e"}`}, items)
+	if tokens[len(tokens)-1] != "" {
+		t.Errorf("expected no continuation token on the last page, got %q", tokens[len(tokens)-1])
+	}
+}
+
+func TestCrossPartitionQueryOrderBy(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		plan     string
+		expected []string
+	}{
+		{
+			name:     "ascending",
+			plan:     orderByQueryPlan,
+			expected: []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`, `{"n":5}`, `{"n":6}`},
+		},
+		{
+			name:     "top",
+			plan:     newTestQueryPlan(t, queryInfo{OrderBy: []sortOrder{sortOrderAscending}, Top: to.Ptr(3), RewrittenQuery: "SELECT TOP 3 c._rid, [{\"item\": c.n}] AS orderByItems, c AS payload FROM c ORDER BY c.n"}),
+			expected: []string{`{"n":1}`, `{"n":2}`, `{"n":3}`},
+		},
+		{
+			name:     "offset limit",
+			plan:     newTestQueryPlan(t, queryInfo{OrderBy: []sortOrder{sortOrderAscending}, Offset: to.Ptr(1), Limit: to.Ptr(3)}),
+			expected: []string{`{"n":2}`, `{"n":3}`, `{"n":4}`},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			gateway := newFakeQueryGateway(test.plan, map[string][]string{
+				"0": orderByDocs(1, 4, 5),
+				"1": orderByDocs(2, 3, 6),
+			})
+			container := newFakeGatewayContainer(t, gateway)
+			items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT * FROM c ORDER BY c.n", &QueryOptions{PageSizeHint: 2}))
+			assertItems(t, test.expected, items)
+		})
+	}
+}
+
+func TestCrossPartitionQueryOrderByDescendingResume(t *testing.T) {
+	plan := newTestQueryPlan(t, queryInfo{OrderBy: []sortOrder{sortOrderDescending}})
+	documents := map[string][]string{
+		"0": orderByDocs(9, 6, 5, 1),
+		"1": orderByDocs(8, 7, 3, 2),
+	}
+	expected := []string{`{"n":9}`, `{"n":8}`, `{"n":7}`, `{"n":6}`, `{"n":5}`, `{"n":3}`, `{"n":2}`, `{"n":1}`}
+
+	container := newFakeGatewayContainer(t, newFakeQueryGateway(plan, documents))
+	items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT * FROM c ORDER BY c.n DESC", nil))
+	assertItems(t, expected, items)
+
+	// resume after each page in turn, as a stateless web tier would
+	var resumed []string
+	token := ""
+	for {
+		container := newFakeGatewayContainer(t, newFakeQueryGateway(plan, documents))
+		pager := container.NewCrossPartitionQueryItemsPager("SELECT * FROM c ORDER BY c.n DESC", &QueryOptions{PageSizeHint: 3, ContinuationToken: token})
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed = append(resumed, itemsToStrings(page.Items)...)
+		if token = page.ContinuationToken; token == "" {
+			break
+		}
+	}
+	assertItems(t, expected, resumed)
+}
+
+func TestCrossPartitionQueryOrderedDistinct(t *testing.T) {
+	plan := newTestQueryPlan(t, queryInfo{OrderBy: []sortOrder{sortOrderAscending}, DistinctType: distinctTypeOrdered})
+	gateway := newFakeQueryGateway(plan, map[string][]string{
+		"0": orderByDocs(1, 2, 2, 3),
+		"1": orderByDocs(1, 3, 4),
+	})
+	container := newFakeGatewayContainer(t, gateway)
+	items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT DISTINCT VALUE c.n FROM c ORDER BY c.n", &QueryOptions{PageSizeHint: 2}))
+	assertItems(t, []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`}, items)
+}
+
+func TestCrossPartitionQueryUnorderedDistinct(t *testing.T) {
+	plan := newTestQueryPlan(t, queryInfo{DistinctType: distinctTypeUnordered})
+	gateway := newFakeQueryGateway(plan, map[string][]string{
+		"0": {`"a"`, `"b"`, `{"x":1,"y":2}`},
+		"1": {`"b"`, `"c"`, `{"y":2,"x":1}`},
+	})
+	container := newFakeGatewayContainer(t, gateway)
+	items, tokens := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT DISTINCT VALUE c.v FROM c", nil))
+	assertItems(t, []string{`"a"`, `"b"`, `"c"`, `{"x":1,"y":2}`}, items)
+	if len(tokens) != 1 || tokens[0] != "" {
+		t.Errorf("expected one page without a continuation token, got %v", tokens)
+	}
+}
+
+func TestCrossPartitionQueryValueAggregates(t *testing.T) {
+	for _, test := range []struct {
+		aggregate aggregateType
+		docs      map[string][]string
+		expected  []string
+	}{
+		{
+			aggregate: aggregateTypeCount,
+			docs:      map[string][]string{"0": {`[{"item":3}]`}, "1": {`[{"item":4}]`}},
+			expected:  []string{"7"},
+		},
+		{
+			aggregate: aggregateTypeSum,
+			docs:      map[string][]string{"0": {`[{"item":1.5}]`}, "1": {`[{"item":2}]`}},
+			expected:  []string{"3.5"},
+		},
+		{
+			aggregate: aggregateTypeAverage,
+			docs:      map[string][]string{"0": {`[{"item":{"sum":10,"count":4}}]`}, "1": {`[{"item":{"sum":5,"count":1}}]`}},
+			expected:  []string{"3"},
+		},
+		{
+			aggregate: aggregateTypeAverage,
+			docs:      map[string][]string{"0": {`[{"item":{"sum":null,"count":0}}]`}, "1": {`[{"item":{"sum":null,"count":0}}]`}},
+			expected:  nil,
+		},
+		{
+			aggregate: aggregateTypeMin,
+			docs:      map[string][]string{"0": {`[{"item":{"min":"b","count":2}}]`}, "1": {`[{"item":{"count":0}}]`}},
+			expected:  []string{`"b"`},
+		},
+		{
+			aggregate: aggregateTypeMax,
+			docs:      map[string][]string{"0": {`[{"item":4}]`}, "1": {`[{"item":9}]`}},
+			expected:  []string{"9"},
+		},
+		{
+			aggregate: aggregateTypeMax,
+			docs:      map[string][]string{"0": {`[{}]`}, "1": {`[{}]`}},
+			expected:  nil,
+		},
+	} {
+		t.Run(string(test.aggregate), func(t *testing.T) {
+			plan := newTestQueryPlan(t, queryInfo{Aggregates: []aggregateType{test.aggregate}, HasSelectValue: true})
+			container := newFakeGatewayContainer(t, newFakeQueryGateway(plan, test.docs))
+			items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT VALUE AGG(c.n) FROM c", nil))
+			assertItems(t, test.expected, items)
+		})
+	}
+}
+
+func TestCrossPartitionQueryGroupBy(t *testing.T) {
+	count, sum := aggregateTypeCount, aggregateTypeSum
+	plan := newTestQueryPlan(t, queryInfo{
+		GroupByExpressions:          []string{"c.team"},
+		GroupByAliases:              []string{"team", "members", "points"},
+		GroupByAliasToAggregateType: map[string]*aggregateType{"team": nil, "members": &count, "points": &sum},
+	})
+	gateway := newFakeQueryGateway(plan, map[string][]string{
+		"0": {
+			`{"groupByItems":[{"item":"red"}],"payload":{"team":"red","members":{"item":2},"points":{"item":10}}}`,
+			`{"groupByItems":[{"item":"blue"}],"payload":{"team":"blue","members":{"item":1},"points":{"item":4}}}`,
+		},
+		"1": {
+			`{"groupByItems":[{"item":"blue"}],"payload":{"team":"blue","members":{"item":3},"points":{"item":6}}}`,
+		},
+	})
+	container := newFakeGatewayContainer(t, gateway)
+	items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT c.team, COUNT(1) AS members, SUM(c.points) AS points FROM c GROUP BY c.team", nil))
+	assertItems(t, []string{
+		`{"team":"red","members":2,"points":10}`,
+		`{"team":"blue","members":4,"points":10}`,
+	}, items)
+}
+
+func TestCrossPartitionQueryNonValueAggregate(t *testing.T) {
+	count := aggregateTypeCount
+	plan := newTestQueryPlan(t, queryInfo{
+		GroupByAliases:              []string{"total"},
+		GroupByAliasToAggregateType: map[string]*aggregateType{"total": &count},
+	})
+	gateway := newFakeQueryGateway(plan, map[string][]string{
+		"0": {`{"groupByItems":[],"payload":{"total":{"item":2}}}`},
+		"1": {`{"groupByItems":[],"payload":{"total":{"item":0}}}`},
+	})
+	container := newFakeGatewayContainer(t, gateway)
+	items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT COUNT(1) AS total FROM c", nil))
+	assertItems(t, []string{`{"total":2}`}, items)
+}
+
+func TestCrossPartitionQueryTargetsOverlappingRanges(t *testing.T) {
+	plan := `{"partitionedQueryExecutionInfoVersion":2,"queryInfo":{"distinctType":"None"},"queryRanges":[{"min":"05C1","max":"05C1","isMinInclusive":true,"isMaxInclusive":true}]}`
+	gateway := newFakeQueryGateway(plan, map[string][]string{
+		"0": {`{"id":"a"}`},
+		"1": {`{"id":"b"}`},
+	})
+	container := newFakeGatewayContainer(t, gateway)
+	items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT * FROM c WHERE c.pk = 'x'", nil))
+	assertItems(t, []string{`{"id":"a"}`}, items)
+	if len(gateway.queries) != 1 || gateway.queries[0] != "0" {
+		t.Errorf("expected a query of range 0 only, got %v", gateway.queries)
+	}
+}
+
+func TestPrefixPartitionKeyQuery(t *testing.T) {
+	def := PartitionKeyDefinition{Paths: []string{"/tenantId", "/userId", "/sessionId"}, Kind: PartitionKeyKindMultiHash, Version: 2}
+	prefix := NewPartitionKey().AppendString("tenant")
+	epk, err := effectivePartitionKey(def, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gateway := newFakeQueryGateway(newTestQueryPlan(t, queryInfo{}), map[string][]string{
+		"0": {`{"id":"a"}`},
+		"1": {`{"id":"b"}`},
+		"2": {`{"id":"c"}`},
+	})
+	gateway.properties = `{"id":"container","partitionKey":{"paths":["/tenantId","/userId","/sessionId"],"kind":"MultiHash","version":2}}`
+	gateway.ranges = []partitionKeyRange{
+		{ID: "0", MinInclusive: "", MaxExclusive: epk},
+		{ID: "1", MinInclusive: epk, MaxExclusive: epk + "2"},
+		{ID: "2", MinInclusive: epk + "2", MaxExclusive: "FF"},
+	}
+	container := newFakeGatewayContainer(t, gateway)
+
+	items, _ := queryAll(t, container.NewPrefixPartitionKeyQueryItemsPager("SELECT * FROM c", prefix, nil))
+	assertItems(t, []string{`{"id":"b"}`, `{"id":"c"}`}, items)
+	if strings.Join(gateway.queries, ",") != "1,2" {
+		t.Fatalf("expected queries of ranges 1 and 2, got %v", gateway.queries)
+	}
+	for i, expected := range []FeedRange{{MinInclusive: epk, MaxExclusive: epk + "2"}, {MinInclusive: epk + "2", MaxExclusive: epk + "FF"}} {
+		h := gateway.headers[i]
+		if h.Get(cosmosHeaderStartEpk) != expected.MinInclusive || h.Get(cosmosHeaderEndEpk) != expected.MaxExclusive {
+			t.Errorf("expected range %s to be queried from %s to %s, got %s to %s", gateway.queries[i],
+				expected.MinInclusive, expected.MaxExclusive, h.Get(cosmosHeaderStartEpk), h.Get(cosmosHeaderEndEpk))
+		}
+	}
+
+	gateway.properties = `{"id":"container","partitionKey":{"paths":["/pk"],"kind":"Hash","version":2}}`
+	pager := container.NewPrefixPartitionKeyQueryItemsPager("SELECT * FROM c", prefix, nil)
+	if _, err := pager.NextPage(context.Background()); err == nil {
+		t.Fatal("expected an error for a container without hierarchical partition keys")
+	}
+}
+
+func TestCrossPartitionQueryFeedRange(t *testing.T) {
+	gateway := newFakeQueryGateway(newTestQueryPlan(t, queryInfo{}), map[string][]string{
+		"0": {`{"id":"a"}`},
+		"1": {`{"id":"b"}`},
+	})
+	container := newFakeGatewayContainer(t, gateway)
+
+	feedRange := FeedRange{MinInclusive: "80", MaxExclusive: "C0"}
+	items, _ := queryAll(t, container.NewCrossPartitionQueryItemsPager("SELECT * FROM c", &QueryOptions{FeedRange: &feedRange}))
+	assertItems(t, []string{`{"id":"b"}`}, items)
+	if len(gateway.queries) != 1 || gateway.queries[0] != "1" {
+		t.Fatalf("expected a query of range 1 only, got %v", gateway.queries)
+	}
+	if h := gateway.headers[0]; h.Get(cosmosHeaderStartEpk) != "80" || h.Get(cosmosHeaderEndEpk) != "C0" {
+		t.Errorf("expected the feed range to be queried, got %s to %s", h.Get(cosmosHeaderStartEpk), h.Get(cosmosHeaderEndEpk))
+	}
+
+	// a feed range outside a prefix partition key's range targets no range
+	gateway.properties = `{"id":"container","partitionKey":{"paths":["/tenantId","/userId"],"kind":"MultiHash","version":2}}`
+	prefix := NewPartitionKey().AppendString("tenant")
+	prefixRange, err := effectivePartitionKeyRange(PartitionKeyDefinition{Paths: []string{"/tenantId", "/userId"}, Kind: PartitionKeyKindMultiHash, Version: 2}, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := FeedRange{MinInclusive: prefixRange.MaxExclusive, MaxExclusive: "FF"}
+	items, _ = queryAll(t, container.NewPrefixPartitionKeyQueryItemsPager("SELECT * FROM c", prefix, &QueryOptions{FeedRange: &outside}))
+	if len(items) != 0 || len(gateway.queries) != 1 {
+		t.Errorf("expected no queries, got %v", gateway.queries[1:])
+	}
+}
+
+func TestCrossPartitionQueryContinuationUnsupported(t *testing.T) {
+	plan := newTestQueryPlan(t, queryInfo{Aggregates: []aggregateType{aggregateTypeCount}, HasSelectValue: true})
+	container := newFakeGatewayContainer(t, newFakeQueryGateway(plan, nil))
+	pager := container.NewCrossPartitionQueryItemsPager("SELECT VALUE COUNT(1) FROM c", &QueryOptions{ContinuationToken: `{"ranges":[]}`})
+	if _, err := pager.NextPage(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCompareQueryValues(t *testing.T) {
+	ordered := []interface{}{undefinedValue{}, nil, false, true, float64(-1), float64(2), "a", "b", []interface{}{float64(1)}, map[string]interface{}{"a": float64(1)}}
+	for i := range ordered {
+		for j := range ordered {
+			c := compareQueryValues(ordered[i], ordered[j])
+			if (i < j && c >= 0) || (i == j && c != 0) || (i > j && c <= 0) {
+				t.Errorf("unexpected comparison %d of %v and %v", c, ordered[i], ordered[j])
+			}
+		}
+	}
+}
+
+func itemsToStrings(items [][]byte) []string {
+	s := make([]string, len(items))
+	for i, item := range items {
+		s[i] = string(item)
+	}
+	return s
+}
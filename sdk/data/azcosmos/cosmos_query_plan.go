@@ -0,0 +1,134 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// supportedQueryFeatures are the query features the client can execute across partitions.
+// The gateway rejects query plan requests for queries using other features.
+const supportedQueryFeatures string = "Aggregate, CompositeAggregate, Distinct, MultipleOrderBy, OffsetAndLimit, OrderBy, Top, GroupBy, MultipleAggregates, NonValueAggregate"
+
+// orderByFilterPlaceholder appears in rewritten ORDER BY queries in place of a filter for resuming queries.
+const orderByFilterPlaceholder string = "{documentdb-formattableorderbyquery-filter}"
+
+type distinctType string
+
+const (
+	distinctTypeNone      distinctType = "None"
+	distinctTypeOrdered   distinctType = "Ordered"
+	distinctTypeUnordered distinctType = "Unordered"
+)
+
+type sortOrder string
+
+const (
+	sortOrderAscending  sortOrder = "Ascending"
+	sortOrderDescending sortOrder = "Descending"
+)
+
+type aggregateType string
+
+const (
+	aggregateTypeAverage aggregateType = "Average"
+	aggregateTypeCount   aggregateType = "Count"
+	aggregateTypeMax     aggregateType = "Max"
+	aggregateTypeMin     aggregateType = "Min"
+	aggregateTypeSum     aggregateType = "Sum"
+)
+
+// queryPlan describes how to execute a query across partitions. The gateway computes it.
+type queryPlan struct {
+	Version     int          `json:"partitionedQueryExecutionInfoVersion"`
+	QueryInfo   queryInfo    `json:"queryInfo"`
+	QueryRanges []queryRange `json:"queryRanges"`
+}
+
+type queryInfo struct {
+	DistinctType                distinctType              `json:"distinctType"`
+	Top                         *int                      `json:"top"`
+	Offset                      *int                      `json:"offset"`
+	Limit                       *int                      `json:"limit"`
+	OrderBy                     []sortOrder               `json:"orderBy"`
+	OrderByExpressions          []string                  `json:"orderByExpressions"`
+	GroupByExpressions          []string                  `json:"groupByExpressions"`
+	GroupByAliases              []string                  `json:"groupByAliases"`
+	Aggregates                  []aggregateType           `json:"aggregates"`
+	GroupByAliasToAggregateType map[string]*aggregateType `json:"groupByAliasToAggregateType"`
+	RewrittenQuery              string                    `json:"rewrittenQuery"`
+	HasSelectValue              bool                      `json:"hasSelectValue"`
+}
+
+// queryRange is a range of effective partition key values a query targets.
+type queryRange struct {
+	Min            string `json:"min"`
+	Max            string `json:"max"`
+	IsMinInclusive bool   `json:"isMinInclusive"`
+	IsMaxInclusive bool   `json:"isMaxInclusive"`
+}
+
+// hasGroupBy returns true when the query groups results, including queries such as
+// SELECT COUNT(1) AS c FROM c which aggregate without a VALUE projection.
+func (qi queryInfo) hasGroupBy() bool {
+	return len(qi.GroupByExpressions) > 0 || len(qi.GroupByAliasToAggregateType) > 0
+}
+
+// hasAggregates returns true when the query is a VALUE aggregate such as SELECT VALUE COUNT(1) FROM c
+func (qi queryInfo) hasAggregates() bool {
+	return len(qi.Aggregates) > 0 && !qi.hasGroupBy()
+}
+
+// requiresFullDrain returns true when the client must read every result before returning any.
+func (qi queryInfo) requiresFullDrain() bool {
+	return qi.hasAggregates() || qi.hasGroupBy() || qi.DistinctType == distinctTypeUnordered
+}
+
+// rangeQuery returns the query to send to each partition key range.
+func (qi queryInfo) rangeQuery(query string) string {
+	if qi.RewrittenQuery == "" {
+		return query
+	}
+	return strings.ReplaceAll(qi.RewrittenQuery, orderByFilterPlaceholder, "true")
+}
+
+// getQueryPlan requests the query plan for a query from the gateway.
+func (c *ContainerClient) getQueryPlan(ctx context.Context, query string, o *QueryOptions) (queryPlan, float32, error) {
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeDocument,
+		resourceAddress: c.link,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeDocument, operationContext.resourceAddress, true)
+	if err != nil {
+		return queryPlan{}, 0, err
+	}
+
+	azResponse, err := c.database.client.sendQueryRequest(
+		path,
+		ctx,
+		query,
+		o.QueryParameters,
+		operationContext,
+		nil,
+		func(r *policy.Request) {
+			r.Raw().Header.Set(cosmosHeaderIsQueryPlanRequest, "True")
+			r.Raw().Header.Set(cosmosHeaderSupportedQueryFeatures, supportedQueryFeatures)
+			r.Raw().Header.Set(cosmosHeaderQueryVersion, "1.0")
+		})
+	if err != nil {
+		return queryPlan{}, 0, err
+	}
+
+	plan := queryPlan{}
+	if err := runtime.UnmarshalAsJSON(azResponse, &plan); err != nil {
+		return queryPlan{}, 0, err
+	}
+
+	return plan, newResponse(azResponse).RequestCharge, nil
+}
@@ -34,6 +34,9 @@ type QueryOptions struct {
 	// QueryParameters allows execution of parametrized queries.
 	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
 	QueryParameters []QueryParameter
+	// FeedRange limits a cross partition query to the items in part of the container, for example a range
+	// returned by ContainerClient.GetFeedRanges. Single partition queries ignore it.
+	FeedRange *FeedRange
 }
 
 func (options *QueryOptions) toHeaders() *map[string]string {
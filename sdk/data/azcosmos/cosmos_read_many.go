@@ -0,0 +1,239 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	defaultReadManyMaxConcurrency = 10
+	defaultReadManyPageSizeHint   = 100
+)
+
+// ItemIdentity identifies an item by its id and partition key.
+type ItemIdentity struct {
+	// ID is the id of the item.
+	ID string
+	// PartitionKey is the partition key of the item.
+	PartitionKey PartitionKey
+}
+
+// ReadManyOptions includes options for ContainerClient.ReadManyItems.
+type ReadManyOptions struct {
+	// SessionToken to be used when using Session consistency on the account.
+	SessionToken string
+	// ConsistencyLevel overrides the account defined consistency level for this operation.
+	// Consistency can only be relaxed.
+	ConsistencyLevel *ConsistencyLevel
+	// MaxConcurrency limits the number of requests in flight. Defaults to 10.
+	MaxConcurrency int
+	// PageSizeHint limits the number of items each query reads, and the number of items in each page of the
+	// query results. Defaults to 100.
+	PageSizeHint int32
+}
+
+// ReadManyItemsResponse contains the items read by ContainerClient.ReadManyItems.
+type ReadManyItemsResponse struct {
+	// RequestCharge is the sum of the request charges of the requests made to read the items.
+	RequestCharge float32
+	// Items contains the items which exist, in the order of the identities which identify them.
+	Items [][]byte
+	// NotFound contains the identities of the items which don't exist.
+	NotFound []ItemIdentity
+}
+
+// ReadManyItems reads items by their id and partition key. The items are grouped by partition key, and each
+// group is read with a point read when it has a single item, or with queries otherwise. Groups are read
+// concurrently. Items which don't exist are reported in ReadManyItemsResponse.NotFound rather than as an error.
+// Repeated identities are read once.
+// ctx - The context for the requests.
+// items - The identities of the items to read.
+// o - Options for the operation.
+func (c *ContainerClient) ReadManyItems(ctx context.Context, items []ItemIdentity, o *ReadManyOptions) (ReadManyItemsResponse, error) {
+	options := ReadManyOptions{}
+	if o != nil {
+		options = *o
+	}
+	if options.MaxConcurrency <= 0 {
+		options.MaxConcurrency = defaultReadManyMaxConcurrency
+	}
+	if options.PageSizeHint <= 0 {
+		options.PageSizeHint = defaultReadManyPageSizeHint
+	}
+
+	// group the ids by partition key, dropping repeated identities
+	groups := map[string]*readManyGroup{}
+	keys := make([]string, len(items))
+	tasks := []readManyTask{}
+	for i, item := range items {
+		key, err := item.PartitionKey.toJsonString()
+		if err != nil {
+			return ReadManyItemsResponse{}, err
+		}
+		keys[i] = key
+		group, ok := groups[key]
+		if !ok {
+			group = &readManyGroup{partitionKey: item.PartitionKey, items: map[string][]byte{}}
+			groups[key] = group
+		}
+		if _, ok := group.items[item.ID]; ok {
+			continue
+		}
+		group.items[item.ID] = nil
+		group.ids = append(group.ids, item.ID)
+	}
+	for _, group := range groups {
+		for start := 0; start < len(group.ids); start += int(options.PageSizeHint) {
+			end := start + int(options.PageSizeHint)
+			if end > len(group.ids) {
+				end = len(group.ids)
+			}
+			tasks = append(tasks, readManyTask{group: group, ids: group.ids[start:end]})
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mtx      sync.Mutex
+		charge   float32
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	queue := make(chan readManyTask)
+	workers := options.MaxConcurrency
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				found, taskCharge, err := c.readMany(ctx, task, options)
+				mtx.Lock()
+				charge += taskCharge
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				for id, item := range found {
+					task.group.items[id] = item
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for _, task := range tasks {
+		select {
+		case queue <- task:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return ReadManyItemsResponse{}, firstErr
+	}
+
+	response := ReadManyItemsResponse{RequestCharge: charge}
+	type identity struct{ partitionKey, id string }
+	reported := map[identity]bool{}
+	for i, item := range items {
+		key := identity{partitionKey: keys[i], id: item.ID}
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+		if found := groups[key.partitionKey].items[item.ID]; found != nil {
+			response.Items = append(response.Items, found)
+		} else {
+			response.NotFound = append(response.NotFound, item)
+		}
+	}
+	return response, nil
+}
+
+// readManyGroup is the set of ids read from one logical partition
+type readManyGroup struct {
+	partitionKey PartitionKey
+	ids          []string
+	// items maps each id to its item, which is nil until the item is found
+	items map[string][]byte
+}
+
+// readManyTask is a request, or the pages of a query, reading some of a group's ids
+type readManyTask struct {
+	group *readManyGroup
+	ids   []string
+}
+
+// readMany reads the items of a task, returning the items found by id and the request charge.
+func (c *ContainerClient) readMany(ctx context.Context, task readManyTask, options ReadManyOptions) (map[string][]byte, float32, error) {
+	found := map[string][]byte{}
+	if len(task.ids) == 1 {
+		response, err := c.ReadItem(ctx, task.group.partitionKey, task.ids[0], &ItemOptions{
+			SessionToken:     options.SessionToken,
+			ConsistencyLevel: options.ConsistencyLevel,
+		})
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound && respErr.RawResponse != nil {
+			return found, newResponse(respErr.RawResponse).RequestCharge, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		found[task.ids[0]] = response.Value
+		return found, response.RequestCharge, nil
+	}
+
+	names := make([]string, len(task.ids))
+	parameters := make([]QueryParameter, len(task.ids))
+	for i, id := range task.ids {
+		names[i] = fmt.Sprintf("@id%d", i)
+		parameters[i] = QueryParameter{Name: names[i], Value: id}
+	}
+	query := fmt.Sprintf("SELECT * FROM c WHERE c.id IN (%s)", strings.Join(names, ", "))
+
+	var charge float32
+	pager := c.NewQueryItemsPager(query, task.group.partitionKey, &QueryOptions{
+		SessionToken:     options.SessionToken,
+		ConsistencyLevel: options.ConsistencyLevel,
+		PageSizeHint:     options.PageSizeHint,
+		QueryParameters:  parameters,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, charge, err
+		}
+		charge += page.RequestCharge
+		for _, item := range page.Items {
+			var doc struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(item, &doc); err != nil {
+				return nil, charge, err
+			}
+			found[doc.ID] = item
+		}
+	}
+	return found, charge, nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// fakeReadManyGateway serves point reads and queries of items stored by partition key and id
+type fakeReadManyGateway struct {
+	mtx      sync.Mutex
+	items    map[string]map[string]string
+	reads    int
+	queries  []queryBody
+	inFlight int
+	maxSeen  int
+	failWith int
+}
+
+func (g *fakeReadManyGateway) Do(req *http.Request) (*http.Response, error) {
+	g.mtx.Lock()
+	g.inFlight++
+	if g.inFlight > g.maxSeen {
+		g.maxSeen = g.inFlight
+	}
+	g.mtx.Unlock()
+	defer func() {
+		g.mtx.Lock()
+		g.inFlight--
+		g.mtx.Unlock()
+	}()
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	header := http.Header{}
+	header.Set(cosmosHeaderRequestCharge, "1")
+	respond := func(status int, body string) (*http.Response, error) {
+		return newFakeGatewayResponse(req, status, header, body), nil
+	}
+	if g.failWith != 0 {
+		return respond(g.failWith, `{"code":"BadRequest"}`)
+	}
+
+	partition := g.items[req.Header.Get(cosmosHeaderPartitionKey)]
+	if req.Method == http.MethodGet {
+		g.reads++
+		segments := strings.Split(req.URL.Path, "/")
+		if item, ok := partition[segments[len(segments)-1]]; ok {
+			return respond(http.StatusOK, item)
+		}
+		return respond(http.StatusNotFound, `{"code":"NotFound"}`)
+	}
+
+	var query queryBody
+	if err := json.NewDecoder(req.Body).Decode(&query); err != nil {
+		return nil, err
+	}
+	g.queries = append(g.queries, query)
+	docs := []string{}
+	for _, p := range query.Parameters {
+		if item, ok := partition[p.Value.(string)]; ok {
+			docs = append(docs, item)
+		}
+	}
+	header.Set(cosmosHeaderRequestCharge, "3")
+	return respond(http.StatusOK, `{"Documents":[`+strings.Join(docs, ",")+`]}`)
+}
+
+func newFakeReadManyGateway(partitions, itemsPerPartition int) *fakeReadManyGateway {
+	g := &fakeReadManyGateway{items: map[string]map[string]string{}}
+	for p := 0; p < partitions; p++ {
+		pk := fmt.Sprintf(`["pk-%d"]`, p)
+		g.items[pk] = map[string]string{}
+		for i := 0; i < itemsPerPartition; i++ {
+			id := fmt.Sprintf("%d-%d", p, i)
+			g.items[pk][id] = fmt.Sprintf(`{"id":"%s","pk":"pk-%d"}`, id, p)
+		}
+	}
+	return g
+}
+
+func itemID(t *testing.T, item []byte) string {
+	var doc struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(item, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.ID
+}
+
+func TestReadManyItems(t *testing.T) {
+	gateway := newFakeReadManyGateway(3, 5)
+	container := newFakeGatewayContainer(t, gateway)
+
+	identities := []ItemIdentity{
+		{ID: "0-3", PartitionKey: NewPartitionKeyString("pk-0")},
+		{ID: "1-0", PartitionKey: NewPartitionKeyString("pk-1")},
+		{ID: "0-1", PartitionKey: NewPartitionKeyString("pk-0")},
+		{ID: "0-9", PartitionKey: NewPartitionKeyString("pk-0")},
+		{ID: "0-3", PartitionKey: NewPartitionKeyString("pk-0")},
+		{ID: "2-2", PartitionKey: NewPartitionKeyString("pk-1")},
+		{ID: "2-2", PartitionKey: NewPartitionKeyString("pk-2")},
+	}
+	response, err := container.ReadManyItems(context.Background(), identities, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+	for _, item := range response.Items {
+		ids = append(ids, itemID(t, item))
+	}
+	if strings.Join(ids, ",") != "0-3,1-0,0-1,2-2" {
+		t.Errorf("unexpected items %v", ids)
+	}
+	if len(response.NotFound) != 2 || response.NotFound[0].ID != "0-9" || response.NotFound[1].ID != "2-2" {
+		t.Errorf("unexpected not found items %v", response.NotFound)
+	}
+
+	// pk-0 and pk-1 are queried, and the single item of pk-2 is a point read
+	if gateway.reads != 1 || len(gateway.queries) != 2 {
+		t.Fatalf("expected a point read and 2 queries, got %d and %d", gateway.reads, len(gateway.queries))
+	}
+	queries := []string{gateway.queries[0].Query, gateway.queries[1].Query}
+	sort.Strings(queries)
+	if queries[0] != "SELECT * FROM c WHERE c.id IN (@id0, @id1)" || queries[1] != "SELECT * FROM c WHERE c.id IN (@id0, @id1, @id2)" {
+		t.Errorf("unexpected queries %v", queries)
+	}
+	if response.RequestCharge != 7 {
+		t.Errorf("expected a request charge of 7, got %v", response.RequestCharge)
+	}
+}
+
+func TestReadManyItemsPageSize(t *testing.T) {
+	gateway := newFakeReadManyGateway(4, 25)
+	container := newFakeGatewayContainer(t, gateway)
+
+	identities := []ItemIdentity{}
+	for p := 0; p < 4; p++ {
+		for i := 0; i < 25; i++ {
+			identities = append(identities, ItemIdentity{ID: fmt.Sprintf("%d-%d", p, i), PartitionKey: NewPartitionKeyString(fmt.Sprintf("pk-%d", p))})
+		}
+	}
+	response, err := container.ReadManyItems(context.Background(), identities, &ReadManyOptions{MaxConcurrency: 2, PageSizeHint: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Items) != 100 || len(response.NotFound) != 0 {
+		t.Fatalf("expected 100 items, got %d", len(response.Items))
+	}
+	for i, item := range response.Items {
+		if id := itemID(t, item); id != identities[i].ID {
+			t.Fatalf("expected item %s at %d, got %s", identities[i].ID, i, id)
+		}
+	}
+
+	sizes := []int{}
+	for _, q := range gateway.queries {
+		sizes = append(sizes, len(q.Parameters))
+	}
+	sort.Ints(sizes)
+	if fmt.Sprint(sizes) != "[5 5 5 5 10 10 10 10 10 10 10 10]" {
+		t.Errorf("unexpected query sizes %v", sizes)
+	}
+	if gateway.maxSeen > 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", gateway.maxSeen)
+	}
+}
+
+func TestReadManyItemsError(t *testing.T) {
+	gateway := newFakeReadManyGateway(2, 2)
+	gateway.failWith = http.StatusBadRequest
+	container := newFakeGatewayContainer(t, gateway)
+
+	_, err := container.ReadManyItems(context.Background(), []ItemIdentity{
+		{ID: "0-0", PartitionKey: NewPartitionKeyString("pk-0")},
+		{ID: "1-0", PartitionKey: NewPartitionKeyString("pk-1")},
+		{ID: "1-1", PartitionKey: NewPartitionKeyString("pk-1")},
+	}, nil)
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a response error, got %v", err)
+	}
+}
+
+func TestReadManyItemsEmpty(t *testing.T) {
+	container := newFakeGatewayContainer(t, newFakeReadManyGateway(0, 0))
+	response, err := container.ReadManyItems(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Items) != 0 || len(response.NotFound) != 0 || response.RequestCharge != 0 {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
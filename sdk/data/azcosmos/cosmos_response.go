@@ -21,6 +21,11 @@ type Response struct {
 	ActivityID string
 	// ETag contains the value from the ETag header.
 	ETag azcore.ETag
+	// ContactedRegion contains the name of the region which served the request. It's empty when
+	// the client doesn't know the account's regions.
+	ContactedRegion string
+	// Diagnostics contains the attempts made to send the request, including retries.
+	Diagnostics *Diagnostics
 }
 
 func newResponse(resp *http.Response) Response {
@@ -29,6 +34,8 @@ func newResponse(resp *http.Response) Response {
 	response.RequestCharge = response.readRequestCharge()
 	response.ActivityID = resp.Header.Get(cosmosHeaderActivityId)
 	response.ETag = azcore.ETag(resp.Header.Get(cosmosHeaderEtag))
+	response.ContactedRegion = contactedRegion(resp)
+	response.Diagnostics = diagnosticsOf(resp)
 	return response
 }
 
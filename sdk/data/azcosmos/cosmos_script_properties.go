@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// StoredProcedureProperties represents the properties of a stored procedure.
+type StoredProcedureProperties struct {
+	// ID contains the unique id of the stored procedure.
+	ID string `json:"id"`
+	// Body contains the JavaScript function of the stored procedure.
+	Body string `json:"body"`
+	// ETag contains the entity etag of the stored procedure.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the stored procedure.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the stored procedure.
+	ResourceID string `json:"_rid,omitempty"`
+	// LastModified contains the last modified time of the stored procedure.
+	LastModified time.Time `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (p *StoredProcedureProperties) UnmarshalJSON(b []byte) error {
+	type properties StoredProcedureProperties
+	return unmarshalTimestampedProperties(b, (*properties)(p), &p.LastModified)
+}
+
+// TriggerProperties represents the properties of a trigger.
+type TriggerProperties struct {
+	// ID contains the unique id of the trigger.
+	ID string `json:"id"`
+	// Body contains the JavaScript function of the trigger.
+	Body string `json:"body"`
+	// Type defines whether the trigger runs before or after an operation.
+	Type TriggerType `json:"triggerType"`
+	// Operation defines the operations which run the trigger.
+	Operation TriggerOperation `json:"triggerOperation"`
+	// ETag contains the entity etag of the trigger.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the trigger.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the trigger.
+	ResourceID string `json:"_rid,omitempty"`
+	// LastModified contains the last modified time of the trigger.
+	LastModified time.Time `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (p *TriggerProperties) UnmarshalJSON(b []byte) error {
+	type properties TriggerProperties
+	return unmarshalTimestampedProperties(b, (*properties)(p), &p.LastModified)
+}
+
+// UserDefinedFunctionProperties represents the properties of a user-defined function.
+type UserDefinedFunctionProperties struct {
+	// ID contains the unique id of the user-defined function. Queries call the function as udf.<ID>.
+	ID string `json:"id"`
+	// Body contains the JavaScript function of the user-defined function.
+	Body string `json:"body"`
+	// ETag contains the entity etag of the user-defined function.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the user-defined function.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the user-defined function.
+	ResourceID string `json:"_rid,omitempty"`
+	// LastModified contains the last modified time of the user-defined function.
+	LastModified time.Time `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (p *UserDefinedFunctionProperties) UnmarshalJSON(b []byte) error {
+	type properties UserDefinedFunctionProperties
+	return unmarshalTimestampedProperties(b, (*properties)(p), &p.LastModified)
+}
+
+// unmarshalTimestampedProperties unmarshals the properties of a resource, which must not implement json.Unmarshaler,
+// and its timestamp.
+func unmarshalTimestampedProperties(b []byte, properties interface{}, lastModified *time.Time) error {
+	if err := json.Unmarshal(b, properties); err != nil {
+		return err
+	}
+
+	var timestamp struct {
+		Ts int64 `json:"_ts"`
+	}
+	if err := json.Unmarshal(b, &timestamp); err != nil {
+		return err
+	}
+	if timestamp.Ts != 0 {
+		*lastModified = time.Unix(timestamp.Ts, 0)
+	}
+	return nil
+}
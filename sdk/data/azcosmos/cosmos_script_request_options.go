@@ -0,0 +1,116 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// ScriptOptions includes options for operations on stored procedures, triggers and user-defined functions.
+type ScriptOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *ScriptOptions) toHeaders() *map[string]string {
+	if options.IfMatchEtag == nil {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	headers[headerIfMatch] = string(*options.IfMatchEtag)
+	return &headers
+}
+
+// ExecuteStoredProcedureOptions includes options for the execution of a stored procedure.
+type ExecuteStoredProcedureOptions struct {
+	// SessionToken to be used when using Session consistency on the account.
+	SessionToken string
+	// ConsistencyLevel overrides the account defined consistency level for this operation.
+	// Consistency can only be relaxed.
+	ConsistencyLevel *ConsistencyLevel
+	// When EnableScriptLogging is true, the response contains the output of the stored procedure's console.log calls.
+	EnableScriptLogging bool
+}
+
+func (options *ExecuteStoredProcedureOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ConsistencyLevel != nil {
+		headers[cosmosHeaderConsistencyLevel] = string(*options.ConsistencyLevel)
+	}
+
+	if options.SessionToken != "" {
+		headers[cosmosHeaderSessionToken] = options.SessionToken
+	}
+
+	if options.EnableScriptLogging {
+		headers[cosmosHeaderScriptEnableLogging] = "true"
+	}
+
+	return &headers
+}
+
+// QueryStoredProceduresOptions are options to query stored procedures
+type QueryStoredProceduresOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryStoredProceduresResponse.ContinuationToken.
+	ContinuationToken string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryStoredProceduresOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != "" {
+		headers[cosmosHeaderContinuationToken] = options.ContinuationToken
+	}
+
+	return &headers
+}
+
+// QueryTriggersOptions are options to query triggers
+type QueryTriggersOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryTriggersResponse.ContinuationToken.
+	ContinuationToken string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryTriggersOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != "" {
+		headers[cosmosHeaderContinuationToken] = options.ContinuationToken
+	}
+
+	return &headers
+}
+
+// QueryUserDefinedFunctionsOptions are options to query user-defined functions
+type QueryUserDefinedFunctionsOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryUserDefinedFunctionsResponse.ContinuationToken.
+	ContinuationToken string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryUserDefinedFunctionsOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != "" {
+		headers[cosmosHeaderContinuationToken] = options.ContinuationToken
+	}
+
+	return &headers
+}
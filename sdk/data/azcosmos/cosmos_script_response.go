@@ -0,0 +1,196 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"net/http"
+	"net/url"
+
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// StoredProcedureResponse represents the response from a stored procedure request.
+type StoredProcedureResponse struct {
+	// StoredProcedureProperties contains the unmarshalled response body in StoredProcedureProperties format.
+	StoredProcedureProperties *StoredProcedureProperties
+	Response
+}
+
+func newStoredProcedureResponse(resp *http.Response) (StoredProcedureResponse, error) {
+	response := StoredProcedureResponse{
+		Response: newResponse(resp),
+	}
+	properties := &StoredProcedureProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.StoredProcedureProperties = properties
+	return response, nil
+}
+
+// TriggerResponse represents the response from a trigger request.
+type TriggerResponse struct {
+	// TriggerProperties contains the unmarshalled response body in TriggerProperties format.
+	TriggerProperties *TriggerProperties
+	Response
+}
+
+func newTriggerResponse(resp *http.Response) (TriggerResponse, error) {
+	response := TriggerResponse{
+		Response: newResponse(resp),
+	}
+	properties := &TriggerProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.TriggerProperties = properties
+	return response, nil
+}
+
+// UserDefinedFunctionResponse represents the response from a user-defined function request.
+type UserDefinedFunctionResponse struct {
+	// UserDefinedFunctionProperties contains the unmarshalled response body in UserDefinedFunctionProperties format.
+	UserDefinedFunctionProperties *UserDefinedFunctionProperties
+	Response
+}
+
+func newUserDefinedFunctionResponse(resp *http.Response) (UserDefinedFunctionResponse, error) {
+	response := UserDefinedFunctionResponse{
+		Response: newResponse(resp),
+	}
+	properties := &UserDefinedFunctionProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.UserDefinedFunctionProperties = properties
+	return response, nil
+}
+
+// ExecuteStoredProcedureResponse represents the response from the execution of a stored procedure.
+type ExecuteStoredProcedureResponse struct {
+	Response
+	// SessionToken contains the value from the session token header to be used on session consistency.
+	SessionToken string
+	// ScriptLog contains the output of the stored procedure's console.log calls when
+	// ExecuteStoredProcedureOptions.EnableScriptLogging is true.
+	ScriptLog string
+	// Value contains the JSON value the stored procedure set as its response body.
+	Value []byte
+}
+
+func newExecuteStoredProcedureResponse(resp *http.Response) (ExecuteStoredProcedureResponse, error) {
+	response := ExecuteStoredProcedureResponse{
+		Response: newResponse(resp),
+	}
+	response.SessionToken = resp.Header.Get(cosmosHeaderSessionToken)
+	if log := resp.Header.Get(cosmosHeaderScriptLogResults); log != "" {
+		if unescaped, err := url.QueryUnescape(log); err == nil {
+			log = unescaped
+		}
+		response.ScriptLog = log
+	}
+	defer resp.Body.Close()
+	body, err := azruntime.Payload(resp)
+	if err != nil {
+		return response, err
+	}
+	response.Value = body
+	return response, nil
+}
+
+// QueryStoredProceduresResponse contains response from the stored procedure query operation.
+type QueryStoredProceduresResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken string
+	// List of stored procedures.
+	StoredProcedures []StoredProcedureProperties
+}
+
+func newStoredProceduresQueryResponse(resp *http.Response) (QueryStoredProceduresResponse, error) {
+	response := QueryStoredProceduresResponse{
+		Response: newResponse(resp),
+	}
+
+	response.ContinuationToken = resp.Header.Get(cosmosHeaderContinuationToken)
+
+	result := queryStoredProceduresServiceResponse{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryStoredProceduresResponse{}, err
+	}
+
+	response.StoredProcedures = result.StoredProcedures
+
+	return response, nil
+}
+
+type queryStoredProceduresServiceResponse struct {
+	StoredProcedures []StoredProcedureProperties `json:"StoredProcedures,omitempty"`
+}
+
+// QueryTriggersResponse contains response from the trigger query operation.
+type QueryTriggersResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken string
+	// List of triggers.
+	Triggers []TriggerProperties
+}
+
+func newTriggersQueryResponse(resp *http.Response) (QueryTriggersResponse, error) {
+	response := QueryTriggersResponse{
+		Response: newResponse(resp),
+	}
+
+	response.ContinuationToken = resp.Header.Get(cosmosHeaderContinuationToken)
+
+	result := queryTriggersServiceResponse{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryTriggersResponse{}, err
+	}
+
+	response.Triggers = result.Triggers
+
+	return response, nil
+}
+
+type queryTriggersServiceResponse struct {
+	Triggers []TriggerProperties `json:"Triggers,omitempty"`
+}
+
+// QueryUserDefinedFunctionsResponse contains response from the user-defined function query operation.
+type QueryUserDefinedFunctionsResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken string
+	// List of user-defined functions.
+	UserDefinedFunctions []UserDefinedFunctionProperties
+}
+
+func newUserDefinedFunctionsQueryResponse(resp *http.Response) (QueryUserDefinedFunctionsResponse, error) {
+	response := QueryUserDefinedFunctionsResponse{
+		Response: newResponse(resp),
+	}
+
+	response.ContinuationToken = resp.Header.Get(cosmosHeaderContinuationToken)
+
+	result := queryUserDefinedFunctionsServiceResponse{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryUserDefinedFunctionsResponse{}, err
+	}
+
+	response.UserDefinedFunctions = result.UserDefinedFunctions
+
+	return response, nil
+}
+
+type queryUserDefinedFunctionsServiceResponse struct {
+	UserDefinedFunctions []UserDefinedFunctionProperties `json:"UserDefinedFunctions,omitempty"`
+}
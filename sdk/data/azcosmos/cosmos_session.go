@@ -0,0 +1,257 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// vectorSessionToken is the session token of a partition key range, in the format
+// version#globalLSN#region1=LSN1#region2=LSN2. Tokens of single region accounts have no regional LSNs.
+type vectorSessionToken struct {
+	version   int64
+	globalLSN int64
+	regions   map[string]int64
+}
+
+func parseVectorSessionToken(s string) (vectorSessionToken, error) {
+	parts := strings.Split(s, "#")
+	if len(parts) < 2 {
+		return vectorSessionToken{}, fmt.Errorf("invalid session token %q", s)
+	}
+	version, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return vectorSessionToken{}, fmt.Errorf("invalid session token %q", s)
+	}
+	globalLSN, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return vectorSessionToken{}, fmt.Errorf("invalid session token %q", s)
+	}
+	t := vectorSessionToken{version: version, globalLSN: globalLSN, regions: map[string]int64{}}
+	for _, part := range parts[2:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return vectorSessionToken{}, fmt.Errorf("invalid session token %q", s)
+		}
+		lsn, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil {
+			return vectorSessionToken{}, fmt.Errorf("invalid session token %q", s)
+		}
+		t.regions[kv[0]] = lsn
+	}
+	return t, nil
+}
+
+// merge returns the token reflecting the progress of both tokens. A token of a later version, which the
+// service creates when the range's regions change, replaces one of an earlier version.
+func (t vectorSessionToken) merge(other vectorSessionToken) vectorSessionToken {
+	if t.version != other.version {
+		if other.version > t.version {
+			return other
+		}
+		return t
+	}
+	merged := vectorSessionToken{version: t.version, globalLSN: t.globalLSN, regions: map[string]int64{}}
+	if other.globalLSN > merged.globalLSN {
+		merged.globalLSN = other.globalLSN
+	}
+	for _, regions := range []map[string]int64{t.regions, other.regions} {
+		for region, lsn := range regions {
+			if lsn > merged.regions[region] {
+				merged.regions[region] = lsn
+			}
+		}
+	}
+	return merged
+}
+
+func (t vectorSessionToken) String() string {
+	var sb strings.Builder
+	sb.WriteString(strconv.FormatInt(t.version, 10))
+	sb.WriteString("#")
+	sb.WriteString(strconv.FormatInt(t.globalLSN, 10))
+	regions := make([]string, 0, len(t.regions))
+	for region := range t.regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		sb.WriteString("#")
+		sb.WriteString(region)
+		sb.WriteString("=")
+		sb.WriteString(strconv.FormatInt(t.regions[region], 10))
+	}
+	return sb.String()
+}
+
+// sessionContainer tracks the session tokens of each partition key range of each container,
+// so reads observe the client's earlier writes under session consistency.
+type sessionContainer struct {
+	mtx sync.RWMutex
+	// tokens maps container links to the session tokens of their partition key ranges
+	tokens map[string]map[string]vectorSessionToken
+}
+
+func newSessionContainer() *sessionContainer {
+	return &sessionContainer{tokens: map[string]map[string]vectorSessionToken{}}
+}
+
+// set merges the session tokens of a response, in the format rangeID:token[,rangeID:token...],
+// into the tokens of a container
+func (s *sessionContainer) set(containerLink string, header string) error {
+	ranges, err := parseSessionTokens(header)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tokens, ok := s.tokens[containerLink]
+	if !ok {
+		tokens = map[string]vectorSessionToken{}
+		s.tokens[containerLink] = tokens
+	}
+	for rangeID, token := range ranges {
+		if existing, ok := tokens[rangeID]; ok {
+			token = existing.merge(token)
+		}
+		tokens[rangeID] = token
+	}
+	return nil
+}
+
+// get returns the session token of a partition key range of a container, or the tokens of all of the container's
+// partition key ranges when pkRangeID is empty. It returns an empty string when there are no tokens.
+func (s *sessionContainer) get(containerLink string, pkRangeID string) string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	tokens := s.tokens[containerLink]
+	if pkRangeID != "" {
+		if token, ok := tokens[pkRangeID]; ok {
+			return pkRangeID + ":" + token.String()
+		}
+		return ""
+	}
+	return formatSessionTokens(tokens)
+}
+
+// remove forgets the tokens of a deleted container
+func (s *sessionContainer) remove(containerLink string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.tokens, containerLink)
+}
+
+// sessionState is the exported state of a sessionContainer. It maps container links to session tokens.
+type sessionState map[string]string
+
+func (s *sessionContainer) export() ([]byte, error) {
+	state := sessionState{}
+	s.mtx.RLock()
+	for containerLink, tokens := range s.tokens {
+		state[containerLink] = formatSessionTokens(tokens)
+	}
+	s.mtx.RUnlock()
+	return json.Marshal(state)
+}
+
+func (s *sessionContainer) merge(b []byte) error {
+	var state sessionState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("invalid session state: %w", err)
+	}
+	for containerLink, header := range state {
+		if err := s.set(containerLink, header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseSessionTokens(header string) (map[string]vectorSessionToken, error) {
+	ranges := map[string]vectorSessionToken{}
+	for _, segment := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(segment), ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid session token %q", segment)
+		}
+		token, err := parseVectorSessionToken(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := ranges[kv[0]]; ok {
+			token = existing.merge(token)
+		}
+		ranges[kv[0]] = token
+	}
+	return ranges, nil
+}
+
+func formatSessionTokens(tokens map[string]vectorSessionToken) string {
+	rangeIDs := make([]string, 0, len(tokens))
+	for rangeID := range tokens {
+		rangeIDs = append(rangeIDs, rangeID)
+	}
+	sort.Strings(rangeIDs)
+	segments := make([]string, len(rangeIDs))
+	for i, rangeID := range rangeIDs {
+		segments[i] = rangeID + ":" + tokens[rangeID].String()
+	}
+	return strings.Join(segments, ",")
+}
+
+// containerLinkOf returns the link of the container owning a resource, or an empty string when the
+// resource doesn't belong to a container
+func containerLinkOf(resourceAddress string) string {
+	segments := strings.SplitN(strings.Trim(resourceAddress, "/"), "/", 5)
+	if len(segments) < 4 || segments[0] != pathSegmentDatabase || segments[2] != pathSegmentCollection {
+		return ""
+	}
+	return strings.Join(segments[:4], "/")
+}
+
+// sessionPolicy attaches the tracked session token to reads of items, and tracks the session tokens
+// of responses.
+type sessionPolicy struct {
+	sessions *sessionContainer
+}
+
+func (p *sessionPolicy) Do(req *policy.Request) (*http.Response, error) {
+	o := pipelineRequestOptions{}
+	if !req.OperationValue(&o) {
+		return req.Next()
+	}
+	containerLink := containerLinkOf(o.resourceAddress)
+	if containerLink == "" {
+		return req.Next()
+	}
+
+	h := req.Raw().Header
+	if o.resourceType == resourceTypeDocument && !o.isWriteOperation && h.Get(cosmosHeaderSessionToken) == "" {
+		if token := p.sessions.get(containerLink, h.Get(cosmosHeaderPartitionKeyRangeID)); token != "" {
+			h.Set(cosmosHeaderSessionToken, token)
+		}
+	}
+
+	resp, err := req.Next()
+	if err != nil {
+		return resp, err
+	}
+
+	if o.resourceType == resourceTypeCollection && req.Raw().Method == http.MethodDelete && resp.StatusCode < 300 {
+		p.sessions.remove(containerLink)
+	} else if token := resp.Header.Get(cosmosHeaderSessionToken); token != "" {
+		// a malformed token only costs the consistency of later reads, so it doesn't fail the request
+		_ = p.sessions.set(containerLink, token)
+	}
+	return resp, nil
+}
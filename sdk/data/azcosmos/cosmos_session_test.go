@@ -0,0 +1,192 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestVectorSessionTokenMerge(t *testing.T) {
+	cases := []struct {
+		a, b, expected string
+	}{
+		{"1#100", "1#90", "1#100"},
+		{"1#100#1=20#2=5", "1#90#1=10#2=8#3=2", "1#100#1=20#2=8#3=2"},
+		{"1#100#1=20", "2#50#1=1", "2#50#1=1"},
+		{"3#50", "2#500", "3#50"},
+	}
+	for _, c := range cases {
+		a, err := parseVectorSessionToken(c.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := parseVectorSessionToken(c.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged := a.merge(b).String(); merged != c.expected {
+			t.Errorf("merging %s and %s: expected %s, got %s", c.a, c.b, c.expected, merged)
+		}
+	}
+
+	for _, invalid := range []string{"", "1", "a#1", "1#b", "1#2#3", "1#2#r=x"} {
+		if _, err := parseVectorSessionToken(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestSessionContainer(t *testing.T) {
+	s := newSessionContainer()
+	if err := s.set("dbs/db/colls/c1", "0:1#100#1=20,1:1#50"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.set("dbs/db/colls/c1", "0:1#90#1=30"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.set("dbs/db/colls/c2", "0:1#7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.set("dbs/db/colls/c2", "invalid"); err == nil {
+		t.Error("expected an invalid token to fail")
+	}
+
+	if token := s.get("dbs/db/colls/c1", "0"); token != "0:1#100#1=30" {
+		t.Errorf("unexpected token %s", token)
+	}
+	if token := s.get("dbs/db/colls/c1", "2"); token != "" {
+		t.Errorf("unexpected token %s", token)
+	}
+	if token := s.get("dbs/db/colls/c1", ""); token != "0:1#100#1=30,1:1#50" {
+		t.Errorf("unexpected token %s", token)
+	}
+
+	state, err := s.export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := newSessionContainer()
+	if err := imported.set("dbs/db/colls/c2", "0:1#9,3:1#1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := imported.merge(state); err != nil {
+		t.Fatal(err)
+	}
+	if token := imported.get("dbs/db/colls/c1", ""); token != "0:1#100#1=30,1:1#50" {
+		t.Errorf("unexpected token %s", token)
+	}
+	if token := imported.get("dbs/db/colls/c2", ""); token != "0:1#9,3:1#1" {
+		t.Errorf("unexpected token %s", token)
+	}
+	if err := imported.merge([]byte("not json")); err == nil {
+		t.Error("expected invalid state to fail")
+	}
+
+	s.remove("dbs/db/colls/c1")
+	if token := s.get("dbs/db/colls/c1", ""); token != "" {
+		t.Errorf("unexpected token %s", token)
+	}
+}
+
+func TestContainerLinkOf(t *testing.T) {
+	cases := map[string]string{
+		"dbs/db/colls/c":            "dbs/db/colls/c",
+		"dbs/db/colls/c/docs/1":     "dbs/db/colls/c",
+		"/dbs/db/colls/c/sprocs/sp": "dbs/db/colls/c",
+		"dbs/db":                    "",
+		"dbs/db/users/u":            "",
+		"":                          "",
+	}
+	for address, expected := range cases {
+		if link := containerLinkOf(address); link != expected {
+			t.Errorf("expected %q for %q, got %q", expected, address, link)
+		}
+	}
+}
+
+func TestSessionPolicy(t *testing.T) {
+	session := newSessionContainer()
+	client, srv, transport, close := newMockClient(testClientOptions{
+		perCall:   []policy.Policy{&sessionPolicy{sessions: session}},
+		configure: func(c *Client) { c.session = session },
+	})
+	defer close()
+	container, _ := client.NewContainer("db", "c")
+	ctx := context.Background()
+	pk := NewPartitionKeyString("1")
+
+	// writes don't send tokens, but track the tokens of their responses
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithHeader(cosmosHeaderSessionToken, "0:1#10"))
+	if _, err := container.CreateItem(ctx, pk, []byte(`{"id":"1"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if token := transport.requests[0].Header.Get(cosmosHeaderSessionToken); token != "" {
+		t.Errorf("unexpected token %s on a write", token)
+	}
+	if token := container.SessionToken(); token != "0:1#10" {
+		t.Errorf("unexpected token %s", token)
+	}
+
+	// reads send the tracked tokens
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"id":"1"}`)), mock.WithHeader(cosmosHeaderSessionToken, "0:1#12"))
+	if _, err := container.ReadItem(ctx, pk, "1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if token := transport.requests[1].Header.Get(cosmosHeaderSessionToken); token != "0:1#10" {
+		t.Errorf("expected the tracked token, got %s", token)
+	}
+
+	// an explicit token is kept
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"id":"1"}`)))
+	if _, err := container.ReadItem(ctx, pk, "1", &ItemOptions{SessionToken: "0:1#5"}); err != nil {
+		t.Fatal(err)
+	}
+	if token := transport.requests[2].Header.Get(cosmosHeaderSessionToken); token != "0:1#5" {
+		t.Errorf("expected the explicit token, got %s", token)
+	}
+
+	state, err := client.ExportSessionState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(state) != `{"dbs/db/colls/c":"0:1#12"}` {
+		t.Errorf("unexpected state %s", state)
+	}
+
+	// deleting the container forgets its tokens
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	if _, err := container.Delete(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if token := container.SessionToken(); token != "" {
+		t.Errorf("unexpected token %s after deleting the container", token)
+	}
+
+	if err := client.ImportSessionState(state); err != nil {
+		t.Fatal(err)
+	}
+	if token := container.SessionToken(); token != "0:1#12" {
+		t.Errorf("unexpected token %s after importing the state", token)
+	}
+}
+
+func TestSessionStateWithoutTracking(t *testing.T) {
+	client := &Client{endpoint: "https://localhost"}
+	state, err := client.ExportSessionState()
+	if err != nil || string(state) != "{}" {
+		t.Errorf("unexpected state %s, %v", state, err)
+	}
+	if err := client.ImportSessionState(state); err == nil {
+		t.Error("expected importing to fail")
+	}
+	container, _ := client.NewContainer("db", "c")
+	if token := container.SessionToken(); token != "" {
+		t.Errorf("unexpected token %s", token)
+	}
+}
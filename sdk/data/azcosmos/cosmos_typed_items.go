@@ -0,0 +1,291 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// partitionKeyTag is the struct tag marking the fields of the partition key of typed items.
+const partitionKeyTag = "azcosmos"
+
+// ItemCodec encodes items to and decodes items from the JSON documents stored in a container.
+type ItemCodec interface {
+	// Marshal returns the JSON document of an item.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes a JSON document into an item.
+	Unmarshal(data []byte, v any) error
+}
+
+// jsonItemCodec is the ItemCodec of encoding/json
+type jsonItemCodec struct{}
+
+func (jsonItemCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonItemCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// TypedItemOptions includes options for operations on typed items.
+type TypedItemOptions[T any] struct {
+	ItemOptions
+	// Codec encodes and decodes items. Defaults to encoding/json.
+	Codec ItemCodec
+	// PartitionKey returns the partition key of an item. When it's nil, the partition key is made of the values
+	// of the item's fields tagged `azcosmos:"partitionKey"`, in the order of the fields. Fields of hierarchical
+	// partition keys are tagged in the order of the container's partition key paths. Tagged fields must be strings,
+	// booleans or numbers, or pointers to them, where a nil pointer is a null value.
+	PartitionKey func(item T) (PartitionKey, error)
+}
+
+func (o *TypedItemOptions[T]) codec() ItemCodec {
+	if o == nil || o.Codec == nil {
+		return jsonItemCodec{}
+	}
+	return o.Codec
+}
+
+func (o *TypedItemOptions[T]) itemOptions() *ItemOptions {
+	if o == nil {
+		return nil
+	}
+	return &o.ItemOptions
+}
+
+func (o *TypedItemOptions[T]) partitionKey(item T) (PartitionKey, error) {
+	if o != nil && o.PartitionKey != nil {
+		return o.PartitionKey(item)
+	}
+	return partitionKeyFromTags(item)
+}
+
+// TypedItemResponse represents the response from an operation on a typed item.
+type TypedItemResponse[T any] struct {
+	// Value contains the item. It's the zero value of T when the response has no item, for example
+	// for writes without ItemOptions.EnableContentResponseOnWrite.
+	Value T
+	Response
+	// SessionToken contains the value from the session token header to be used on session consistency.
+	SessionToken string
+}
+
+func newTypedItemResponse[T any](response ItemResponse, codec ItemCodec) (TypedItemResponse[T], error) {
+	typed := TypedItemResponse[T]{
+		Response:     response.Response,
+		SessionToken: response.SessionToken,
+	}
+	if len(response.Value) == 0 {
+		return typed, nil
+	}
+	if err := codec.Unmarshal(response.Value, &typed.Value); err != nil {
+		return typed, err
+	}
+	return typed, nil
+}
+
+// CreateItemAs creates an item in a Cosmos container. The item's partition key is derived from the item.
+// ctx - The context for the request.
+// container - The container to create the item in.
+// item - The item to create.
+// o - Options for the operation.
+func CreateItemAs[T any](ctx context.Context, container *ContainerClient, item T, o *TypedItemOptions[T]) (TypedItemResponse[T], error) {
+	partitionKey, body, err := encodeTypedItem(item, o)
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	response, err := container.CreateItem(ctx, partitionKey, body, o.itemOptions())
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	return newTypedItemResponse[T](response, o.codec())
+}
+
+// UpsertItemAs creates or replaces an item in a Cosmos container. The item's partition key is derived from the item.
+// ctx - The context for the request.
+// container - The container to upsert the item in.
+// item - The item to upsert.
+// o - Options for the operation.
+func UpsertItemAs[T any](ctx context.Context, container *ContainerClient, item T, o *TypedItemOptions[T]) (TypedItemResponse[T], error) {
+	partitionKey, body, err := encodeTypedItem(item, o)
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	response, err := container.UpsertItem(ctx, partitionKey, body, o.itemOptions())
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	return newTypedItemResponse[T](response, o.codec())
+}
+
+// ReplaceItemAs replaces an item in a Cosmos container. The item's partition key is derived from the item.
+// ctx - The context for the request.
+// container - The container of the item.
+// itemId - The id of the item to replace.
+// item - The content to be used to replace.
+// o - Options for the operation.
+func ReplaceItemAs[T any](ctx context.Context, container *ContainerClient, itemId string, item T, o *TypedItemOptions[T]) (TypedItemResponse[T], error) {
+	partitionKey, body, err := encodeTypedItem(item, o)
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	response, err := container.ReplaceItem(ctx, partitionKey, itemId, body, o.itemOptions())
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	return newTypedItemResponse[T](response, o.codec())
+}
+
+// ReadItemAs reads an item in a Cosmos container and decodes it.
+// ctx - The context for the request.
+// container - The container of the item.
+// partitionKey - The partition key for the item.
+// itemId - The id of the item to read.
+// o - Options for the operation.
+func ReadItemAs[T any](ctx context.Context, container *ContainerClient, partitionKey PartitionKey, itemId string, o *TypedItemOptions[T]) (TypedItemResponse[T], error) {
+	response, err := container.ReadItem(ctx, partitionKey, itemId, o.itemOptions())
+	if err != nil {
+		return TypedItemResponse[T]{}, err
+	}
+
+	return newTypedItemResponse[T](response, o.codec())
+}
+
+func encodeTypedItem[T any](item T, o *TypedItemOptions[T]) (PartitionKey, []byte, error) {
+	partitionKey, err := o.partitionKey(item)
+	if err != nil {
+		return PartitionKey{}, nil, err
+	}
+	body, err := o.codec().Marshal(item)
+	if err != nil {
+		return PartitionKey{}, nil, err
+	}
+	return partitionKey, body, nil
+}
+
+// TypedQueryItemsResponse contains a page of typed items returned by a query.
+type TypedQueryItemsResponse[T any] struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken string
+	// Contains the query metrics related to the query execution
+	QueryMetrics *string
+	// IndexMetrics contains the index utilization metrics if QueryOptions.PopulateIndexMetrics = true
+	IndexMetrics *string
+	// List of items.
+	Items []T
+}
+
+// NewTypedQueryItemsPager decodes the items of the pages of a query pager, such as the pagers returned by
+// ContainerClient.NewQueryItemsPager and ContainerClient.NewCrossPartitionQueryItemsPager.
+// pager - The pager of the query.
+// codec - Decodes the items. Defaults to encoding/json when it's nil.
+func NewTypedQueryItemsPager[T any](pager *runtime.Pager[QueryItemsResponse], codec ItemCodec) *runtime.Pager[TypedQueryItemsResponse[T]] {
+	if codec == nil {
+		codec = jsonItemCodec{}
+	}
+
+	return runtime.NewPager(runtime.PagingHandler[TypedQueryItemsResponse[T]]{
+		More: func(page TypedQueryItemsResponse[T]) bool {
+			return pager.More()
+		},
+		Fetcher: func(ctx context.Context, page *TypedQueryItemsResponse[T]) (TypedQueryItemsResponse[T], error) {
+			response, err := pager.NextPage(ctx)
+			if err != nil {
+				return TypedQueryItemsResponse[T]{}, err
+			}
+
+			typed := TypedQueryItemsResponse[T]{
+				Response:          response.Response,
+				ContinuationToken: response.ContinuationToken,
+				QueryMetrics:      response.QueryMetrics,
+				IndexMetrics:      response.IndexMetrics,
+				Items:             make([]T, len(response.Items)),
+			}
+			for i, item := range response.Items {
+				if err := codec.Unmarshal(item, &typed.Items[i]); err != nil {
+					return TypedQueryItemsResponse[T]{}, err
+				}
+			}
+			return typed, nil
+		},
+	})
+}
+
+// partitionKeyFields caches the indexes of the partition key fields of struct types
+var partitionKeyFields sync.Map
+
+// partitionKeyFromTags returns the partition key made of the values of an item's fields tagged `azcosmos:"partitionKey"`
+func partitionKeyFromTags(item any) (PartitionKey, error) {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return PartitionKey{}, errors.New("the item is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return PartitionKey{}, fmt.Errorf("can't derive the partition key of %T, which isn't a struct, without TypedItemOptions.PartitionKey", item)
+	}
+
+	fields, ok := partitionKeyFields.Load(v.Type())
+	if !ok {
+		indexes := [][]int{}
+		for _, field := range reflect.VisibleFields(v.Type()) {
+			if field.Tag.Get(partitionKeyTag) == "partitionKey" {
+				indexes = append(indexes, field.Index)
+			}
+		}
+		fields, _ = partitionKeyFields.LoadOrStore(v.Type(), indexes)
+	}
+	indexes := fields.([][]int)
+	if len(indexes) == 0 {
+		return PartitionKey{}, fmt.Errorf("%s has no fields tagged `%s:\"partitionKey\"`", v.Type(), partitionKeyTag)
+	}
+
+	pk := NewPartitionKey()
+	for _, index := range indexes {
+		field, err := v.FieldByIndexErr(index)
+		if err != nil {
+			return PartitionKey{}, err
+		}
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				pk = pk.AppendNull()
+				continue
+			}
+			field = field.Elem()
+		}
+		switch field.Kind() {
+		case reflect.String:
+			pk = pk.AppendString(field.String())
+		case reflect.Bool:
+			pk = pk.AppendBool(field.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			pk = pk.AppendNumber(float64(field.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			pk = pk.AppendNumber(float64(field.Uint()))
+		case reflect.Float32, reflect.Float64:
+			pk = pk.AppendNumber(field.Float())
+		default:
+			return PartitionKey{}, fmt.Errorf("partition key field of type %s isn't a string, boolean or number", field.Type())
+		}
+	}
+	return pk, nil
+}
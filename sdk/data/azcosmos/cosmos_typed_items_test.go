@@ -0,0 +1,177 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+type typedOrder struct {
+	ID       string  `json:"id"`
+	Tenant   string  `json:"tenant" azcosmos:"partitionKey"`
+	Customer *string `json:"customer" azcosmos:"partitionKey"`
+	Total    float64 `json:"total"`
+}
+
+// markerCodec counts the items it encodes and decodes
+type markerCodec struct {
+	marshalled, unmarshalled int
+}
+
+func (c *markerCodec) Marshal(v any) ([]byte, error) {
+	c.marshalled++
+	return json.Marshal(v)
+}
+
+func (c *markerCodec) Unmarshal(data []byte, v any) error {
+	c.unmarshalled++
+	return json.Unmarshal(data, v)
+}
+
+func TestPartitionKeyFromTags(t *testing.T) {
+	customer := "customer"
+	type embedded struct {
+		Region string `azcosmos:"partitionKey"`
+	}
+	type numbers struct {
+		embedded
+		Count uint8   `azcosmos:"partitionKey"`
+		Ratio float32 `azcosmos:"partitionKey"`
+		Flag  bool    `azcosmos:"partitionKey"`
+	}
+
+	for _, test := range []struct {
+		item     any
+		expected string
+	}{
+		{item: typedOrder{Tenant: "tenant", Customer: &customer}, expected: `["tenant","customer"]`},
+		{item: &typedOrder{Tenant: "tenant"}, expected: `["tenant",null]`},
+		{item: numbers{embedded: embedded{Region: "west"}, Count: 3, Ratio: 0.5, Flag: true}, expected: `["west",3,0.5,true]`},
+	} {
+		pk, err := partitionKeyFromTags(test.item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, _ := pk.toJsonString(); actual != test.expected {
+			t.Errorf("expected partition key %s, got %s", test.expected, actual)
+		}
+	}
+
+	for _, item := range []any{
+		struct{ ID string }{},
+		struct {
+			Tags []string `azcosmos:"partitionKey"`
+		}{},
+		"not a struct",
+		(*typedOrder)(nil),
+	} {
+		if _, err := partitionKeyFromTags(item); err == nil {
+			t.Errorf("expected an error for %#v", item)
+		}
+	}
+}
+
+func TestTypedItems(t *testing.T) {
+	container, srv, transport, close := newTestScriptsContainer(t)
+	defer close()
+	ctx := context.Background()
+	customer := "customer"
+	order := typedOrder{ID: "1", Tenant: "tenant", Customer: &customer, Total: 10}
+
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusCreated),
+		mock.WithBody([]byte(`{"id":"1","tenant":"tenant","customer":"customer","total":10,"_etag":"\"1\""}`)),
+		mock.WithHeader(cosmosHeaderEtag, `"1"`),
+		mock.WithHeader(cosmosHeaderRequestCharge, "6.2"))
+	created, err := CreateItemAs(ctx, container, order, &TypedItemOptions[typedOrder]{ItemOptions: ItemOptions{EnableContentResponseOnWrite: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(created.Value, order) || created.ETag != `"1"` || created.RequestCharge != 6.2 {
+		t.Errorf("unexpected response %+v", created)
+	}
+	if pk := transport.requests[0].Header.Get(cosmosHeaderPartitionKey); pk != `["tenant","customer"]` {
+		t.Errorf("unexpected partition key %s", pk)
+	}
+	expectedBody, _ := json.Marshal(order)
+	if !bytes.Equal(transport.bodies[0], expectedBody) {
+		t.Errorf("unexpected body %s", transport.bodies[0])
+	}
+
+	// writes without content responses return the zero value
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	upserted, err := UpsertItemAs(ctx, container, order, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upserted.Value != (typedOrder{}) || transport.requests[1].Header.Get(cosmosHeaderIsUpsert) != "true" {
+		t.Errorf("unexpected response %+v", upserted)
+	}
+
+	// an extractor overrides the tags, and a codec replaces encoding/json
+	codec := &markerCodec{}
+	options := &TypedItemOptions[typedOrder]{
+		Codec:        codec,
+		PartitionKey: func(item typedOrder) (PartitionKey, error) { return NewPartitionKeyString(item.ID), nil },
+	}
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	if _, err := ReplaceItemAs(ctx, container, "1", order, options); err != nil {
+		t.Fatal(err)
+	}
+	req := transport.requests[2]
+	if req.Header.Get(cosmosHeaderPartitionKey) != `["1"]` || req.URL.Path != "/dbs/db/colls/container/docs/1" || codec.marshalled != 1 {
+		t.Errorf("unexpected request %s %v", req.URL.Path, req.Header)
+	}
+
+	srv.AppendResponse(mock.WithBody(expectedBody), mock.WithHeader(cosmosHeaderEtag, `"2"`))
+	read, err := ReadItemAs(ctx, container, NewPartitionKeyString("1"), "1", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Value, order) || read.ETag != `"2"` || codec.unmarshalled != 1 {
+		t.Errorf("unexpected response %+v", read)
+	}
+
+	if _, err := CreateItemAs(ctx, container, struct{ ID string }{ID: "1"}, nil); err == nil {
+		t.Error("expected an error for an item without a partition key")
+	}
+}
+
+func TestTypedQueryItemsPager(t *testing.T) {
+	container, srv, _, close := newTestScriptsContainer(t)
+	defer close()
+
+	srv.AppendResponse(
+		mock.WithBody([]byte(`{"Documents":[{"id":"1","tenant":"a","total":1},{"id":"2","tenant":"a","total":2}]}`)),
+		mock.WithHeader(cosmosHeaderContinuationToken, "next"),
+		mock.WithHeader(cosmosHeaderRequestCharge, "3"))
+	srv.AppendResponse(mock.WithBody([]byte(`{"Documents":[{"id":"3","tenant":"a","total":3}]}`)))
+
+	pager := NewTypedQueryItemsPager[typedOrder](container.NewQueryItemsPager("SELECT * FROM c", NewPartitionKeyString("a"), nil), nil)
+	var total float64
+	pages := 0
+	for pager.More() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages == 0 && (page.ContinuationToken != "next" || page.RequestCharge != 3) {
+			t.Errorf("unexpected page %+v", page)
+		}
+		for _, order := range page.Items {
+			total += order.Total
+		}
+		pages++
+	}
+	if pages != 2 || total != 6 {
+		t.Errorf("expected 2 pages with a total of 6, got %d pages with a total of %v", pages, total)
+	}
+}
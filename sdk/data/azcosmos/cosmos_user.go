@@ -0,0 +1,421 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// UserClient lets you perform read, replace, and delete user operations, and manage the user's permissions.
+type UserClient struct {
+	// The Id of the Cosmos user
+	id string
+	// The database that contains the user
+	database *DatabaseClient
+	// The resource link
+	link string
+}
+
+func newUser(id string, database *DatabaseClient) (*UserClient, error) {
+	return &UserClient{
+		id:       id,
+		database: database,
+		link:     createLink(database.link, pathSegmentUser, id)}, nil
+}
+
+// NewUser returns a struct that represents a user and allows user level operations.
+// id - The id of the user.
+func (db *DatabaseClient) NewUser(id string) (*UserClient, error) {
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+
+	return newUser(id, db)
+}
+
+// CreateUser creates a user in the Cosmos database.
+// ctx - The context for the request.
+// properties - The properties of the user.
+// o - Options for the operation.
+func (db *DatabaseClient) CreateUser(
+	ctx context.Context,
+	properties UserProperties,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypeUser,
+		resourceAddress:  db.link,
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeUser, db.link, true)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	azResponse, err := db.client.sendPostRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// NewQueryUsersPager executes query for users within a database.
+// query - The SQL query to execute, for example "SELECT * FROM u".
+// o - Options for the operation.
+func (db *DatabaseClient) NewQueryUsersPager(query string, o *QueryUsersOptions) *runtime.Pager[QueryUsersResponse] {
+	queryOptions := &QueryUsersOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeUser,
+		resourceAddress: db.link,
+	}
+
+	path, _ := generatePathForNameBased(resourceTypeUser, operationContext.resourceAddress, true)
+
+	return runtime.NewPager(runtime.PagingHandler[QueryUsersResponse]{
+		More: func(page QueryUsersResponse) bool {
+			return page.ContinuationToken != ""
+		},
+		Fetcher: func(ctx context.Context, page *QueryUsersResponse) (QueryUsersResponse, error) {
+			if page != nil {
+				if page.ContinuationToken != "" {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := db.client.sendQueryRequest(
+				path,
+				ctx,
+				query,
+				queryOptions.QueryParameters,
+				operationContext,
+				queryOptions,
+				nil)
+			if err != nil {
+				return QueryUsersResponse{}, err
+			}
+
+			return newUsersQueryResponse(azResponse)
+		},
+	})
+}
+
+// ID returns the identifier of the Cosmos user.
+func (u *UserClient) ID() string {
+	return u.id
+}
+
+// Read obtains the information for a Cosmos user.
+// ctx - The context for the request.
+// o - Options for the operation.
+func (u *UserClient) Read(
+	ctx context.Context,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeUser,
+		resourceAddress: u.link,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeUser, u.link, false)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendGetRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// Replace a Cosmos user, for example to rename it. The user's permissions keep their tokens.
+// ctx - The context for the request.
+// properties - The new properties of the user.
+// o - Options for the operation.
+func (u *UserClient) Replace(
+	ctx context.Context,
+	properties UserProperties,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypeUser,
+		resourceAddress:  u.link,
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeUser, u.link, false)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendPutRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// Delete a Cosmos user and its permissions.
+// ctx - The context for the request.
+// o - Options for the operation.
+func (u *UserClient) Delete(
+	ctx context.Context,
+	o *UserOptions) (UserResponse, error) {
+	if o == nil {
+		o = &UserOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypeUser,
+		resourceAddress:  u.link,
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypeUser, u.link, false)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendDeleteRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return UserResponse{}, err
+	}
+
+	return newUserResponse(azResponse)
+}
+
+// CreatePermission creates a permission for the user. The response contains the permission's resource token.
+// ctx - The context for the request.
+// properties - The properties of the permission.
+// o - Options for the operation.
+func (u *UserClient) CreatePermission(
+	ctx context.Context,
+	properties PermissionProperties,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypePermission,
+		resourceAddress:  u.link,
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypePermission, u.link, true)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendPostRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// ReadPermission reads a permission of the user. The response contains a new resource token for the permission.
+// ctx - The context for the request.
+// id - The id of the permission.
+// o - Options for the operation.
+func (u *UserClient) ReadPermission(
+	ctx context.Context,
+	id string,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypePermission,
+		resourceAddress: createLink(u.link, pathSegmentPermission, id),
+	}
+
+	path, err := generatePathForNameBased(resourceTypePermission, operationContext.resourceAddress, false)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendGetRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// ReplacePermission replaces a permission of the user.
+// ctx - The context for the request.
+// properties - The properties of the permission. The ID identifies the permission to replace.
+// o - Options for the operation.
+func (u *UserClient) ReplacePermission(
+	ctx context.Context,
+	properties PermissionProperties,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypePermission,
+		resourceAddress:  createLink(u.link, pathSegmentPermission, properties.ID),
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypePermission, operationContext.resourceAddress, false)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendPutRequest(
+		path,
+		ctx,
+		properties,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// DeletePermission deletes a permission of the user. Its resource tokens are no longer valid.
+// ctx - The context for the request.
+// id - The id of the permission.
+// o - Options for the operation.
+func (u *UserClient) DeletePermission(
+	ctx context.Context,
+	id string,
+	o *PermissionOptions) (PermissionResponse, error) {
+	if o == nil {
+		o = &PermissionOptions{}
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:     resourceTypePermission,
+		resourceAddress:  createLink(u.link, pathSegmentPermission, id),
+		isWriteOperation: true,
+	}
+
+	path, err := generatePathForNameBased(resourceTypePermission, operationContext.resourceAddress, false)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	azResponse, err := u.database.client.sendDeleteRequest(
+		path,
+		ctx,
+		operationContext,
+		o,
+		nil)
+	if err != nil {
+		return PermissionResponse{}, err
+	}
+
+	return newPermissionResponse(azResponse)
+}
+
+// NewQueryPermissionsPager executes query for the permissions of the user.
+// query - The SQL query to execute, for example "SELECT * FROM p".
+// o - Options for the operation.
+func (u *UserClient) NewQueryPermissionsPager(query string, o *QueryPermissionsOptions) *runtime.Pager[QueryPermissionsResponse] {
+	queryOptions := &QueryPermissionsOptions{}
+	if o != nil {
+		originalOptions := *o
+		queryOptions = &originalOptions
+	}
+
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypePermission,
+		resourceAddress: u.link,
+	}
+
+	path, _ := generatePathForNameBased(resourceTypePermission, operationContext.resourceAddress, true)
+
+	return runtime.NewPager(runtime.PagingHandler[QueryPermissionsResponse]{
+		More: func(page QueryPermissionsResponse) bool {
+			return page.ContinuationToken != ""
+		},
+		Fetcher: func(ctx context.Context, page *QueryPermissionsResponse) (QueryPermissionsResponse, error) {
+			if page != nil {
+				if page.ContinuationToken != "" {
+					// Use the previous page continuation if available
+					queryOptions.ContinuationToken = page.ContinuationToken
+				}
+			}
+
+			azResponse, err := u.database.client.sendQueryRequest(
+				path,
+				ctx,
+				query,
+				queryOptions.QueryParameters,
+				operationContext,
+				queryOptions,
+				nil)
+			if err != nil {
+				return QueryPermissionsResponse{}, err
+			}
+
+			return newPermissionsQueryResponse(azResponse)
+		},
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// UserProperties represents the properties of a user.
+type UserProperties struct {
+	// ID contains the unique id of the user.
+	ID string `json:"id"`
+	// ETag contains the entity etag of the user.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the user.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the user.
+	ResourceID string `json:"_rid,omitempty"`
+	// PermissionsLink contains the link of the user's permissions feed.
+	PermissionsLink string `json:"_permissions,omitempty"`
+	// LastModified contains the last modified time of the user.
+	LastModified time.Time `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (p *UserProperties) UnmarshalJSON(b []byte) error {
+	type properties UserProperties
+	return unmarshalTimestampedProperties(b, (*properties)(p), &p.LastModified)
+}
+
+// PermissionProperties represents the properties of a permission, which grants a user access to a resource.
+type PermissionProperties struct {
+	// ID contains the unique id of the permission.
+	ID string `json:"id"`
+	// Mode defines the access the permission grants.
+	Mode PermissionMode `json:"permissionMode"`
+	// ResourceLink contains the link of the resource the permission grants access to, for example
+	// dbs/database/colls/container.
+	ResourceLink string `json:"resource"`
+	// ResourcePartitionKey optionally limits the permission to the items of a logical partition of a container.
+	ResourcePartitionKey *PartitionKey `json:"-"`
+	// Token contains the resource token of the permission, which authenticates requests with a
+	// ResourceTokenCredential. The service returns a new token with each response.
+	Token string `json:"_token,omitempty"`
+	// ETag contains the entity etag of the permission.
+	ETag *azcore.ETag `json:"_etag,omitempty"`
+	// SelfLink contains the self-link of the permission.
+	SelfLink string `json:"_self,omitempty"`
+	// ResourceID contains the resource id of the permission.
+	ResourceID string `json:"_rid,omitempty"`
+	// LastModified contains the last modified time of the permission.
+	LastModified time.Time `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (p PermissionProperties) MarshalJSON() ([]byte, error) {
+	type properties PermissionProperties
+	permission := struct {
+		properties
+		ResourcePartitionKey json.RawMessage `json:"resourcePartitionKey,omitempty"`
+	}{properties: properties(p)}
+
+	if p.ResourcePartitionKey != nil {
+		pk, err := p.ResourcePartitionKey.toJsonString()
+		if err != nil {
+			return nil, err
+		}
+		permission.ResourcePartitionKey = json.RawMessage(pk)
+	}
+
+	return json.Marshal(permission)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (p *PermissionProperties) UnmarshalJSON(b []byte) error {
+	type properties PermissionProperties
+	if err := unmarshalTimestampedProperties(b, (*properties)(p), &p.LastModified); err != nil {
+		return err
+	}
+
+	var partitionKey struct {
+		ResourcePartitionKey []interface{} `json:"resourcePartitionKey"`
+	}
+	if err := json.Unmarshal(b, &partitionKey); err != nil {
+		return err
+	}
+	if partitionKey.ResourcePartitionKey != nil {
+		pk := NewPartitionKey()
+		for _, value := range partitionKey.ResourcePartitionKey {
+			if _, ok := value.(map[string]interface{}); ok {
+				// undefined values are serialized as {}
+				value = undefinedPartitionKeyValue{}
+			}
+			pk = pk.append(value)
+		}
+		p.ResourcePartitionKey = &pk
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// UserOptions includes options for operations on users.
+type UserOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+}
+
+func (options *UserOptions) toHeaders() *map[string]string {
+	if options.IfMatchEtag == nil {
+		return nil
+	}
+
+	headers := make(map[string]string)
+	headers[headerIfMatch] = string(*options.IfMatchEtag)
+	return &headers
+}
+
+// PermissionOptions includes options for operations on permissions.
+type PermissionOptions struct {
+	// IfMatchEtag is used to ensure optimistic concurrency control.
+	IfMatchEtag *azcore.ETag
+	// ResourceTokenExpiry is the validity period of the resource token in the response, between 10 minutes
+	// and 5 hours. The service defaults to 1 hour.
+	ResourceTokenExpiry time.Duration
+}
+
+func (options *PermissionOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.IfMatchEtag != nil {
+		headers[headerIfMatch] = string(*options.IfMatchEtag)
+	}
+
+	if options.ResourceTokenExpiry > 0 {
+		headers[cosmosHeaderResourceTokenExpiry] = strconv.FormatInt(int64(options.ResourceTokenExpiry/time.Second), 10)
+	}
+
+	return &headers
+}
+
+// QueryUsersOptions are options to query users
+type QueryUsersOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryUsersResponse.ContinuationToken.
+	ContinuationToken string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+}
+
+func (options *QueryUsersOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != "" {
+		headers[cosmosHeaderContinuationToken] = options.ContinuationToken
+	}
+
+	return &headers
+}
+
+// QueryPermissionsOptions are options to query permissions
+type QueryPermissionsOptions struct {
+	// ContinuationToken to be used to continue a previous query execution.
+	// Obtained from QueryPermissionsResponse.ContinuationToken.
+	ContinuationToken string
+
+	// QueryParameters allows execution of parametrized queries.
+	// See https://docs.microsoft.com/azure/cosmos-db/sql/sql-query-parameterized-queries
+	QueryParameters []QueryParameter
+
+	// ResourceTokenExpiry is the validity period of the resource tokens of the permissions, between 10 minutes
+	// and 5 hours. The service defaults to 1 hour.
+	ResourceTokenExpiry time.Duration
+}
+
+func (options *QueryPermissionsOptions) toHeaders() *map[string]string {
+	headers := make(map[string]string)
+
+	if options.ContinuationToken != "" {
+		headers[cosmosHeaderContinuationToken] = options.ContinuationToken
+	}
+
+	if options.ResourceTokenExpiry > 0 {
+		headers[cosmosHeaderResourceTokenExpiry] = strconv.FormatInt(int64(options.ResourceTokenExpiry/time.Second), 10)
+	}
+
+	return &headers
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"net/http"
+
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// UserResponse represents the response from a user request.
+type UserResponse struct {
+	// UserProperties contains the unmarshalled response body in UserProperties format.
+	UserProperties *UserProperties
+	Response
+}
+
+func newUserResponse(resp *http.Response) (UserResponse, error) {
+	response := UserResponse{
+		Response: newResponse(resp),
+	}
+	properties := &UserProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.UserProperties = properties
+	return response, nil
+}
+
+// PermissionResponse represents the response from a permission request.
+type PermissionResponse struct {
+	// PermissionProperties contains the unmarshalled response body in PermissionProperties format.
+	PermissionProperties *PermissionProperties
+	Response
+}
+
+func newPermissionResponse(resp *http.Response) (PermissionResponse, error) {
+	response := PermissionResponse{
+		Response: newResponse(resp),
+	}
+	properties := &PermissionProperties{}
+	err := azruntime.UnmarshalAsJSON(resp, properties)
+	if err != nil {
+		return response, err
+	}
+	response.PermissionProperties = properties
+	return response, nil
+}
+
+// QueryUsersResponse contains response from the user query operation.
+type QueryUsersResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken string
+	// List of users.
+	Users []UserProperties
+}
+
+func newUsersQueryResponse(resp *http.Response) (QueryUsersResponse, error) {
+	response := QueryUsersResponse{
+		Response: newResponse(resp),
+	}
+
+	response.ContinuationToken = resp.Header.Get(cosmosHeaderContinuationToken)
+
+	result := queryUsersServiceResponse{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryUsersResponse{}, err
+	}
+
+	response.Users = result.Users
+
+	return response, nil
+}
+
+type queryUsersServiceResponse struct {
+	Users []UserProperties `json:"Users,omitempty"`
+}
+
+// QueryPermissionsResponse contains response from the permission query operation.
+type QueryPermissionsResponse struct {
+	Response
+	// ContinuationToken contains the value of the x-ms-continuation header in the response.
+	// It can be used to stop a query and resume it later.
+	ContinuationToken string
+	// List of permissions.
+	Permissions []PermissionProperties
+}
+
+func newPermissionsQueryResponse(resp *http.Response) (QueryPermissionsResponse, error) {
+	response := QueryPermissionsResponse{
+		Response: newResponse(resp),
+	}
+
+	response.ContinuationToken = resp.Header.Get(cosmosHeaderContinuationToken)
+
+	result := queryPermissionsServiceResponse{}
+	if err := azruntime.UnmarshalAsJSON(resp, &result); err != nil {
+		return QueryPermissionsResponse{}, err
+	}
+
+	response.Permissions = result.Permissions
+
+	return response, nil
+}
+
+type queryPermissionsServiceResponse struct {
+	Permissions []PermissionProperties `json:"Permissions,omitempty"`
+}
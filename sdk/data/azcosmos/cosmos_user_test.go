@@ -0,0 +1,207 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func newTestUsersDatabase(t *testing.T) (*DatabaseClient, *mock.Server, *capturingTransport, func()) {
+	client, srv, transport, close := newMockClient(testClientOptions{})
+	database, err := client.NewDatabase("db")
+	if err != nil {
+		close()
+		t.Fatal(err)
+	}
+	return database, srv, transport, close
+}
+
+func TestDatabaseUsers(t *testing.T) {
+	database, srv, transport, close := newTestUsersDatabase(t)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusCreated),
+		mock.WithBody([]byte(`{"id":"alice","_etag":"\"1\"","_rid":"rid","_permissions":"permissions/","_ts":1600000000}`)))
+	created, err := database.CreateUser(ctx, UserProperties{ID: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.UserProperties.ID != "alice" || created.UserProperties.PermissionsLink != "permissions/" || *created.UserProperties.ETag != `"1"` {
+		t.Errorf("unexpected response %+v", created.UserProperties)
+	}
+	if !created.UserProperties.LastModified.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("unexpected LastModified %v", created.UserProperties.LastModified)
+	}
+	if req := transport.requests[0]; req.Method != http.MethodPost || req.URL.Path != "/dbs/db/users" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	if string(transport.bodies[0]) != `{"id":"alice"}` {
+		t.Errorf("unexpected body %s", transport.bodies[0])
+	}
+
+	user, err := database.NewUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.NewUser(""); err == nil {
+		t.Error("expected an empty id to fail")
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"alice"}`)))
+	if _, err := user.Read(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.requests[1]; req.Method != http.MethodGet || req.URL.Path != "/dbs/db/users/alice" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	etag := azcore.ETag(`"1"`)
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"bob"}`)))
+	replaced, err := user.Replace(ctx, UserProperties{ID: "bob"}, &UserOptions{IfMatchEtag: &etag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.requests[2]; req.Method != http.MethodPut || req.URL.Path != "/dbs/db/users/alice" || req.Header.Get(headerIfMatch) != `"1"` {
+		t.Errorf("unexpected request %s %s %v", req.Method, req.URL.Path, req.Header)
+	}
+	if replaced.UserProperties.ID != "bob" {
+		t.Errorf("unexpected response %+v", replaced.UserProperties)
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	if _, err := user.Delete(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.requests[3]; req.Method != http.MethodDelete || req.URL.Path != "/dbs/db/users/alice" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"Users":[{"id":"alice"},{"id":"bob"}]}`)))
+	pager := database.NewQueryUsersPager("SELECT * FROM u", nil)
+	page, err := pager.NextPage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 2 || page.Users[1].ID != "bob" || pager.More() {
+		t.Errorf("unexpected page %+v", page.Users)
+	}
+	if req := transport.requests[4]; req.Method != http.MethodPost || req.URL.Path != "/dbs/db/users" || req.Header.Get(cosmosHeaderQuery) != "True" {
+		t.Errorf("unexpected request %s %s %v", req.Method, req.URL.Path, req.Header)
+	}
+}
+
+func TestUserPermissions(t *testing.T) {
+	database, srv, transport, close := newTestUsersDatabase(t)
+	defer close()
+	ctx := context.Background()
+	user, _ := database.NewUser("alice")
+
+	srv.AppendResponse(
+		mock.WithStatusCode(http.StatusCreated),
+		mock.WithBody([]byte(`{"id":"orders","permissionMode":"All","resource":"dbs/db/colls/orders","resourcePartitionKey":["alice"],"_token":"type=resource&ver=1.0&sig=abc","_ts":1600000000}`)))
+	pk := NewPartitionKeyString("alice")
+	created, err := user.CreatePermission(ctx, PermissionProperties{
+		ID:                   "orders",
+		Mode:                 PermissionModeAll,
+		ResourceLink:         "dbs/db/colls/orders",
+		ResourcePartitionKey: &pk,
+	}, &PermissionOptions{ResourceTokenExpiry: 2 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	permission := created.PermissionProperties
+	if permission.Token != "type=resource&ver=1.0&sig=abc" || permission.Mode != PermissionModeAll || permission.ResourceLink != "dbs/db/colls/orders" {
+		t.Errorf("unexpected response %+v", permission)
+	}
+	if permission.ResourcePartitionKey == nil {
+		t.Fatal("expected a resource partition key")
+	}
+	if s, _ := permission.ResourcePartitionKey.toJsonString(); s != `["alice"]` {
+		t.Errorf("unexpected resource partition key %s", s)
+	}
+
+	req := transport.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/dbs/db/users/alice/permissions" || req.Header.Get(cosmosHeaderResourceTokenExpiry) != "7200" {
+		t.Errorf("unexpected request %s %s %v", req.Method, req.URL.Path, req.Header)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(transport.bodies[0], &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["id"] != "orders" || body["permissionMode"] != "All" || body["resource"] != "dbs/db/colls/orders" {
+		t.Errorf("unexpected body %s", transport.bodies[0])
+	}
+	if values, ok := body["resourcePartitionKey"].([]interface{}); !ok || len(values) != 1 || values[0] != "alice" {
+		t.Errorf("unexpected resource partition key in %s", transport.bodies[0])
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"orders","permissionMode":"Read","resource":"dbs/db/colls/orders"}`)))
+	read, err := user.ReadPermission(ctx, "orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.PermissionProperties.ResourcePartitionKey != nil || transport.requests[1].URL.Path != "/dbs/db/users/alice/permissions/orders" {
+		t.Errorf("unexpected response %+v", read.PermissionProperties)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"orders","permissionMode":"Read","resource":"dbs/db/colls/orders"}`)))
+	if _, err := user.ReplacePermission(ctx, PermissionProperties{ID: "orders", Mode: PermissionModeRead, ResourceLink: "dbs/db/colls/orders"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.requests[2]; req.Method != http.MethodPut || req.URL.Path != "/dbs/db/users/alice/permissions/orders" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	if string(transport.bodies[2]) != `{"id":"orders","permissionMode":"Read","resource":"dbs/db/colls/orders"}` {
+		t.Errorf("unexpected body %s", transport.bodies[2])
+	}
+
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	if _, err := user.DeletePermission(ctx, "orders", nil); err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.requests[3]; req.Method != http.MethodDelete || req.URL.Path != "/dbs/db/users/alice/permissions/orders" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"Permissions":[{"id":"orders","_token":"t1"},{"id":"profile","_token":"t2"}]}`)))
+	pager := user.NewQueryPermissionsPager("SELECT * FROM p", &QueryPermissionsOptions{ResourceTokenExpiry: 20 * time.Minute})
+	page, err := pager.NextPage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Permissions) != 2 || page.Permissions[1].Token != "t2" {
+		t.Errorf("unexpected page %+v", page.Permissions)
+	}
+	if req := transport.requests[4]; req.URL.Path != "/dbs/db/users/alice/permissions" || req.Header.Get(cosmosHeaderResourceTokenExpiry) != "1200" {
+		t.Errorf("unexpected request %s %v", req.URL.Path, req.Header)
+	}
+}
+
+func TestPermissionPropertiesSerialization(t *testing.T) {
+	pk := NewPartitionKey().AppendString("tenant").AppendNumber(5).AppendNull()
+	b, err := json.Marshal(PermissionProperties{ID: "p", Mode: PermissionModeRead, ResourceLink: "dbs/db/colls/c", ResourcePartitionKey: &pk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"id":"p","permissionMode":"Read","resource":"dbs/db/colls/c","resourcePartitionKey":["tenant",5,null]}` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+
+	var permission PermissionProperties
+	if err := json.Unmarshal(b, &permission); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := permission.ResourcePartitionKey.toJsonString(); s != `["tenant",5,null]` {
+		t.Errorf("unexpected resource partition key %s", s)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+	"unicode/utf16"
+)
+
+// partition key component markers written before each component's value when hashing
+const (
+	partitionKeyComponentUndefined byte = 0x00
+	partitionKeyComponentNull      byte = 0x01
+	partitionKeyComponentFalse     byte = 0x02
+	partitionKeyComponentTrue      byte = 0x03
+	partitionKeyComponentNumber    byte = 0x05
+	partitionKeyComponentString    byte = 0x08
+)
+
+// version 1 of hash partitioning hashes the first 100 UTF-16 code units of strings, and the binary
+// encoding of effective partition keys keeps the first 100 bytes of their UTF-8 encoding
+const (
+	maxHashedStringLengthV1 = 100
+	maxEncodedStringBytesV1 = 100
+)
+
+// canComputeEffectivePartitionKey returns true when the client can hash partition keys of containers
+// having the given partition key definition.
+func canComputeEffectivePartitionKey(def PartitionKeyDefinition) bool {
+	switch def.Kind {
+	case PartitionKeyKindHash, "":
+		return len(def.Paths) == 1
+	case PartitionKeyKindMultiHash:
+		return def.Version == 2
+	}
+	return false
+}
+
+// effectivePartitionKey returns the effective partition key of a partition key of a container having the
+// given partition key definition. The value determines the partition key range storing the partition key's items.
+// The effective partition key of a hierarchical partition key is the concatenation of the hashes of its values,
+// so the effective partition keys of the items of a prefix partition key share the prefix's effective partition key.
+func effectivePartitionKey(def PartitionKeyDefinition, pk PartitionKey) (string, error) {
+	if def.Kind != PartitionKeyKindMultiHash {
+		if def.Version < 2 {
+			return effectivePartitionKeyV1(pk)
+		}
+		return effectivePartitionKeyV2(pk)
+	}
+	if len(pk.values) == 0 || len(pk.values) > len(def.Paths) {
+		return "", fmt.Errorf("the partition key has %d values, but the container's partition key has %d paths", len(pk.values), len(def.Paths))
+	}
+	epk := strings.Builder{}
+	for _, v := range pk.values {
+		hash, err := effectivePartitionKeyV2(PartitionKey{values: []interface{}{v}})
+		if err != nil {
+			return "", err
+		}
+		epk.WriteString(hash)
+	}
+	return epk.String(), nil
+}
+
+// effectivePartitionKeyRange returns the range of effective partition keys of the items of a prefix partition key.
+func effectivePartitionKeyRange(def PartitionKeyDefinition, prefix PartitionKey) (FeedRange, error) {
+	if def.Kind != PartitionKeyKindMultiHash || def.Version != 2 {
+		return FeedRange{}, errors.New("prefix partition keys require a container with hierarchical partition keys")
+	}
+	epk, err := effectivePartitionKey(def, prefix)
+	if err != nil {
+		return FeedRange{}, err
+	}
+	return FeedRange{MinInclusive: epk, MaxExclusive: epk + maxExclusiveEffectivePartitionKey}, nil
+}
+
+// effectivePartitionKeyV1 returns the effective partition key of a partition key of a container
+// using version 1 of hash partitioning, which is the binary encoding of the partition key's hash
+// followed by its values.
+func effectivePartitionKeyV1(pk PartitionKey) (string, error) {
+	values := make([]interface{}, len(pk.values))
+	buf := bytes.Buffer{}
+	for i, v := range pk.values {
+		if s, ok := v.(string); ok {
+			if u := utf16.Encode([]rune(s)); len(u) > maxHashedStringLengthV1 {
+				v = string(utf16.Decode(u[:maxHashedStringLengthV1]))
+			}
+		}
+		values[i] = v
+
+		switch c := v.(type) {
+		case undefinedPartitionKeyValue:
+			buf.WriteByte(partitionKeyComponentUndefined)
+		case nil:
+			buf.WriteByte(partitionKeyComponentNull)
+		case bool:
+			if c {
+				buf.WriteByte(partitionKeyComponentTrue)
+			} else {
+				buf.WriteByte(partitionKeyComponentFalse)
+			}
+		case float64:
+			buf.WriteByte(partitionKeyComponentNumber)
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(c))
+			buf.Write(b[:])
+		case string:
+			buf.WriteByte(partitionKeyComponentString)
+			buf.WriteString(c)
+			buf.WriteByte(0x00)
+		default:
+			return "", fmt.Errorf("unsupported partition key value %v", v)
+		}
+	}
+
+	encoded := bytes.Buffer{}
+	writeBinaryEncodedNumber(&encoded, float64(murmurHash3x8632(buf.Bytes(), 0)))
+	for _, v := range values {
+		switch c := v.(type) {
+		case undefinedPartitionKeyValue:
+			encoded.WriteByte(partitionKeyComponentUndefined)
+		case nil:
+			encoded.WriteByte(partitionKeyComponentNull)
+		case bool:
+			if c {
+				encoded.WriteByte(partitionKeyComponentTrue)
+			} else {
+				encoded.WriteByte(partitionKeyComponentFalse)
+			}
+		case float64:
+			writeBinaryEncodedNumber(&encoded, c)
+		case string:
+			writeBinaryEncodedString(&encoded, c)
+		}
+	}
+	return strings.ToUpper(hex.EncodeToString(encoded.Bytes())), nil
+}
+
+// writeBinaryEncodedNumber writes a number so that the encodings of numbers sort in numeric order.
+// The first byte holds 8 bits of the number, and each following byte holds 7 bits followed by a bit
+// which is set in every byte but the last.
+func writeBinaryEncodedNumber(buf *bytes.Buffer, n float64) {
+	buf.WriteByte(partitionKeyComponentNumber)
+
+	// flip the sign bit of positive numbers, and negate negative numbers, so the payloads sort in numeric order
+	payload := math.Float64bits(n)
+	if payload&(1<<63) == 0 {
+		payload ^= 1 << 63
+	} else {
+		payload = ^payload + 1
+	}
+
+	buf.WriteByte(byte(payload >> 56))
+	payload <<= 8
+	var b byte
+	for first := true; first || payload != 0; first = false {
+		if !first {
+			buf.WriteByte(b)
+		}
+		b = byte(payload>>56) | 0x01
+		payload <<= 7
+	}
+	buf.WriteByte(b & 0xFE)
+}
+
+// writeBinaryEncodedString writes the first bytes of a string, each incremented so that 0x00 terminates strings
+// which aren't truncated.
+func writeBinaryEncodedString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(partitionKeyComponentString)
+
+	short := len(s) <= maxEncodedStringBytesV1
+	n := len(s)
+	if !short {
+		n = maxEncodedStringBytesV1 + 1
+	}
+	for i := 0; i < n; i++ {
+		b := s[i]
+		if b < 0xFF {
+			b++
+		}
+		buf.WriteByte(b)
+	}
+	if short {
+		buf.WriteByte(0x00)
+	}
+}
+
+// effectivePartitionKeyV2 returns the effective partition key of a partition key of a container
+// using version 2 of hash partitioning.
+func effectivePartitionKeyV2(pk PartitionKey) (string, error) {
+	buf := bytes.Buffer{}
+	for _, v := range pk.values {
+		switch c := v.(type) {
+		case undefinedPartitionKeyValue:
+			buf.WriteByte(partitionKeyComponentUndefined)
+		case nil:
+			buf.WriteByte(partitionKeyComponentNull)
+		case bool:
+			if c {
+				buf.WriteByte(partitionKeyComponentTrue)
+			} else {
+				buf.WriteByte(partitionKeyComponentFalse)
+			}
+		case float64:
+			buf.WriteByte(partitionKeyComponentNumber)
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(c))
+			buf.Write(b[:])
+		case string:
+			buf.WriteByte(partitionKeyComponentString)
+			buf.WriteString(c)
+			buf.WriteByte(0xFF)
+		default:
+			return "", fmt.Errorf("unsupported partition key value %v", v)
+		}
+	}
+
+	h1, h2 := murmurHash3x64128(buf.Bytes(), 0)
+	// the hash's bytes in reverse order, which is h2 then h1 in big endian order
+	var hash [16]byte
+	binary.BigEndian.PutUint64(hash[:8], h2)
+	binary.BigEndian.PutUint64(hash[8:], h1)
+	// clear the top two bits so the value sorts before the maximum effective partition key, "FF"
+	hash[0] &= 0x3F
+	return strings.ToUpper(hex.EncodeToString(hash[:])), nil
+}
+
+// murmurHash3x8632 computes the x86 32-bit variant of MurmurHash3.
+func murmurHash3x8632(data []byte, seed uint32) uint32 {
+	const (
+		c1 uint32 = 0xcc9e2d51
+		c2 uint32 = 0x1b873593
+	)
+	h := seed
+
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[nblocks*4:]
+	if len(tail) > 0 {
+		var k uint32
+		for i := len(tail) - 1; i >= 0; i-- {
+			k ^= uint32(tail[i]) << (i * 8)
+		}
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// murmurHash3x64128 computes the x64 128-bit variant of MurmurHash3.
+func murmurHash3x64128(data []byte, seed uint64) (uint64, uint64) {
+	const (
+		c1 uint64 = 0x87c37b91114253d5
+		c2 uint64 = 0x4cf5ad432745937f
+	)
+	h1, h2 := seed, seed
+
+	nblocks := len(data) / 16
+	for i := 0; i < nblocks; i++ {
+		k1 := binary.LittleEndian.Uint64(data[i*16:])
+		k2 := binary.LittleEndian.Uint64(data[i*16+8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := data[nblocks*16:]
+	if len(tail) > 8 {
+		var k2 uint64
+		for i := len(tail) - 1; i >= 8; i-- {
+			k2 ^= uint64(tail[i]) << ((i - 8) * 8)
+		}
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	if len(tail) > 0 {
+		var k1 uint64
+		for i := 0; i < len(tail) && i < 8; i++ {
+			k1 ^= uint64(tail[i]) << (i * 8)
+		}
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(len(data))
+	h2 ^= uint64(len(data))
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
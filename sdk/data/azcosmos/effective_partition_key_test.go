@@ -0,0 +1,169 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMurmurHash3x64128(t *testing.T) {
+	for _, test := range []struct {
+		data     string
+		seed     uint64
+		expected string
+	}{
+		{data: "", seed: 0, expected: "00000000000000000000000000000000"},
+		{data: "The quick brown fox jumps over the lazy dog", seed: 0, expected: "e34bbc7bbc071b6c7a433ca9c49a9347"},
+	} {
+		h1, h2 := murmurHash3x64128([]byte(test.data), test.seed)
+		if actual := fmt.Sprintf("%016x%016x", h1, h2); actual != test.expected {
+			t.Errorf("expected %s for %q, got %s", test.expected, test.data, actual)
+		}
+	}
+}
+
+func TestMurmurHash3x8632(t *testing.T) {
+	for _, test := range []struct {
+		data     string
+		seed     uint32
+		expected uint32
+	}{
+		{data: "", seed: 0, expected: 0},
+		{data: "", seed: 1, expected: 0x514e28b7},
+		{data: "", seed: 0xffffffff, expected: 0x81f16f39},
+		{data: "aaaa", seed: 0x9747b28c, expected: 0x5a97808a},
+		{data: "Hello, world!", seed: 0x9747b28c, expected: 0x24884cba},
+		{data: "The quick brown fox jumps over the lazy dog", seed: 0x9747b28c, expected: 0x2fa826cd},
+	} {
+		if actual := murmurHash3x8632([]byte(test.data), test.seed); actual != test.expected {
+			t.Errorf("expected %08x for %q with seed %08x, got %08x", test.expected, test.data, test.seed, actual)
+		}
+	}
+}
+
+func TestEffectivePartitionKeyVectors(t *testing.T) {
+	for _, test := range []struct {
+		pk PartitionKey
+		v1 string
+		v2 string
+	}{
+		{NewPartitionKeyString(""), "05C1CF33970FF80800", "32E9366E637A71B4E710384B2F4970A0"},
+		{NewPartitionKeyString("partitionKey"), "05C1E1B3D9CD2608716273756A756A706F4C667A00", "013AEFCF77FA271571CF665A58C933F1"},
+		{NewPartitionKey().AppendNull(), "05C1ED45D7475601", "378867E4430E67857ACE5C908374FE16"},
+		{NewPartitionKey().AppendUndefined(), "05C1D529E345DC00", "11622DAA78F835834610ABE56EFF5CB5"},
+		{NewPartitionKeyBool(true), "05C1D7C5A903D803", "0E711127C5B5A8E4726AC6DD306A3E59"},
+		{NewPartitionKeyBool(false), "05C1DB857D857C02", "2FE1BE91E90A3439635E0E9E37361EF2"},
+		{NewPartitionKeyNumber(-128), "05C1D73349F54C053FA0", "01DAEDABF913540367FE219B2AD06148"},
+		{NewPartitionKeyNumber(127), "05C1DD539DDFCC05C05FE0", "0C507ACAC853ECA7977BF4CEFB562A25"},
+	} {
+		v1, err := effectivePartitionKey(PartitionKeyDefinition{Paths: []string{"/pk"}, Kind: PartitionKeyKindHash}, test.pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v1 != test.v1 {
+			t.Errorf("expected version 1 effective partition key %s for %v, got %s", test.v1, test.pk.values, v1)
+		}
+		v2, err := effectivePartitionKey(PartitionKeyDefinition{Paths: []string{"/pk"}, Kind: PartitionKeyKindHash, Version: 2}, test.pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v2 != test.v2 {
+			t.Errorf("expected version 2 effective partition key %s for %v, got %s", test.v2, test.pk.values, v2)
+		}
+	}
+}
+
+func TestEffectivePartitionKeyV1LongString(t *testing.T) {
+	// strings are hashed and encoded up to their first 100 characters
+	long, _ := effectivePartitionKeyV1(NewPartitionKeyString(strings.Repeat("a", 1024)))
+	truncated, _ := effectivePartitionKeyV1(NewPartitionKeyString(strings.Repeat("a", 100)))
+	if long != truncated {
+		t.Errorf("expected %s, got %s", truncated, long)
+	}
+
+	// the encoding of a string longer than 100 bytes has no terminator
+	buf := bytes.Buffer{}
+	writeBinaryEncodedString(&buf, strings.Repeat("é", 60))
+	if buf.Len() != 102 || buf.Bytes()[101] == 0x00 {
+		t.Errorf("unexpected encoding %X", buf.Bytes())
+	}
+}
+
+func TestEffectivePartitionKeyV2(t *testing.T) {
+	seen := map[string]bool{}
+	for _, pk := range []PartitionKey{
+		NewPartitionKeyString(""),
+		NewPartitionKeyString("redmond"),
+		NewPartitionKeyString("seattle"),
+		NewPartitionKeyNumber(0),
+		NewPartitionKeyNumber(1.5),
+		NewPartitionKeyBool(true),
+		NewPartitionKeyBool(false),
+	} {
+		epk, err := effectivePartitionKeyV2(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(epk) != 32 || epk >= maxExclusiveEffectivePartitionKey || epk[0] > '3' {
+			t.Errorf("unexpected effective partition key %s for %v", epk, pk.values)
+		}
+		if seen[epk] {
+			t.Errorf("duplicate effective partition key %s", epk)
+		}
+		seen[epk] = true
+
+		again, _ := effectivePartitionKeyV2(pk)
+		if again != epk {
+			t.Errorf("expected the effective partition key of %v to be stable", pk.values)
+		}
+	}
+}
+
+func TestEffectivePartitionKeyMultiHash(t *testing.T) {
+	def := PartitionKeyDefinition{Paths: []string{"/tenantId", "/userId", "/sessionId"}, Kind: PartitionKeyKindMultiHash, Version: 2}
+	if !canComputeEffectivePartitionKey(def) {
+		t.Fatal("expected the effective partition keys of hierarchical partition keys to be computable")
+	}
+
+	pk := NewPartitionKey().AppendString("tenant").AppendNull().AppendUndefined()
+	epk, err := effectivePartitionKey(def, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(epk) != 96 {
+		t.Fatalf("expected a hash for each value, got %s", epk)
+	}
+	for i, v := range pk.values {
+		hash, _ := effectivePartitionKeyV2(PartitionKey{values: []interface{}{v}})
+		if epk[i*32:(i+1)*32] != hash {
+			t.Errorf("expected value %d to hash to %s, got %s", i, hash, epk[i*32:(i+1)*32])
+		}
+	}
+	if epk[32:64] == epk[64:] {
+		t.Error("expected null and undefined values to hash differently")
+	}
+
+	// the items of a prefix partition key are in the prefix's range
+	prefixRange, err := effectivePartitionKeyRange(def, NewPartitionKey().AppendString("tenant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epk < prefixRange.MinInclusive || epk >= prefixRange.MaxExclusive {
+		t.Errorf("expected %s to be in %v", epk, prefixRange)
+	}
+	other, _ := effectivePartitionKey(def, NewPartitionKey().AppendString("other tenant").AppendString("user"))
+	if other >= prefixRange.MinInclusive && other < prefixRange.MaxExclusive {
+		t.Errorf("expected %s not to be in %v", other, prefixRange)
+	}
+
+	if _, err := effectivePartitionKey(def, pk.AppendString("too many")); err == nil {
+		t.Error("expected an error for a partition key with more values than paths")
+	}
+	if _, err := effectivePartitionKeyRange(PartitionKeyDefinition{Paths: []string{"/pk"}, Version: 2}, pk); err == nil {
+		t.Error("expected an error for a prefix of a container without hierarchical partition keys")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// EncryptionAlgorithmAEADAES256CBCHMACSHA256 is the algorithm encrypting properties with client encryption keys.
+// It's the algorithm of Always Encrypted, which authenticates AES-256 CBC ciphertext with an HMAC-SHA256 tag.
+const EncryptionAlgorithmAEADAES256CBCHMACSHA256 = "AEAD_AES_256_CBC_HMAC_SHA256"
+
+const (
+	aeadAlgorithmVersion byte = 0x01
+	aeadKeySize               = 32
+	aeadBlockSize             = aes.BlockSize
+	aeadTagSize               = sha256.Size
+	// aeadMinCiphertextSize is the size of the version byte, tag, IV and one block of ciphertext
+	aeadMinCiphertextSize = 1 + aeadTagSize + aeadBlockSize + aeadBlockSize
+)
+
+// the salts deriving the keys of AEAD_AES_256_CBC_HMAC_SHA256 from a data encryption key, as in Always Encrypted
+const (
+	aeadEncryptionKeySalt = "Microsoft SQL Server cell encryption key with encryption algorithm:" + EncryptionAlgorithmAEADAES256CBCHMACSHA256 + " and key length:256"
+	aeadMACKeySalt        = "Microsoft SQL Server cell MAC key with encryption algorithm:" + EncryptionAlgorithmAEADAES256CBCHMACSHA256 + " and key length:256"
+	aeadIVKeySalt         = "Microsoft SQL Server cell IV key with encryption algorithm:" + EncryptionAlgorithmAEADAES256CBCHMACSHA256 + " and key length:256"
+)
+
+// aeadKey holds the keys AEAD_AES_256_CBC_HMAC_SHA256 derives from a data encryption key.
+type aeadKey struct {
+	encryptionKey []byte
+	macKey        []byte
+	ivKey         []byte
+}
+
+func newAEADKey(dataEncryptionKey []byte) (*aeadKey, error) {
+	if len(dataEncryptionKey) != aeadKeySize {
+		return nil, fmt.Errorf("data encryption keys have %d bytes, got %d bytes", aeadKeySize, len(dataEncryptionKey))
+	}
+	return &aeadKey{
+		encryptionKey: deriveAEADKey(dataEncryptionKey, aeadEncryptionKeySalt),
+		macKey:        deriveAEADKey(dataEncryptionKey, aeadMACKeySalt),
+		ivKey:         deriveAEADKey(dataEncryptionKey, aeadIVKeySalt),
+	}, nil
+}
+
+// deriveAEADKey returns the HMAC-SHA256 of the UTF-16LE encoding of salt
+func deriveAEADKey(key []byte, salt string) []byte {
+	units := utf16.Encode([]rune(salt))
+	b := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return hmacSHA256(key, b)
+}
+
+func hmacSHA256(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// encrypt returns the version byte, the authentication tag, the IV and the AES-256 CBC ciphertext of plaintext.
+// Deterministic encryption derives the IV from plaintext, so equal plaintexts have equal ciphertexts.
+func (k *aeadKey) encrypt(plaintext []byte, deterministic bool) ([]byte, error) {
+	iv := make([]byte, aeadBlockSize)
+	if deterministic {
+		copy(iv, hmacSHA256(k.ivKey, plaintext))
+	} else if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(k.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	// PKCS #7 padding
+	padding := aeadBlockSize - len(plaintext)%aeadBlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	result := make([]byte, 0, 1+aeadTagSize+len(iv)+len(ciphertext))
+	result = append(result, aeadAlgorithmVersion)
+	result = append(result, k.tag(iv, ciphertext)...)
+	result = append(result, iv...)
+	result = append(result, ciphertext...)
+	return result, nil
+}
+
+// decrypt authenticates and decrypts the result of encrypt.
+func (k *aeadKey) decrypt(encrypted []byte) ([]byte, error) {
+	if len(encrypted) < aeadMinCiphertextSize || (len(encrypted)-1-aeadTagSize)%aeadBlockSize != 0 {
+		return nil, errors.New("invalid ciphertext length")
+	}
+	if encrypted[0] != aeadAlgorithmVersion {
+		return nil, fmt.Errorf("unsupported ciphertext version %d", encrypted[0])
+	}
+	tag := encrypted[1 : 1+aeadTagSize]
+	iv := encrypted[1+aeadTagSize : 1+aeadTagSize+aeadBlockSize]
+	ciphertext := encrypted[1+aeadTagSize+aeadBlockSize:]
+	if !hmac.Equal(tag, k.tag(iv, ciphertext)) {
+		return nil, errors.New("the ciphertext's authentication tag is invalid")
+	}
+
+	block, err := aes.NewCipher(k.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aeadBlockSize {
+		return nil, errors.New("invalid padding")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// tag authenticates the version byte, the IV, the ciphertext and the size of the version byte
+func (k *aeadKey) tag(iv, ciphertext []byte) []byte {
+	return hmacSHA256(k.macKey, []byte{aeadAlgorithmVersion}, iv, ciphertext, []byte{1})
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAEADKeyRoundTrip(t *testing.T) {
+	key, err := newAEADKey(bytes.Repeat([]byte{7}, aeadKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range [][]byte{{}, []byte("secret"), bytes.Repeat([]byte{1}, aeadBlockSize)} {
+		for _, deterministic := range []bool{true, false} {
+			encrypted, err := key.encrypt(plaintext, deterministic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(encrypted) != 1+aeadTagSize+aeadBlockSize+(len(plaintext)/aeadBlockSize+1)*aeadBlockSize {
+				t.Errorf("unexpected ciphertext length %d", len(encrypted))
+			}
+			decrypted, err := key.decrypt(encrypted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("expected %q, got %q", plaintext, decrypted)
+			}
+		}
+	}
+}
+
+func TestAEADKeyDeterministic(t *testing.T) {
+	key, err := newAEADKey(bytes.Repeat([]byte{7}, aeadKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := key.encrypt([]byte("secret"), true)
+	second, _ := key.encrypt([]byte("secret"), true)
+	if !bytes.Equal(first, second) {
+		t.Error("expected deterministic encryption to return equal ciphertexts")
+	}
+	first, _ = key.encrypt([]byte("secret"), false)
+	second, _ = key.encrypt([]byte("secret"), false)
+	if bytes.Equal(first, second) {
+		t.Error("expected randomized encryption to return different ciphertexts")
+	}
+
+	other, err := newAEADKey(bytes.Repeat([]byte{8}, aeadKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.decrypt(first); err == nil {
+		t.Error("expected decryption with another key to fail")
+	}
+}
+
+func TestAEADKeyTampering(t *testing.T) {
+	key, err := newAEADKey(bytes.Repeat([]byte{7}, aeadKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := key.encrypt([]byte("secret"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range encrypted {
+		tampered := append([]byte{}, encrypted...)
+		tampered[i] ^= 1
+		if _, err := key.decrypt(tampered); err == nil {
+			t.Errorf("expected changing byte %d to fail decryption", i)
+		}
+	}
+	if _, err := key.decrypt(encrypted[:len(encrypted)-1]); err == nil {
+		t.Error("expected a truncated ciphertext to fail decryption")
+	}
+	if _, err := newAEADKey([]byte("short")); err == nil {
+		t.Error("expected a short key to fail")
+	}
+}
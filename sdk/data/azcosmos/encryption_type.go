@@ -0,0 +1,26 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// EncryptionType defines how client-side encryption encrypts a property.
+type EncryptionType string
+
+const (
+	// EncryptionTypeDeterministic encrypts equal values to equal ciphertexts, so queries can compare encrypted
+	// properties for equality. It can reveal which items have equal values.
+	EncryptionTypeDeterministic EncryptionType = "Deterministic"
+	// EncryptionTypeRandomized encrypts equal values to different ciphertexts. Queries can't filter on
+	// properties encrypted this way.
+	EncryptionTypeRandomized EncryptionType = "Randomized"
+)
+
+// Returns a list of available encryption types
+func EncryptionTypeValues() []EncryptionType {
+	return []EncryptionType{EncryptionTypeDeterministic, EncryptionTypeRandomized}
+}
+
+// ToPtr returns a *EncryptionType
+func (c EncryptionType) ToPtr() *EncryptionType {
+	return &c
+}
@@ -0,0 +1,172 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// batchOperation is an operation of a batch request
+type batchOperation struct {
+	OperationType string          `json:"operationType"`
+	ID            string          `json:"id"`
+	IfMatch       string          `json:"ifMatch"`
+	PartitionKey  string          `json:"partitionKey"`
+	ResourceBody  json.RawMessage `json:"resourceBody"`
+}
+
+// batchResult is the result of an operation of a batch request
+type batchResult struct {
+	StatusCode    int             `json:"statusCode"`
+	RequestCharge float64         `json:"requestCharge"`
+	ETag          string          `json:"eTag,omitempty"`
+	ResourceBody  json.RawMessage `json:"resourceBody,omitempty"`
+}
+
+// executeBatch executes a batch request. An atomic batch, the default, commits all its operations or none:
+// when an operation fails, the others fail with status 424 and the response has status 207. Operations of a
+// non-atomic batch, which azcosmos sends for bulk operations, succeed or fail independently.
+func (s *Server) executeBatch(req *request, c *container) error {
+	var operations []batchOperation
+	if err := unmarshal(req.body, &operations); err != nil {
+		return newStatusError(http.StatusBadRequest, "invalid batch: %v", err)
+	}
+	if len(operations) == 0 || len(operations) > maxOperationsPerBatch {
+		return newStatusError(http.StatusBadRequest, "a batch must have between 1 and %d operations", maxOperationsPerBatch)
+	}
+	atomic := !strings.EqualFold(req.Header.Get(headerIsBatchAtomic), "false")
+	partitionKey, ok, err := requestPartitionKey(req)
+	if err != nil {
+		return err
+	}
+	if atomic && !ok {
+		return newStatusError(http.StatusBadRequest, "a transactional batch must have a partition key")
+	}
+
+	// an atomic batch writes a copy of the container's items, which replaces them when every operation succeeds
+	items := c.items
+	if atomic {
+		items = make(map[itemKey]*item, len(c.items))
+		for k, v := range c.items {
+			items[k] = v
+		}
+	}
+	staged := &container{properties: c.properties, partitionKeyPaths: c.partitionKeyPaths, items: items, seq: c.seq}
+	minimal := req.Header.Get(headerPrefer) == preferMinimal
+
+	results := make([]batchResult, len(operations))
+	failed := -1
+	for i, op := range operations {
+		pk := partitionKey
+		if op.PartitionKey != "" {
+			var values []any
+			if err := unmarshal([]byte(op.PartitionKey), &values); err != nil {
+				return newStatusError(http.StatusBadRequest, "invalid partition key %q", op.PartitionKey)
+			}
+			pk = canonicalPartitionKey(undefinedValues(values))
+		}
+		results[i] = s.executeBatchOperation(staged, pk, op, minimal)
+		if atomic && results[i].StatusCode >= 400 {
+			failed = i
+			break
+		}
+	}
+
+	statusCode := http.StatusOK
+	if failed >= 0 {
+		for i := range results {
+			if i != failed {
+				results[i] = batchResult{StatusCode: http.StatusFailedDependency}
+			}
+		}
+		statusCode = http.StatusMultiStatus
+	} else {
+		c.items, c.seq = staged.items, staged.seq
+	}
+
+	charge := 0.0
+	for _, r := range results {
+		charge += r.RequestCharge
+	}
+	s.writeJSON(req, statusCode, results, charge)
+	return nil
+}
+
+// executeBatchOperation executes an operation of a batch on the items of c
+func (s *Server) executeBatchOperation(c *container, partitionKey string, op batchOperation, minimal bool) batchResult {
+	result, err := s.applyBatchOperation(c, partitionKey, op)
+	if err != nil {
+		e, ok := err.(*statusError)
+		if !ok {
+			e = newStatusError(http.StatusInternalServerError, "%v", err)
+		}
+		return batchResult{StatusCode: e.statusCode, RequestCharge: readCharge}
+	}
+	if minimal && op.OperationType != "Read" {
+		result.ResourceBody = nil
+	}
+	return result
+}
+
+func (s *Server) applyBatchOperation(c *container, partitionKey string, op batchOperation) (batchResult, error) {
+	var doc map[string]any
+	switch op.OperationType {
+	case "Create", "Upsert", "Replace":
+		var err error
+		if doc, err = parseItem(op.ResourceBody); err != nil {
+			return batchResult{}, err
+		}
+		if op.ID == "" {
+			op.ID = doc["id"].(string)
+		} else if doc["id"] != op.ID {
+			return batchResult{}, newStatusError(http.StatusBadRequest, "the id of the item doesn't match the id of the operation")
+		}
+		if itemPartitionKey := c.partitionKeyOf(doc); itemPartitionKey != partitionKey {
+			return batchResult{}, newStatusError(http.StatusBadRequest, "partition key %s extracted from the item doesn't match the partition key %s of the batch", itemPartitionKey, partitionKey)
+		}
+	case "Read", "Delete", "Patch":
+	default:
+		return batchResult{}, newStatusError(http.StatusBadRequest, "unsupported operation type %q", op.OperationType)
+	}
+
+	key := itemKey{partitionKey: partitionKey, id: op.ID}
+	existing, exists := c.items[key]
+	switch {
+	case op.OperationType == "Create" && exists:
+		return batchResult{}, newStatusError(http.StatusConflict, "entity with the specified id already exists in the system")
+	case op.OperationType == "Create", op.OperationType == "Upsert" && !exists:
+		if op.IfMatch != "" {
+			return batchResult{}, newStatusError(http.StatusPreconditionFailed, "operation cannot be performed because one of the specified precondition is not met")
+		}
+		s.putItem(c, key, doc)
+		return newBatchResult(http.StatusCreated, doc, writeCharge), nil
+	case !exists:
+		return batchResult{}, newStatusError(http.StatusNotFound, "entity with the specified id does not exist in the system")
+	case op.IfMatch != "" && op.IfMatch != "*" && op.IfMatch != existing.doc["_etag"]:
+		return batchResult{}, newStatusError(http.StatusPreconditionFailed, "operation cannot be performed because one of the specified precondition is not met")
+	}
+
+	switch op.OperationType {
+	case "Read":
+		return newBatchResult(http.StatusOK, existing.doc, readCharge), nil
+	case "Delete":
+		delete(c.items, key)
+		s.nextETag()
+		return batchResult{StatusCode: http.StatusNoContent, RequestCharge: writeCharge}, nil
+	case "Patch":
+		var err error
+		if doc, err = c.patch(existing.doc, op.ResourceBody); err != nil {
+			return batchResult{}, err
+		}
+	}
+	s.putItem(c, key, doc)
+	return newBatchResult(http.StatusOK, doc, writeCharge), nil
+}
+
+func newBatchResult(statusCode int, doc map[string]any, charge float64) batchResult {
+	body, _ := json.Marshal(doc)
+	return batchResult{StatusCode: statusCode, RequestCharge: charge, ETag: doc["_etag"].(string), ResourceBody: body}
+}
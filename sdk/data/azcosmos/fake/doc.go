@@ -0,0 +1,31 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+/*
+Package fake provides an in-memory fake of the Cosmos DB service for unit testing code that uses azcosmos.
+
+Server implements the subset of the Cosmos DB REST API azcosmos uses: databases, containers, item CRUD with
+ETags, patch, transactional batch, single partition queries, throughput and continuation tokens. It validates
+the signature of requests authenticated with an azcosmos.KeyCredential. Set it as the Transport of a client's
+ClientOptions, so the client sends requests to the server whatever its endpoint:
+
+	srv, err := fake.NewServer(nil)
+	handle(err)
+	defer srv.Close()
+	cred, err := azcosmos.NewKeyCredential(srv.Key())
+	handle(err)
+	client, err := azcosmos.NewClientWithKey("https://localhost:8081/", cred, &azcosmos.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: srv},
+	})
+	handle(err)
+
+Queries must target a single partition, and support a subset of the SQL grammar:
+
+	SELECT [TOP n] * | expression [AS name], ... FROM alias [WHERE condition] [ORDER BY expression [ASC | DESC], ...]
+
+Conditions compare properties and parameters with =, !=, <, <=, >, >=, IN and BETWEEN, combined with AND, OR
+and NOT, and may call the functions IS_DEFINED, IS_NULL, IS_STRING, IS_NUMBER, IS_BOOL, CONTAINS, STARTSWITH,
+ENDSWITH, LOWER, UPPER, LENGTH, ARRAY_LENGTH and ARRAY_CONTAINS. The server responds to requests it doesn't
+support, such as cross partition queries, the change feed and stored procedures, with status 501.
+*/
+package fake
@@ -0,0 +1,449 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// itemKey identifies an item by its partition key, as canonical JSON, and id
+type itemKey struct {
+	partitionKey string
+	id           string
+}
+
+type item struct {
+	doc map[string]any
+	seq int64
+}
+
+// partitionKeyOf returns the canonical partition key of an item. Missing properties have undefined values.
+func (c *container) partitionKeyOf(doc map[string]any) string {
+	values := make([]any, len(c.partitionKeyPaths))
+	for i, path := range c.partitionKeyPaths {
+		var v any = doc
+		for _, name := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+			m, ok := v.(map[string]any)
+			if !ok {
+				v = undefined{}
+				break
+			}
+			if v, ok = m[name]; !ok {
+				v = undefined{}
+				break
+			}
+		}
+		values[i] = v
+	}
+	return canonicalPartitionKey(values)
+}
+
+// canonicalPartitionKey returns the JSON of partition key values, with numbers formatted alike and
+// undefined values as empty objects
+func canonicalPartitionKey(values []any) string {
+	canonical := make([]any, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case json.Number:
+			f, _ := x.Float64()
+			canonical[i] = f
+		case undefined:
+			canonical[i] = map[string]any{}
+		default:
+			canonical[i] = v
+		}
+	}
+	b, _ := json.Marshal(canonical)
+	return string(b)
+}
+
+// requestPartitionKey returns the canonical partition key of a request's header. It returns false when
+// the request doesn't have a partition key.
+func requestPartitionKey(req *request) (string, bool, error) {
+	header := req.Header.Get(headerPartitionKey)
+	if header == "" {
+		return "", false, nil
+	}
+	var values []any
+	if err := unmarshal([]byte(header), &values); err != nil {
+		return "", false, newStatusError(http.StatusBadRequest, "invalid partition key %q", header)
+	}
+	if len(values) == 0 {
+		return "", false, nil
+	}
+	return canonicalPartitionKey(undefinedValues(values)), true, nil
+}
+
+// undefinedValues replaces the empty objects of partition key values, which represent undefined values, by undefined
+func undefinedValues(values []any) []any {
+	for i, v := range values {
+		if m, ok := v.(map[string]any); ok && len(m) == 0 {
+			values[i] = undefined{}
+		}
+	}
+	return values
+}
+
+// sortedItems returns the documents of items having a partition key, or all items when partitionKey is "",
+// in the order of their creation
+func (c *container) sortedItems(partitionKey string) []map[string]any {
+	items := make([]*item, 0, len(c.items))
+	for k, i := range c.items {
+		if partitionKey == "" || k.partitionKey == partitionKey {
+			items = append(items, i)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].seq < items[j].seq })
+	docs := make([]map[string]any, len(items))
+	for i, item := range items {
+		docs[i] = item.doc
+	}
+	return docs
+}
+
+func (s *Server) routeItems(req *request, c *container) error {
+	switch {
+	case req.Method != http.MethodPost:
+		if req.Header.Get(headerChangeFeed) != "" {
+			return newStatusError(http.StatusNotImplemented, "the fake doesn't support the change feed")
+		}
+		return newStatusError(http.StatusNotImplemented, "the fake doesn't support %s %s", req.Method, req.URL.Path)
+	case req.Header.Get(headerIsQueryPlan) != "":
+		return newStatusError(http.StatusNotImplemented, "the fake doesn't support cross partition queries")
+	case req.Header.Get(headerIsQuery) != "":
+		return s.queryItems(req, c)
+	case req.Header.Get(headerIsBatchRequest) != "":
+		return s.executeBatch(req, c)
+	}
+
+	doc, err := parseItem(req.body)
+	if err != nil {
+		return err
+	}
+	key, err := c.keyOf(req, doc)
+	if err != nil {
+		return err
+	}
+	existing, exists := c.items[key]
+	upsert := strings.EqualFold(req.Header.Get(headerIsUpsert), "true")
+	statusCode := http.StatusCreated
+	switch {
+	case exists && !upsert:
+		return newStatusError(http.StatusConflict, "entity with the specified id already exists in the system")
+	case exists:
+		if err := checkIfMatch(req, existing.doc["_etag"]); err != nil {
+			return err
+		}
+		statusCode = http.StatusOK
+	case req.Header.Get("If-Match") != "":
+		return newStatusError(http.StatusPreconditionFailed, "operation cannot be performed because one of the specified precondition is not met")
+	}
+	s.putItem(c, key, doc)
+	s.writeItem(req, statusCode, doc, writeCharge)
+	return nil
+}
+
+func (s *Server) routeItem(req *request, c *container, id string) error {
+	partitionKey, ok, err := requestPartitionKey(req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newStatusError(http.StatusBadRequest, "the request must have a partition key")
+	}
+	key := itemKey{partitionKey: partitionKey, id: id}
+	existing, exists := c.items[key]
+	if !exists {
+		return newStatusError(http.StatusNotFound, "entity with the specified id does not exist in the system")
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" && ifNoneMatch == existing.doc["_etag"] {
+			s.writeJSON(req, http.StatusNotModified, nil, readCharge)
+			return nil
+		}
+		s.writeJSON(req, http.StatusOK, existing.doc, readCharge)
+		return nil
+	case http.MethodPut:
+		if err := checkIfMatch(req, existing.doc["_etag"]); err != nil {
+			return err
+		}
+		doc, err := parseItem(req.body)
+		if err != nil {
+			return err
+		}
+		if doc["id"] != id {
+			return newStatusError(http.StatusBadRequest, "the id of the item doesn't match the id of the request")
+		}
+		if _, err := c.keyOf(req, doc); err != nil {
+			return err
+		}
+		s.putItem(c, key, doc)
+		s.writeItem(req, http.StatusOK, doc, writeCharge)
+		return nil
+	case http.MethodPatch:
+		if err := checkIfMatch(req, existing.doc["_etag"]); err != nil {
+			return err
+		}
+		doc, err := c.patch(existing.doc, req.body)
+		if err != nil {
+			return err
+		}
+		s.putItem(c, key, doc)
+		s.writeItem(req, http.StatusOK, doc, writeCharge)
+		return nil
+	case http.MethodDelete:
+		if err := checkIfMatch(req, existing.doc["_etag"]); err != nil {
+			return err
+		}
+		delete(c.items, key)
+		s.nextETag()
+		s.writeJSON(req, http.StatusNoContent, nil, writeCharge)
+		return nil
+	}
+	return newStatusError(http.StatusMethodNotAllowed, "unsupported method %s", req.Method)
+}
+
+// keyOf returns the key of an item written by a request, whose partition key must match the item's
+func (c *container) keyOf(req *request, doc map[string]any) (itemKey, error) {
+	partitionKey := c.partitionKeyOf(doc)
+	if requested, ok, err := requestPartitionKey(req); err != nil {
+		return itemKey{}, err
+	} else if ok && requested != partitionKey {
+		return itemKey{}, newStatusError(http.StatusBadRequest, "partition key %s extracted from the item doesn't match the partition key %s of the request", partitionKey, requested)
+	}
+	return itemKey{partitionKey: partitionKey, id: doc["id"].(string)}, nil
+}
+
+// putItem creates or replaces an item, setting its system properties
+func (s *Server) putItem(c *container, key itemKey, doc map[string]any) {
+	var rid string
+	var seq int64
+	if existing, ok := c.items[key]; ok {
+		rid, seq = existing.doc["_rid"].(string), existing.seq
+	} else {
+		c.seq++
+		rid, seq = newRID(), c.seq
+	}
+	s.systemProperties(doc, rid, c.properties["_self"].(string)+"docs/"+rid+"/")
+	doc["_attachments"] = "attachments/"
+	c.items[key] = &item{doc: doc, seq: seq}
+}
+
+// writeItem writes the response to a write, whose body is empty when the request prefers a minimal response
+func (s *Server) writeItem(req *request, statusCode int, doc map[string]any, charge float64) {
+	req.w.Header().Set("etag", doc["_etag"].(string))
+	if req.Header.Get(headerPrefer) == preferMinimal {
+		s.writeJSON(req, statusCode, nil, charge)
+		return
+	}
+	s.writeJSON(req, statusCode, doc, charge)
+}
+
+func (s *Server) queryItems(req *request, c *container) error {
+	q, err := parseQueryRequest(req)
+	if err != nil {
+		return err
+	}
+	partitionKey, _, err := requestPartitionKey(req)
+	if err != nil {
+		return err
+	}
+	return s.writePage(req, "Documents", q.run(c.sortedItems(partitionKey)), c.properties["_rid"].(string))
+}
+
+// parseItem parses an item, which must have an id
+func parseItem(body []byte) (map[string]any, error) {
+	doc, err := parseResource(body)
+	if err != nil {
+		return nil, err
+	}
+	for k := range doc {
+		// clients can't set system properties
+		if k == "_rid" || k == "_self" || k == "_etag" || k == "_ts" || k == "_attachments" {
+			delete(doc, k)
+		}
+	}
+	return doc, nil
+}
+
+// patch returns a copy of doc with the patch operations of body applied
+func (c *container) patch(doc map[string]any, body []byte) (map[string]any, error) {
+	var patch struct {
+		Condition  string `json:"condition"`
+		Operations []struct {
+			Op    string `json:"op"`
+			Path  string `json:"path"`
+			Value any    `json:"value"`
+		} `json:"operations"`
+	}
+	if err := unmarshal(body, &patch); err != nil {
+		return nil, newStatusError(http.StatusBadRequest, "invalid patch: %v", err)
+	}
+	if len(patch.Operations) == 0 {
+		return nil, newStatusError(http.StatusBadRequest, "the patch doesn't have operations")
+	}
+	if patch.Condition != "" {
+		q, err := parseQuery("SELECT * "+patch.Condition, nil)
+		if err != nil {
+			return nil, newStatusError(http.StatusBadRequest, "invalid patch condition: %v", err)
+		}
+		if !q.matches(doc) {
+			return nil, newStatusError(http.StatusPreconditionFailed, "the patch condition %q isn't satisfied", patch.Condition)
+		}
+	}
+
+	patched := clone(doc).(map[string]any)
+	for _, o := range patch.Operations {
+		if o.Path == "/id" || o.Path == "/_rid" || o.Path == "/_etag" || o.Path == "/_ts" || o.Path == "/_self" {
+			return nil, newStatusError(http.StatusBadRequest, "the patch can't modify %s", o.Path)
+		}
+		for _, path := range c.partitionKeyPaths {
+			if o.Path == path || strings.HasPrefix(path, o.Path+"/") {
+				return nil, newStatusError(http.StatusBadRequest, "the patch can't modify the partition key %s", path)
+			}
+		}
+		if err := applyPatchOperation(patched, o.Op, o.Path, o.Value); err != nil {
+			return nil, err
+		}
+	}
+	return patched, nil
+}
+
+// applyPatchOperation applies an add, set, replace, remove or incr operation to the property at a JSON pointer
+func applyPatchOperation(doc map[string]any, op, path string, value any) error {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	var parent any = doc
+	for _, segment := range segments[:len(segments)-1] {
+		parent = child(parent, segment)
+		if parent == nil {
+			return newStatusError(http.StatusBadRequest, "the path %s doesn't exist", path)
+		}
+	}
+	last := strings.NewReplacer("~1", "/", "~0", "~").Replace(segments[len(segments)-1])
+
+	switch p := parent.(type) {
+	case map[string]any:
+		current, exists := p[last]
+		switch op {
+		case "add", "set":
+			p[last] = value
+		case "replace":
+			if !exists {
+				return newStatusError(http.StatusBadRequest, "the path %s doesn't exist", path)
+			}
+			p[last] = value
+		case "remove":
+			if !exists {
+				return newStatusError(http.StatusBadRequest, "the path %s doesn't exist", path)
+			}
+			delete(p, last)
+		case "incr":
+			if !exists {
+				p[last] = value
+				return nil
+			}
+			sum, err := increment(current, value, path)
+			if err != nil {
+				return err
+			}
+			p[last] = sum
+		default:
+			return newStatusError(http.StatusBadRequest, "unsupported patch operation %q", op)
+		}
+		return nil
+	case []any:
+		// arrays are patched in place, so the parent array's owner sees the change
+		owner, ownerKey := ownerOf(doc, segments)
+		i, err := strconv.Atoi(last)
+		if last == "-" && (op == "add" || op == "set") {
+			i, err = len(p), nil
+		}
+		if err != nil || i < 0 || i > len(p) || (i == len(p) && op != "add" && op != "set") {
+			return newStatusError(http.StatusBadRequest, "invalid array index in path %s", path)
+		}
+		switch op {
+		case "add":
+			p = append(p[:i], append([]any{value}, p[i:]...)...)
+		case "set", "replace":
+			if i == len(p) {
+				p = append(p, value)
+			} else {
+				p[i] = value
+			}
+		case "remove":
+			p = append(p[:i], p[i+1:]...)
+		case "incr":
+			sum, err := increment(p[i], value, path)
+			if err != nil {
+				return err
+			}
+			p[i] = sum
+		default:
+			return newStatusError(http.StatusBadRequest, "unsupported patch operation %q", op)
+		}
+		setChild(owner, ownerKey, p)
+		return nil
+	}
+	return newStatusError(http.StatusBadRequest, "the path %s doesn't exist", path)
+}
+
+// child returns the property or array element named by a JSON pointer segment, or nil
+func child(v any, segment string) any {
+	segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+	switch x := v.(type) {
+	case map[string]any:
+		return x[segment]
+	case []any:
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= len(x) {
+			return nil
+		}
+		return x[i]
+	}
+	return nil
+}
+
+// ownerOf returns the container of the array at segments[:len(segments)-1], and the array's key in it
+func ownerOf(doc map[string]any, segments []string) (any, string) {
+	var owner any = doc
+	for _, segment := range segments[:len(segments)-2] {
+		owner = child(owner, segment)
+	}
+	return owner, segments[len(segments)-2]
+}
+
+func setChild(owner any, key string, value any) {
+	key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	switch x := owner.(type) {
+	case map[string]any:
+		x[key] = value
+	case []any:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(x) {
+			x[i] = value
+		}
+	}
+}
+
+// increment adds numbers, as integers when both are integers
+func increment(current, value any, path string) (any, error) {
+	a, ok1 := current.(json.Number)
+	b, ok2 := value.(json.Number)
+	if !ok1 || !ok2 {
+		return nil, newStatusError(http.StatusBadRequest, "the value at %s isn't a number", path)
+	}
+	x, err1 := a.Int64()
+	y, err2 := b.Int64()
+	if err1 == nil && err2 == nil {
+		return json.Number(strconv.FormatInt(x+y, 10)), nil
+	}
+	f, _ := a.Float64()
+	g, _ := b.Float64()
+	return json.Number(strconv.FormatFloat(f+g, 'g', -1, 64)), nil
+}
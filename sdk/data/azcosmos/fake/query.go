@@ -0,0 +1,934 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// undefined is the value of properties an item doesn't have, and of expressions comparing values of different types
+type undefined struct{}
+
+// query is a parsed query of the subset of the Cosmos SQL grammar the server supports:
+//
+//	SELECT [TOP n] * | expression [AS name], ... FROM alias [WHERE expression] [ORDER BY expression [ASC|DESC], ...]
+type query struct {
+	top        int
+	projection []projection
+	alias      string
+	where      expression
+	orderBy    []orderBy
+}
+
+type projection struct {
+	name string
+	expr expression
+}
+
+type orderBy struct {
+	expr       expression
+	descending bool
+}
+
+// expression evaluates to a JSON value, or undefined
+type expression func(doc map[string]any) any
+
+// parseQuery parses query text. Parameters replace the @names in the text.
+func parseQuery(text string, parameters map[string]any) (*query, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, parameters: parameters}
+	q, err := p.query()
+	if err != nil {
+		return nil, fmt.Errorf("syntax error in query %q: %w", text, err)
+	}
+	return q, nil
+}
+
+// matches evaluates the query's WHERE clause
+func (q *query) matches(doc map[string]any) bool {
+	return q.where == nil || q.where(doc) == true
+}
+
+// run returns the items matching the query, ordered and projected. docs are in the order of their creation.
+func (q *query) run(docs []map[string]any) []map[string]any {
+	var results []map[string]any
+	for _, doc := range docs {
+		if q.matches(doc) {
+			results = append(results, doc)
+		}
+	}
+	if len(q.orderBy) > 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			for _, o := range q.orderBy {
+				c := compareForOrder(o.expr(results[i]), o.expr(results[j]))
+				if c != 0 {
+					return (c < 0) != o.descending
+				}
+			}
+			return false
+		})
+	}
+	if q.top >= 0 && len(results) > q.top {
+		results = results[:q.top]
+	}
+	if q.projection == nil {
+		return results
+	}
+	projected := make([]map[string]any, len(results))
+	for i, doc := range results {
+		projected[i] = map[string]any{}
+		for _, p := range q.projection {
+			if v := p.expr(doc); !isUndefined(v) {
+				projected[i][p.name] = v
+			}
+		}
+	}
+	return projected
+}
+
+type tokenKind int
+
+const (
+	tokenIdentifier tokenKind = iota
+	tokenKeyword
+	tokenNumber
+	tokenString
+	tokenParameter
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// raw is the text of keywords as written, for properties named like keywords
+	raw string
+}
+
+var keywords = map[string]bool{
+	"SELECT": true, "TOP": true, "FROM": true, "WHERE": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true,
+	"AND": true, "OR": true, "NOT": true, "AS": true, "IN": true, "BETWEEN": true,
+	"TRUE": true, "FALSE": true, "NULL": true, "UNDEFINED": true,
+	"VALUE": true, "DISTINCT": true, "GROUP": true, "JOIN": true, "OFFSET": true, "LIMIT": true,
+}
+
+// symbols are the operators and punctuation of queries, longest first
+var symbols = []string{"!=", "<>", "<=", ">=", "=", "<", ">", "(", ")", ",", ".", "[", "]", "*", "-"}
+
+var comparisonOperators = map[string]bool{"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_' || r == '$' || r == '@':
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			word := string(runes[start:i])
+			switch {
+			case r == '@':
+				tokens = append(tokens, token{kind: tokenParameter, text: word})
+			case keywords[strings.ToUpper(word)]:
+				tokens = append(tokens, token{tokenKeyword, strings.ToUpper(word), word})
+			default:
+				tokens = append(tokens, token{kind: tokenIdentifier, text: word})
+			}
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i])})
+		case r == '\'' || r == '"':
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[i])
+					}
+					continue
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: sb.String()})
+		default:
+			symbol := ""
+			for _, s := range symbols {
+				if strings.HasPrefix(string(runes[i:]), s) {
+					symbol = s
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol})
+			i += len(symbol)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens     []token
+	pos        int
+	parameters map[string]any
+	// alias is the name of the item in the FROM clause
+	alias string
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes the next token when it's the keyword or symbol text
+func (p *parser) accept(text string) bool {
+	if t, ok := p.peek(); ok && (t.kind == tokenKeyword || t.kind == tokenSymbol) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(text)
+	}
+	return nil
+}
+
+func (p *parser) unexpected(expected string) error {
+	if t, ok := p.peek(); ok {
+		return fmt.Errorf("expected %s, got %q", expected, t.text)
+	}
+	return fmt.Errorf("expected %s, got the end of the query", expected)
+}
+
+func (p *parser) identifier() (string, error) {
+	t, ok := p.peek()
+	if !ok || t.kind != tokenIdentifier {
+		return "", p.unexpected("an identifier")
+	}
+	p.pos++
+	return t.text, nil
+}
+
+func (p *parser) query() (*query, error) {
+	q := &query{top: -1}
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for _, unsupported := range []string{"VALUE", "DISTINCT"} {
+		if p.accept(unsupported) {
+			return nil, fmt.Errorf("SELECT %s isn't supported", unsupported)
+		}
+	}
+	if p.accept("TOP") {
+		t, ok := p.peek()
+		if !ok || (t.kind != tokenNumber && t.kind != tokenParameter) {
+			return nil, p.unexpected("a number")
+		}
+		p.pos++
+		n, err := p.literal(t)
+		if err != nil {
+			return nil, err
+		}
+		top, err := strconv.Atoi(fmt.Sprint(n))
+		if err != nil || top < 0 {
+			return nil, fmt.Errorf("invalid TOP %v", n)
+		}
+		q.top = top
+	}
+
+	// the projection refers to the alias, which follows it
+	start := p.pos
+	for depth := 0; ; p.pos++ {
+		t, ok := p.peek()
+		if !ok {
+			return nil, p.unexpected("FROM")
+		}
+		if t.kind == tokenSymbol && (t.text == "(" || t.text == "[") {
+			depth++
+		} else if t.kind == tokenSymbol && (t.text == ")" || t.text == "]") {
+			depth--
+		} else if depth == 0 && t.kind == tokenKeyword && t.text == "FROM" && p.tokens[p.pos-1].text != "." {
+			break
+		}
+	}
+	end := p.pos
+	p.pos++
+	alias, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	p.accept("AS")
+	if t, ok := p.peek(); ok && t.kind == tokenIdentifier {
+		// FROM root r
+		p.pos++
+		alias = t.text
+	}
+	q.alias, p.alias = alias, alias
+
+	if p.accept("WHERE") {
+		if q.where, err = p.expression(); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			expr, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			o := orderBy{expr: expr}
+			if p.accept("DESC") {
+				o.descending = true
+			} else {
+				p.accept("ASC")
+			}
+			q.orderBy = append(q.orderBy, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if t, ok := p.peek(); ok {
+		if t.kind == tokenKeyword {
+			return nil, fmt.Errorf("%s isn't supported", t.text)
+		}
+		return nil, p.unexpected("the end of the query")
+	}
+
+	// parse the projection now that the alias is known
+	rest := p.tokens
+	p.tokens, p.pos = p.tokens[start:end], 0
+	defer func() { p.tokens = rest }()
+	if p.accept("*") {
+		if _, ok := p.peek(); ok {
+			return nil, p.unexpected("FROM")
+		}
+		return q, nil
+	}
+	q.projection = []projection{}
+	for {
+		start := p.pos
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("$%d", len(q.projection)+1)
+		if p.accept("AS") {
+			if name, err = p.identifier(); err != nil {
+				return nil, err
+			}
+		} else if pathName := pathName(p.tokens[start:p.pos]); pathName != "" {
+			name = pathName
+		}
+		q.projection = append(q.projection, projection{name: name, expr: expr})
+		if !p.accept(",") {
+			break
+		}
+	}
+	if _, ok := p.peek(); ok {
+		return nil, p.unexpected("FROM")
+	}
+	return q, nil
+}
+
+func (p *parser) expression() (expression, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(doc map[string]any) any {
+			a, b := l(doc), right(doc)
+			if a == true || b == true {
+				return true
+			}
+			if a == false && b == false {
+				return false
+			}
+			return undefined{}
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (expression, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(doc map[string]any) any {
+			a, b := l(doc), right(doc)
+			if a == false || b == false {
+				return false
+			}
+			if a == true && b == true {
+				return true
+			}
+			return undefined{}
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (expression, error) {
+	if p.accept("NOT") {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(doc map[string]any) any {
+			if b, ok := operand(doc).(bool); ok {
+				return !b
+			}
+			return undefined{}
+		}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expression, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	t, _ := p.peek()
+	switch {
+	case t.kind == tokenSymbol && comparisonOperators[t.text]:
+		p.pos++
+		right, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		op := t.text
+		return func(doc map[string]any) any {
+			return compare(op, left(doc), right(doc))
+		}, nil
+	case t.kind == tokenKeyword && (t.text == "IN" || t.text == "NOT"):
+		negate := p.accept("NOT")
+		if err := p.expect("IN"); err != nil {
+			return nil, err
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var values []expression
+		for {
+			value, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(doc map[string]any) any {
+			v := left(doc)
+			if isUndefined(v) {
+				return undefined{}
+			}
+			for _, value := range values {
+				if compare("=", v, value(doc)) == true {
+					return !negate
+				}
+			}
+			return negate
+		}, nil
+	case t.kind == tokenKeyword && t.text == "BETWEEN":
+		p.pos++
+		low, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return func(doc map[string]any) any {
+			v := left(doc)
+			a, b := compare(">=", v, low(doc)), compare("<=", v, high(doc))
+			if isUndefined(a) || isUndefined(b) {
+				return undefined{}
+			}
+			return a == true && b == true
+		}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) primary() (expression, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.unexpected("an expression")
+	}
+	switch t.kind {
+	case tokenNumber, tokenString, tokenParameter:
+		p.pos++
+		v, err := p.literal(t)
+		if err != nil {
+			return nil, err
+		}
+		return func(map[string]any) any { return v }, nil
+	case tokenKeyword:
+		p.pos++
+		switch t.text {
+		case "TRUE":
+			return func(map[string]any) any { return true }, nil
+		case "FALSE":
+			return func(map[string]any) any { return false }, nil
+		case "NULL":
+			return func(map[string]any) any { return nil }, nil
+		case "UNDEFINED":
+			return func(map[string]any) any { return undefined{} }, nil
+		}
+		p.pos--
+		return nil, p.unexpected("an expression")
+	case tokenSymbol:
+		switch t.text {
+		case "(":
+			p.pos++
+			expr, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "-":
+			p.pos++
+			operand, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return func(doc map[string]any) any {
+				if f, ok := toFloat(operand(doc)); ok {
+					return json.Number(strconv.FormatFloat(-f, 'g', -1, 64))
+				}
+				return undefined{}
+			}, nil
+		}
+		return nil, p.unexpected("an expression")
+	}
+
+	p.pos++
+	if next, ok := p.peek(); ok && next.kind == tokenSymbol && next.text == "(" {
+		return p.function(t.text)
+	}
+	return p.path(t.text)
+}
+
+// literal returns the value of a number, string or parameter token
+func (p *parser) literal(t token) (any, error) {
+	switch t.kind {
+	case tokenNumber:
+		if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %s", t.text)
+		}
+		return json.Number(t.text), nil
+	case tokenParameter:
+		v, ok := p.parameters[t.text]
+		if !ok {
+			return nil, fmt.Errorf("parameter %s isn't defined", t.text)
+		}
+		return v, nil
+	}
+	return t.text, nil
+}
+
+func (p *parser) path(root string) (expression, error) {
+	if root != p.alias {
+		return nil, fmt.Errorf("identifier %s could not be resolved", root)
+	}
+	var steps []any
+	for {
+		if p.accept(".") {
+			t, ok := p.peek()
+			if !ok || (t.kind != tokenIdentifier && t.kind != tokenKeyword) {
+				return nil, p.unexpected("a property name")
+			}
+			p.pos++
+			name := t.text
+			if t.kind == tokenKeyword {
+				name = t.raw
+			}
+			steps = append(steps, name)
+		} else if p.accept("[") {
+			t, ok := p.peek()
+			if !ok || (t.kind != tokenString && t.kind != tokenNumber && t.kind != tokenParameter) {
+				return nil, p.unexpected("a property name or index")
+			}
+			p.pos++
+			v, err := p.literal(t)
+			if err != nil {
+				return nil, err
+			}
+			if n, ok := v.(json.Number); ok {
+				i, err := n.Int64()
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %s", n)
+				}
+				steps = append(steps, int(i))
+			} else {
+				steps = append(steps, fmt.Sprint(v))
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		} else {
+			break
+		}
+	}
+	return func(doc map[string]any) any {
+		var v any = doc
+		for _, step := range steps {
+			switch s := step.(type) {
+			case string:
+				m, ok := v.(map[string]any)
+				if !ok {
+					return undefined{}
+				}
+				if v, ok = m[s]; !ok {
+					return undefined{}
+				}
+			case int:
+				a, ok := v.([]any)
+				if !ok || s < 0 || s >= len(a) {
+					return undefined{}
+				}
+				v = a[s]
+			}
+		}
+		return v
+	}, nil
+}
+
+// pathName returns the name of a projected path, which is its last property name. It returns "" when
+// tokens aren't a path or the path ends with an array index.
+func pathName(tokens []token) string {
+	if len(tokens) == 0 || tokens[0].kind != tokenIdentifier {
+		return ""
+	}
+	name := tokens[0].text
+	for i := 1; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.kind == tokenSymbol && (t.text == "." || t.text == "[" || t.text == "]"):
+		case t.kind == tokenIdentifier || t.kind == tokenString:
+			name = t.text
+		case t.kind == tokenKeyword && tokens[i-1].text == ".":
+			name = t.raw
+		default:
+			return ""
+		}
+	}
+	return name
+}
+
+func (p *parser) function(name string) (expression, error) {
+	p.pos++ // (
+	var args []expression
+	if !p.accept(")") {
+		for {
+			arg, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := functions[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("function %s isn't supported", name)
+	}
+	if len(args) < f.minArgs || len(args) > f.maxArgs {
+		return nil, fmt.Errorf("function %s takes %d to %d arguments, got %d", name, f.minArgs, f.maxArgs, len(args))
+	}
+	return func(doc map[string]any) any {
+		values := make([]any, len(args))
+		for i, arg := range args {
+			values[i] = arg(doc)
+		}
+		return f.fn(values)
+	}, nil
+}
+
+type function struct {
+	minArgs, maxArgs int
+	fn               func(args []any) any
+}
+
+// stringFunction returns a function of two strings and an optional boolean ignoring case
+func stringFunction(fn func(s, substr string) bool) function {
+	return function{2, 3, func(args []any) any {
+		s, ok1 := args[0].(string)
+		substr, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return undefined{}
+		}
+		if len(args) == 3 && args[2] == true {
+			s, substr = strings.ToLower(s), strings.ToLower(substr)
+		}
+		return fn(s, substr)
+	}}
+}
+
+var functions = map[string]function{
+	"IS_DEFINED": {1, 1, func(args []any) any { return !isUndefined(args[0]) }},
+	"IS_NULL":    {1, 1, func(args []any) any { return args[0] == nil }},
+	"IS_STRING":  {1, 1, func(args []any) any { _, ok := args[0].(string); return ok }},
+	"IS_NUMBER":  {1, 1, func(args []any) any { _, ok := args[0].(json.Number); return ok }},
+	"IS_BOOL":    {1, 1, func(args []any) any { _, ok := args[0].(bool); return ok }},
+	"CONTAINS":   stringFunction(strings.Contains),
+	"STARTSWITH": stringFunction(strings.HasPrefix),
+	"ENDSWITH":   stringFunction(strings.HasSuffix),
+	"LOWER": {1, 1, func(args []any) any {
+		if s, ok := args[0].(string); ok {
+			return strings.ToLower(s)
+		}
+		return undefined{}
+	}},
+	"UPPER": {1, 1, func(args []any) any {
+		if s, ok := args[0].(string); ok {
+			return strings.ToUpper(s)
+		}
+		return undefined{}
+	}},
+	"LENGTH": {1, 1, func(args []any) any {
+		if s, ok := args[0].(string); ok {
+			return json.Number(strconv.Itoa(len([]rune(s))))
+		}
+		return undefined{}
+	}},
+	"ARRAY_LENGTH": {1, 1, func(args []any) any {
+		if a, ok := args[0].([]any); ok {
+			return json.Number(strconv.Itoa(len(a)))
+		}
+		return undefined{}
+	}},
+	"ARRAY_CONTAINS": {2, 2, func(args []any) any {
+		a, ok := args[0].([]any)
+		if !ok {
+			return undefined{}
+		}
+		for _, v := range a {
+			if compare("=", v, args[1]) == true {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// compare applies a comparison operator. Values of different types are unequal, and aren't ordered.
+func compare(op string, a, b any) any {
+	if isUndefined(a) || isUndefined(b) {
+		return undefined{}
+	}
+	switch op {
+	case "=":
+		return equal(a, b)
+	case "!=", "<>":
+		return !equal(a, b)
+	}
+	var c int
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return undefined{}
+		}
+		f, _ := x.Float64()
+		g, _ := y.Float64()
+		c = compareFloats(f, g)
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return undefined{}
+		}
+		c = strings.Compare(x, y)
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return undefined{}
+		}
+		c = compareBools(x, y)
+	default:
+		return undefined{}
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// equal compares JSON values, comparing numbers by value
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		f, _ := x.Float64()
+		g, _ := y.Float64()
+		return f == g
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// compareForOrder orders values of all types like ORDER BY: undefined, null, booleans, numbers, then strings
+func compareForOrder(a, b any) int {
+	rank := func(v any) int {
+		switch v.(type) {
+		case undefined:
+			return 0
+		case nil:
+			return 1
+		case bool:
+			return 2
+		case json.Number:
+			return 3
+		case string:
+			return 4
+		}
+		return 5
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case bool:
+		return compareBools(x, b.(bool))
+	case json.Number:
+		f, _ := x.Float64()
+		g, _ := b.(json.Number).Float64()
+		return compareFloats(f, g)
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	return 0
+}
+
+func compareFloats(f, g float64) int {
+	switch {
+	case f < g:
+		return -1
+	case f > g:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(x, y bool) int {
+	switch {
+	case x == y:
+		return 0
+	case !x:
+		return -1
+	}
+	return 1
+}
+
+func isUndefined(v any) bool {
+	_, ok := v.(undefined)
+	return ok
+}
+
+func toFloat(v any) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil && !math.IsNaN(f)
+}
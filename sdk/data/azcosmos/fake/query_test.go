@@ -0,0 +1,98 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQueryMatches(t *testing.T) {
+	var doc map[string]any
+	if err := unmarshal([]byte(`{"id":"1","n":2,"name":"Widget","tags":["a","b"],"nested":{"ok":true},"null":null}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		where   string
+		matches bool
+	}{
+		{"c.n = 2", true},
+		{"c.n = 2.0", true},
+		{"c.n != 2", false},
+		{"c.n <> 3", true},
+		{"c.n > 1 AND c.n <= 2", true},
+		{"c.n < 1 OR c.name = 'Widget'", true},
+		{"NOT (c.n = 2)", false},
+		{"c.n = '2'", false},
+		{"c.n BETWEEN 1 AND 3", true},
+		{"c.n IN (1, 3)", false},
+		{"c.n NOT IN (1, 3)", true},
+		{"c.n = -@neg", true},
+		{"c.missing = 1", false},
+		{"NOT (c.missing = 1)", false},
+		{"IS_DEFINED(c.missing)", false},
+		{"IS_NULL(c[\"null\"])", true},
+		{"c.nested.ok", true},
+		{"c.tags[1] = 'b'", true},
+		{"ARRAY_CONTAINS(c.tags, 'a')", true},
+		{"ARRAY_LENGTH(c.tags) = 2", true},
+		{"CONTAINS(c.name, 'dg')", true},
+		{"STARTSWITH(c.name, 'widget')", false},
+		{"STARTSWITH(c.name, 'widget', true)", true},
+		{"LOWER(c.name) = 'widget'", true},
+		{"LENGTH(c.name) = 6", true},
+		{"IS_STRING(c.name) AND IS_NUMBER(c.n) AND IS_BOOL(c.nested.ok)", true},
+	} {
+		t.Run(test.where, func(t *testing.T) {
+			q, err := parseQuery("SELECT * FROM c WHERE "+test.where, map[string]any{"@neg": json.Number("-2")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := q.matches(doc); actual != test.matches {
+				t.Fatalf("expected %t, got %t", test.matches, actual)
+			}
+		})
+	}
+}
+
+func TestQueryRun(t *testing.T) {
+	var docs []map[string]any
+	if err := unmarshal([]byte(`[{"id":"1","n":2,"value":{"x":1}},{"id":"2","n":1},{"id":"3"},{"id":"4","n":2}]`), &docs); err != nil {
+		t.Fatal(err)
+	}
+	q, err := parseQuery("SELECT TOP 3 c.id, c.value.x, c.n + 0 AS n FROM root c ORDER BY c.n DESC, c.id", nil)
+	if err == nil {
+		t.Fatalf("expected an error for an unsupported operator, got %v", q)
+	}
+	q, err = parseQuery("SELECT TOP 3 c.id, c.value.x AS x, c.n FROM root c ORDER BY c.n DESC, c.id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := json.Marshal(q.run(docs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"id":"1","n":2,"x":1},{"id":"4","n":2},{"id":"2","n":1}]`; string(results) != expected {
+		t.Fatalf("expected %s, got %s", expected, results)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"SELECT",
+		"SELECT * FROM",
+		"SELECT * FROM c WHERE",
+		"SELECT * FROM c WHERE d.id = 1",
+		"SELECT * FROM c WHERE c.id = @missing",
+		"SELECT * FROM c WHERE UNKNOWN(c.id)",
+		"SELECT * FROM c JOIN t IN c.tags",
+		"SELECT VALUE c.id FROM c",
+		"SELECT * FROM c WHERE c.id = 'unterminated",
+	} {
+		if _, err := parseQuery(text, nil); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type database struct {
+	properties map[string]any
+	// containers in the order of their creation
+	containers []*container
+}
+
+type container struct {
+	properties        map[string]any
+	partitionKeyPaths []string
+	items             map[itemKey]*item
+	// seq orders items by creation, like the resource ids of the service
+	seq int64
+}
+
+type offer struct {
+	properties map[string]any
+}
+
+func (s *Server) database(id string) *database {
+	for _, db := range s.databases {
+		if db.properties["id"] == id {
+			return db
+		}
+	}
+	return nil
+}
+
+func (db *database) container(id string) *container {
+	for _, c := range db.containers {
+		if c.properties["id"] == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) routeDatabases(req *request) error {
+	switch {
+	case req.Method == http.MethodPost && req.Header.Get(headerIsQuery) != "":
+		return s.queryResources(req, "Databases", s.databasesProperties(), "")
+	case req.Method == http.MethodPost:
+		return s.createDatabase(req)
+	case req.Method == http.MethodGet:
+		return s.writePage(req, "Databases", s.databasesProperties(), "")
+	}
+	return newStatusError(http.StatusMethodNotAllowed, "unsupported method %s", req.Method)
+}
+
+func (s *Server) databasesProperties() []map[string]any {
+	properties := make([]map[string]any, len(s.databases))
+	for i, db := range s.databases {
+		properties[i] = db.properties
+	}
+	return properties
+}
+
+func (s *Server) createDatabase(req *request) error {
+	properties, err := parseResource(req.body)
+	if err != nil {
+		return err
+	}
+	if s.database(properties["id"].(string)) != nil {
+		return newStatusError(http.StatusConflict, "database %s already exists", properties["id"])
+	}
+	rid := newRID()
+	s.systemProperties(properties, rid, "dbs/"+rid+"/")
+	properties["_colls"] = "colls/"
+	properties["_users"] = "users/"
+	if err := s.createOffer(req, properties); err != nil {
+		return err
+	}
+	s.databases = append(s.databases, &database{properties: properties})
+	s.writeJSON(req, http.StatusCreated, properties, writeCharge)
+	return nil
+}
+
+func (s *Server) routeDatabase(req *request, db *database) error {
+	switch req.Method {
+	case http.MethodGet:
+		s.writeJSON(req, http.StatusOK, db.properties, readCharge)
+		return nil
+	case http.MethodDelete:
+		if err := checkIfMatch(req, db.properties["_etag"]); err != nil {
+			return err
+		}
+		for i := range s.databases {
+			if s.databases[i] == db {
+				s.databases = append(s.databases[:i], s.databases[i+1:]...)
+				break
+			}
+		}
+		s.deleteOffer(db.properties)
+		for _, c := range db.containers {
+			s.deleteOffer(c.properties)
+		}
+		s.nextETag()
+		s.writeJSON(req, http.StatusNoContent, nil, writeCharge)
+		return nil
+	}
+	return newStatusError(http.StatusMethodNotAllowed, "unsupported method %s", req.Method)
+}
+
+func (s *Server) routeContainers(req *request, db *database) error {
+	switch {
+	case req.Method == http.MethodPost && req.Header.Get(headerIsQuery) != "":
+		return s.queryResources(req, "DocumentCollections", db.containersProperties(), db.properties["_rid"].(string))
+	case req.Method == http.MethodPost:
+		return s.createContainer(req, db)
+	case req.Method == http.MethodGet:
+		return s.writePage(req, "DocumentCollections", db.containersProperties(), db.properties["_rid"].(string))
+	}
+	return newStatusError(http.StatusMethodNotAllowed, "unsupported method %s", req.Method)
+}
+
+func (db *database) containersProperties() []map[string]any {
+	properties := make([]map[string]any, len(db.containers))
+	for i, c := range db.containers {
+		properties[i] = c.properties
+	}
+	return properties
+}
+
+func (s *Server) createContainer(req *request, db *database) error {
+	properties, err := parseResource(req.body)
+	if err != nil {
+		return err
+	}
+	if db.container(properties["id"].(string)) != nil {
+		return newStatusError(http.StatusConflict, "container %s already exists", properties["id"])
+	}
+	paths, err := partitionKeyPaths(properties)
+	if err != nil {
+		return err
+	}
+	if _, ok := properties["indexingPolicy"]; !ok {
+		properties["indexingPolicy"] = map[string]any{
+			"indexingMode":  "consistent",
+			"automatic":     true,
+			"includedPaths": []any{map[string]any{"path": "/*"}},
+			"excludedPaths": []any{map[string]any{"path": `/"_etag"/?`}},
+		}
+	}
+	rid := newRID()
+	s.systemProperties(properties, rid, db.properties["_self"].(string)+"colls/"+rid+"/")
+	properties["_docs"] = "docs/"
+	properties["_sprocs"] = "sprocs/"
+	properties["_triggers"] = "triggers/"
+	properties["_udfs"] = "udfs/"
+	properties["_conflicts"] = "conflicts/"
+	if err := s.createOffer(req, properties); err != nil {
+		return err
+	}
+	db.containers = append(db.containers, &container{properties: properties, partitionKeyPaths: paths, items: map[itemKey]*item{}})
+	s.writeJSON(req, http.StatusCreated, properties, writeCharge)
+	return nil
+}
+
+// partitionKeyPaths returns the paths of a container's partition key definition
+func partitionKeyPaths(properties map[string]any) ([]string, error) {
+	definition, _ := properties["partitionKey"].(map[string]any)
+	values, _ := definition["paths"].([]any)
+	if len(values) == 0 {
+		return nil, newStatusError(http.StatusBadRequest, "the container's partition key definition must have paths")
+	}
+	paths := make([]string, len(values))
+	for i, v := range values {
+		path, ok := v.(string)
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, newStatusError(http.StatusBadRequest, "invalid partition key path %v", v)
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
+func (s *Server) routeContainer(req *request, db *database, c *container) error {
+	switch req.Method {
+	case http.MethodGet:
+		s.writeJSON(req, http.StatusOK, c.properties, readCharge)
+		return nil
+	case http.MethodPut:
+		return s.replaceContainer(req, c)
+	case http.MethodDelete:
+		if err := checkIfMatch(req, c.properties["_etag"]); err != nil {
+			return err
+		}
+		for i := range db.containers {
+			if db.containers[i] == c {
+				db.containers = append(db.containers[:i], db.containers[i+1:]...)
+				break
+			}
+		}
+		s.deleteOffer(c.properties)
+		s.nextETag()
+		s.writeJSON(req, http.StatusNoContent, nil, writeCharge)
+		return nil
+	}
+	return newStatusError(http.StatusMethodNotAllowed, "unsupported method %s", req.Method)
+}
+
+func (s *Server) replaceContainer(req *request, c *container) error {
+	if err := checkIfMatch(req, c.properties["_etag"]); err != nil {
+		return err
+	}
+	properties, err := parseResource(req.body)
+	if err != nil {
+		return err
+	}
+	if properties["id"] != c.properties["id"] {
+		return newStatusError(http.StatusBadRequest, "the id of the container can't change")
+	}
+	paths, err := partitionKeyPaths(properties)
+	if err != nil {
+		return err
+	}
+	if strings.Join(paths, ",") != strings.Join(c.partitionKeyPaths, ",") {
+		return newStatusError(http.StatusBadRequest, "the partition key of the container can't change")
+	}
+	for k, v := range c.properties {
+		if strings.HasPrefix(k, "_") {
+			properties[k] = v
+		}
+	}
+	s.systemProperties(properties, c.properties["_rid"].(string), c.properties["_self"].(string))
+	c.properties = properties
+	s.writeJSON(req, http.StatusOK, properties, writeCharge)
+	return nil
+}
+
+func (s *Server) readPartitionKeyRanges(req *request, c *container) error {
+	s.writeJSON(req, http.StatusOK, map[string]any{
+		"_rid": c.properties["_rid"],
+		"PartitionKeyRanges": []any{map[string]any{
+			"id":           "0",
+			"_rid":         c.properties["_rid"],
+			"minInclusive": "",
+			"maxExclusive": "FF",
+			"status":       "online",
+			"parents":      []any{},
+		}},
+		"_count": 1,
+	}, readCharge)
+	return nil
+}
+
+// createOffer creates the offer of a database or container created with throughput
+func (s *Server) createOffer(req *request, resource map[string]any) error {
+	content := map[string]any{}
+	if throughput := req.Header.Get(headerOfferThroughput); throughput != "" {
+		if _, err := strconv.Atoi(throughput); err != nil {
+			return newStatusError(http.StatusBadRequest, "invalid throughput %q", throughput)
+		}
+		content["offerThroughput"] = json.Number(throughput)
+	} else if autoscale := req.Header.Get(headerOfferAutoscale); autoscale != "" {
+		var settings map[string]any
+		if err := unmarshal([]byte(autoscale), &settings); err != nil {
+			return newStatusError(http.StatusBadRequest, "invalid autoscale settings %q", autoscale)
+		}
+		content["offerAutopilotSettings"] = settings
+	} else {
+		return nil
+	}
+	// offers are addressed by their resource id, which clients sign in lower case
+	rid := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(newRID()))
+	properties := map[string]any{
+		"id":              rid,
+		"offerVersion":    "V2",
+		"offerType":       "Invalid",
+		"resource":        resource["_self"],
+		"offerResourceId": resource["_rid"],
+		"content":         content,
+	}
+	s.systemProperties(properties, rid, "offers/"+rid+"/")
+	s.offers = append(s.offers, &offer{properties: properties})
+	return nil
+}
+
+func (s *Server) deleteOffer(resource map[string]any) {
+	for i, o := range s.offers {
+		if o.properties["offerResourceId"] == resource["_rid"] {
+			s.offers = append(s.offers[:i], s.offers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) routeOffers(req *request) error {
+	offers := make([]map[string]any, len(s.offers))
+	for i, o := range s.offers {
+		offers[i] = o.properties
+	}
+	switch {
+	case len(req.segments) == 1 && req.Method == http.MethodPost && req.Header.Get(headerIsQuery) != "":
+		return s.queryResources(req, "Offers", offers, "")
+	case len(req.segments) == 1 && req.Method == http.MethodGet:
+		return s.writePage(req, "Offers", offers, "")
+	case len(req.segments) != 2:
+		return newStatusError(http.StatusNotImplemented, "the fake doesn't support %s %s", req.Method, req.URL.Path)
+	}
+
+	var o *offer
+	for _, candidate := range s.offers {
+		if candidate.properties["id"] == req.segments[1] {
+			o = candidate
+		}
+	}
+	if o == nil {
+		return newStatusError(http.StatusNotFound, "offer %s doesn't exist", req.segments[1])
+	}
+	switch req.Method {
+	case http.MethodGet:
+		s.writeJSON(req, http.StatusOK, o.properties, readCharge)
+		return nil
+	case http.MethodPut:
+		if err := checkIfMatch(req, o.properties["_etag"]); err != nil {
+			return err
+		}
+		var replacement map[string]any
+		if err := unmarshal(req.body, &replacement); err != nil {
+			return newStatusError(http.StatusBadRequest, "invalid offer: %v", err)
+		}
+		content, ok := replacement["content"].(map[string]any)
+		if !ok {
+			return newStatusError(http.StatusBadRequest, "the offer doesn't have content")
+		}
+		properties := clone(o.properties).(map[string]any)
+		properties["content"] = content
+		s.systemProperties(properties, properties["_rid"].(string), properties["_self"].(string))
+		o.properties = properties
+		s.writeJSON(req, http.StatusOK, properties, writeCharge)
+		return nil
+	}
+	return newStatusError(http.StatusMethodNotAllowed, "unsupported method %s", req.Method)
+}
+
+// queryResources queries databases, containers or offers
+func (s *Server) queryResources(req *request, name string, resources []map[string]any, rid string) error {
+	q, err := parseQueryRequest(req)
+	if err != nil {
+		return err
+	}
+	return s.writePage(req, name, q.run(resources), rid)
+}
+
+// parseResource parses the body of a request creating a resource, which must have an id
+func parseResource(body []byte) (map[string]any, error) {
+	var properties map[string]any
+	if err := unmarshal(body, &properties); err != nil || properties == nil {
+		return nil, newStatusError(http.StatusBadRequest, "the request body must be a JSON object")
+	}
+	id, ok := properties["id"].(string)
+	if !ok || id == "" {
+		return nil, newStatusError(http.StatusBadRequest, "the resource must have a string id")
+	}
+	if strings.ContainsAny(id, `/\?#`) {
+		return nil, newStatusError(http.StatusBadRequest, "the id %q contains an invalid character", id)
+	}
+	return properties, nil
+}
@@ -0,0 +1,439 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Region is the name of the server's only region
+const Region = "Fake Region"
+
+// headers of the Cosmos DB REST API
+const (
+	headerActivityID       = "x-ms-activity-id"
+	headerContinuation     = "x-ms-continuation"
+	headerDate             = "x-ms-date"
+	headerIsBatchRequest   = "x-ms-cosmos-is-batch-request"
+	headerIsBatchAtomic    = "x-ms-cosmos-batch-atomic"
+	headerIsQuery          = "x-ms-documentdb-query"
+	headerIsQueryPlan      = "x-ms-cosmos-is-query-plan-request"
+	headerIsUpsert         = "x-ms-documentdb-is-upsert"
+	headerItemCount        = "x-ms-item-count"
+	headerMaxItemCount     = "x-ms-max-item-count"
+	headerOfferAutoscale   = "x-ms-cosmos-offer-autopilot-settings"
+	headerOfferThroughput  = "x-ms-offer-throughput"
+	headerPartitionKey     = "x-ms-documentdb-partitionkey"
+	headerPrefer           = "Prefer"
+	headerRequestCharge    = "x-ms-request-charge"
+	headerSessionToken     = "x-ms-session-token"
+	headerChangeFeed       = "A-IM"
+	preferMinimal          = "return=minimal"
+	defaultMaxItemCount    = 100
+	maxOperationsPerBatch  = 100
+	forwardedProtoHeader   = "X-Forwarded-Proto"
+	readCharge             = 1
+	writeCharge            = 5
+	queryChargePerDocument = 0.5
+)
+
+// ServerOptions contains optional parameters for Server.
+type ServerOptions struct {
+	// Key is the base64 encoded account key clients authenticate with, as passed to azcosmos.NewKeyCredential.
+	// By default, the server generates a key, which Key returns.
+	Key string
+}
+
+// Server is an in-memory fake of the Cosmos DB REST API subset azcosmos uses: databases, containers,
+// item CRUD with ETags, patch, transactional batch, single partition queries, throughput and continuation
+// tokens. It validates the signatures of requests authenticated with an azcosmos.KeyCredential.
+// It's safe for concurrent use.
+//
+// Server is an azcore policy.Transporter that routes every request to its server, regardless of the
+// request's host. Set it as the Transport of a client's ClientOptions to use any endpoint, or create a
+// client with the endpoint returned by Endpoint.
+type Server struct {
+	key []byte
+	mtx sync.Mutex
+	// databases in the order of their creation
+	databases []*database
+	offers    []*offer
+	// lsn counts writes. It's the session token of responses, and distinguishes ETags.
+	lsn int64
+	srv *httptest.Server
+}
+
+// NewServer starts a Server. Call Close to stop it. Pass nil for options to accept defaults.
+func NewServer(options *ServerOptions) (*Server, error) {
+	if options == nil {
+		options = &ServerOptions{}
+	}
+	s := &Server{}
+	if options.Key != "" {
+		key, err := base64.StdEncoding.DecodeString(options.Key)
+		if err != nil {
+			return nil, fmt.Errorf("decode key: %w", err)
+		}
+		s.key = key
+	} else {
+		s.key = make([]byte, 64)
+		if _, err := rand.Read(s.key); err != nil {
+			return nil, err
+		}
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Endpoint returns the server's endpoint, for example to pass to azcosmos.NewClientWithKey.
+func (s *Server) Endpoint() string {
+	return s.srv.URL + "/"
+}
+
+// Key returns the base64 encoded account key clients authenticate with.
+func (s *Server) Key() string {
+	return base64.StdEncoding.EncodeToString(s.key)
+}
+
+// ConnectionString returns a connection string for the server, suitable for azcosmos.NewClientFromConnectionString.
+func (s *Server) ConnectionString() string {
+	return "AccountEndpoint=" + s.Endpoint() + ";AccountKey=" + s.Key() + ";"
+}
+
+// Do implements the policy.Transporter interface by sending req to the server. The request keeps its
+// host, so the database account the server returns has the client's endpoint.
+func (s *Server) Do(req *http.Request) (*http.Response, error) {
+	u, err := url.Parse(s.srv.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Host = req.URL.Host
+	r.Header.Set(forwardedProtoHeader, req.URL.Scheme)
+	r.URL.Scheme, r.URL.Host = u.Scheme, u.Host
+	return s.srv.Client().Do(r)
+}
+
+// request is a request to a resource or a feed of resources
+type request struct {
+	*http.Request
+	w        http.ResponseWriter
+	segments []string
+	body     []byte
+}
+
+// statusError is an error response
+type statusError struct {
+	statusCode int
+	code       string
+	message    string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func newStatusError(statusCode int, format string, a ...any) *statusError {
+	code := strings.ReplaceAll(http.StatusText(statusCode), " ", "")
+	return &statusError{statusCode: statusCode, code: code, message: fmt.Sprintf(format, a...)}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, newStatusError(http.StatusBadRequest, "%v", err))
+		return
+	}
+	var segments []string
+	if path := strings.Trim(r.URL.Path, "/"); path != "" {
+		segments = strings.Split(path, "/")
+	}
+	req := &request{Request: r, w: w, segments: segments, body: body}
+	if err := s.authorize(req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.route(req); err != nil {
+		writeError(w, err)
+	}
+}
+
+// authorize validates the signature of the Authorization header, which azcosmos.KeyCredential computes
+// from the method, resource type, resource link and date of the request
+func (s *Server) authorize(req *request) error {
+	header, err := url.QueryUnescape(req.Header.Get("Authorization"))
+	if err != nil || header == "" {
+		return newStatusError(http.StatusUnauthorized, "the request doesn't have a valid authorization header")
+	}
+	// the header is escaped once, so the values aren't query escaped and the signature may contain "+"
+	values := map[string]string{}
+	for _, pair := range strings.Split(header, "&") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			values[k] = v
+		}
+	}
+	if values["type"] != "master" {
+		return newStatusError(http.StatusUnauthorized, "the server only supports key authorization, got %q", values["type"])
+	}
+	date := req.Header.Get(headerDate)
+	if date == "" {
+		return newStatusError(http.StatusUnauthorized, "the request doesn't have an %s header", headerDate)
+	}
+
+	resourceType, resourceLink := "", ""
+	n := len(req.segments)
+	switch {
+	case n == 0:
+	case n%2 == 1:
+		// a feed, for example dbs/db/colls
+		resourceType, resourceLink = req.segments[n-1], strings.Join(req.segments[:n-1], "/")
+	case req.segments[0] == "offers":
+		// offers are addressed by their resource id
+		resourceType, resourceLink = req.segments[0], strings.ToLower(req.segments[1])
+	default:
+		resourceType, resourceLink = req.segments[n-2], strings.Join(req.segments, "/")
+	}
+	stringToSign := strings.ToLower(req.Method) + "\n" + strings.ToLower(resourceType) + "\n" + resourceLink + "\n" + strings.ToLower(date) + "\n\n"
+	h := hmac.New(sha256.New, s.key)
+	_, _ = h.Write([]byte(stringToSign))
+	expected := h.Sum(nil)
+	signature, err := base64.StdEncoding.DecodeString(values["sig"])
+	if err != nil || !hmac.Equal(signature, expected) {
+		return newStatusError(http.StatusUnauthorized, "the input authorization token can't serve the request. The signature of %q doesn't match", strings.ReplaceAll(stringToSign, "\n", `\n`))
+	}
+	return nil
+}
+
+func (s *Server) route(req *request) error {
+	segments := req.segments
+	n := len(segments)
+	switch {
+	case n == 0 && req.Method == http.MethodGet:
+		return s.readAccount(req)
+	case n >= 1 && segments[0] == "offers":
+		return s.routeOffers(req)
+	case n < 1 || segments[0] != "dbs":
+		return newStatusError(http.StatusNotImplemented, "the fake doesn't support %s %s", req.Method, req.URL.Path)
+	case n == 1:
+		return s.routeDatabases(req)
+	}
+
+	db := s.database(segments[1])
+	if db == nil {
+		return newStatusError(http.StatusNotFound, "database %s doesn't exist", segments[1])
+	}
+	switch {
+	case n == 2:
+		return s.routeDatabase(req, db)
+	case segments[2] != "colls":
+		return newStatusError(http.StatusNotImplemented, "the fake doesn't support %s %s", req.Method, req.URL.Path)
+	case n == 3:
+		return s.routeContainers(req, db)
+	}
+
+	c := db.container(segments[3])
+	if c == nil {
+		return newStatusError(http.StatusNotFound, "container %s doesn't exist", segments[3])
+	}
+	switch {
+	case n == 4:
+		return s.routeContainer(req, db, c)
+	case n == 5 && segments[4] == "pkranges" && req.Method == http.MethodGet:
+		return s.readPartitionKeyRanges(req, c)
+	case segments[4] != "docs" || n > 6:
+		return newStatusError(http.StatusNotImplemented, "the fake doesn't support %s %s", req.Method, req.URL.Path)
+	case n == 5:
+		return s.routeItems(req, c)
+	}
+	return s.routeItem(req, c, segments[5])
+}
+
+func (s *Server) readAccount(req *request) error {
+	scheme := req.Header.Get(forwardedProtoHeader)
+	if scheme == "" {
+		scheme = "http"
+	}
+	endpoint := scheme + "://" + req.Host + "/"
+	regions := []map[string]any{{"name": Region, "databaseAccountEndpoint": endpoint}}
+	s.writeJSON(req, http.StatusOK, map[string]any{
+		"id":                           "fake",
+		"_rid":                         req.Host,
+		"writableLocations":            regions,
+		"readableLocations":            regions,
+		"enableMultipleWriteLocations": false,
+		"userConsistencyPolicy":        map[string]any{"defaultConsistencyLevel": "Session"},
+	}, readCharge)
+	return nil
+}
+
+// query is the body of query requests
+type queryBody struct {
+	Query      string `json:"query"`
+	Parameters []struct {
+		Name  string `json:"name"`
+		Value any    `json:"value"`
+	} `json:"parameters"`
+}
+
+// parseQueryRequest parses the query of a query request
+func parseQueryRequest(req *request) (*query, error) {
+	var body queryBody
+	if err := unmarshal(req.body, &body); err != nil {
+		return nil, newStatusError(http.StatusBadRequest, "invalid query: %v", err)
+	}
+	parameters := map[string]any{}
+	for _, p := range body.Parameters {
+		parameters[p.Name] = p.Value
+	}
+	q, err := parseQuery(body.Query, parameters)
+	if err != nil {
+		return nil, newStatusError(http.StatusBadRequest, "%v", err)
+	}
+	return q, nil
+}
+
+// writePage writes a page of the results of a query or a read feed. The continuation token is the offset
+// of the page in the results.
+func (s *Server) writePage(req *request, name string, results []map[string]any, rid string) error {
+	offset := 0
+	if token := req.Header.Get(headerContinuation); token != "" {
+		var err error
+		if offset, err = strconv.Atoi(token); err != nil || offset < 0 || offset > len(results) {
+			return newStatusError(http.StatusBadRequest, "invalid continuation token %q", token)
+		}
+	}
+	size := defaultMaxItemCount
+	if max, err := strconv.Atoi(req.Header.Get(headerMaxItemCount)); err == nil && max > 0 {
+		size = max
+	}
+	end := offset + size
+	if end >= len(results) {
+		end = len(results)
+	} else {
+		req.w.Header().Set(headerContinuation, strconv.Itoa(end))
+	}
+	page := results[offset:end]
+	if page == nil {
+		page = []map[string]any{}
+	}
+	req.w.Header().Set(headerItemCount, strconv.Itoa(len(page)))
+	s.writeJSON(req, http.StatusOK, map[string]any{"_rid": rid, name: page, "_count": len(page)}, readCharge+queryChargePerDocument*float64(len(page)))
+	return nil
+}
+
+// writeJSON writes a successful response, having the headers of every response
+func (s *Server) writeJSON(req *request, statusCode int, v any, charge float64) {
+	var b []byte
+	if v != nil {
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			writeError(req.w, newStatusError(http.StatusInternalServerError, "%v", err))
+			return
+		}
+	}
+	h := req.w.Header()
+	h.Set(headerActivityID, newID())
+	h.Set(headerRequestCharge, strconv.FormatFloat(charge, 'f', -1, 64))
+	h.Set(headerSessionToken, "0:-1#"+strconv.FormatInt(s.lsn, 10))
+	if b != nil {
+		h.Set("Content-Type", "application/json")
+	}
+	req.w.WriteHeader(statusCode)
+	_, _ = req.w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*statusError)
+	if !ok {
+		e = newStatusError(http.StatusInternalServerError, "%v", err)
+	}
+	b, _ := json.Marshal(map[string]string{"code": e.code, "message": e.message})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(headerActivityID, newID())
+	w.WriteHeader(e.statusCode)
+	_, _ = w.Write(b)
+}
+
+// nextETag counts a write and returns a new ETag
+func (s *Server) nextETag() string {
+	s.lsn++
+	return fmt.Sprintf(`"%08x-0000-0000-0000-%012x"`, s.lsn, time.Now().UnixNano()&0xffffffffffff)
+}
+
+// systemProperties sets the system properties of a resource
+func (s *Server) systemProperties(properties map[string]any, rid, self string) {
+	properties["_rid"] = rid
+	properties["_self"] = self
+	properties["_etag"] = s.nextETag()
+	properties["_ts"] = json.Number(strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+// newRID returns a random resource id
+func newRID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// unmarshal unmarshals JSON keeping numbers as json.Number, so they're returned as they were written
+func unmarshal(b []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// clone returns a deep copy of a JSON value
+func clone(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(x))
+		for k, v := range x {
+			c[k] = clone(v)
+		}
+		return c
+	case []any:
+		c := make([]any, len(x))
+		for i, v := range x {
+			c[i] = clone(v)
+		}
+		return c
+	}
+	return v
+}
+
+// checkIfMatch returns a 412 error when the request's If-Match header doesn't match etag
+func checkIfMatch(req *request, etag any) error {
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" && ifMatch != etag {
+		return newStatusError(http.StatusPreconditionFailed, "operation cannot be performed because one of the specified precondition is not met")
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// newTestContainer starts a server, and creates a database and a container partitioned by /pk
+func newTestContainer(t *testing.T) (*Server, *azcosmos.ContainerClient) {
+	srv, err := NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	client := newTestClient(t, srv, srv.Key())
+	ctx := context.Background()
+	if _, err := client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: "db"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	db, err := client.NewDatabase("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	throughput := azcosmos.NewManualThroughputProperties(400)
+	_, err = db.CreateContainer(ctx, azcosmos.ContainerProperties{
+		ID:                     "container",
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{Paths: []string{"/pk"}},
+	}, &azcosmos.CreateContainerOptions{ThroughputProperties: &throughput})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := db.NewContainer("container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, container
+}
+
+func newTestClient(t *testing.T, srv *Server, key string) *azcosmos.Client {
+	cred, err := azcosmos.NewKeyCredential(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := azcosmos.NewClientWithKey("https://localhost:8081/", cred, &azcosmos.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: srv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func statusCode(err error) int {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode
+	}
+	return 0
+}
+
+func TestServerAuthorization(t *testing.T) {
+	srv, err := NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	other, err := NewServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	client := newTestClient(t, srv, other.Key())
+	_, err = client.CreateDatabase(context.Background(), azcosmos.DatabaseProperties{ID: "db"}, nil)
+	if statusCode(err) != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 for a wrong key, got %v", err)
+	}
+
+	client, err = azcosmos.NewClientFromConnectionString(srv.ConnectionString(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.CreateDatabase(context.Background(), azcosmos.DatabaseProperties{ID: "db"}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerItems(t *testing.T) {
+	_, container := newTestContainer(t)
+	ctx := context.Background()
+	pk := azcosmos.NewPartitionKeyString("a")
+
+	created, err := container.CreateItem(ctx, pk, []byte(`{"id":"1","pk":"a","value":1}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ETag == "" {
+		t.Fatal("expected an ETag")
+	}
+	if _, err = container.CreateItem(ctx, pk, []byte(`{"id":"1","pk":"a"}`), nil); statusCode(err) != http.StatusConflict {
+		t.Fatalf("expected status 409, got %v", err)
+	}
+	if _, err = container.CreateItem(ctx, pk, []byte(`{"id":"2","pk":"b"}`), nil); statusCode(err) != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for a mismatched partition key, got %v", err)
+	}
+
+	read, err := container.ReadItem(ctx, pk, "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item map[string]any
+	if err := json.Unmarshal(read.Value, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item["value"] != float64(1) || item["_etag"] != string(created.ETag) {
+		t.Fatalf("unexpected item %v", item)
+	}
+	if _, err = container.ReadItem(ctx, azcosmos.NewPartitionKeyString("b"), "1", nil); statusCode(err) != http.StatusNotFound {
+		t.Fatalf("expected status 404 in another partition, got %v", err)
+	}
+
+	replaced, err := container.ReplaceItem(ctx, pk, "1", []byte(`{"id":"1","pk":"a","value":2}`), &azcosmos.ItemOptions{IfMatchEtag: &created.ETag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.ETag == created.ETag {
+		t.Fatal("expected the ETag to change")
+	}
+	if _, err = container.ReplaceItem(ctx, pk, "1", []byte(`{"id":"1","pk":"a"}`), &azcosmos.ItemOptions{IfMatchEtag: &created.ETag}); statusCode(err) != http.StatusPreconditionFailed {
+		t.Fatalf("expected status 412, got %v", err)
+	}
+
+	if _, err = container.UpsertItem(ctx, pk, []byte(`{"id":"2","pk":"a","value":3}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = container.DeleteItem(ctx, pk, "2", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = container.DeleteItem(ctx, pk, "2", nil); statusCode(err) != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %v", err)
+	}
+}
+
+func TestServerPatch(t *testing.T) {
+	_, container := newTestContainer(t)
+	ctx := context.Background()
+	pk := azcosmos.NewPartitionKeyString("a")
+	if _, err := container.CreateItem(ctx, pk, []byte(`{"id":"1","pk":"a","count":1,"tags":["x"],"old":true}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := azcosmos.PatchOperations{}
+	patch.AppendIncrement("/count", 2)
+	patch.AppendAdd("/tags/-", "y")
+	patch.AppendSet("/name", "n")
+	patch.AppendRemove("/old")
+	if _, err := container.PatchItem(ctx, pk, "1", patch, nil); err != nil {
+		t.Fatal(err)
+	}
+	read, err := container.ReadItem(ctx, pk, "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item struct {
+		Count int      `json:"count"`
+		Tags  []string `json:"tags"`
+		Name  string   `json:"name"`
+		Old   *bool    `json:"old"`
+	}
+	if err := json.Unmarshal(read.Value, &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Count != 3 || len(item.Tags) != 2 || item.Tags[1] != "y" || item.Name != "n" || item.Old != nil {
+		t.Fatalf("unexpected item %s", read.Value)
+	}
+
+	conditional := azcosmos.PatchOperations{}
+	conditional.SetCondition("FROM c WHERE c.count > 5")
+	conditional.AppendSet("/name", "m")
+	if _, err := container.PatchItem(ctx, pk, "1", conditional, nil); statusCode(err) != http.StatusPreconditionFailed {
+		t.Fatalf("expected status 412 when the condition isn't satisfied, got %v", err)
+	}
+
+	pkPatch := azcosmos.PatchOperations{}
+	pkPatch.AppendSet("/pk", "b")
+	if _, err := container.PatchItem(ctx, pk, "1", pkPatch, nil); statusCode(err) != http.StatusBadRequest {
+		t.Fatalf("expected status 400 when patching the partition key, got %v", err)
+	}
+}
+
+func TestServerTransactionalBatch(t *testing.T) {
+	_, container := newTestContainer(t)
+	ctx := context.Background()
+	pk := azcosmos.NewPartitionKeyString("a")
+	if _, err := container.CreateItem(ctx, pk, []byte(`{"id":"1","pk":"a"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := container.NewTransactionalBatch(pk)
+	batch.CreateItem([]byte(`{"id":"2","pk":"a"}`), nil)
+	batch.ReadItem("1", nil)
+	batch.DeleteItem("1", nil)
+	resp, err := container.ExecuteTransactionalBatch(ctx, batch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected the batch to succeed, got %v", resp.OperationResults)
+	}
+	expected := []int32{http.StatusCreated, http.StatusOK, http.StatusNoContent}
+	for i, r := range resp.OperationResults {
+		if r.StatusCode != expected[i] {
+			t.Fatalf("expected status %d for operation %d, got %d", expected[i], i, r.StatusCode)
+		}
+	}
+	if len(resp.OperationResults[1].ResourceBody) == 0 {
+		t.Fatal("expected the read operation to return the item")
+	}
+
+	batch = container.NewTransactionalBatch(pk)
+	batch.CreateItem([]byte(`{"id":"3","pk":"a"}`), nil)
+	batch.CreateItem([]byte(`{"id":"2","pk":"a"}`), nil)
+	resp, err = container.ExecuteTransactionalBatch(ctx, batch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success {
+		t.Fatal("expected the batch to fail")
+	}
+	if resp.OperationResults[0].StatusCode != http.StatusFailedDependency || resp.OperationResults[1].StatusCode != http.StatusConflict {
+		t.Fatalf("unexpected results %v", resp.OperationResults)
+	}
+	if _, err := container.ReadItem(ctx, pk, "3", nil); statusCode(err) != http.StatusNotFound {
+		t.Fatalf("expected the failed batch to roll back, got %v", err)
+	}
+}
+
+func TestServerQueryItems(t *testing.T) {
+	_, container := newTestContainer(t)
+	ctx := context.Background()
+	for _, item := range []string{
+		`{"id":"1","pk":"a","n":3,"name":"three"}`,
+		`{"id":"2","pk":"a","n":1,"name":"one"}`,
+		`{"id":"3","pk":"a","n":2,"name":"two"}`,
+		`{"id":"4","pk":"b","n":4,"name":"four"}`,
+	} {
+		var pk struct {
+			PK string `json:"pk"`
+		}
+		if err := json.Unmarshal([]byte(item), &pk); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := container.CreateItem(ctx, azcosmos.NewPartitionKeyString(pk.PK), []byte(item), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pager := container.NewQueryItemsPager("SELECT c.id, c.name FROM c WHERE c.n >= @min ORDER BY c.n DESC", azcosmos.NewPartitionKeyString("a"), &azcosmos.QueryOptions{
+		PageSizeHint:    2,
+		QueryParameters: []azcosmos.QueryParameter{{Name: "@min", Value: 1}},
+	})
+	var ids []string
+	pages := 0
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, b := range page.Items {
+			var item map[string]any
+			if err := json.Unmarshal(b, &item); err != nil {
+				t.Fatal(err)
+			}
+			if len(item) != 2 {
+				t.Fatalf("expected a projection of id and name, got %s", b)
+			}
+			ids = append(ids, item["id"].(string))
+		}
+	}
+	if pages != 2 || len(ids) != 3 || ids[0] != "1" || ids[1] != "3" || ids[2] != "2" {
+		t.Fatalf("unexpected results %v in %d pages", ids, pages)
+	}
+
+	pager = container.NewQueryItemsPager("SELECT * FROM c WHERE", azcosmos.NewPartitionKeyString("a"), nil)
+	if _, err := pager.NextPage(ctx); statusCode(err) != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an invalid query, got %v", err)
+	}
+}
+
+func TestServerThroughput(t *testing.T) {
+	srv, container := newTestContainer(t)
+	ctx := context.Background()
+	resp, err := container.ReadThroughput(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if throughput, ok := resp.ThroughputProperties.ManualThroughput(); !ok || throughput != 400 {
+		t.Fatalf("expected manual throughput 400, got %d", throughput)
+	}
+
+	client := newTestClient(t, srv, srv.Key())
+	autoscale := azcosmos.NewAutoscaleThroughputProperties(4000)
+	if _, err = client.CreateDatabase(ctx, azcosmos.DatabaseProperties{ID: "autoscale"}, &azcosmos.CreateDatabaseOptions{ThroughputProperties: &autoscale}); err != nil {
+		t.Fatal(err)
+	}
+	db, err := client.NewDatabase("autoscale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = db.ReadThroughput(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if throughput, ok := resp.ThroughputProperties.AutoscaleMaxThroughput(); !ok || throughput != 4000 {
+		t.Fatalf("expected autoscale max throughput 4000, got %d", throughput)
+	}
+
+	db, err = client.NewDatabase("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.ReadThroughput(ctx, nil); statusCode(err) != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a database without throughput, got %v", err)
+	}
+}
+
+func TestServerContainers(t *testing.T) {
+	srv, container := newTestContainer(t)
+	ctx := context.Background()
+	read, err := container.Read(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := *read.ContainerProperties
+	ttl := int32(60)
+	properties.DefaultTimeToLive = &ttl
+	if _, err = container.Replace(ctx, properties, nil); err != nil {
+		t.Fatal(err)
+	}
+	if read, err = container.Read(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if read.ContainerProperties.DefaultTimeToLive == nil || *read.ContainerProperties.DefaultTimeToLive != ttl {
+		t.Fatal("expected the replaced container to have a default TTL")
+	}
+
+	client := newTestClient(t, srv, srv.Key())
+	db, err := client.NewDatabase("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pager := db.NewQueryContainersPager("SELECT * FROM c WHERE c.id = 'container'", nil)
+	page, err := pager.NextPage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Containers) != 1 {
+		t.Fatalf("expected 1 container, got %d", len(page.Containers))
+	}
+
+	if _, err = container.Delete(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = container.Read(ctx, nil); statusCode(err) != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %v", err)
+	}
+}
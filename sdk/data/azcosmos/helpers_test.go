@@ -0,0 +1,113 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+// fakeGatewayEndpoint is the endpoint of clients sending requests to fake gateways
+const fakeGatewayEndpoint = "https://fake.documents.azure.com"
+
+// capturingTransport records requests and their bodies before sending them to a mock server
+type capturingTransport struct {
+	srv      *mock.Server
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (c *capturingTransport) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, body)
+	return c.srv.Do(req)
+}
+
+// testClientOptions configures the clients returned by newTestClient and the helpers built on it
+type testClientOptions struct {
+	// perCall and perRetry are added to the pipeline after headerPolicies
+	perCall  []policy.Policy
+	perRetry []policy.Policy
+
+	// configure sets fields of the client, such as its caches, after it's constructed
+	configure func(*Client)
+}
+
+// newTestClient returns a client sending requests to transport. Retries are delayed by a millisecond.
+func newTestClient(endpoint string, transport policy.Transporter, o testClientOptions) *Client {
+	pl := azruntime.NewPipeline("azcosmostest", "v1.0.0",
+		azruntime.PipelineOptions{PerCall: append([]policy.Policy{&headerPolicies{}}, o.perCall...), PerRetry: o.perRetry},
+		&policy.ClientOptions{Transport: transport, Retry: policy.RetryOptions{RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond}})
+	client := &Client{endpoint: endpoint, pipeline: pl}
+	if o.configure != nil {
+		o.configure(client)
+	}
+	return client
+}
+
+// newMockClient returns a client sending requests through a capturingTransport to a mock server, and a function
+// closing the server
+func newMockClient(o testClientOptions) (*Client, *mock.Server, *capturingTransport, func()) {
+	srv, close := mock.NewTLSServer()
+	transport := &capturingTransport{srv: srv}
+	return newTestClient(srv.URL(), transport, o), srv, transport, close
+}
+
+// newMockContainer returns a client for the container "db/<id>" of a client from newMockClient
+func newMockContainer(t *testing.T, id string, o testClientOptions) (*ContainerClient, *mock.Server, *capturingTransport, func()) {
+	client, srv, transport, close := newMockClient(o)
+	container, err := client.NewContainer("db", id)
+	if err != nil {
+		close()
+		t.Fatal(err)
+	}
+	return container, srv, transport, close
+}
+
+// newFakeGatewayContainer returns a client for the container "db/container" sending requests to a fake gateway
+func newFakeGatewayContainer(t *testing.T, gateway policy.Transporter) *ContainerClient {
+	container, err := newTestClient(fakeGatewayEndpoint, gateway, testClientOptions{}).NewContainer("db", "container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return container
+}
+
+// newFakeGatewayRanges returns the partition key ranges of fake gateways, "0" and "1", divided at divider
+func newFakeGatewayRanges(divider string) []partitionKeyRange {
+	return []partitionKeyRange{
+		{ID: "0", MinInclusive: "", MaxExclusive: divider},
+		{ID: "1", MinInclusive: divider, MaxExclusive: "FF"},
+	}
+}
+
+// isPartitionKeyRangesRequest returns true for requests reading a container's partition key ranges
+func isPartitionKeyRangesRequest(req *http.Request) bool {
+	return req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/"+pathSegmentPartitionKeyRange)
+}
+
+// partitionKeyRangesBody returns the body of a response listing partition key ranges
+func partitionKeyRangesBody(ranges []partitionKeyRange) string {
+	b, _ := json.Marshal(partitionKeyRangesResponse{PartitionKeyRanges: ranges})
+	return string(b)
+}
+
+// newFakeGatewayResponse returns a fake gateway's response to req
+func newFakeGatewayResponse(req *http.Request, status int, header http.Header, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// KeyWrapMetadata identifies the key encryption key wrapping a client encryption key.
+type KeyWrapMetadata struct {
+	// Type identifies the KeyWrapProvider of the key encryption key, for example "akvso" for Azure Key Vault keys.
+	Type string `json:"type"`
+	// Name is a name for the key encryption key.
+	Name string `json:"name"`
+	// Value identifies the key encryption key for its provider, for example the identifier of a Key Vault key.
+	Value string `json:"value"`
+	// Algorithm is the algorithm wrapping the data encryption key, for example "RSA-OAEP".
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+// KeyWrapProvider wraps and unwraps the data encryption keys of client encryption keys with key encryption keys.
+// Implementations must be goroutine-safe.
+type KeyWrapProvider interface {
+	// WrapKey encrypts a data encryption key with the key encryption key of metadata.
+	WrapKey(ctx context.Context, metadata KeyWrapMetadata, key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data encryption key wrapped with the key encryption key of metadata.
+	UnwrapKey(ctx context.Context, metadata KeyWrapMetadata, wrappedKey []byte) ([]byte, error)
+}
+
+// keyWrapTypeLocal is the KeyWrapMetadata.Type of keys wrapped by a LocalKeyWrapProvider
+const keyWrapTypeLocal = "local"
+
+// LocalKeyWrapProvider wraps data encryption keys with AES-GCM keys held in memory. It's intended for tests
+// and development, because its keys aren't protected like keys in a key management service.
+type LocalKeyWrapProvider struct {
+	mtx  sync.RWMutex
+	keys map[string][]byte
+}
+
+// NewLocalKeyWrapProvider creates a LocalKeyWrapProvider.
+// keys - The key encryption keys, by KeyWrapMetadata.Value. Keys are 16, 24 or 32 bytes long.
+func NewLocalKeyWrapProvider(keys map[string][]byte) (*LocalKeyWrapProvider, error) {
+	p := &LocalKeyWrapProvider{keys: map[string][]byte{}}
+	for name, key := range keys {
+		if err := p.SetKey(name, key); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// SetKey adds or replaces a key encryption key.
+// name - The KeyWrapMetadata.Value identifying the key.
+// key - The key, which is 16, 24 or 32 bytes long.
+func (p *LocalKeyWrapProvider) SetKey(name string, key []byte) error {
+	if _, err := aes.NewCipher(key); err != nil {
+		return fmt.Errorf("invalid key %q: %w", name, err)
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.keys[name] = append([]byte{}, key...)
+	return nil
+}
+
+// NewLocalKeyWrapMetadata returns the metadata of a key of a LocalKeyWrapProvider.
+// name - The key's name, as passed to SetKey.
+func NewLocalKeyWrapMetadata(name string) KeyWrapMetadata {
+	return KeyWrapMetadata{Type: keyWrapTypeLocal, Name: name, Value: name, Algorithm: "A256GCM"}
+}
+
+func (p *LocalKeyWrapProvider) aead(metadata KeyWrapMetadata) (cipher.AEAD, error) {
+	p.mtx.RLock()
+	key, ok := p.keys[metadata.Value]
+	p.mtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no key encryption key named %q", metadata.Value)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// WrapKey implements the KeyWrapProvider interface.
+func (p *LocalKeyWrapProvider) WrapKey(ctx context.Context, metadata KeyWrapMetadata, key []byte) ([]byte, error) {
+	aead, err := p.aead(metadata)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, key, []byte(metadata.Value)), nil
+}
+
+// UnwrapKey implements the KeyWrapProvider interface.
+func (p *LocalKeyWrapProvider) UnwrapKey(ctx context.Context, metadata KeyWrapMetadata, wrappedKey []byte) ([]byte, error) {
+	aead, err := p.aead(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, errors.New("invalid wrapped key")
+	}
+	nonce, ciphertext := wrappedKey[:aead.NonceSize()], wrappedKey[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(metadata.Value))
+}
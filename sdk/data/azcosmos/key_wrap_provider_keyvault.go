@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// keyWrapTypeKeyVault is the KeyWrapMetadata.Type of keys wrapped by Azure Key Vault keys, as in other Cosmos SDKs
+const keyWrapTypeKeyVault = "akvso"
+
+// keyVaultAlgorithmRSAOAEP is the Key Vault name of the RSA-OAEP key wrapping algorithm
+const keyVaultAlgorithmRSAOAEP = "RSA-OAEP"
+
+// KeyVaultKeyClient wraps and unwraps keys with the keys of an Azure Key Vault. An implementation calls the WrapKey
+// and UnwrapKey methods of an azkeys.Client, so that this module doesn't depend on the azkeys module.
+type KeyVaultKeyClient interface {
+	// WrapKey wraps key with the version of the key named name. An empty version is the key's latest version.
+	// algorithm is a Key Vault key wrapping algorithm such as RSA-OAEP.
+	WrapKey(ctx context.Context, name string, version string, algorithm string, key []byte) ([]byte, error)
+
+	// UnwrapKey unwraps wrappedKey with the version of the key named name.
+	UnwrapKey(ctx context.Context, name string, version string, algorithm string, wrappedKey []byte) ([]byte, error)
+}
+
+// KeyVaultKeyWrapProvider wraps data encryption keys with RSA keys in Azure Key Vault.
+type KeyVaultKeyWrapProvider struct {
+	vaultHost string
+	client    KeyVaultKeyClient
+}
+
+// NewKeyVaultKeyWrapProvider creates a KeyVaultKeyWrapProvider. It wraps keys only with keys of the vault at vaultURL.
+// vaultURL - The URL of the vault storing the key encryption keys, for example https://myvault.vault.azure.net.
+// client - The client of the vault.
+func NewKeyVaultKeyWrapProvider(vaultURL string, client KeyVaultKeyClient) (*KeyVaultKeyWrapProvider, error) {
+	if client == nil {
+		return nil, errors.New("client is required")
+	}
+	u, err := url.Parse(vaultURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault URL %q: %w", vaultURL, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid vault URL %q", vaultURL)
+	}
+	return &KeyVaultKeyWrapProvider{vaultHost: u.Host, client: client}, nil
+}
+
+// NewKeyVaultKeyWrapMetadata returns the metadata of a Key Vault key wrapping data encryption keys with RSA-OAEP.
+// name - A name for the key encryption key.
+// keyID - The identifier of the key, for example https://myvault.vault.azure.net/keys/mykey/version.
+func NewKeyVaultKeyWrapMetadata(name string, keyID string) KeyWrapMetadata {
+	return KeyWrapMetadata{
+		Type:      keyWrapTypeKeyVault,
+		Name:      name,
+		Value:     keyID,
+		Algorithm: keyVaultAlgorithmRSAOAEP,
+	}
+}
+
+// WrapKey implements the KeyWrapProvider interface.
+func (p *KeyVaultKeyWrapProvider) WrapKey(ctx context.Context, metadata KeyWrapMetadata, key []byte) ([]byte, error) {
+	name, version, algorithm, err := p.parseKeyVaultKeyWrapMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.WrapKey(ctx, name, version, algorithm, key)
+}
+
+// UnwrapKey implements the KeyWrapProvider interface.
+func (p *KeyVaultKeyWrapProvider) UnwrapKey(ctx context.Context, metadata KeyWrapMetadata, wrappedKey []byte) ([]byte, error) {
+	name, version, algorithm, err := p.parseKeyVaultKeyWrapMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.UnwrapKey(ctx, name, version, algorithm, wrappedKey)
+}
+
+// parseKeyVaultKeyWrapMetadata returns the name and version of the key identified by metadata.Value, and the wrapping
+// algorithm. Keys of other vaults than the provider's are rejected, as the client would send them to its own vault.
+func (p *KeyVaultKeyWrapProvider) parseKeyVaultKeyWrapMetadata(metadata KeyWrapMetadata) (string, string, string, error) {
+	if metadata.Type != keyWrapTypeKeyVault {
+		return "", "", "", fmt.Errorf("key wrap metadata of type %q doesn't identify a Key Vault key", metadata.Type)
+	}
+	u, err := url.Parse(metadata.Value)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid Key Vault key identifier %q: %w", metadata.Value, err)
+	}
+	if !strings.EqualFold(u.Host, p.vaultHost) {
+		return "", "", "", fmt.Errorf("key %q isn't a key of the vault %s", metadata.Value, p.vaultHost)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || segments[0] != "keys" {
+		return "", "", "", fmt.Errorf("invalid Key Vault key identifier %q", metadata.Value)
+	}
+	version := ""
+	if len(segments) == 3 {
+		version = segments[2]
+	}
+	algorithm := keyVaultAlgorithmRSAOAEP
+	if metadata.Algorithm != "" {
+		algorithm = metadata.Algorithm
+	}
+	return segments[1], version, algorithm, nil
+}
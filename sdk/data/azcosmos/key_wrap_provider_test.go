@@ -0,0 +1,116 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestLocalKeyWrapProvider(t *testing.T) {
+	ctx := context.Background()
+	provider, err := NewLocalKeyWrapProvider(map[string][]byte{"kek": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := NewLocalKeyWrapMetadata("kek")
+	key := bytes.Repeat([]byte{2}, 32)
+	wrapped, err := provider.WrapKey(ctx, metadata, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Error("expected the wrapped key to be encrypted")
+	}
+	unwrapped, err := provider.UnwrapKey(ctx, metadata, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, key) {
+		t.Errorf("expected %v, got %v", key, unwrapped)
+	}
+
+	if err := provider.SetKey("other", bytes.Repeat([]byte{3}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.UnwrapKey(ctx, NewLocalKeyWrapMetadata("other"), wrapped); err == nil {
+		t.Error("expected unwrapping with another key to fail")
+	}
+	if _, err := provider.WrapKey(ctx, NewLocalKeyWrapMetadata("missing"), key); err == nil {
+		t.Error("expected wrapping with a missing key to fail")
+	}
+	if err := provider.SetKey("invalid", []byte("short")); err == nil {
+		t.Error("expected an invalid key to fail")
+	}
+}
+
+type fakeKeyVaultKeyClient struct {
+	name      string
+	version   string
+	algorithm string
+}
+
+func (c *fakeKeyVaultKeyClient) WrapKey(ctx context.Context, name string, version string, algorithm string, key []byte) ([]byte, error) {
+	c.name, c.version, c.algorithm = name, version, algorithm
+	return append([]byte("wrapped:"), key...), nil
+}
+
+func (c *fakeKeyVaultKeyClient) UnwrapKey(ctx context.Context, name string, version string, algorithm string, wrappedKey []byte) ([]byte, error) {
+	c.name, c.version, c.algorithm = name, version, algorithm
+	return bytes.TrimPrefix(wrappedKey, []byte("wrapped:")), nil
+}
+
+func TestKeyVaultKeyWrapProvider(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKeyVaultKeyClient{}
+	provider, err := NewKeyVaultKeyWrapProvider("https://myvault.vault.azure.net", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := NewKeyVaultKeyWrapMetadata("kek", "https://myvault.vault.azure.net/keys/mykey/abc123")
+
+	wrapped, err := provider.WrapKey(ctx, metadata, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.name != "mykey" || client.version != "abc123" || client.algorithm != "RSA-OAEP" {
+		t.Errorf("unexpected key %s/%s and algorithm %s", client.name, client.version, client.algorithm)
+	}
+	unwrapped, err := provider.UnwrapKey(ctx, metadata, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(unwrapped) != "key" {
+		t.Errorf("unexpected unwrapped key %q", unwrapped)
+	}
+
+	// the latest version of a key
+	if _, err := provider.WrapKey(ctx, NewKeyVaultKeyWrapMetadata("kek", "https://myvault.vault.azure.net/keys/mykey"), []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	if client.name != "mykey" || client.version != "" {
+		t.Errorf("unexpected key %s/%s", client.name, client.version)
+	}
+
+	for _, invalid := range []KeyWrapMetadata{
+		NewLocalKeyWrapMetadata("kek"),
+		NewKeyVaultKeyWrapMetadata("kek", "https://myvault.vault.azure.net/secrets/mysecret"),
+		NewKeyVaultKeyWrapMetadata("kek", "https://myvault.vault.azure.net/keys/mykey/version/extra"),
+		NewKeyVaultKeyWrapMetadata("kek", "https://othervault.vault.azure.net/keys/mykey/abc123"),
+	} {
+		if _, err := provider.WrapKey(ctx, invalid, []byte("key")); err == nil {
+			t.Errorf("expected metadata %v to be invalid", invalid)
+		}
+	}
+}
+
+func TestNewKeyVaultKeyWrapProvider(t *testing.T) {
+	if _, err := NewKeyVaultKeyWrapProvider("https://myvault.vault.azure.net", nil); err == nil {
+		t.Error("expected a nil client to fail")
+	}
+	if _, err := NewKeyVaultKeyWrapProvider("myvault", &fakeKeyVaultKeyClient{}); err == nil {
+		t.Error("expected a vault URL without a host to fail")
+	}
+}
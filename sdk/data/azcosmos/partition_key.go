@@ -10,10 +10,23 @@ import (
 )
 
 // PartitionKey represents a logical partition key value.
+// Hierarchical partition keys have a value for each path of the container's partition key definition,
+// and are created by appending values to NewPartitionKey(). A partition key having values for only
+// the first paths of a hierarchy is a prefix partition key.
 type PartitionKey struct {
 	values []interface{}
 }
 
+// undefinedPartitionKeyValue is the value of a partition key for items which don't have the partition key path.
+type undefinedPartitionKeyValue struct{}
+
+// NewPartitionKey creates a partition key without values. Append values to it to create a hierarchical partition key.
+func NewPartitionKey() PartitionKey {
+	return PartitionKey{
+		values: []interface{}{},
+	}
+}
+
 // NewPartitionKeyString creates a partition key with a string value.
 func NewPartitionKeyString(value string) PartitionKey {
 	components := []interface{}{value}
@@ -38,6 +51,41 @@ func NewPartitionKeyNumber(value float64) PartitionKey {
 	}
 }
 
+// AppendString returns a copy of the partition key with a string value appended.
+func (pk PartitionKey) AppendString(value string) PartitionKey {
+	return pk.append(value)
+}
+
+// AppendBool returns a copy of the partition key with a boolean value appended.
+func (pk PartitionKey) AppendBool(value bool) PartitionKey {
+	return pk.append(value)
+}
+
+// AppendNumber returns a copy of the partition key with a numeric value appended.
+func (pk PartitionKey) AppendNumber(value float64) PartitionKey {
+	return pk.append(value)
+}
+
+// AppendNull returns a copy of the partition key with a null value appended, which matches items whose
+// value for the partition key path is null.
+func (pk PartitionKey) AppendNull() PartitionKey {
+	return pk.append(nil)
+}
+
+// AppendUndefined returns a copy of the partition key with an undefined value appended, which matches items
+// which don't have the partition key path.
+func (pk PartitionKey) AppendUndefined() PartitionKey {
+	return pk.append(undefinedPartitionKeyValue{})
+}
+
+func (pk PartitionKey) append(value interface{}) PartitionKey {
+	values := make([]interface{}, len(pk.values), len(pk.values)+1)
+	copy(values, pk.values)
+	return PartitionKey{
+		values: append(values, value),
+	}
+}
+
 func (pk *PartitionKey) toJsonString() (string, error) {
 	var completeJson strings.Builder
 	completeJson.Grow(256)
@@ -5,9 +5,12 @@ package azcosmos
 
 // PartitionKeyDefinition represents a partition key definition in the Azure Cosmos DB database service.
 // A partition key definition defines the path for the partition key property.
+// Containers with hierarchical partition keys have up to three paths, Kind PartitionKeyKindMultiHash and Version 2.
 type PartitionKeyDefinition struct {
 	// Paths returns the list of partition key paths of the container.
 	Paths []string `json:"paths"`
+	// Kind returns the partitioning scheme of the container. The service uses PartitionKeyKindHash when it's empty.
+	Kind PartitionKeyKind `json:"kind,omitempty"`
 	// Version returns the version of the hash partitioning of the container.
 	Version int `json:"version,omitempty"`
 }
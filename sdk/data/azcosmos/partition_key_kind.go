@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// PartitionKeyKind defines the supported partitioning schemes in the Azure Cosmos DB service.
+type PartitionKeyKind string
+
+const (
+	// PartitionKeyKindHash partitions items by the hash of a single partition key path.
+	PartitionKeyKindHash PartitionKeyKind = "Hash"
+	// PartitionKeyKindMultiHash partitions items by the hashes of up to three partition key paths, forming a hierarchy.
+	PartitionKeyKindMultiHash PartitionKeyKind = "MultiHash"
+)
+
+// Returns a list of available partition key kinds
+func PartitionKeyKindValues() []PartitionKeyKind {
+	return []PartitionKeyKind{PartitionKeyKindHash, PartitionKeyKindMultiHash}
+}
+
+// ToPtr returns a *PartitionKeyKind
+func (c PartitionKeyKind) ToPtr() *PartitionKeyKind {
+	return &c
+}
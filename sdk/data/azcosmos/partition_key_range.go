@@ -0,0 +1,114 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const (
+	minInclusiveEffectivePartitionKey string = ""
+	maxExclusiveEffectivePartitionKey string = "FF"
+)
+
+const (
+	subStatusPartitionKeyRangeGone        string = "1002"
+	subStatusCompletingSplit              string = "1007"
+	subStatusCompletingPartitionMigration string = "1008"
+)
+
+// partitionKeyRange is a range of effective partition key values served by one physical partition.
+type partitionKeyRange struct {
+	ID           string   `json:"id"`
+	MinInclusive string   `json:"minInclusive"`
+	MaxExclusive string   `json:"maxExclusive"`
+	Parents      []string `json:"parents,omitempty"`
+}
+
+// overlaps returns true when the partition key range contains part of r.
+func (pkr partitionKeyRange) overlaps(r queryRange) bool {
+	if r.Min == r.Max && r.IsMaxInclusive {
+		// a single effective partition key
+		return pkr.MinInclusive <= r.Min && r.Min < pkr.MaxExclusive
+	}
+	return r.Min < pkr.MaxExclusive && pkr.MinInclusive < r.Max
+}
+
+// isPartitionKeyRangeGone returns true when err reports that a request targeted a partition key range
+// which no longer exists because it split or merged.
+func isPartitionKeyRangeGone(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && isPartitionKeyRangeGoneResponse(respErr.RawResponse)
+}
+
+// isPartitionKeyRangeGoneResponse returns true when resp reports that a request targeted a partition key range
+// which no longer exists.
+func isPartitionKeyRangeGoneResponse(resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusGone {
+		return false
+	}
+	switch resp.Header.Get(cosmosHeaderSubStatus) {
+	case subStatusPartitionKeyRangeGone, subStatusCompletingSplit, subStatusCompletingPartitionMigration:
+		return true
+	}
+	return false
+}
+
+type partitionKeyRangesResponse struct {
+	PartitionKeyRanges []partitionKeyRange `json:"PartitionKeyRanges"`
+}
+
+// readPartitionKeyRanges reads all partition key ranges of the container.
+func (c *ContainerClient) readPartitionKeyRanges(ctx context.Context) ([]partitionKeyRange, float32, error) {
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypePartitionKeyRange,
+		resourceAddress: c.link,
+	}
+
+	path, err := generatePathForNameBased(resourceTypePartitionKeyRange, c.link, true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ranges []partitionKeyRange
+	var charge float32
+	continuation := ""
+	for {
+		token := continuation
+		azResponse, err := c.database.client.sendGetRequest(
+			path,
+			ctx,
+			operationContext,
+			nil,
+			func(r *policy.Request) {
+				if token != "" {
+					r.Raw().Header.Set(cosmosHeaderContinuationToken, token)
+				}
+			})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		charge += newResponse(azResponse).RequestCharge
+		continuation = azResponse.Header.Get(cosmosHeaderContinuationToken)
+
+		var page partitionKeyRangesResponse
+		if err := runtime.UnmarshalAsJSON(azResponse, &page); err != nil {
+			return nil, 0, err
+		}
+		ranges = append(ranges, page.PartitionKeyRanges...)
+
+		if continuation == "" {
+			break
+		}
+	}
+
+	return ranges, charge, nil
+}
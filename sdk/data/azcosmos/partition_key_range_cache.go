@@ -0,0 +1,194 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// partitionKeyRangeCache caches the partition key ranges and partition key definitions of containers, by
+// container link. Ranges are read again after a request reports that a range split or merged. It is goroutine-safe.
+type partitionKeyRangeCache struct {
+	mtx     sync.Mutex
+	entries map[string]*partitionKeyRangeCacheEntry
+}
+
+type partitionKeyRangeCacheEntry struct {
+	// readMtx serializes reads of the container's partition key ranges, so concurrent requests read them once
+	readMtx sync.Mutex
+
+	// the fields below are guarded by the cache's mtx
+	ranges       []partitionKeyRange
+	pkDefinition *PartitionKeyDefinition
+	// generation counts invalidations, so ranges read before an invalidation aren't cached
+	generation int
+}
+
+func newPartitionKeyRangeCache() *partitionKeyRangeCache {
+	return &partitionKeyRangeCache{entries: map[string]*partitionKeyRangeCacheEntry{}}
+}
+
+func (c *partitionKeyRangeCache) entry(containerLink string) *partitionKeyRangeCacheEntry {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.entries[containerLink]
+	if !ok {
+		e = &partitionKeyRangeCacheEntry{}
+		c.entries[containerLink] = e
+	}
+	return e
+}
+
+func (c *partitionKeyRangeCache) cached(e *partitionKeyRangeCacheEntry) ([]partitionKeyRange, int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return e.ranges, e.generation
+}
+
+// getRanges returns the cached partition key ranges of a container, reading them when they aren't cached.
+// The request charge is 0 when the ranges are cached.
+func (c *partitionKeyRangeCache) getRanges(
+	ctx context.Context,
+	containerLink string,
+	read func(context.Context) ([]partitionKeyRange, float32, error)) ([]partitionKeyRange, float32, error) {
+	e := c.entry(containerLink)
+	if ranges, _ := c.cached(e); ranges != nil {
+		return ranges, 0, nil
+	}
+
+	e.readMtx.Lock()
+	defer e.readMtx.Unlock()
+	ranges, generation := c.cached(e)
+	if ranges != nil {
+		return ranges, 0, nil
+	}
+
+	ranges, charge, err := read(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if e.generation == generation {
+		e.ranges = ranges
+	}
+	return ranges, charge, nil
+}
+
+func (c *partitionKeyRangeCache) getDefinition(containerLink string) *PartitionKeyDefinition {
+	e := c.entry(containerLink)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return e.pkDefinition
+}
+
+func (c *partitionKeyRangeCache) setDefinition(containerLink string, def PartitionKeyDefinition) {
+	e := c.entry(containerLink)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e.pkDefinition = &def
+}
+
+// invalidate removes the cached partition key ranges of a container
+func (c *partitionKeyRangeCache) invalidate(containerLink string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if e, ok := c.entries[containerLink]; ok {
+		e.ranges = nil
+		e.generation++
+	}
+}
+
+// remove removes everything cached for a container, which was deleted
+func (c *partitionKeyRangeCache) remove(containerLink string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if e, ok := c.entries[containerLink]; ok {
+		e.ranges = nil
+		e.pkDefinition = nil
+		e.generation++
+		delete(c.entries, containerLink)
+	}
+}
+
+// getPartitionKeyRanges returns the container's partition key ranges sorted by effective partition key, from the
+// client's cache when it has them. The request charge is 0 when the ranges are cached.
+func (c *ContainerClient) getPartitionKeyRanges(ctx context.Context) ([]partitionKeyRange, float32, error) {
+	read := func(ctx context.Context) ([]partitionKeyRange, float32, error) {
+		ranges, charge, err := c.readPartitionKeyRanges(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		sort.Slice(ranges, func(i, j int) bool { return ranges[i].MinInclusive < ranges[j].MinInclusive })
+		return ranges, charge, nil
+	}
+
+	cache := c.database.client.pkRanges
+	if cache == nil {
+		return read(ctx)
+	}
+	ranges, charge, err := cache.getRanges(ctx, c.link, read)
+	if err != nil {
+		return nil, 0, err
+	}
+	// callers may modify the ranges
+	return append([]partitionKeyRange(nil), ranges...), charge, nil
+}
+
+// getPartitionKeyDefinition returns the container's partition key definition, from the client's cache when
+// it has it. The request charge is 0 when the definition is cached.
+func (c *ContainerClient) getPartitionKeyDefinition(ctx context.Context) (PartitionKeyDefinition, float32, error) {
+	cache := c.database.client.pkRanges
+	if cache != nil {
+		if def := cache.getDefinition(c.link); def != nil {
+			return *def, 0, nil
+		}
+	}
+
+	response, err := c.Read(ctx, nil)
+	if err != nil {
+		return PartitionKeyDefinition{}, 0, err
+	}
+	def := response.ContainerProperties.PartitionKeyDefinition
+	if cache != nil {
+		cache.setDefinition(c.link, def)
+	}
+	return def, response.RequestCharge, nil
+}
+
+// partitionKeyRangeCachePolicy invalidates the cached partition key ranges of a container when a request
+// reports that a range split or merged, and removes what's cached for a container when it's deleted.
+type partitionKeyRangeCachePolicy struct {
+	cache *partitionKeyRangeCache
+}
+
+func (p *partitionKeyRangeCachePolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+	if err != nil {
+		return resp, err
+	}
+
+	o := pipelineRequestOptions{}
+	if !req.OperationValue(&o) {
+		return resp, nil
+	}
+	containerLink := containerLinkOf(o.resourceAddress)
+	if containerLink == "" {
+		return resp, nil
+	}
+
+	switch {
+	case o.resourceType == resourceTypeCollection && req.Raw().Method == http.MethodDelete && resp.StatusCode < 300:
+		p.cache.remove(containerLink)
+	case isPartitionKeyRangeGoneResponse(resp):
+		p.cache.invalidate(containerLink)
+	}
+	return resp, nil
+}
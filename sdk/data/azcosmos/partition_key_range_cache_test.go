@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func newTestCachingContainer(t *testing.T) (*ContainerClient, *mock.Server, *capturingTransport, func()) {
+	cache := newPartitionKeyRangeCache()
+	return newMockContainer(t, "container", testClientOptions{
+		perCall:   []policy.Policy{&partitionKeyRangeCachePolicy{cache: cache}},
+		configure: func(c *Client) { c.pkRanges = cache },
+	})
+}
+
+func TestGetFeedRanges(t *testing.T) {
+	container, srv, transport, close := newTestCachingContainer(t)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"PartitionKeyRanges":[{"id":"1","minInclusive":"7F","maxExclusive":"FF"},{"id":"0","minInclusive":"","maxExclusive":"7F"}]}`)))
+	feedRanges, err := container.GetFeedRanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FeedRange{{MinInclusive: "", MaxExclusive: "7F"}, {MinInclusive: "7F", MaxExclusive: "FF"}}
+	if !reflect.DeepEqual(feedRanges, expected) {
+		t.Errorf("expected %v, got %v", expected, feedRanges)
+	}
+	if req := transport.requests[0]; req.Method != http.MethodGet || req.URL.Path != "/dbs/db/colls/container/pkranges" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	// the ranges are cached
+	if _, err := container.GetFeedRanges(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.requests) != 1 {
+		t.Fatalf("expected cached ranges, got %d requests", len(transport.requests))
+	}
+
+	// a split range invalidates the cache
+	srv.AppendResponse(mock.WithStatusCode(http.StatusGone), mock.WithHeader(cosmosHeaderSubStatus, subStatusPartitionKeyRangeGone))
+	if _, err := container.ReadItem(ctx, NewPartitionKeyString("1"), "1", nil); !isPartitionKeyRangeGone(err) {
+		t.Fatalf("expected a gone error, got %v", err)
+	}
+	srv.AppendResponse(mock.WithBody([]byte(`{"PartitionKeyRanges":[{"id":"0","minInclusive":"","maxExclusive":"7F"},{"id":"2","minInclusive":"7F","maxExclusive":"BF"},{"id":"3","minInclusive":"BF","maxExclusive":"FF"}]}`)))
+	feedRanges, err = container.GetFeedRanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feedRanges) != 3 || len(transport.requests) != 3 {
+		t.Errorf("expected the ranges to be read again, got %v", feedRanges)
+	}
+}
+
+func TestFeedRangeFromPartitionKey(t *testing.T) {
+	container, srv, transport, close := newTestCachingContainer(t)
+	defer close()
+	ctx := context.Background()
+	pk := NewPartitionKeyString("partitionKey")
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"container","partitionKey":{"paths":["/pk"],"kind":"Hash","version":2}}`)))
+	for i := 0; i < 2; i++ {
+		feedRange, err := container.FeedRangeFromPartitionKey(ctx, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feedRange.MinInclusive != "013AEFCF77FA271571CF665A58C933F1" || feedRange.MaxExclusive != "013AEFCF77FA271571CF665A58C933F100" {
+			t.Errorf("unexpected feed range %v", feedRange)
+		}
+	}
+	if len(transport.requests) != 1 {
+		t.Errorf("expected the partition key definition to be cached, got %d requests", len(transport.requests))
+	}
+
+	// deleting the container removes its cached definition
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNoContent))
+	if _, err := container.Delete(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"container","partitionKey":{"paths":["/pk"],"kind":"Hash"}}`)))
+	feedRange, err := container.FeedRangeFromPartitionKey(ctx, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feedRange.MinInclusive != "05C1E1B3D9CD2608716273756A756A706F4C667A00" {
+		t.Errorf("expected a version 1 effective partition key, got %v", feedRange)
+	}
+}
+
+func TestFeedRangeFromPrefixPartitionKey(t *testing.T) {
+	container, srv, _, close := newTestCachingContainer(t)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"container","partitionKey":{"paths":["/tenantId","/userId"],"kind":"MultiHash","version":2}}`)))
+	prefix := NewPartitionKey().AppendString("tenant")
+	prefixRange, err := container.FeedRangeFromPartitionKey(ctx, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemRange, err := container.FeedRangeFromPartitionKey(ctx, prefix.AppendString("user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prefixRange.overlaps(itemRange) || itemRange.MinInclusive < prefixRange.MinInclusive || itemRange.MaxExclusive > prefixRange.MaxExclusive {
+		t.Errorf("expected %v to contain %v", prefixRange, itemRange)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"testing"
+)
+
+func TestPartitionKeyRangeOverlaps(t *testing.T) {
+	pkr := partitionKeyRange{ID: "1", MinInclusive: "3F", MaxExclusive: "7F"}
+	for _, test := range []struct {
+		r        queryRange
+		expected bool
+	}{
+		{queryRange{Min: "", Max: "FF", IsMinInclusive: true}, true},
+		{queryRange{Min: "", Max: "3F", IsMinInclusive: true}, false},
+		{queryRange{Min: "7F", Max: "FF", IsMinInclusive: true}, false},
+		{queryRange{Min: "40", Max: "50", IsMinInclusive: true}, true},
+		{queryRange{Min: "3F", Max: "3F", IsMinInclusive: true, IsMaxInclusive: true}, true},
+		{queryRange{Min: "7F", Max: "7F", IsMinInclusive: true, IsMaxInclusive: true}, false},
+	} {
+		if actual := pkr.overlaps(test.r); actual != test.expected {
+			t.Errorf("expected overlaps(%v) to be %v", test.r, test.expected)
+		}
+	}
+}
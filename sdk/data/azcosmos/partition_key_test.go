@@ -69,3 +69,38 @@ func TestPartitionKeyEquality(t *testing.T) {
 		t.Errorf("Expected %v to equal %v", pk, pk2)
 	}
 }
+
+func TestHierarchicalPartitionKeySerialization(t *testing.T) {
+	validTypes := map[string]PartitionKey{
+		"[]":                       NewPartitionKey(),
+		"[\"tenant\",\"user\",10]": NewPartitionKey().AppendString("tenant").AppendString("user").AppendNumber(10),
+		"[true,null,{}]":           NewPartitionKey().AppendBool(true).AppendNull().AppendUndefined(),
+		"[null]":                   NewPartitionKey().AppendNull(),
+		"[{}]":                     NewPartitionKey().AppendUndefined(),
+		"[\"some string\",false]":  NewPartitionKeyString("some string").AppendBool(false),
+	}
+
+	for expectedSerialization, pk := range validTypes {
+		serialization, err := pk.toJsonString()
+		if err != nil {
+			t.Errorf("Failed to serialize PK for %v, got %v", pk, err)
+		}
+
+		if serialization != expectedSerialization {
+			t.Errorf("Expected serialization %v, but got %v", expectedSerialization, serialization)
+		}
+	}
+}
+
+func TestPartitionKeyAppendCopies(t *testing.T) {
+	prefix := NewPartitionKey().AppendString("tenant")
+	a := prefix.AppendString("a")
+	b := prefix.AppendString("b")
+
+	if len(prefix.values) != 1 {
+		t.Errorf("Expected the prefix to have 1 component, but it has %v", len(prefix.values))
+	}
+	if a.values[1] != "a" || b.values[1] != "b" {
+		t.Errorf("Expected appended values to be independent, got %v and %v", a.values, b.values)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// PermissionMode defines the access a permission grants in the Azure Cosmos DB service.
+type PermissionMode string
+
+const (
+	// PermissionModeRead grants read access to the resource.
+	PermissionModeRead PermissionMode = "Read"
+	// PermissionModeAll grants read, write and delete access to the resource.
+	PermissionModeAll PermissionMode = "All"
+)
+
+// Returns a list of available permission modes
+func PermissionModeValues() []PermissionMode {
+	return []PermissionMode{PermissionModeRead, PermissionModeAll}
+}
+
+// ToPtr returns a *PermissionMode
+func (c PermissionMode) ToPtr() *PermissionMode {
+	return &c
+}
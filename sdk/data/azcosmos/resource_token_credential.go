@@ -0,0 +1,213 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	azruntime "github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+// ResourceToken is a resource token, and the link of the resource it grants access to.
+type ResourceToken struct {
+	// ResourceLink is the link of the resource, for example dbs/database/colls/container.
+	// The token also grants access to the resource's children, such as the items of a container.
+	ResourceLink string
+	// Token is the resource token, as in PermissionProperties.Token.
+	Token string
+}
+
+// ResourceTokenCredentialOptions contains optional parameters for NewResourceTokenCredential.
+type ResourceTokenCredentialOptions struct {
+	// Refresh returns new resource tokens, which replace the credential's tokens. The credential calls it
+	// when none of its tokens grants access to a resource, and when the service rejects a token, typically
+	// because it expired, before retrying the request once.
+	Refresh func(ctx context.Context) ([]ResourceToken, error)
+}
+
+// ResourceTokenCredential authenticates requests with resource tokens, which grant access to the resources of
+// a user's permissions, such as a container or the items of a partition key. Requests use the token of the most
+// specific resource containing the requested resource. It is goroutine-safe.
+type ResourceTokenCredential struct {
+	mtx sync.RWMutex
+	// tokens are sorted from the most to the least specific resource
+	tokens []ResourceToken
+	// version counts the updates of tokens, so concurrent requests rejected with the same tokens refresh them once
+	version    int
+	refreshMtx sync.Mutex
+	refresh    func(ctx context.Context) ([]ResourceToken, error)
+}
+
+// NewResourceTokenCredential creates a ResourceTokenCredential.
+// tokens - The resource tokens. It can be empty when options.Refresh provides the tokens.
+// options - Optional parameters. Pass nil to accept default values.
+func NewResourceTokenCredential(tokens []ResourceToken, options *ResourceTokenCredentialOptions) (*ResourceTokenCredential, error) {
+	if options == nil {
+		options = &ResourceTokenCredentialOptions{}
+	}
+	if len(tokens) == 0 && options.Refresh == nil {
+		return nil, errors.New("resource tokens or a refresh function are required")
+	}
+
+	c := &ResourceTokenCredential{refresh: options.Refresh}
+	c.Update(tokens)
+	return c, nil
+}
+
+// Update replaces the credential's resource tokens.
+func (c *ResourceTokenCredential) Update(tokens []ResourceToken) {
+	sorted := make([]ResourceToken, len(tokens))
+	for i, token := range tokens {
+		sorted[i] = ResourceToken{ResourceLink: normalizeResourceLink(token.ResourceLink), Token: token.Token}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i].ResourceLink, "/") > strings.Count(sorted[j].ResourceLink, "/")
+	})
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.tokens = sorted
+	c.version++
+}
+
+// tokenFor returns the token of the most specific resource containing a resource, and the version of the tokens.
+// Database account reads, which aren't scoped to a resource, use any token.
+func (c *ResourceTokenCredential) tokenFor(resourceType resourceType, resourceAddress string) (string, int, bool) {
+	resourceAddress = normalizeResourceLink(resourceAddress)
+
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	for _, token := range c.tokens {
+		if resourceType == resourceTypeDatabaseAccount ||
+			resourceAddress == token.ResourceLink ||
+			strings.HasPrefix(resourceAddress, token.ResourceLink+"/") {
+			return token.Token, c.version, true
+		}
+	}
+	return "", c.version, false
+}
+
+// refreshTokens replaces the tokens with the tokens returned by the refresh function, unless another
+// request refreshed them since they were at the given version.
+func (c *ResourceTokenCredential) refreshTokens(ctx context.Context, version int) error {
+	c.refreshMtx.Lock()
+	defer c.refreshMtx.Unlock()
+
+	c.mtx.RLock()
+	refreshed := c.version != version
+	c.mtx.RUnlock()
+	if refreshed {
+		return nil
+	}
+
+	tokens, err := c.refresh(ctx)
+	if err != nil {
+		return fmt.Errorf("refresh resource tokens: %w", err)
+	}
+	c.Update(tokens)
+	return nil
+}
+
+// authorize returns the authorization header of a request, refreshing the tokens when none grants access
+// to the resource.
+func (c *ResourceTokenCredential) authorize(ctx context.Context, o pipelineRequestOptions) (string, int, error) {
+	token, version, ok := c.tokenFor(o.resourceType, o.resourceAddress)
+	if !ok && c.refresh != nil {
+		if err := c.refreshTokens(ctx, version); err != nil {
+			return "", version, err
+		}
+		token, version, ok = c.tokenFor(o.resourceType, o.resourceAddress)
+	}
+	if !ok {
+		return "", version, fmt.Errorf("no resource token grants access to %q", o.resourceAddress)
+	}
+
+	// tokens returned by the service aren't URL encoded
+	if strings.HasPrefix(token, "type=") {
+		token = url.QueryEscape(token)
+	}
+	return token, version, nil
+}
+
+// normalizeResourceLink removes the leading and trailing slashes and the URL encoding of a resource link
+func normalizeResourceLink(link string) string {
+	link = strings.Trim(link, "/")
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+	return link
+}
+
+// resourceTokenError is an error authorizing a request, which retrying the request doesn't resolve
+type resourceTokenError struct {
+	error
+}
+
+// NonRetriable implements errorinfo.NonRetriable
+func (*resourceTokenError) NonRetriable() {}
+
+func (e *resourceTokenError) Unwrap() error {
+	return e.error
+}
+
+type resourceTokenCredPolicy struct {
+	cred *ResourceTokenCredential
+}
+
+func newResourceTokenCredPolicy(cred *ResourceTokenCredential) *resourceTokenCredPolicy {
+	return &resourceTokenCredPolicy{
+		cred: cred,
+	}
+}
+
+func (p *resourceTokenCredPolicy) Do(req *policy.Request) (*http.Response, error) {
+	// Add a x-ms-date header if it doesn't already exist
+	if d := req.Raw().Header.Get(headerXmsDate); d == "" {
+		req.Raw().Header.Set(headerXmsDate, time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	o := pipelineRequestOptions{}
+	if !req.OperationValue(&o) {
+		return req.Next()
+	}
+
+	ctx := req.Raw().Context()
+	authHeader, version, err := p.cred.authorize(ctx, o)
+	if err != nil {
+		return nil, &resourceTokenError{err}
+	}
+	req.Raw().Header.Set(headerAuthorization, authHeader)
+
+	response, err := req.Next()
+	if err != nil || response.StatusCode != http.StatusUnauthorized || p.cred.refresh == nil {
+		return response, err
+	}
+
+	// the token expired or the permission changed, retry once with new tokens
+	log.Write(azlog.EventRetryPolicy, "Resource token rejected, refreshing the resource tokens")
+	if err := p.cred.refreshTokens(ctx, version); err != nil {
+		log.Writef(azlog.EventRetryPolicy, "Failed to refresh the resource tokens: %v", err)
+		return response, nil
+	}
+	if authHeader, _, err = p.cred.authorize(ctx, o); err != nil {
+		return response, nil
+	}
+	azruntime.Drain(response)
+	if err := req.RewindBody(); err != nil {
+		return nil, err
+	}
+	req.Raw().Header.Set(headerAuthorization, authHeader)
+	return req.Next()
+}
@@ -0,0 +1,138 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+)
+
+func TestResourceTokenCredentialTokenFor(t *testing.T) {
+	cred, err := NewResourceTokenCredential([]ResourceToken{
+		{ResourceLink: "dbs/db/colls/orders", Token: "container"},
+		{ResourceLink: "/dbs/db/colls/orders/docs/special/", Token: "item"},
+		{ResourceLink: "dbs/db/colls/my orders", Token: "escaped"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		resourceType    resourceType
+		resourceAddress string
+		token           string
+	}{
+		{resourceTypeDocument, "dbs/db/colls/orders/docs/1", "container"},
+		{resourceTypeDocument, "dbs/db/colls/orders/docs/special", "item"},
+		{resourceTypeCollection, "dbs/db/colls/orders", "container"},
+		{resourceTypeDocument, "dbs/db/colls/my%20orders/docs/1", "escaped"},
+		{resourceTypeDatabaseAccount, "", "item"},
+		{resourceTypeDocument, "dbs/db/colls/ordersarchive/docs/1", ""},
+		{resourceTypeDatabase, "dbs/db", ""},
+	}
+	for _, c := range cases {
+		token, _, ok := cred.tokenFor(c.resourceType, c.resourceAddress)
+		if token != c.token || ok != (c.token != "") {
+			t.Errorf("expected token %q for %s, got %q", c.token, c.resourceAddress, token)
+		}
+	}
+
+	if _, err := NewResourceTokenCredential(nil, nil); err == nil {
+		t.Error("expected a credential without tokens to fail")
+	}
+}
+
+func newTestResourceTokenContainer(t *testing.T, cred *ResourceTokenCredential) (*ContainerClient, *mock.Server, *capturingTransport, func()) {
+	return newMockContainer(t, "orders", testClientOptions{perRetry: []policy.Policy{newResourceTokenCredPolicy(cred)}})
+}
+
+func TestResourceTokenCredentialPolicy(t *testing.T) {
+	refreshes := 0
+	cred, err := NewResourceTokenCredential([]ResourceToken{{ResourceLink: "dbs/db/colls/orders", Token: "type=resource&ver=1.0&sig=old"}}, &ResourceTokenCredentialOptions{
+		Refresh: func(ctx context.Context) ([]ResourceToken, error) {
+			refreshes++
+			return []ResourceToken{{ResourceLink: "dbs/db/colls/orders", Token: "type=resource&ver=1.0&sig=new"}}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, srv, transport, close := newTestResourceTokenContainer(t, cred)
+	defer close()
+	ctx := context.Background()
+
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"1"}`)))
+	if _, err := container.ReadItem(ctx, NewPartitionKeyString("1"), "1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if auth := transport.requests[0].Header.Get(headerAuthorization); auth != url.QueryEscape("type=resource&ver=1.0&sig=old") {
+		t.Errorf("unexpected authorization %s", auth)
+	}
+	if transport.requests[0].Header.Get(headerXmsDate) == "" {
+		t.Error("expected a date header")
+	}
+
+	// a rejected token is refreshed, and the request is sent again
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithBody([]byte(`{"id":"2"}`)))
+	if _, err := container.CreateItem(ctx, NewPartitionKeyString("2"), []byte(`{"id":"2"}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if refreshes != 1 || len(transport.requests) != 3 {
+		t.Fatalf("expected a refresh and a retry, got %d refreshes and %d requests", refreshes, len(transport.requests))
+	}
+	if auth := transport.requests[2].Header.Get(headerAuthorization); auth != url.QueryEscape("type=resource&ver=1.0&sig=new") {
+		t.Errorf("unexpected authorization %s", auth)
+	}
+	if string(transport.bodies[2]) != `{"id":"2"}` {
+		t.Errorf("expected the body to be sent again, got %s", transport.bodies[2])
+	}
+
+	// the retry is only attempted once
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusUnauthorized))
+	_, err = container.ReadItem(ctx, NewPartitionKeyString("1"), "1", nil)
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+	if refreshes != 2 || len(transport.requests) != 5 {
+		t.Fatalf("expected a single retry, got %d refreshes and %d requests", refreshes, len(transport.requests))
+	}
+}
+
+func TestResourceTokenCredentialPolicyWithoutToken(t *testing.T) {
+	cred, err := NewResourceTokenCredential([]ResourceToken{{ResourceLink: "dbs/db/colls/other", Token: "token"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, srv, transport, close := newTestResourceTokenContainer(t, cred)
+	defer close()
+
+	if _, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "1", nil); err == nil {
+		t.Fatal("expected a request without a token to fail")
+	}
+	if len(transport.requests) != 0 {
+		t.Errorf("expected no requests, got %d", len(transport.requests))
+	}
+
+	// tokens are requested when none grants access to the resource
+	cred.refresh = func(ctx context.Context) ([]ResourceToken, error) {
+		return []ResourceToken{{ResourceLink: "dbs/db/colls/orders/docs/1", Token: "item"}}, nil
+	}
+	srv.AppendResponse(mock.WithBody([]byte(`{"id":"1"}`)))
+	if _, err := container.ReadItem(context.Background(), NewPartitionKeyString("1"), "1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.requests) != 1 || transport.requests[0].Header.Get(headerAuthorization) != "item" {
+		t.Errorf("expected a request with the refreshed token")
+	}
+}
@@ -58,6 +58,11 @@ func (c *KeyCredential) buildCanonicalizedAuthHeaderFromRequest(req *policy.Requ
 	value := ""
 
 	if req.OperationValue(&opValues) {
+		if opValues.resourceType == resourceTypeDatabaseAccount {
+			// database account reads are signed with an empty resource type and address
+			return c.sign(req.Raw().Method, "", "", req.Raw().Header.Get(headerXmsDate), "master", "1.0"), nil
+		}
+
 		resourceTypePath, err := getResourcePath(opValues.resourceType)
 
 		if err != nil {
@@ -81,6 +86,10 @@ func (c *KeyCredential) buildCanonicalizedAuthHeader(method, resourceType, resou
 		return ""
 	}
 
+	return c.sign(method, resourceType, resourceAddress, xmsDate, tokenType, version)
+}
+
+func (c *KeyCredential) sign(method, resourceType, resourceAddress, xmsDate, tokenType, version string) string {
 	resourceAddress, _ = url.PathUnescape(resourceAddress)
 
 	// https://docs.microsoft.com/en-us/rest/api/cosmos-db/access-control-on-cosmosdb-resources#constructkeytoken
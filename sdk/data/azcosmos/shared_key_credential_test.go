@@ -76,6 +76,33 @@ func Test_buildCanonicalizedAuthHeaderFromRequest(t *testing.T) {
 	assert.Equal(t, expected, authHeader)
 }
 
+func Test_buildCanonicalizedAuthHeaderFromRequestForDatabaseAccount(t *testing.T) {
+	key := "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
+
+	cred, err := NewKeyCredential(key)
+
+	assert.NoError(t, err)
+
+	xmsDate := "Thu, 27 Apr 2017 00:51:12 GMT"
+
+	stringToSign := join("get", "\n", "", "\n", "", "\n", strings.ToLower(xmsDate), "\n", "", "\n")
+	signature := cred.computeHMACSHA256(stringToSign)
+	expected := url.QueryEscape(fmt.Sprintf("type=%s&ver=%s&sig=%s", "master", "1.0", signature))
+
+	req, _ := azruntime.NewRequest(context.TODO(), http.MethodGet, "http://localhost")
+	operationContext := pipelineRequestOptions{
+		resourceType:    resourceTypeDatabaseAccount,
+		resourceAddress: "",
+	}
+
+	req.Raw().Header.Set(headerXmsDate, xmsDate)
+	req.Raw().Header.Set(headerXmsVersion, "2020-11-05")
+	req.SetOperationValue(operationContext)
+	authHeader, _ := cred.buildCanonicalizedAuthHeaderFromRequest(req)
+
+	assert.Equal(t, expected, authHeader)
+}
+
 func Test_buildCanonicalizedAuthHeaderFromRequestWithRid(t *testing.T) {
 	key := "C2y6yDjf5/R+ob0N8A7Cgv30VRDJIWEHLM+4QDU5DE2nQ9nDuVTqobD4b8mGGyPMbIZnqyMsEcaGQy67XIw/Jw=="
 
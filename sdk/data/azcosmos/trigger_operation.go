@@ -0,0 +1,30 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// TriggerOperation defines the operations which run a trigger in the Azure Cosmos DB service.
+type TriggerOperation string
+
+const (
+	// TriggerOperationAll runs the trigger for all operations.
+	TriggerOperationAll TriggerOperation = "All"
+	// TriggerOperationCreate runs the trigger for create operations.
+	TriggerOperationCreate TriggerOperation = "Create"
+	// TriggerOperationReplace runs the trigger for replace operations.
+	TriggerOperationReplace TriggerOperation = "Replace"
+	// TriggerOperationDelete runs the trigger for delete operations.
+	TriggerOperationDelete TriggerOperation = "Delete"
+	// TriggerOperationUpsert runs the trigger for upsert operations.
+	TriggerOperationUpsert TriggerOperation = "Upsert"
+)
+
+// Returns a list of available trigger operations
+func TriggerOperationValues() []TriggerOperation {
+	return []TriggerOperation{TriggerOperationAll, TriggerOperationCreate, TriggerOperationReplace, TriggerOperationDelete, TriggerOperationUpsert}
+}
+
+// ToPtr returns a *TriggerOperation
+func (c TriggerOperation) ToPtr() *TriggerOperation {
+	return &c
+}
@@ -0,0 +1,24 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcosmos
+
+// TriggerType defines when a trigger runs in the Azure Cosmos DB service.
+type TriggerType string
+
+const (
+	// TriggerTypePre triggers run before the operation.
+	TriggerTypePre TriggerType = "Pre"
+	// TriggerTypePost triggers run after the operation.
+	TriggerTypePost TriggerType = "Post"
+)
+
+// Returns a list of available trigger types
+func TriggerTypeValues() []TriggerType {
+	return []TriggerType{TriggerTypePre, TriggerTypePost}
+}
+
+// ToPtr returns a *TriggerType
+func (c TriggerType) ToPtr() *TriggerType {
+	return &c
+}
@@ -0,0 +1,310 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+const (
+	// maxTransactionActions is the maximum number of actions in a transaction.
+	maxTransactionActions = 100
+
+	// maxTransactionBytes is the maximum size of a transaction's payload.
+	maxTransactionBytes = 4 * 1024 * 1024
+
+	// maxCosmosTransactionBytes is the maximum size of a transaction's payload for the Cosmos DB Table API.
+	maxCosmosTransactionBytes = 2 * 1024 * 1024
+
+	// transactionActionOverhead estimates the size of the request line and headers of an action in a transaction.
+	transactionActionOverhead = 1024
+)
+
+var errBulkWriterClosed = errors.New("the BulkWriter is closed")
+
+// BulkWriterOptions contains optional parameters for Client.NewBulkWriter
+type BulkWriterOptions struct {
+	// Concurrency is the maximum number of transactions submitted at once. The default is 4.
+	Concurrency int
+
+	// MaxRetries is the maximum number of times a throttled transaction is submitted again. The default is 3. Specify
+	// a value less than zero to disable these retries.
+	MaxRetries int32
+
+	// RetryDelay is the delay before submitting a throttled transaction again. The delay doubles with each retry. The
+	// default is 1 second.
+	RetryDelay time.Duration
+}
+
+// BulkWriteFailure describes an action a BulkWriter didn't write.
+type BulkWriteFailure struct {
+	// Action is the action that wasn't written.
+	Action TransactionAction
+
+	// PartitionKey and RowKey identify the action's entity.
+	PartitionKey string
+	RowKey       string
+
+	// Err is the reason the action wasn't written. It's an *azcore.ResponseError for an action the service rejected.
+	Err error
+}
+
+// BulkWriterCloseResponse contains response fields for BulkWriter.Close
+type BulkWriterCloseResponse struct {
+	// Written is the number of actions the service accepted.
+	Written int
+
+	// Failures lists the actions that weren't written.
+	Failures []BulkWriteFailure
+}
+
+// BulkWriter writes an unordered stream of TransactionActions in transactions. It groups actions by PartitionKey into
+// transactions of at most 100 actions and 4 MB, or 2 MB for the Cosmos DB Table API, and submits them concurrently.
+// Actions for the same PartitionKey are submitted in the order they were added. Don't use a BulkWriter after calling
+// its Close method.
+//
+// Transactions are atomic, so when the service rejects an action the other actions of its transaction aren't written.
+// The BulkWriter submits them again without the rejected action. Each BulkWriter is safe for concurrent use.
+type BulkWriter struct {
+	client     *Client
+	maxBytes   int
+	maxRetries int32
+	retryDelay time.Duration
+
+	// sem bounds the number of transactions being submitted, and queue the number waiting to be submitted.
+	sem   chan struct{}
+	queue chan struct{}
+	wg    sync.WaitGroup
+
+	// mu guards the pending transactions. Add holds it while waiting for the queue, so submitted transactions record
+	// their results under resultsMu instead.
+	mu         sync.Mutex
+	closed     bool
+	partitions map[string]*bulkPartition
+
+	resultsMu sync.Mutex
+	written   int
+	failures  []BulkWriteFailure
+}
+
+// bulkPartition is the pending transaction for a PartitionKey.
+type bulkPartition struct {
+	actions []bulkAction
+	rowKeys map[string]struct{}
+	size    int
+
+	// done is closed when the partition's last submitted transaction completes.
+	done chan struct{}
+}
+
+type bulkAction struct {
+	action TransactionAction
+	rowKey string
+}
+
+// NewBulkWriter creates a BulkWriter that writes TransactionActions to the table. Specify nil for options if you want
+// to use the default options.
+func (t *Client) NewBulkWriter(options *BulkWriterOptions) *BulkWriter {
+	if options == nil {
+		options = &BulkWriterOptions{}
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	maxRetries := options.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 3
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+	retryDelay := options.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = time.Second
+	}
+	maxBytes := maxTransactionBytes
+	if isCosmosEndpoint(t.con.Endpoint()) {
+		maxBytes = maxCosmosTransactionBytes
+	}
+	return &BulkWriter{
+		client:     t,
+		maxBytes:   maxBytes,
+		maxRetries: maxRetries,
+		retryDelay: retryDelay,
+		sem:        make(chan struct{}, concurrency),
+		queue:      make(chan struct{}, 2*concurrency),
+		partitions: map[string]*bulkPartition{},
+	}
+}
+
+// Add adds an action to the pending transaction for its entity's PartitionKey, and submits the transaction when it
+// can't hold more actions. Add blocks while too many transactions are waiting to be submitted. It returns an error if
+// the action's entity doesn't have string PartitionKey and RowKey properties. The context is used for the transaction
+// Add submits, if any, including its retries.
+func (b *BulkWriter) Add(ctx context.Context, action TransactionAction) error {
+	var entity map[string]interface{}
+	if err := json.Unmarshal(action.Entity, &entity); err != nil {
+		return err
+	}
+	pk, pkOK := entity[partitionKey].(string)
+	rk, rkOK := entity[rowKey].(string)
+	if !pkOK || !rkOK {
+		return errPartitionKeyRowKeyError
+	}
+	size := len(action.Entity) + transactionActionOverhead
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errBulkWriterClosed
+	}
+	p := b.partitions[pk]
+	if p == nil {
+		p = &bulkPartition{rowKeys: map[string]struct{}{}}
+		b.partitions[pk] = p
+	}
+	// a transaction can only have one action for each entity
+	if _, ok := p.rowKeys[rk]; ok || len(p.actions) == maxTransactionActions || p.size+size > b.maxBytes {
+		if err := b.submit(ctx, pk, p); err != nil {
+			return err
+		}
+	}
+	p.actions = append(p.actions, bulkAction{action: action, rowKey: rk})
+	p.rowKeys[rk] = struct{}{}
+	p.size += size
+	return nil
+}
+
+// Close submits the pending transactions with the context, waits for all transactions to complete, and returns the
+// results. It returns an error only if the context is done before the transactions complete.
+func (b *BulkWriter) Close(ctx context.Context) (BulkWriterCloseResponse, error) {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for pk, p := range b.partitions {
+			if len(p.actions) == 0 {
+				continue
+			}
+			if err := b.submit(ctx, pk, p); err != nil {
+				b.mu.Unlock()
+				return BulkWriterCloseResponse{}, err
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return BulkWriterCloseResponse{}, ctx.Err()
+	}
+
+	b.resultsMu.Lock()
+	defer b.resultsMu.Unlock()
+	return BulkWriterCloseResponse{Written: b.written, Failures: b.failures}, nil
+}
+
+// submit starts submitting the pending transaction for a partition after the partition's previous transaction
+// completes. The caller must hold b.mu.
+func (b *BulkWriter) submit(ctx context.Context, pk string, p *bulkPartition) error {
+	select {
+	case b.queue <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	actions, previous, done := p.actions, p.done, make(chan struct{})
+	p.actions, p.rowKeys, p.size, p.done = nil, map[string]struct{}{}, 0, done
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer close(done)
+		defer func() { <-b.queue }()
+		if previous != nil {
+			select {
+			case <-previous:
+			case <-ctx.Done():
+				b.fail(pk, actions, ctx.Err())
+				return
+			}
+		}
+		b.write(ctx, pk, actions)
+	}()
+	return nil
+}
+
+// write submits a transaction, submitting it again when the service throttles it or rejects one of its actions,
+// and records the results.
+func (b *BulkWriter) write(ctx context.Context, pk string, actions []bulkAction) {
+	for retries := int32(0); len(actions) > 0; {
+		err := b.submitTransaction(ctx, actions)
+		if err == nil {
+			b.resultsMu.Lock()
+			b.written += len(actions)
+			b.resultsMu.Unlock()
+			return
+		}
+		var te *transactionError
+		if errors.As(err, &te) && te.actionIndex >= 0 && !isThrottled(te.actionErr) {
+			b.fail(pk, actions[te.actionIndex:te.actionIndex+1], te.actionErr)
+			actions = append(actions[:te.actionIndex:te.actionIndex], actions[te.actionIndex+1:]...)
+			continue
+		}
+		if retries >= b.maxRetries || !(isThrottled(err) || te != nil && isThrottled(te.actionErr)) {
+			b.fail(pk, actions, err)
+			return
+		}
+		select {
+		case <-time.After(b.retryDelay << retries):
+		case <-ctx.Done():
+			b.fail(pk, actions, ctx.Err())
+			return
+		}
+		retries++
+	}
+}
+
+func (b *BulkWriter) submitTransaction(ctx context.Context, actions []bulkAction) error {
+	select {
+	case b.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-b.sem }()
+	transactionActions := make([]TransactionAction, len(actions))
+	for i, a := range actions {
+		transactionActions[i] = a.action
+	}
+	_, err := b.client.SubmitTransaction(ctx, transactionActions, nil)
+	return err
+}
+
+func (b *BulkWriter) fail(pk string, actions []bulkAction, err error) {
+	b.resultsMu.Lock()
+	defer b.resultsMu.Unlock()
+	for _, a := range actions {
+		b.failures = append(b.failures, BulkWriteFailure{Action: a.action, PartitionKey: pk, RowKey: a.rowKey, Err: err})
+	}
+}
+
+// isThrottled returns true for errors the service returns when it's too busy to handle a request.
+func isThrottled(err error) bool {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	return respErr.StatusCode == http.StatusTooManyRequests || respErr.StatusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,252 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBatchTable is a table handling transactions of add actions. It rejects adding an entity that already exists.
+type fakeBatchTable struct {
+	mu        sync.Mutex
+	entities  map[string]map[string]interface{}
+	batches   [][]string
+	throttle  int
+	inFlight  int
+	maxFlight int
+}
+
+func (f *fakeBatchTable) do(req *http.Request) (*http.Response, error) {
+	keys, entities, err := readTransaction(req)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxFlight {
+		f.maxFlight = f.inFlight
+	}
+	f.mu.Unlock()
+	time.Sleep(time.Millisecond)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	if f.throttle > 0 {
+		f.throttle--
+		return newJSONResponse(req, http.StatusServiceUnavailable, `{"odata.error":{"code":"ServerBusy"}}`, nil), nil
+	}
+	f.batches = append(f.batches, keys)
+	for i, key := range keys {
+		if f.entities[key] != nil {
+			message := fmt.Sprintf(`{"odata.error":{"code":"EntityAlreadyExists","message":{"lang":"en-US","value":"%d:The specified entity already exists."}}}`, i)
+			return newTransactionResponse(req, "HTTP/1.1 409 Conflict\r\nContent-Type: application/json\r\n\r\n"+message), nil
+		}
+	}
+	inner := make([]string, len(keys))
+	for i, key := range keys {
+		f.entities[key] = entities[i]
+		inner[i] = "HTTP/1.1 204 No Content\r\n\r\n"
+	}
+	return newTransactionResponse(req, inner...), nil
+}
+
+// readTransaction returns the entities in a transaction request and their "PartitionKey/RowKey" keys.
+func readTransaction(req *http.Request) ([]string, []map[string]interface{}, error) {
+	_, params, err := mime.ParseMediaType(req.Header.Get(headerContentType))
+	if err != nil {
+		return nil, nil, err
+	}
+	changeset, err := multipart.NewReader(req.Body, params["boundary"]).NextPart()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, params, err = mime.ParseMediaType(changeset.Header.Get(headerContentType))
+	if err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	var entities []map[string]interface{}
+	actions := multipart.NewReader(changeset, params["boundary"])
+	for part, err := actions.NextPart(); err != io.EOF; part, err = actions.NextPart() {
+		if err != nil {
+			return nil, nil, err
+		}
+		r, err := http.ReadRequest(bufio.NewReader(part))
+		if err != nil {
+			return nil, nil, err
+		}
+		var entity map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&entity); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, fmt.Sprintf("%s/%s", entity[partitionKey], entity[rowKey]))
+		entities = append(entities, entity)
+	}
+	return keys, entities, nil
+}
+
+func newTransactionResponse(req *http.Request, inner ...string) *http.Response {
+	body := &bytes.Buffer{}
+	body.WriteString("--batchresponse_1\r\nContent-Type: multipart/mixed; boundary=changesetresponse_1\r\n\r\n")
+	for _, r := range inner {
+		body.WriteString("--changesetresponse_1\r\nContent-Type: application/http\r\nContent-Transfer-Encoding: binary\r\n\r\n" + r + "\r\n")
+	}
+	body.WriteString("--changesetresponse_1--\r\n--batchresponse_1--\r\n")
+	return &http.Response{
+		StatusCode: http.StatusAccepted,
+		Header:     http.Header{headerContentType: []string{"multipart/mixed; boundary=batchresponse_1"}},
+		Body:       io.NopCloser(body),
+		Request:    req,
+	}
+}
+
+func addAction(pk string, rk string) TransactionAction {
+	return TransactionAction{ActionType: TransactionTypeAdd, Entity: []byte(fmt.Sprintf(`{"PartitionKey":%q,"RowKey":%q}`, pk, rk))}
+}
+
+func TestBulkWriter(t *testing.T) {
+	table := &fakeBatchTable{entities: map[string]map[string]interface{}{}}
+	client := newTransportFuncClient(t, table.do)
+	writer := client.NewBulkWriter(&BulkWriterOptions{Concurrency: 2})
+
+	for i := 0; i < 250; i++ {
+		require.NoError(t, writer.Add(ctx, addAction(fmt.Sprintf("pk%d", i%3), fmt.Sprint(i))))
+	}
+	resp, err := writer.Close(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 250, resp.Written)
+	require.Empty(t, resp.Failures)
+	require.Len(t, table.entities, 250)
+	require.LessOrEqual(t, table.maxFlight, 2)
+
+	require.Len(t, table.batches, 3)
+	for _, batch := range table.batches {
+		require.LessOrEqual(t, len(batch), maxTransactionActions)
+		pk := strings.Split(batch[0], "/")[0]
+		for _, key := range batch {
+			require.True(t, strings.HasPrefix(key, pk+"/"), "transaction for %s has an action for %s", pk, key)
+		}
+	}
+
+	require.ErrorIs(t, writer.Add(ctx, addAction("pk", "rk")), errBulkWriterClosed)
+}
+
+func TestBulkWriterChunking(t *testing.T) {
+	table := &fakeBatchTable{entities: map[string]map[string]interface{}{}}
+	client := newTransportFuncClient(t, table.do)
+	writer := client.NewBulkWriter(nil)
+
+	for i := 0; i < 201; i++ {
+		require.NoError(t, writer.Add(ctx, addAction("pk", fmt.Sprint(i))))
+	}
+	large := fmt.Sprintf(`{"PartitionKey":"large","RowKey":"%%d","Value":"%s"}`, strings.Repeat("x", 3*1024*1024))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, writer.Add(ctx, TransactionAction{ActionType: TransactionTypeAdd, Entity: []byte(fmt.Sprintf(large, i))}))
+	}
+	resp, err := writer.Close(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 203, resp.Written)
+
+	sizes := map[string][]int{}
+	for _, batch := range table.batches {
+		pk := strings.Split(batch[0], "/")[0]
+		sizes[pk] = append(sizes[pk], len(batch))
+	}
+	require.Equal(t, []int{100, 100, 1}, sizes["pk"])
+	require.Equal(t, []int{1, 1}, sizes["large"])
+
+	cosmos, err := NewClientWithNoCredential("https://fakeaccount.table.cosmos.azure.com/table", nil)
+	require.NoError(t, err)
+	require.Equal(t, maxCosmosTransactionBytes, cosmos.NewBulkWriter(nil).maxBytes)
+}
+
+func TestBulkWriterSameRowKey(t *testing.T) {
+	table := &fakeBatchTable{entities: map[string]map[string]interface{}{}}
+	client := newTransportFuncClient(t, table.do)
+	writer := client.NewBulkWriter(nil)
+
+	for _, rk := range []string{"a", "b", "a", "c"} {
+		require.NoError(t, writer.Add(ctx, addAction("pk", rk)))
+	}
+	resp, err := writer.Close(ctx)
+	require.NoError(t, err)
+
+	// the second add of "a" is in a later transaction, which the service rejects because "a" exists
+	require.Equal(t, [][]string{{"pk/a", "pk/b"}, {"pk/a", "pk/c"}, {"pk/c"}}, table.batches)
+	require.Equal(t, 3, resp.Written)
+	require.Len(t, resp.Failures, 1)
+	require.Equal(t, "pk", resp.Failures[0].PartitionKey)
+	require.Equal(t, "a", resp.Failures[0].RowKey)
+	var respErr *azcore.ResponseError
+	require.ErrorAs(t, resp.Failures[0].Err, &respErr)
+	require.Equal(t, http.StatusConflict, respErr.StatusCode)
+	require.Equal(t, string(EntityAlreadyExists), respErr.ErrorCode)
+}
+
+func TestBulkWriterThrottling(t *testing.T) {
+	table := &fakeBatchTable{entities: map[string]map[string]interface{}{}, throttle: 2}
+	client := newTransportFuncClient(t, table.do)
+	writer := client.NewBulkWriter(&BulkWriterOptions{RetryDelay: time.Millisecond})
+	require.NoError(t, writer.Add(ctx, addAction("pk", "rk")))
+	resp, err := writer.Close(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Written)
+	require.Empty(t, resp.Failures)
+
+	table = &fakeBatchTable{entities: map[string]map[string]interface{}{}, throttle: 2}
+	client = newTransportFuncClient(t, table.do)
+	writer = client.NewBulkWriter(&BulkWriterOptions{MaxRetries: 1, RetryDelay: time.Millisecond})
+	require.NoError(t, writer.Add(ctx, addAction("pk", "rk")))
+	resp, err = writer.Close(ctx)
+	require.NoError(t, err)
+	require.Zero(t, resp.Written)
+	require.Len(t, resp.Failures, 1)
+	var respErr *azcore.ResponseError
+	require.ErrorAs(t, resp.Failures[0].Err, &respErr)
+	require.Equal(t, http.StatusServiceUnavailable, respErr.StatusCode)
+}
+
+func TestBulkWriterErrors(t *testing.T) {
+	writer := newTransportFuncClient(t, nil).NewBulkWriter(nil)
+	for _, entity := range []string{`{"PartitionKey":"pk"}`, `{"PartitionKey":"pk","RowKey":1}`, `not json`} {
+		require.Error(t, writer.Add(ctx, TransactionAction{ActionType: TransactionTypeAdd, Entity: []byte(entity)}))
+	}
+	resp, err := writer.Close(ctx)
+	require.NoError(t, err)
+	require.Zero(t, resp.Written)
+	require.Empty(t, resp.Failures)
+}
+
+func TestBulkWriterCancelled(t *testing.T) {
+	table := &fakeBatchTable{entities: map[string]map[string]interface{}{}, throttle: 1}
+	client := newTransportFuncClient(t, table.do)
+	writer := client.NewBulkWriter(&BulkWriterOptions{RetryDelay: time.Hour})
+	cancelled, cancel := context.WithCancel(ctx)
+	// the last Add submits the first transaction, which is throttled and then cancelled while waiting to retry
+	for i := 0; i <= maxTransactionActions; i++ {
+		require.NoError(t, writer.Add(cancelled, addAction("pk", fmt.Sprint(i))))
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	resp, err := writer.Close(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Written)
+	require.Len(t, resp.Failures, maxTransactionActions)
+	require.ErrorIs(t, resp.Failures[0].Err, context.Canceled)
+}
@@ -0,0 +1,545 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// EDM type names of entity properties, as they appear in "<property>@odata.type" annotations
+const (
+	edmBinary   = "Edm.Binary"
+	edmBoolean  = "Edm.Boolean"
+	edmDateTime = "Edm.DateTime"
+	edmDouble   = "Edm.Double"
+	edmGUID     = "Edm.Guid"
+	edmInt32    = "Edm.Int32"
+	edmInt64    = "Edm.Int64"
+	edmString   = "Edm.String"
+
+	odataTypeSuffix    = "@odata.type"
+	flattenSeparator   = "_"
+	tagName            = "aztables"
+	etagPropertyName   = "ETag"
+	tagOptionFlatten   = "flatten"
+	tagOptionOmitEmpty = "omitempty"
+)
+
+var (
+	dateTimeType = reflect.TypeOf(EDMDateTime{})
+	etagType     = reflect.TypeOf(azcore.ETag(""))
+	guidType     = reflect.TypeOf(EDMGUID(""))
+	int64Type    = reflect.TypeOf(EDMInt64(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	uuidType     = reflect.TypeOf([16]byte{})
+)
+
+// PropertyTypeError is returned by UnmarshalEntity and the typed entity functions when an entity property can't
+// be stored in the struct field it maps to, for example because the property's EDM type is Edm.Int64 and the field
+// is an int32, or the value of an Edm.Int32 property overflows an int8 field. It's also returned when a struct
+// field has a type that no EDM type represents.
+type PropertyTypeError struct {
+	// Property is the name of the entity property.
+	Property string
+
+	// EDMType is the EDM type of the property, such as "Edm.Int64". It's empty when the field's type
+	// has no EDM type.
+	EDMType string
+
+	// FieldType is the type of the struct field.
+	FieldType reflect.Type
+}
+
+// Error implements the error interface for type PropertyTypeError.
+func (e *PropertyTypeError) Error() string {
+	if e.EDMType == "" {
+		return fmt.Sprintf("aztables: the field of property %s has type %v, which no EDM type represents", e.Property, e.FieldType)
+	}
+	return fmt.Sprintf("aztables: property %s of EDM type %s can't be stored in a field of type %v", e.Property, e.EDMType, e.FieldType)
+}
+
+// entityField maps a struct field to an entity property
+type entityField struct {
+	// index of the field, as for reflect.Value.FieldByIndex. It passes through pointers to flattened structs.
+	index     []int
+	name      string
+	omitEmpty bool
+	typ       reflect.Type
+}
+
+var entityFieldsCache sync.Map // map[reflect.Type][]entityField
+
+// entityFields returns the fields of a struct type mapped to entity properties. Fields are named by their aztables
+// tag, or else their Go name. Like encoding/json, the fields of embedded structs are promoted, and a tag of "-" skips
+// a field. The "flatten" option maps the fields of a nested struct, or pointer to struct, to properties prefixed by
+// the field's name and "_", and "omitempty" omits zero values when marshalling.
+func entityFields(t reflect.Type) ([]entityField, error) {
+	if fields, ok := entityFieldsCache.Load(t); ok {
+		return fields.([]entityField), nil
+	}
+	fields, err := appendEntityFields(nil, t, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, f := range fields {
+		if names[f.name] {
+			return nil, fmt.Errorf("aztables: %v maps more than one field to property %s", t, f.name)
+		}
+		names[f.name] = true
+	}
+	entityFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+func appendEntityFields(fields []entityField, t reflect.Type, index []int, prefix string) ([]entityField, error) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		flatten, omitEmpty := false, false
+		for _, option := range strings.Split(options, ",") {
+			switch option {
+			case tagOptionFlatten:
+				flatten = true
+			case tagOptionOmitEmpty:
+				omitEmpty = true
+			}
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && !isEDMStruct(ft) {
+			var err error
+			if fields, err = appendEntityFields(fields, ft, fieldIndex, prefix); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if flatten {
+			if ft.Kind() != reflect.Struct || isEDMStruct(ft) {
+				return nil, fmt.Errorf("aztables: field %s of %v can't be flattened because it isn't a struct", sf.Name, t)
+			}
+			var err error
+			if fields, err = appendEntityFields(fields, ft, fieldIndex, prefix+name+flattenSeparator); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		fields = append(fields, entityField{index: fieldIndex, name: prefix + name, omitEmpty: omitEmpty, typ: sf.Type})
+	}
+	return fields, nil
+}
+
+// isEDMStruct returns true for struct types which are the value of a single property
+func isEDMStruct(t reflect.Type) bool {
+	return t == timeType || t == dateTimeType
+}
+
+// MarshalEntity returns the JSON encoding of an entity mapped from a struct, or pointer to struct, by the rules of
+// the typed entity functions such as AddEntityAs. Properties have the EDM types of their fields:
+//   - string: Edm.String
+//   - bool: Edm.Boolean
+//   - int8, int16, int32, uint8 and uint16: Edm.Int32
+//   - int, int64, uint, uint32, uint64 and EDMInt64: Edm.Int64
+//   - float32 and float64: Edm.Double
+//   - time.Time and EDMDateTime: Edm.DateTime
+//   - []byte and EDMBinary: Edm.Binary
+//   - EDMGUID, and [16]byte and the UUID types of common packages defined from it: Edm.Guid
+//
+// Nil pointers are omitted. PartitionKey and RowKey fields must be strings. Timestamp and ETag fields aren't
+// marshalled, because the service sets them; an ETag field receives the entity's "odata.etag" when unmarshalling.
+func MarshalEntity(v interface{}) ([]byte, error) {
+	entity, err := marshalEntity(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(entity)
+}
+
+// marshalEntity maps a struct to the properties of an entity
+func marshalEntity(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, errors.New("aztables: can't marshal a nil entity")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("aztables: can't marshal %v as an entity because it isn't a struct", rv.Type())
+	}
+	fields, err := entityFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	entity := map[string]interface{}{}
+	for _, f := range fields {
+		if f.name == timestamp || f.name == etagPropertyName {
+			continue
+		}
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok || (f.omitEmpty && fv.IsZero()) {
+			continue
+		}
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Pointer {
+			continue
+		}
+		if (f.name == partitionKey || f.name == rowKey) && fv.Kind() != reflect.String {
+			return nil, &PropertyTypeError{Property: f.name, EDMType: edmString, FieldType: f.typ}
+		}
+		value, edmType, err := marshalProperty(f.name, fv)
+		if err != nil {
+			return nil, err
+		}
+		entity[f.name] = value
+		if edmType != "" {
+			entity[f.name+odataTypeSuffix] = edmType
+		}
+	}
+	if _, ok := entity[partitionKey]; !ok {
+		return nil, errPartitionKeyRowKeyError
+	}
+	if _, ok := entity[rowKey]; !ok {
+		return nil, errPartitionKeyRowKeyError
+	}
+	return entity, nil
+}
+
+// fieldByIndex returns a nested field, or false when the path to it has a nil pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// marshalProperty returns the JSON value of a property and the EDM type annotating it, which is empty for types
+// the service infers from JSON
+func marshalProperty(name string, v reflect.Value) (interface{}, string, error) {
+	switch v.Type() {
+	case dateTimeType:
+		return time.Time(v.Interface().(EDMDateTime)).UTC().Format(rfc3339), edmDateTime, nil
+	case timeType:
+		return v.Interface().(time.Time).UTC().Format(rfc3339), edmDateTime, nil
+	case guidType:
+		return v.String(), edmGUID, nil
+	case int64Type:
+		return strconv.FormatInt(v.Int(), 10), edmInt64, nil
+	}
+	if isUUIDType(v.Type()) {
+		return formatGUID(v.Convert(uuidType).Interface().([16]byte)), edmGUID, nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return base64.StdEncoding.EncodeToString(v.Bytes()), edmBinary, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), "", nil
+	case reflect.Bool:
+		return v.Bool(), "", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return v.Int(), "", nil
+	case reflect.Uint8, reflect.Uint16:
+		return v.Uint(), "", nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), edmInt64, nil
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, "", fmt.Errorf("aztables: the value %d of property %s overflows Edm.Int64", v.Uint(), name)
+		}
+		return strconv.FormatUint(v.Uint(), 10), edmInt64, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return "NaN", edmDouble, nil
+		case math.IsInf(f, 1):
+			return "Infinity", edmDouble, nil
+		case math.IsInf(f, -1):
+			return "-Infinity", edmDouble, nil
+		}
+		// annotating doubles distinguishes integral values from Edm.Int32
+		return f, edmDouble, nil
+	}
+	return nil, "", &PropertyTypeError{Property: name, FieldType: v.Type()}
+}
+
+// isUUIDType returns true for [16]byte and the types defined from it, such as the UUID types of common packages
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+func formatGUID(b [16]byte) string {
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func parseGUID(s string) ([16]byte, error) {
+	var b [16]byte
+	decoded, err := hex.DecodeString(strings.ReplaceAll(strings.Trim(s, "{}"), "-", ""))
+	if err != nil || len(decoded) != len(b) {
+		return b, fmt.Errorf("aztables: %q isn't a GUID", s)
+	}
+	copy(b[:], decoded)
+	return b, nil
+}
+
+// UnmarshalEntity stores the JSON encoding of an entity, such as GetEntityResponse.Value, in the struct pointed to
+// by v, by the rules described for MarshalEntity. Properties without a field are ignored. It returns a
+// *PropertyTypeError when a property's EDM type doesn't match the type of its field, so that writing the entity back
+// would change the property's type or lose data. Fields marshalled as Edm.Int64 and Edm.Double also accept Edm.Int32
+// properties, which widen without loss.
+func UnmarshalEntity(data []byte, v interface{}) error {
+	var entity map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return err
+	}
+	return unmarshalEntity(entity, v)
+}
+
+func unmarshalEntity(entity map[string]json.RawMessage, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("aztables: can't unmarshal an entity into %T, which isn't a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	fields, err := entityFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		name := f.name
+		if name == etagPropertyName {
+			name = etagOData
+		}
+		raw, ok := entity[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		edmType := ""
+		if annotation, ok := entity[name+odataTypeSuffix]; ok {
+			if err := json.Unmarshal(annotation, &edmType); err != nil {
+				return err
+			}
+		}
+		switch {
+		case edmType != "":
+		case f.name == timestamp:
+			edmType = edmDateTime
+		default:
+			edmType = inferEDMType(raw)
+		}
+		fv := allocField(rv, f.index)
+		for fv.Kind() == reflect.Pointer {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+		if err := unmarshalProperty(f.name, raw, edmType, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocField returns a nested field, allocating the flattened structs on the path to it
+func allocField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// inferEDMType returns the EDM type of a property the service didn't annotate
+func inferEDMType(raw json.RawMessage) string {
+	switch raw[0] {
+	case '"':
+		return edmString
+	case 't', 'f':
+		return edmBoolean
+	}
+	if strings.ContainsAny(string(raw), ".eE") {
+		return edmDouble
+	}
+	return edmInt32
+}
+
+func unmarshalProperty(name string, raw json.RawMessage, edmType string, v reflect.Value) error {
+	mismatch := &PropertyTypeError{Property: name, EDMType: edmType, FieldType: v.Type()}
+	var s string
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+	}
+
+	switch v.Type() {
+	case dateTimeType, timeType:
+		if edmType != edmDateTime {
+			return mismatch
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		if v.Type() == dateTimeType {
+			v.Set(reflect.ValueOf(EDMDateTime(t)))
+		} else {
+			v.Set(reflect.ValueOf(t))
+		}
+		return nil
+	case guidType:
+		if edmType != edmGUID {
+			return mismatch
+		}
+		v.SetString(s)
+		return nil
+	case etagType:
+		v.SetString(s)
+		return nil
+	}
+	if isUUIDType(v.Type()) {
+		if edmType != edmGUID {
+			return mismatch
+		}
+		b, err := parseGUID(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(b).Convert(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		if edmType != edmBinary {
+			return mismatch
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if edmType != edmString {
+			return mismatch
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		if edmType != edmBoolean {
+			return mismatch
+		}
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if edmType != edmInt32 && (edmType != edmInt64 || !isInt64Kind(v.Kind())) {
+			return mismatch
+		}
+		i, err := strconv.ParseInt(strings.Trim(string(raw), `"`), 10, 64)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return mismatch
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if edmType != edmInt32 && (edmType != edmInt64 || !isInt64Kind(v.Kind())) {
+			return mismatch
+		}
+		i, err := strconv.ParseInt(strings.Trim(string(raw), `"`), 10, 64)
+		if err != nil {
+			return err
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return mismatch
+		}
+		v.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		// integral doubles written without an annotation have type Edm.Int32
+		if edmType != edmDouble && edmType != edmInt32 {
+			return mismatch
+		}
+		var f float64
+		switch s {
+		case "NaN":
+			f = math.NaN()
+		case "Infinity":
+			f = math.Inf(1)
+		case "-Infinity":
+			f = math.Inf(-1)
+		default:
+			var err error
+			if f, err = strconv.ParseFloat(strings.Trim(string(raw), `"`), 64); err != nil {
+				return err
+			}
+		}
+		if v.OverflowFloat(f) {
+			return mismatch
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	return &PropertyTypeError{Property: name, FieldType: v.Type()}
+}
+
+// isInt64Kind returns true for the kinds of integers marshalled as Edm.Int64. Edm.Int64 properties can't be stored in
+// narrower fields, which would change the property's type when the entity is written back.
+func isInt64Kind(k reflect.Kind) bool {
+	return k == reflect.Int || k == reflect.Int64 || k == reflect.Uint || k == reflect.Uint32 || k == reflect.Uint64
+}
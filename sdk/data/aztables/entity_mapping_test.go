@@ -0,0 +1,287 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+type mappedAddress struct {
+	Street string
+	City   string `aztables:"Town"`
+}
+
+// mappedUUID is defined like the UUID types of common packages
+type mappedUUID [16]byte
+
+type mappedEntity struct {
+	Entity
+	ETag     azcore.ETag
+	Name     string
+	Count    int32
+	Big      int64
+	Small    uint8
+	Ratio    float64
+	Active   bool
+	When     time.Time
+	Data     []byte
+	ID       EDMGUID
+	UUID     [16]byte
+	Named    mappedUUID
+	Optional *string
+	Renamed  string         `aztables:"OtherName"`
+	Skipped  string         `aztables:"-"`
+	Empty    string         `aztables:",omitempty"`
+	Home     mappedAddress  `aztables:",flatten"`
+	Work     *mappedAddress `aztables:"Office,flatten"`
+	ignored  string
+}
+
+func newMappedEntity() mappedEntity {
+	optional := "optional"
+	return mappedEntity{
+		Entity:   Entity{PartitionKey: "pk", RowKey: "rk"},
+		Name:     "name",
+		Count:    42,
+		Big:      math.MaxInt64,
+		Small:    7,
+		Ratio:    2,
+		Active:   true,
+		When:     time.Date(2022, 6, 16, 1, 2, 3, 400, time.UTC),
+		Data:     []byte{1, 2, 3},
+		ID:       "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		UUID:     [16]byte{0x7c, 0x9e, 0x66, 0x79, 0x74, 0x25, 0x40, 0xde, 0x94, 0x4b, 0xe0, 0x7f, 0xc1, 0xf9, 0x0a, 0xe7},
+		Named:    mappedUUID{0x7c, 0x9e, 0x66, 0x79, 0x74, 0x25, 0x40, 0xde, 0x94, 0x4b, 0xe0, 0x7f, 0xc1, 0xf9, 0x0a, 0xe7},
+		Optional: &optional,
+		Renamed:  "renamed",
+		Skipped:  "skipped",
+		Home:     mappedAddress{Street: "1 Main St", City: "Redmond"},
+		Work:     &mappedAddress{Street: "2 Side St", City: "Seattle"},
+		ignored:  "ignored",
+	}
+}
+
+func TestMarshalEntity(t *testing.T) {
+	marshalled, err := MarshalEntity(newMappedEntity())
+	require.NoError(t, err)
+
+	var properties map[string]interface{}
+	require.NoError(t, json.Unmarshal(marshalled, &properties))
+	expected := map[string]interface{}{
+		"PartitionKey":     "pk",
+		"RowKey":           "rk",
+		"Name":             "name",
+		"Count":            float64(42),
+		"Big":              "9223372036854775807",
+		"Big@odata.type":   edmInt64,
+		"Small":            float64(7),
+		"Ratio":            float64(2),
+		"Ratio@odata.type": edmDouble,
+		"Active":           true,
+		"When":             "2022-06-16T01:02:03.0000004Z",
+		"When@odata.type":  edmDateTime,
+		"Data":             "AQID",
+		"Data@odata.type":  edmBinary,
+		"ID":               "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"ID@odata.type":    edmGUID,
+		"UUID":             "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"UUID@odata.type":  edmGUID,
+		"Named":            "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+		"Named@odata.type": edmGUID,
+		"Optional":         "optional",
+		"OtherName":        "renamed",
+		"Home_Street":      "1 Main St",
+		"Home_Town":        "Redmond",
+		"Office_Street":    "2 Side St",
+		"Office_Town":      "Seattle",
+	}
+	require.Equal(t, expected, properties)
+}
+
+func TestMarshalEntityErrors(t *testing.T) {
+	_, err := MarshalEntity(struct{ PartitionKey string }{PartitionKey: "pk"})
+	require.ErrorIs(t, err, errPartitionKeyRowKeyError)
+
+	_, err = MarshalEntity(struct {
+		PartitionKey, RowKey string
+		Map                  map[string]string
+	}{PartitionKey: "pk", RowKey: "rk", Map: map[string]string{}})
+	var typeErr *PropertyTypeError
+	require.True(t, errors.As(err, &typeErr))
+	require.Equal(t, "Map", typeErr.Property)
+	require.Empty(t, typeErr.EDMType)
+
+	_, err = MarshalEntity(struct {
+		PartitionKey, RowKey string
+		Huge                 uint64
+	}{PartitionKey: "pk", RowKey: "rk", Huge: math.MaxUint64})
+	require.Error(t, err)
+
+	_, err = MarshalEntity(struct {
+		PartitionKey, RowKey string
+		Flat                 string `aztables:",flatten"`
+	}{})
+	require.Error(t, err)
+
+	_, err = MarshalEntity("entity")
+	require.Error(t, err)
+}
+
+func TestUnmarshalEntityRoundTrip(t *testing.T) {
+	entity := newMappedEntity()
+	marshalled, err := MarshalEntity(entity)
+	require.NoError(t, err)
+
+	// the service adds the timestamp and etag
+	var properties map[string]interface{}
+	require.NoError(t, json.Unmarshal(marshalled, &properties))
+	properties["Timestamp"] = "2022-06-16T01:02:03.1234567Z"
+	properties["Timestamp@odata.type"] = edmDateTime
+	properties[etagOData] = `W/"datetime'2022-06-16T01%3A02%3A03.1234567Z'"`
+	properties["Unknown"] = "ignored"
+	marshalled, err = json.Marshal(properties)
+	require.NoError(t, err)
+
+	var received mappedEntity
+	require.NoError(t, UnmarshalEntity(marshalled, &received))
+	entity.Skipped, entity.ignored = "", ""
+	entity.Timestamp = EDMDateTime(time.Date(2022, 6, 16, 1, 2, 3, 123456700, time.UTC))
+	entity.ETag = `W/"datetime'2022-06-16T01%3A02%3A03.1234567Z'"`
+	require.Equal(t, entity, received)
+}
+
+func TestUnmarshalEntityTypeMismatch(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		entity  string
+		edmType string
+	}{
+		{name: "Int64 into int32", entity: `{"Count":"5","Count@odata.type":"Edm.Int64"}`, edmType: edmInt64},
+		{name: "Int32 overflows uint8", entity: `{"Small":300}`, edmType: edmInt32},
+		{name: "negative into uint8", entity: `{"Small":-1}`, edmType: edmInt32},
+		{name: "String into int32", entity: `{"Count":"5"}`, edmType: edmString},
+		{name: "Double into int32", entity: `{"Count":1.5}`, edmType: edmDouble},
+		{name: "String into time", entity: `{"When":"2022-06-16T01:02:03Z"}`, edmType: edmString},
+		{name: "String into GUID", entity: `{"ID":"7c9e6679-7425-40de-944b-e07fc1f90ae7"}`, edmType: edmString},
+		{name: "Guid into string", entity: `{"Name":"7c9e6679-7425-40de-944b-e07fc1f90ae7","Name@odata.type":"Edm.Guid"}`, edmType: edmGUID},
+		{name: "Boolean into string", entity: `{"Name":true}`, edmType: edmBoolean},
+		{name: "String into binary", entity: `{"Data":"AQID"}`, edmType: edmString},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var received mappedEntity
+			err := UnmarshalEntity([]byte(test.entity), &received)
+			var typeErr *PropertyTypeError
+			require.True(t, errors.As(err, &typeErr), "expected a *PropertyTypeError, got %v", err)
+			require.Equal(t, test.edmType, typeErr.EDMType)
+		})
+	}
+
+	var received mappedEntity
+	require.NoError(t, UnmarshalEntity([]byte(`{"Big":5,"Ratio":3,"Count":"7","Count@odata.type":"Edm.Int32"}`), &received))
+	require.Equal(t, int64(5), received.Big)
+	require.Equal(t, float64(3), received.Ratio)
+	require.Equal(t, int32(7), received.Count)
+
+	require.Error(t, UnmarshalEntity([]byte(`{}`), received))
+}
+
+func TestUnmarshalEntityDoubles(t *testing.T) {
+	var received struct {
+		A, B, C float64
+		D       float32
+	}
+	require.NoError(t, UnmarshalEntity([]byte(`{"A":"NaN","A@odata.type":"Edm.Double","B":"-Infinity","B@odata.type":"Edm.Double","C":1.5,"D":2.5}`), &received))
+	require.True(t, math.IsNaN(received.A))
+	require.True(t, math.IsInf(received.B, -1))
+	require.Equal(t, 1.5, received.C)
+	require.Equal(t, float32(2.5), received.D)
+}
+
+// transportFunc is a policy.Transporter responding to requests with a function
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTransportFuncClient(t *testing.T, f transportFunc) *Client {
+	client, err := NewClientWithNoCredential("https://fakeaccount.table.core.windows.net/table", &ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: f, Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func newJSONResponse(req *http.Request, statusCode int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json;odata=minimalmetadata")
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}
+}
+
+func TestTypedEntities(t *testing.T) {
+	var added map[string]interface{}
+	client := newTransportFuncClient(t, func(req *http.Request) (*http.Response, error) {
+		switch req.Method {
+		case http.MethodPost:
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&added))
+			return newJSONResponse(req, http.StatusNoContent, "", http.Header{"Etag": []string{"added"}}), nil
+		case http.MethodGet:
+			if req.URL.Query().Get("NextPartitionKey") == "" && req.URL.Path == "/table()" {
+				return newJSONResponse(req, http.StatusOK, `{"value":[{"PartitionKey":"pk","RowKey":"1","Count":1}]}`, http.Header{
+					"X-Ms-Continuation-Nextpartitionkey": []string{"pk"},
+					"X-Ms-Continuation-Nextrowkey":       []string{"2"},
+				}), nil
+			}
+			if req.URL.Path == "/table()" {
+				return newJSONResponse(req, http.StatusOK, `{"value":[{"PartitionKey":"pk","RowKey":"2","Count":"2","Count@odata.type":"Edm.Int64"}]}`, nil), nil
+			}
+			return newJSONResponse(req, http.StatusOK, `{"PartitionKey":"pk","RowKey":"rk","Count":3}`, http.Header{"Etag": []string{"etag"}}), nil
+		}
+		return nil, errors.New("unexpected request")
+	})
+
+	type counter struct {
+		PartitionKey string
+		RowKey       string
+		ETag         string
+		Count        int32
+	}
+	resp, err := AddEntityAs(ctx, client, counter{PartitionKey: "pk", RowKey: "rk", ETag: "ignored", Count: 3}, nil)
+	require.NoError(t, err)
+	require.Equal(t, azcore.ETag("added"), resp.ETag)
+	require.Equal(t, map[string]interface{}{"PartitionKey": "pk", "RowKey": "rk", "Count": float64(3)}, added)
+
+	got, err := GetEntityAs[counter](ctx, client, "pk", "rk", nil)
+	require.NoError(t, err)
+	require.Equal(t, counter{PartitionKey: "pk", RowKey: "rk", ETag: "etag", Count: 3}, got.Value)
+
+	pager := NewListEntitiesAsPager[counter](client, nil)
+	require.True(t, pager.More())
+	page, err := pager.NextPage(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []counter{{PartitionKey: "pk", RowKey: "1", Count: 1}}, page.Entities)
+	require.True(t, pager.More())
+	_, err = pager.NextPage(ctx)
+	var typeErr *PropertyTypeError
+	require.True(t, errors.As(err, &typeErr), "expected a *PropertyTypeError, got %v", err)
+	require.Equal(t, "Count", typeErr.Property)
+}
@@ -0,0 +1,310 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Filter is an OData filter expression for ListEntitiesOptions.Filter and ListTablesOptions.Filter, built by
+// comparing properties to values with Eq, Ne, Gt, Ge, Lt and Le, and combining filters with And, Or and Not.
+// Values are formatted as literals of their EDM types, as described for FormatFilter, so they can't change the
+// meaning of the expression. Call Build to get the expression.
+type Filter struct {
+	expr string
+	// compound is true for filters combining other filters, which are parenthesized when combined again
+	compound bool
+	err      error
+}
+
+// Build returns the filter expression, or the first error building the filter, such as a value of a type no
+// EDM type represents.
+func (f Filter) Build() (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if f.expr == "" {
+		return "", errors.New("aztables: the filter is empty")
+	}
+	return f.expr, nil
+}
+
+// Eq returns a filter selecting entities whose property equals value.
+func Eq(property string, value interface{}) Filter {
+	return compare(property, "eq", value)
+}
+
+// Ne returns a filter selecting entities whose property doesn't equal value.
+func Ne(property string, value interface{}) Filter {
+	return compare(property, "ne", value)
+}
+
+// Gt returns a filter selecting entities whose property is greater than value.
+func Gt(property string, value interface{}) Filter {
+	return compare(property, "gt", value)
+}
+
+// Ge returns a filter selecting entities whose property is greater than or equal to value.
+func Ge(property string, value interface{}) Filter {
+	return compare(property, "ge", value)
+}
+
+// Lt returns a filter selecting entities whose property is less than value.
+func Lt(property string, value interface{}) Filter {
+	return compare(property, "lt", value)
+}
+
+// Le returns a filter selecting entities whose property is less than or equal to value.
+func Le(property string, value interface{}) Filter {
+	return compare(property, "le", value)
+}
+
+func compare(property string, operator string, value interface{}) Filter {
+	if !isPropertyName(property) {
+		return Filter{err: fmt.Errorf("aztables: %q isn't a valid property name", property)}
+	}
+	literal, err := formatFilterValue(value)
+	if err != nil {
+		return Filter{err: err}
+	}
+	return Filter{expr: property + " " + operator + " " + literal}
+}
+
+// isPropertyName returns true when name is an identifier, which can't inject operators into a filter
+func isPropertyName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// And returns a filter selecting entities selected by all filters.
+func And(filters ...Filter) Filter {
+	return combine("and", filters)
+}
+
+// Or returns a filter selecting entities selected by any of filters.
+func Or(filters ...Filter) Filter {
+	return combine("or", filters)
+}
+
+func combine(operator string, filters []Filter) Filter {
+	if len(filters) == 0 {
+		return Filter{err: fmt.Errorf("aztables: %s requires at least one filter", operator)}
+	}
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	operands := make([]string, len(filters))
+	for i, f := range filters {
+		if f.err != nil {
+			return f
+		}
+		operands[i] = f.operand()
+	}
+	return Filter{expr: strings.Join(operands, " "+operator+" "), compound: true}
+}
+
+// Not returns a filter selecting entities not selected by f.
+func Not(f Filter) Filter {
+	if f.err != nil {
+		return f
+	}
+	return Filter{expr: "not (" + f.expr + ")", compound: true}
+}
+
+// operand returns the expression of a filter combined with others
+func (f Filter) operand() string {
+	if f.compound {
+		return "(" + f.expr + ")"
+	}
+	return f.expr
+}
+
+// PartitionKeyStartsWith returns a filter selecting entities whose PartitionKey starts with prefix. The service
+// doesn't support string functions, so the filter selects the range of keys from prefix to the next prefix.
+func PartitionKeyStartsWith(prefix string) Filter {
+	return keyStartsWith(partitionKey, prefix)
+}
+
+// RowKeyStartsWith returns a filter selecting entities whose RowKey starts with prefix. The service doesn't support
+// string functions, so the filter selects the range of keys from prefix to the next prefix.
+func RowKeyStartsWith(prefix string) Filter {
+	return keyStartsWith(rowKey, prefix)
+}
+
+// surrogateMin and surrogateMax are the first and last UTF-16 surrogates.
+const (
+	surrogateMin = 0xD800
+	surrogateMax = 0xDFFF
+)
+
+func keyStartsWith(property string, prefix string) Filter {
+	lower := Ge(property, prefix)
+	// the upper bound increments the last character which can be incremented, skipping the surrogates, which
+	// aren't valid characters
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] < utf8.MaxRune {
+			runes[i]++
+			if runes[i] == surrogateMin {
+				runes[i] = surrogateMax + 1
+			}
+			return And(lower, Lt(property, string(runes[:i+1])))
+		}
+	}
+	return lower
+}
+
+// FormatFilter returns a filter expression with the @name parameters of template replaced by the literals of
+// params["name"], formatted by their EDM types like the values of Eq. Parameters in string literals aren't
+// replaced. The parameter syntax is compatible with the Azure Tables client libraries for other languages:
+//
+//	filter, err := aztables.FormatFilter("PartitionKey eq @pk and Price lt @price", map[string]interface{}{
+//		"pk":    "O'Connor",
+//		"price": 5.5,
+//	})
+//
+// Values have the EDM types described for MarshalEntity, except int and uint values: strings are quoted and
+// escaped, int and uint values fitting in 32 bits are Edm.Int32 literals, as in the other client libraries, while
+// int64, EDMInt64 and larger values have an "L" suffix. time.Time and EDMDateTime values are datetime'...'
+// literals, EDMGUID and UUID values are guid'...' literals, and []byte values are X'...' literals.
+//
+// The service doesn't match Edm.Int32 literals against Edm.Int64 properties, nor the reverse. MarshalEntity stores
+// int fields as Edm.Int64, so compare such properties to int64 or EDMInt64 values.
+func FormatFilter(template string, params map[string]interface{}) (string, error) {
+	var b strings.Builder
+	inString := false
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '\'':
+			// the escaped quotes of string literals toggle inString twice
+			inString = !inString
+		case c == '@' && !inString:
+			end := i + 1
+			for end < len(template) && isParameterByte(template[end], end == i+1) {
+				end++
+			}
+			name := template[i+1 : end]
+			if name == "" {
+				return "", fmt.Errorf("aztables: the filter has an @ without a parameter name at offset %d", i)
+			}
+			value, ok := params[name]
+			if !ok {
+				if value, ok = params["@"+name]; !ok {
+					return "", fmt.Errorf("aztables: the filter's parameter @%s has no value", name)
+				}
+			}
+			literal, err := formatFilterValue(value)
+			if err != nil {
+				return "", fmt.Errorf("aztables: parameter @%s: %w", name, err)
+			}
+			b.WriteString(literal)
+			i = end - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if inString {
+		return "", errors.New("aztables: the filter has an unterminated string literal")
+	}
+	return b.String(), nil
+}
+
+func isParameterByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// formatFilterValue returns the OData literal of a value
+func formatFilterValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", errors.New("aztables: filters can't compare properties to null")
+	case EDMDateTime:
+		return "datetime'" + time.Time(v).UTC().Format(rfc3339) + "'", nil
+	case time.Time:
+		return "datetime'" + v.UTC().Format(rfc3339) + "'", nil
+	case EDMGUID:
+		return "guid'" + escapeFilterString(string(v)) + "'", nil
+	case [16]byte:
+		return "guid'" + formatGUID(v) + "'", nil
+	case EDMInt64:
+		return strconv.FormatInt(int64(v), 10) + "L", nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "", errors.New("aztables: filters can't compare properties to null")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type() != reflect.TypeOf(value) {
+		return formatFilterValue(rv.Interface())
+	}
+	if isUUIDType(rv.Type()) {
+		return formatFilterValue(rv.Convert(uuidType).Interface())
+	}
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+		return "X'" + hex.EncodeToString(rv.Bytes()) + "'", nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return "'" + escapeFilterString(rv.String()) + "'", nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint8, reflect.Uint16:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Int:
+		// untyped integer constants are ints, and properties holding small integers are usually stored as
+		// Edm.Int32, which the service doesn't compare to Edm.Int64 literals
+		if i := rv.Int(); i >= math.MinInt32 && i <= math.MaxInt32 {
+			return strconv.FormatInt(i, 10), nil
+		}
+		return strconv.FormatInt(rv.Int(), 10) + "L", nil
+	case reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10) + "L", nil
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		if rv.Kind() == reflect.Uint && rv.Uint() <= math.MaxInt32 {
+			return strconv.FormatUint(rv.Uint(), 10), nil
+		}
+		if rv.Uint() > math.MaxInt64 {
+			return "", fmt.Errorf("aztables: the value %d overflows Edm.Int64", rv.Uint())
+		}
+		return strconv.FormatUint(rv.Uint(), 10) + "L", nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("aztables: filters can't compare properties to %v", f)
+		}
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			// distinguishes the Edm.Double literal from Edm.Int32
+			s += ".0"
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("aztables: no EDM type represents values of type %T", value)
+}
+
+func escapeFilterString(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
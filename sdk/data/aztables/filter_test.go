@@ -0,0 +1,139 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterValues(t *testing.T) {
+	when := time.Date(2008, 7, 10, 0, 0, 0, 0, time.FixedZone("PDT", -7*60*60))
+	name := "O'Connor"
+	for _, test := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{"O'Connor", "Name eq 'O''Connor'"},
+		{&name, "Name eq 'O''Connor'"},
+		{true, "Name eq true"},
+		{int32(-5), "Name eq -5"},
+		{uint8(5), "Name eq 5"},
+		{5, "Name eq 5"},
+		{-5, "Name eq -5"},
+		{math.MaxInt32 + 1, "Name eq 2147483648L"},
+		{math.MinInt32 - 1, "Name eq -2147483649L"},
+		{uint(5), "Name eq 5"},
+		{uint(math.MaxInt32 + 1), "Name eq 2147483648L"},
+		{uint32(5), "Name eq 5L"},
+		{int64(5), "Name eq 5L"},
+		{int64(12345678901234), "Name eq 12345678901234L"},
+		{EDMInt64(7), "Name eq 7L"},
+		{2.5, "Name eq 2.5"},
+		{float32(3), "Name eq 3.0"},
+		{when, "Name eq datetime'2008-07-10T07:00:00Z'"},
+		{EDMDateTime(when), "Name eq datetime'2008-07-10T07:00:00Z'"},
+		{EDMGUID("a455c695-df98-5678-aaaa-81d3367e5a34"), "Name eq guid'a455c695-df98-5678-aaaa-81d3367e5a34'"},
+		{mappedUUID{0xa4, 0x55, 0xc6, 0x95, 0xdf, 0x98, 0x56, 0x78, 0xaa, 0xaa, 0x81, 0xd3, 0x36, 0x7e, 0x5a, 0x34}, "Name eq guid'a455c695-df98-5678-aaaa-81d3367e5a34'"},
+		{[]byte{0x0a, 0xff}, "Name eq X'0aff'"},
+		{EDMBinary{0x01}, "Name eq X'01'"},
+	} {
+		filter, err := Eq("Name", test.value).Build()
+		require.NoError(t, err)
+		require.Equal(t, test.expected, filter)
+	}
+
+	for _, value := range []interface{}{nil, (*string)(nil), map[string]string{}, uint64(1 << 63)} {
+		_, err := Eq("Name", value).Build()
+		require.Error(t, err, "expected an error for %v", value)
+	}
+}
+
+func TestFilterOperators(t *testing.T) {
+	filter, err := And(
+		Eq("PartitionKey", "pk"),
+		Or(Gt("Price", 5.5), Le("Count", int32(3))),
+		Not(Ne("Active", true)),
+	).Build()
+	require.NoError(t, err)
+	require.Equal(t, "PartitionKey eq 'pk' and (Price gt 5.5 or Count le 3) and (not (Active ne true))", filter)
+
+	filter, err = Or(Ge("A", int32(1)), Lt("B", int32(2))).Build()
+	require.NoError(t, err)
+	require.Equal(t, "A ge 1 or B lt 2", filter)
+
+	filter, err = And(Eq("A", int32(1))).Build()
+	require.NoError(t, err)
+	require.Equal(t, "A eq 1", filter)
+
+	for _, f := range []Filter{
+		{},
+		And(),
+		Eq("Name eq 'x' or Name", "y"),
+		Eq("", "y"),
+		And(Eq("A", int32(1)), Eq("B", nil)),
+		Not(Eq("B", nil)),
+	} {
+		_, err := f.Build()
+		require.Error(t, err)
+	}
+}
+
+func TestFilterKeyStartsWith(t *testing.T) {
+	filter, err := PartitionKeyStartsWith("ab").Build()
+	require.NoError(t, err)
+	require.Equal(t, "PartitionKey ge 'ab' and PartitionKey lt 'ac'", filter)
+
+	filter, err = RowKeyStartsWith("it'z").Build()
+	require.NoError(t, err)
+	require.Equal(t, "RowKey ge 'it''z' and RowKey lt 'it''{'", filter)
+
+	filter, err = RowKeyStartsWith("a\U0010FFFF").Build()
+	require.NoError(t, err)
+	require.Equal(t, "RowKey ge 'a\U0010FFFF' and RowKey lt 'b'", filter)
+
+	filter, err = RowKeyStartsWith("a\uD7FF").Build()
+	require.NoError(t, err)
+	require.Equal(t, "RowKey ge 'a\uD7FF' and RowKey lt 'a\uE000'", filter)
+
+	filter, err = PartitionKeyStartsWith("").Build()
+	require.NoError(t, err)
+	require.Equal(t, "PartitionKey ge ''", filter)
+}
+
+func TestFormatFilter(t *testing.T) {
+	filter, err := FormatFilter("PartitionKey eq @pk and (Price lt @price or Name eq 'user@example.com') and When gt @when", map[string]interface{}{
+		"pk":     "O'Connor",
+		"price":  5.5,
+		"@when":  time.Date(2021, 8, 21, 1, 1, 0, 0, time.UTC),
+		"unused": 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "PartitionKey eq 'O''Connor' and (Price lt 5.5 or Name eq 'user@example.com') and When gt datetime'2021-08-21T01:01:00Z'", filter)
+
+	filter, err = FormatFilter("Name eq 'it''s @literal' and Count eq @count1", map[string]interface{}{"count1": 5})
+	require.NoError(t, err)
+	require.Equal(t, "Name eq 'it''s @literal' and Count eq 5", filter)
+
+	filter, err = FormatFilter("Count eq @small and Total eq @large and Big eq @big", map[string]interface{}{
+		"small": 5,
+		"large": int64(5),
+		"big":   EDMInt64(5),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Count eq 5 and Total eq 5L and Big eq 5L", filter)
+
+	for _, template := range []string{
+		"Name eq @missing",
+		"Name eq @",
+		"Name eq 'unterminated",
+		"Name eq @bad",
+	} {
+		_, err := FormatFilter(template, map[string]interface{}{"bad": struct{}{}})
+		require.Error(t, err, "expected an error for %q", template)
+	}
+}
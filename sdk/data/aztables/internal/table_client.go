@@ -603,7 +603,7 @@ func (client *TableClient) queryHandleResponse(resp *http.Response) (TableClient
 // options - TableClientQueryEntitiesOptions contains the optional parameters for the TableClient.QueryEntities method.
 // QueryOptions - QueryOptions contains a group of parameters for the TableClient.Query method.
 func (client *TableClient) QueryEntities(ctx context.Context, dataServiceVersion Enum1, table string, options *TableClientQueryEntitiesOptions, queryOptions *QueryOptions) (TableClientQueryEntitiesResponse, error) {
-	req, err := client.queryEntitiesCreateRequest(ctx, dataServiceVersion, table, options, queryOptions)
+	req, err := client.QueryEntitiesCreateRequest(ctx, dataServiceVersion, table, options, queryOptions)
 	if err != nil {
 		return TableClientQueryEntitiesResponse{}, err
 	}
@@ -618,7 +618,7 @@ func (client *TableClient) QueryEntities(ctx context.Context, dataServiceVersion
 }
 
 // queryEntitiesCreateRequest creates the QueryEntities request.
-func (client *TableClient) queryEntitiesCreateRequest(ctx context.Context, dataServiceVersion Enum1, table string, options *TableClientQueryEntitiesOptions, queryOptions *QueryOptions) (*policy.Request, error) {
+func (client *TableClient) QueryEntitiesCreateRequest(ctx context.Context, dataServiceVersion Enum1, table string, options *TableClientQueryEntitiesOptions, queryOptions *QueryOptions) (*policy.Request, error) {
 	urlPath := "/{table}()"
 	if table == "" {
 		return nil, errors.New("parameter table cannot be empty")
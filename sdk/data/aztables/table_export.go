@@ -0,0 +1,249 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	generated "github.com/Azure/azure-sdk-for-go/sdk/data/aztables/internal"
+)
+
+// maxImportLineBytes is the maximum size of a line read by ImportEntities. It's larger than the largest entity the
+// service accepts, to allow for escaping in the JSON.
+const maxImportLineBytes = 8 * 1024 * 1024
+
+// ExportCheckpoint is the position of an export after a page of entities.
+type ExportCheckpoint struct {
+	// Exported is the number of entities written so far.
+	Exported int
+
+	// NextPartitionKey and NextRowKey are the continuation to resume the export from. They're nil after the last page.
+	NextPartitionKey *string
+	NextRowKey       *string
+}
+
+// ExportEntitiesOptions contains optional parameters for Client.ExportEntities
+type ExportEntitiesOptions struct {
+	// OData filter expression selecting the entities to export. By default, every entity is exported.
+	Filter *string
+
+	// The NextPartitionKey to resume exporting from
+	NextPartitionKey *string
+
+	// The NextRowKey to resume exporting from
+	NextRowKey *string
+
+	// Checkpoint is called after each page of entities is written. Flush the writer before saving the checkpoint,
+	// to resume the export from the checkpoint after a failure. Returning an error stops the export.
+	Checkpoint func(ExportCheckpoint) error
+}
+
+// ExportEntitiesResponse contains response fields for Client.ExportEntities
+type ExportEntitiesResponse struct {
+	// Exported is the number of entities written.
+	Exported int
+}
+
+// ExportEntities writes the entities of the table, or those selected by options.Filter, to w as JSON Lines: one JSON
+// object per line, with the @odata.type annotations of the entity's properties. Double properties are annotated
+// even when the service omits the annotation, so that whole numbers stay doubles when they're imported. The odata.etag
+// and other odata properties are omitted. Specify nil for options if you want to use the default options.
+func (t *Client) ExportEntities(ctx context.Context, w io.Writer, options *ExportEntitiesOptions) (ExportEntitiesResponse, error) {
+	if options == nil {
+		options = &ExportEntitiesOptions{}
+	}
+	checkpoint := ExportCheckpoint{NextPartitionKey: options.NextPartitionKey, NextRowKey: options.NextRowKey}
+	for {
+		page, err := t.queryRawEntities(ctx, options.Filter, checkpoint.NextPartitionKey, checkpoint.NextRowKey)
+		if err != nil {
+			return ExportEntitiesResponse{}, err
+		}
+		for _, entity := range page.Value {
+			line, err := exportEntity(entity)
+			if err != nil {
+				return ExportEntitiesResponse{}, err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return ExportEntitiesResponse{}, err
+			}
+		}
+		checkpoint.Exported += len(page.Value)
+		checkpoint.NextPartitionKey, checkpoint.NextRowKey = page.nextPartitionKey, page.nextRowKey
+		if options.Checkpoint != nil {
+			if err := options.Checkpoint(checkpoint); err != nil {
+				return ExportEntitiesResponse{}, err
+			}
+		}
+		if checkpoint.NextPartitionKey == nil && checkpoint.NextRowKey == nil {
+			return ExportEntitiesResponse{Exported: checkpoint.Exported}, nil
+		}
+	}
+}
+
+// rawEntitiesPage is a page of entities with their properties' JSON as the service returned it.
+type rawEntitiesPage struct {
+	Value []map[string]json.RawMessage `json:"value"`
+
+	nextPartitionKey *string
+	nextRowKey       *string
+}
+
+// queryRawEntities queries a page of entities. Unlike Client.NewListEntitiesPager it doesn't decode property values,
+// which would make whole number doubles indistinguishable from integers.
+func (t *Client) queryRawEntities(ctx context.Context, filter *string, nextPartitionKey *string, nextRowKey *string) (rawEntitiesPage, error) {
+	req, err := t.client.QueryEntitiesCreateRequest(ctx, generated.Enum1Three0, t.name, &generated.TableClientQueryEntitiesOptions{
+		NextPartitionKey: nextPartitionKey,
+		NextRowKey:       nextRowKey,
+	}, &generated.QueryOptions{
+		Filter: filter,
+		Format: to.Ptr(generated.ODataMetadataFormatApplicationJSONODataMinimalmetadata),
+	})
+	if err != nil {
+		return rawEntitiesPage{}, err
+	}
+	resp, err := t.con.Pipeline().Do(req)
+	if err != nil {
+		return rawEntitiesPage{}, err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return rawEntitiesPage{}, runtime.NewResponseError(resp)
+	}
+	var page rawEntitiesPage
+	if err := runtime.UnmarshalAsJSON(resp, &page); err != nil {
+		return rawEntitiesPage{}, err
+	}
+	if val := resp.Header.Get("x-ms-continuation-NextPartitionKey"); val != "" {
+		page.nextPartitionKey = &val
+	}
+	if val := resp.Header.Get("x-ms-continuation-NextRowKey"); val != "" {
+		page.nextRowKey = &val
+	}
+	return page, nil
+}
+
+// exportEntity returns the JSON of an entity without its odata properties, and with Edm.Double annotations for
+// unannotated numbers that aren't Edm.Int32 values.
+func exportEntity(entity map[string]json.RawMessage) ([]byte, error) {
+	exported := make(map[string]json.RawMessage, len(entity))
+	for name, value := range entity {
+		if strings.HasPrefix(name, "odata.") {
+			continue
+		}
+		exported[name] = value
+		if strings.HasSuffix(name, odataTypeSuffix) {
+			continue
+		}
+		if _, ok := entity[name+odataTypeSuffix]; !ok && isDoubleLiteral(value) {
+			exported[name+odataTypeSuffix] = json.RawMessage(strconv.Quote(edmDouble))
+		}
+	}
+	return json.Marshal(exported)
+}
+
+// isDoubleLiteral returns true for a JSON number the service would read as an Edm.Double.
+func isDoubleLiteral(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || (value[0] != '-' && (value[0] < '0' || value[0] > '9')) {
+		return false
+	}
+	if bytes.ContainsAny(value, ".eE") {
+		return true
+	}
+	i, err := strconv.ParseInt(string(value), 10, 64)
+	return err != nil || i < math.MinInt32 || i > math.MaxInt32
+}
+
+// ImportEntitiesOptions contains optional parameters for Client.ImportEntities
+type ImportEntitiesOptions struct {
+	// UpdateMode specifies how entities that already exist are updated. The default is UpdateModeReplace.
+	UpdateMode UpdateMode
+
+	// BulkWriterOptions configures the BulkWriter writing the entities.
+	BulkWriterOptions *BulkWriterOptions
+}
+
+// ImportEntitiesResponse contains response fields for Client.ImportEntities
+type ImportEntitiesResponse struct {
+	// Imported is the number of entities written.
+	Imported int
+
+	// Failures lists the entities the service rejected.
+	Failures []BulkWriteFailure
+}
+
+// ImportEntities reads entities in the JSON Lines format written by ExportEntities from r, and inserts them into the
+// table, or updates them as specified by options.UpdateMode, with a BulkWriter. Empty lines are skipped, and the
+// Timestamp and odata properties of the entities are ignored. Importing is idempotent, so an import that failed can
+// be run again. If a line isn't an entity with PartitionKey and RowKey properties, the function writes the entities
+// read before it, and returns an error together with the response for those entities. Specify nil for options if you
+// want to use the default options.
+//
+// For the Cosmos DB Table API, transactions are limited to 2 MB and merges are sent as POST requests, as for other
+// transactions. No other differences of the Cosmos DB Table API are accounted for, so an entity exported from one
+// service and imported into the other is only written as the service it's imported into accepts it.
+func (t *Client) ImportEntities(ctx context.Context, r io.Reader, options *ImportEntitiesOptions) (ImportEntitiesResponse, error) {
+	if options == nil {
+		options = &ImportEntitiesOptions{}
+	}
+	actionType := TransactionTypeInsertReplace
+	switch options.UpdateMode {
+	case "", UpdateModeReplace:
+	case UpdateModeMerge:
+		actionType = TransactionTypeInsertMerge
+	default:
+		return ImportEntitiesResponse{}, errInvalidUpdateMode
+	}
+
+	writer := t.NewBulkWriter(options.BulkWriterOptions)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxImportLineBytes)
+	var readErr error
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		entity, err := importEntity(scanner.Bytes())
+		if err == nil {
+			err = writer.Add(ctx, TransactionAction{ActionType: actionType, Entity: entity})
+		}
+		if err != nil {
+			readErr = fmt.Errorf("line %d: %w", line, err)
+			break
+		}
+	}
+	if readErr == nil {
+		readErr = scanner.Err()
+	}
+
+	resp, err := writer.Close(ctx)
+	if err != nil {
+		return ImportEntitiesResponse{}, err
+	}
+	return ImportEntitiesResponse{Imported: resp.Written, Failures: resp.Failures}, readErr
+}
+
+// importEntity returns the JSON of an exported entity without the properties the service sets.
+func importEntity(line []byte) ([]byte, error) {
+	var entity map[string]json.RawMessage
+	if err := json.Unmarshal(line, &entity); err != nil {
+		return nil, err
+	}
+	for name := range entity {
+		if strings.HasPrefix(name, "odata.") || name == timestamp || name == timestamp+odataTypeSuffix {
+			delete(entity, name)
+		}
+	}
+	return json.Marshal(entity)
+}
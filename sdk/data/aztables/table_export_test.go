@@ -0,0 +1,121 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/stretchr/testify/require"
+)
+
+const exportedEntities = `{"Big":"9007199254740993","Big@odata.type":"Edm.Int64","Count":3,"PartitionKey":"pk","Price":5.0,"Price@odata.type":"Edm.Double","Ratio":2.5e10,"Ratio@odata.type":"Edm.Double","RowKey":"1","Timestamp":"2022-08-01T10:00:00.1234567Z","Timestamp@odata.type":"Edm.DateTime","Wide":3000000000,"Wide@odata.type":"Edm.Double"}
+{"Name":"O'Connor","PartitionKey":"pk","RowKey":"2","When":"2008-07-10T00:00:00Z","When@odata.type":"Edm.DateTime"}
+`
+
+func newExportClient(t *testing.T, queries *[]string) *Client {
+	return newTransportFuncClient(t, func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+		*queries = append(*queries, query.Get("$filter")+"|"+query.Get("NextPartitionKey")+"|"+query.Get("NextRowKey"))
+		if query.Get("NextPartitionKey") == "" {
+			return newJSONResponse(req, http.StatusOK, `{"odata.metadata":"https://fakeaccount.table.core.windows.net/$metadata#table","value":[
+				{"odata.etag":"W/\"datetime'2022-08-01T10%3A00%3A00.1234567Z'\"","PartitionKey":"pk","RowKey":"1","Timestamp":"2022-08-01T10:00:00.1234567Z","Timestamp@odata.type":"Edm.DateTime","Price":5.0,"Count":3,"Big":"9007199254740993","Big@odata.type":"Edm.Int64","Ratio":2.5e10,"Wide":3000000000}
+			]}`, http.Header{
+				"X-Ms-Continuation-Nextpartitionkey": []string{"1!4!cGs-"},
+				"X-Ms-Continuation-Nextrowkey":       []string{"1!4!Mg--"},
+			}), nil
+		}
+		return newJSONResponse(req, http.StatusOK, `{"value":[
+			{"odata.etag":"W/\"2\"","PartitionKey":"pk","RowKey":"2","Name":"O'Connor","When":"2008-07-10T00:00:00Z","When@odata.type":"Edm.DateTime"}
+		]}`, nil), nil
+	})
+}
+
+func TestExportEntities(t *testing.T) {
+	var queries []string
+	client := newExportClient(t, &queries)
+	var checkpoints []ExportCheckpoint
+	out := &bytes.Buffer{}
+	resp, err := client.ExportEntities(ctx, out, &ExportEntitiesOptions{
+		Filter: to.Ptr("PartitionKey eq 'pk'"),
+		Checkpoint: func(c ExportCheckpoint) error {
+			checkpoints = append(checkpoints, c)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Exported)
+	require.Equal(t, exportedEntities, out.String())
+	require.Equal(t, []string{"PartitionKey eq 'pk'||", "PartitionKey eq 'pk'|1!4!cGs-|1!4!Mg--"}, queries)
+	require.Equal(t, []ExportCheckpoint{
+		{Exported: 1, NextPartitionKey: to.Ptr("1!4!cGs-"), NextRowKey: to.Ptr("1!4!Mg--")},
+		{Exported: 2},
+	}, checkpoints)
+
+	// resume from the first checkpoint
+	queries = nil
+	out.Reset()
+	resp, err = client.ExportEntities(ctx, out, &ExportEntitiesOptions{
+		NextPartitionKey: checkpoints[0].NextPartitionKey,
+		NextRowKey:       checkpoints[0].NextRowKey,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Exported)
+	require.Equal(t, strings.SplitAfter(exportedEntities, "\n")[1], out.String())
+	require.Equal(t, []string{"|1!4!cGs-|1!4!Mg--"}, queries)
+
+	stop := errors.New("stop")
+	_, err = client.ExportEntities(ctx, out, &ExportEntitiesOptions{
+		Checkpoint: func(ExportCheckpoint) error { return stop },
+	})
+	require.ErrorIs(t, err, stop)
+}
+
+func TestImportEntities(t *testing.T) {
+	table := &fakeBatchTable{entities: map[string]map[string]interface{}{}}
+	client := newTransportFuncClient(t, table.do)
+	resp, err := client.ImportEntities(ctx, strings.NewReader(exportedEntities+"\n"), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Imported)
+	require.Empty(t, resp.Failures)
+	require.Equal(t, map[string]interface{}{
+		"PartitionKey":     "pk",
+		"RowKey":           "1",
+		"Big":              "9007199254740993",
+		"Big@odata.type":   "Edm.Int64",
+		"Count":            float64(3),
+		"Price":            float64(5),
+		"Price@odata.type": "Edm.Double",
+		"Ratio":            2.5e10,
+		"Ratio@odata.type": "Edm.Double",
+		"Wide":             float64(3000000000),
+		"Wide@odata.type":  "Edm.Double",
+	}, table.entities["pk/1"])
+	require.Equal(t, "O'Connor", table.entities["pk/2"]["Name"])
+
+	_, err = client.ImportEntities(ctx, strings.NewReader(exportedEntities), &ImportEntitiesOptions{UpdateMode: "invalid"})
+	require.ErrorIs(t, err, errInvalidUpdateMode)
+
+	table = &fakeBatchTable{entities: map[string]map[string]interface{}{}}
+	client = newTransportFuncClient(t, table.do)
+	_, err = client.ImportEntities(ctx, strings.NewReader(exportedEntities+`{"RowKey":"3"}`+"\n"), &ImportEntitiesOptions{UpdateMode: UpdateModeMerge})
+	require.ErrorIs(t, err, errPartitionKeyRowKeyError)
+	require.Contains(t, err.Error(), "line 3")
+	require.Len(t, table.entities, 2)
+
+	// a malformed line stops the import, and the response counts the entities written before it
+	table = &fakeBatchTable{entities: map[string]map[string]interface{}{}}
+	client = newTransportFuncClient(t, table.do)
+	lines := strings.SplitAfter(exportedEntities, "\n")
+	resp, err = client.ImportEntities(ctx, strings.NewReader(lines[0]+"{not json\n"+lines[1]), nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 2")
+	require.Equal(t, 1, resp.Imported)
+	require.Empty(t, resp.Failures)
+	require.Len(t, table.entities, 1)
+}
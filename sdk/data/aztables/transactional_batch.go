@@ -14,6 +14,8 @@ import (
 	"net/http"
 	"net/textproto"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
@@ -174,7 +176,7 @@ func buildTransactionResponse(req *policy.Request, resp *http.Response, itemCoun
 			return TransactionResponse{}, err
 		}
 		if r.StatusCode >= 400 {
-			return TransactionResponse{}, runtime.NewResponseError(resp)
+			return TransactionResponse{}, newTransactionError(runtime.NewResponseError(resp), r, itemCount)
 		}
 		i++
 	}
@@ -182,6 +184,55 @@ func buildTransactionResponse(req *policy.Request, resp *http.Response, itemCoun
 	return TransactionResponse{}, nil
 }
 
+// transactionError is the error for a transaction the service rejected because of one of its actions. It unwraps
+// to the *azcore.ResponseError for the whole transaction and records the response for the rejected action.
+type transactionError struct {
+	err error
+
+	// actionIndex is the index of the rejected action in the transaction, or -1 if the service didn't identify it.
+	actionIndex int
+
+	// actionErr is the *azcore.ResponseError for the rejected action.
+	actionErr error
+}
+
+func (e *transactionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transactionError) Unwrap() error {
+	return e.err
+}
+
+// newTransactionError creates a transactionError from the inner response for a rejected action. Storage prefixes
+// the error message with the index of the action, as in "1:The specified entity already exists.".
+func newTransactionError(err error, inner *http.Response, itemCount int) error {
+	te := &transactionError{err: err, actionIndex: -1, actionErr: runtime.NewResponseError(inner)}
+	if itemCount == 1 {
+		te.actionIndex = 0
+	}
+	body, payloadErr := runtime.Payload(inner)
+	if payloadErr != nil {
+		return te
+	}
+	var odataErr struct {
+		Error struct {
+			Message struct {
+				Value string `json:"value"`
+			} `json:"message"`
+		} `json:"odata.error"`
+	}
+	if json.Unmarshal(body, &odataErr) != nil {
+		return te
+	}
+	if prefix, _, ok := strings.Cut(odataErr.Error.Message.Value, ":"); ok {
+		if i, err := strconv.Atoi(prefix); err == nil && i >= 0 && i < itemCount {
+			te.actionIndex = i
+		}
+	}
+	return te
+}
+
 func getBoundaryName(bytesBody []byte) string {
 	end := bytes.Index(bytesBody, []byte("\n"))
 	if end > 0 && bytesBody[end-1] == '\r' {
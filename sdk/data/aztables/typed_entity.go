@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package aztables
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// AddEntityAs adds an entity mapped from a struct to the table, as described for MarshalEntity. This method returns an
+// error if an entity with the same PartitionKey and RowKey already exists in the table. If the service returns a
+// non-successful HTTP status code, the function returns an *azcore.ResponseError type. Specify nil for options if you
+// want to use the default options.
+func AddEntityAs[T any](ctx context.Context, client *Client, entity T, options *AddEntityOptions) (AddEntityResponse, error) {
+	marshalled, err := MarshalEntity(entity)
+	if err != nil {
+		return AddEntityResponse{}, err
+	}
+	return client.AddEntity(ctx, marshalled, options)
+}
+
+// UpsertEntityAs inserts an entity mapped from a struct, as described for MarshalEntity, if it does not already exist in
+// the table. If the entity does exist, the entity is replaced or merged as specified by options.UpdateMode. If the service
+// returns a non-successful HTTP status code, the function returns an *azcore.ResponseError type. Specify nil for options
+// if you want to use the default options.
+func UpsertEntityAs[T any](ctx context.Context, client *Client, entity T, options *UpsertEntityOptions) (UpsertEntityResponse, error) {
+	marshalled, err := MarshalEntity(entity)
+	if err != nil {
+		return UpsertEntityResponse{}, err
+	}
+	return client.UpsertEntity(ctx, marshalled, options)
+}
+
+// UpdateEntityAs updates an existing entity with the properties mapped from a struct, as described for MarshalEntity.
+// The entity is replaced or merged as specified by options.UpdateMode. If the service returns a non-successful HTTP
+// status code, the function returns an *azcore.ResponseError type. Specify nil for options if you want to use the
+// default options.
+func UpdateEntityAs[T any](ctx context.Context, client *Client, entity T, options *UpdateEntityOptions) (UpdateEntityResponse, error) {
+	marshalled, err := MarshalEntity(entity)
+	if err != nil {
+		return UpdateEntityResponse{}, err
+	}
+	return client.UpdateEntity(ctx, marshalled, options)
+}
+
+// GetEntityAsResponse contains response fields for GetEntityAs
+type GetEntityAsResponse[T any] struct {
+	// ETag contains the information returned from the ETag header response.
+	ETag azcore.ETag
+
+	// The table entity, unmarshalled as described for UnmarshalEntity.
+	Value T
+}
+
+// GetEntityAs retrieves a specific entity from the service using the specified partitionKey and rowKey values, and
+// unmarshals it into a T, which must be a struct, as described for UnmarshalEntity. If no entity is available it returns
+// an error. If the service returns a non-successful HTTP status code, the function returns an *azcore.ResponseError type.
+// Specify nil for options if you want to use the default options.
+func GetEntityAs[T any](ctx context.Context, client *Client, partitionKey string, rowKey string, options *GetEntityOptions) (GetEntityAsResponse[T], error) {
+	resp, err := client.GetEntity(ctx, partitionKey, rowKey, options)
+	if err != nil {
+		return GetEntityAsResponse[T]{}, err
+	}
+	var entity map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Value, &entity); err != nil {
+		return GetEntityAsResponse[T]{}, err
+	}
+	if _, ok := entity[etagOData]; !ok && resp.ETag != "" {
+		etag, err := json.Marshal(string(resp.ETag))
+		if err != nil {
+			return GetEntityAsResponse[T]{}, err
+		}
+		entity[etagOData] = etag
+	}
+	var value T
+	if err := unmarshalEntity(entity, &value); err != nil {
+		return GetEntityAsResponse[T]{}, err
+	}
+	return GetEntityAsResponse[T]{ETag: resp.ETag, Value: value}, nil
+}
+
+// ListEntitiesAsResponse contains response fields for the pager returned by NewListEntitiesAsPager
+type ListEntitiesAsResponse[T any] struct {
+	// NextPartitionKey contains the information returned from the x-ms-continuation-NextPartitionKey header response.
+	NextPartitionKey *string
+
+	// NextRowKey contains the information returned from the x-ms-continuation-NextRowKey header response.
+	NextRowKey *string
+
+	// List of table entities, unmarshalled as described for UnmarshalEntity.
+	Entities []T
+}
+
+// NewListEntitiesAsPager queries the entities using the specified ListEntitiesOptions, as NewListEntitiesPager does, and
+// unmarshals them into values of T, which must be a struct, as described for UnmarshalEntity. Use nil for listOptions if
+// you want to use the default options.
+func NewListEntitiesAsPager[T any](client *Client, listOptions *ListEntitiesOptions) *runtime.Pager[ListEntitiesAsResponse[T]] {
+	pager := client.NewListEntitiesPager(listOptions)
+	return runtime.NewPager(runtime.PagingHandler[ListEntitiesAsResponse[T]]{
+		More: func(ListEntitiesAsResponse[T]) bool {
+			return pager.More()
+		},
+		Fetcher: func(ctx context.Context, _ *ListEntitiesAsResponse[T]) (ListEntitiesAsResponse[T], error) {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return ListEntitiesAsResponse[T]{}, err
+			}
+			entities := make([]T, len(page.Entities))
+			for i, e := range page.Entities {
+				if err := UnmarshalEntity(e, &entities[i]); err != nil {
+					return ListEntitiesAsResponse[T]{}, err
+				}
+			}
+			return ListEntitiesAsResponse[T]{
+				NextPartitionKey: page.NextPartitionKey,
+				NextRowKey:       page.NextRowKey,
+				Entities:         entities,
+			}, nil
+		},
+	})
+}